@@ -0,0 +1,443 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ways an employee acknowledges a schedule.
+const (
+	ackViaLink  = "link"
+	ackViaSlack = "slack"
+	ackViaAPI   = "api"
+)
+
+// AcknowledgmentConfig sets how employees acknowledge published schedules.
+// ACK_LINK_SECRET and SLACK_SIGNING_SECRET take precedence over the config
+// file.
+type AcknowledgmentConfig struct {
+	// Secret signs the acknowledgment links sent to employees.
+	Secret string `json:"secret,omitempty"`
+	// BaseURL is the address employees reach the server at, such as
+	// "https://scheduler.example.com", for the links.
+	BaseURL string `json:"base_url,omitempty"`
+	// SlackSigningSecret verifies the clicks on Slack acknowledge buttons.
+	SlackSigningSecret string `json:"slack_signing_secret,omitempty"`
+}
+
+func acknowledgmentSettings(cfg Config) AcknowledgmentConfig {
+	ac := cfg.Acknowledgments
+	if v := os.Getenv("ACK_LINK_SECRET"); v != "" {
+		ac.Secret = v
+	}
+	if v := os.Getenv("SLACK_SIGNING_SECRET"); v != "" {
+		ac.SlackSigningSecret = v
+	}
+	ac.BaseURL = strings.TrimSuffix(ac.BaseURL, "/")
+	return ac
+}
+
+// Acknowledgment records when and how an employee acknowledged a schedule.
+type Acknowledgment struct {
+	At  time.Time `json:"at"`
+	Via string    `json:"via"`
+}
+
+// ackToken signs an employee's acknowledgment link for a job.
+func ackToken(secret, jobID, employee string) string {
+	return feedToken(secret, "ack/"+jobID, employee)
+}
+
+// ackLink returns an employee's acknowledgment link for a job, or "" when
+// links aren't configured.
+func (ac AcknowledgmentConfig) ackLink(jobID, employee string) string {
+	if ac.Secret == "" || ac.BaseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/ack/%s/%s?token=%s", ac.BaseURL, url.PathEscape(jobID), url.PathEscape(employee), ackToken(ac.Secret, jobID, employee))
+}
+
+// scheduleEmployees returns the employees on a job's schedule, in order.
+func scheduleEmployees(jobID string) ([]string, error) {
+	entries, err := loadScheduleDir(filepath.Join(dataDir(), "schedules", jobID))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, obj := range entries {
+		if !slices.Contains(names, obj["Employee"]) {
+			names = append(names, obj["Employee"])
+		}
+	}
+	return names, nil
+}
+
+// acknowledge records that an employee acknowledged a published job's
+// schedule. Acknowledging again keeps the first acknowledgment.
+func (q *jobQueue) acknowledge(id, employee, via string) (bool, error) {
+	names, err := scheduleEmployees(id)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return false, nil
+	}
+	if job.Status != jobPublished {
+		return true, fmt.Errorf("job %s is %s, not published", id, job.Status)
+	}
+	if err != nil {
+		return true, err
+	}
+	if !slices.Contains(names, employee) {
+		return true, fmt.Errorf("%s is not on the schedule of job %s", employee, id)
+	}
+	if _, ok := job.Acknowledgments[employee]; ok {
+		return true, nil
+	}
+	if job.Acknowledgments == nil {
+		job.Acknowledgments = make(map[string]Acknowledgment)
+	}
+	job.Acknowledgments[employee] = Acknowledgment{At: time.Now().UTC(), Via: via}
+	log.Printf("%s acknowledged the schedule of job %s by %s", employee, id, via)
+	return true, q.save(job)
+}
+
+// requestAcknowledgments asks the employees on a newly published job's
+// schedule to acknowledge it: with a button posted to their Slack webhook
+// when Slack clicks can be verified, and otherwise with their link by Slack
+// or email. Text messages carry the link through textPublished.
+func requestAcknowledgments(job Job) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config for job %s acknowledgments: %v", job.ID, err)
+		return
+	}
+	ac := acknowledgmentSettings(cfg)
+	if ac.SlackSigningSecret == "" && (ac.Secret == "" || ac.BaseURL == "") {
+		return
+	}
+	names, err := scheduleEmployees(job.ID)
+	if err != nil {
+		log.Printf("Error loading schedule for job %s acknowledgments: %v", job.ID, err)
+		return
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		log.Printf("Error loading roster for job %s acknowledgments: %v", job.ID, err)
+		return
+	}
+	rc := reminderSettings(cfg)
+	for _, e := range roster {
+		if !slices.Contains(names, e.Name) {
+			continue
+		}
+		link := ac.ackLink(job.ID, e.Name)
+		text := fmt.Sprintf("Your schedule (job %s) is published. Please acknowledge it", job.ID)
+		if e.SlackWebhook != "" && (ac.SlackSigningSecret != "" || link != "") {
+			var err error
+			if ac.SlackSigningSecret != "" {
+				err = postSlack(e.SlackWebhook, ackSlackMessage(job.ID, e.Name, text))
+			} else {
+				err = sendSlack(e.SlackWebhook, text+": "+link)
+			}
+			if err != nil {
+				log.Printf("Error asking %s to acknowledge job %s: %v", e.Name, job.ID, err)
+			}
+		}
+		if e.Email != "" && link != "" {
+			if err := sendEmail(rc.SMTP, []string{e.Email}, "Please acknowledge your schedule", text+":\r\n\r\n"+link); err != nil {
+				log.Printf("Error asking %s to acknowledge job %s: %v", e.Name, job.ID, err)
+			}
+		}
+	}
+}
+
+// ackButtonValue is the value of a Slack acknowledge button.
+type ackButtonValue struct {
+	Job      string `json:"job"`
+	Employee string `json:"employee"`
+}
+
+// ackSlackMessage builds a Slack message with an acknowledge button.
+func ackSlackMessage(jobID, employee, text string) map[string]any {
+	value, _ := json.Marshal(ackButtonValue{Job: jobID, Employee: employee})
+	return map[string]any{
+		"text": text,
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text + "."}},
+			map[string]any{"type": "actions", "elements": []any{map[string]any{
+				"type":      "button",
+				"action_id": "acknowledge",
+				"style":     "primary",
+				"text":      map[string]string{"type": "plain_text", "text": "Acknowledge"},
+				"value":     string(value),
+			}}},
+		},
+	}
+}
+
+// validSlackSignature checks the X-Slack-Signature of a request from Slack,
+// rejecting requests older than five minutes so they can't be replayed.
+func validSlackSignature(secret string, body []byte, timestamp, header string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || now.Sub(time.Unix(ts, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	sig, ok := strings.CutPrefix(header, "v0=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// handleSlackActions receives the clicks on Slack acknowledge, open shift
+// claim, overtime accept, and plan approval buttons.
+// Slack authenticates them by signing the request.
+func (s *server) handleSlackActions(w http.ResponseWriter, r *http.Request) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	secret := acknowledgmentSettings(cfg).SlackSigningSecret
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "Slack signing secret not configured")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validSlackSignature(secret, body, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), time.Now()) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var payload struct {
+		ResponseURL string `json:"response_url"`
+		User        struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	for _, action := range payload.Actions {
+		var text string
+		replace := true
+		switch action.ActionID {
+		case "acknowledge":
+			var v ackButtonValue
+			if err := json.Unmarshal([]byte(action.Value), &v); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid button value: %v", err))
+				return
+			}
+			text = fmt.Sprintf("Thanks, %s. Your schedule (job %s) is acknowledged.", v.Employee, v.Job)
+			if found, err := s.queue.acknowledge(v.Job, v.Employee, ackViaSlack); !found {
+				text = fmt.Sprintf("Job %s no longer exists.", v.Job)
+			} else if err != nil {
+				text = fmt.Sprintf("Could not acknowledge the schedule: %v", err)
+			}
+		case "claim_shift":
+			// Open shifts are announced to a channel, so the claimant is
+			// whoever pressed the button, and only they see the reply.
+			replace = false
+			employee, ok := slackEmployee(payload.User.ID)
+			if !ok {
+				text = "Your Slack account isn't linked to an employee on the roster."
+				break
+			}
+			slot, found, err := claimOpenShift(action.Value, employee, ackViaSlack, time.Now())
+			if !found {
+				err = fmt.Errorf("open shift %s no longer exists", action.Value)
+			}
+			text = claimText(slot, employee, err)
+		case "accept_overtime":
+			employee, ok := slackEmployee(payload.User.ID)
+			if !ok {
+				text = "Your Slack account isn't linked to an employee on the roster."
+				break
+			}
+			offer, found, err := acceptOvertime(action.Value, employee, ackViaSlack, time.Now())
+			if !found {
+				err = fmt.Errorf("overtime offer %s no longer exists", action.Value)
+			}
+			text = acceptText(offer, employee, err)
+		case "approve_plan", "reject_plan":
+			// Applying a plan runs hooks that may outlast Slack's
+			// three-second deadline, so the reply follows once it's done.
+			go func() {
+				text, replace := reviewPlan(action.Value, payload.User.ID, action.ActionID == "approve_plan")
+				replySlack(payload.ResponseURL, text, replace)
+			}()
+			continue
+		default:
+			continue
+		}
+		go replySlack(payload.ResponseURL, text, replace)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// replySlack answers a button click. Slack updates the message through its
+// response URL, not the response to the click's request; without replace
+// only the user who clicked sees the reply.
+func replySlack(responseURL, text string, replace bool) {
+	if responseURL == "" {
+		return
+	}
+	msg := map[string]any{"replace_original": replace, "text": text}
+	if !replace {
+		msg["response_type"] = "ephemeral"
+	}
+	if err := postSlack(responseURL, msg); err != nil {
+		log.Printf("Error updating Slack message: %v", err)
+	}
+}
+
+// handleAckLink records the acknowledgment of an employee who clicked their
+// link. Links are authenticated by their token.
+func (s *server) handleAckLink(w http.ResponseWriter, r *http.Request) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	secret := acknowledgmentSettings(cfg).Secret
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "acknowledgment secret not configured")
+		return
+	}
+	id, employee := r.PathValue("id"), r.PathValue("employee")
+	if !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(ackToken(secret, id, employee))) {
+		writeError(w, http.StatusUnauthorized, "invalid acknowledgment token")
+		return
+	}
+	found, err := s.queue.acknowledge(id, employee, ackViaLink)
+	if !found {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Thanks, %s. Your schedule is acknowledged.\n", employee)
+}
+
+// handleAcknowledge records an acknowledgment made through the API, such as
+// from an intranet page.
+func (s *server) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Employee string `json:"employee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Employee == "" {
+		writeError(w, http.StatusBadRequest, `body must be {"employee": "<name>"}`)
+		return
+	}
+	found, err := s.queue.acknowledge(r.PathValue("id"), req.Employee, ackViaAPI)
+	if !found {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.handleAcknowledgments(w, r)
+}
+
+// ackReport lists who has and hasn't acknowledged a job's schedule.
+type ackReport struct {
+	Job          string                    `json:"job"`
+	Starts       string                    `json:"starts,omitempty"`
+	Acknowledged map[string]Acknowledgment `json:"acknowledged"`
+	Pending      []string                  `json:"pending"`
+	// Links are the pending employees' acknowledgment links, to follow up
+	// with.
+	Links map[string]string `json:"links,omitempty"`
+}
+
+// handleAcknowledgments reports who hasn't acknowledged a job's schedule,
+// for planners to follow up with before the week starts.
+func (s *server) handleAcknowledgments(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.queue.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if job.Status != jobPublished {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job %s is %s, not published", job.ID, job.Status))
+		return
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entries, err := loadScheduleDir(filepath.Join(dataDir(), "schedules", job.ID))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ac := acknowledgmentSettings(cfg)
+	report := ackReport{Job: job.ID, Acknowledged: make(map[string]Acknowledgment), Pending: []string{}}
+	var starts time.Time
+	now := time.Now()
+	for _, obj := range entries {
+		for key := range obj {
+			if d, ok := labelDate(key, now); ok && (starts.IsZero() || d.Before(starts)) {
+				starts = d
+			}
+		}
+		name := obj["Employee"]
+		if a, ok := job.Acknowledgments[name]; ok {
+			report.Acknowledged[name] = a
+		} else if !slices.Contains(report.Pending, name) {
+			report.Pending = append(report.Pending, name)
+			if link := ac.ackLink(job.ID, name); link != "" {
+				if report.Links == nil {
+					report.Links = make(map[string]string)
+				}
+				report.Links[name] = link
+			}
+		}
+	}
+	if !starts.IsZero() {
+		report.Starts = starts.Format(time.DateOnly)
+	}
+	sort.Strings(report.Pending)
+	writeJSON(w, http.StatusOK, report)
+}
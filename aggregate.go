@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// aggregatesVersion is bumped whenever Aggregates or the way records are
+// parsed changes, so stale cache entries are ignored.
+const aggregatesVersion = 9
+
+// Aggregates summarises the call records of one or more input files. It is
+// everything the forecast needs, so cached aggregates spare re-parsing files
+// that have been seen before.
+type Aggregates struct {
+	Version int `json:"version"`
+	Rows    int `json:"rows"`
+	// Rejects are the rows that could not be parsed and were skipped.
+	Rejects []rejectedRow `json:"rejects,omitempty"`
+	// Duplicates and Overlapping count the records dropped by deduplication.
+	Duplicates  int `json:"duplicates"`
+	Overlapping int `json:"overlapping"`
+	// DayCounts maps day of month to calls.
+	DayCounts map[int]int `json:"day_counts"`
+	// HourCounts maps hour of day to calls.
+	HourCounts map[int]int `json:"hour_counts"`
+	// HalfHourCounts maps half hour of day, 0 for 00:00 to 47 for 23:30,
+	// to calls.
+	HalfHourCounts map[int]int `json:"half_hour_counts"`
+	// DateCounts maps calendar dates (2006-01-02) to calls.
+	DateCounts map[string]int `json:"date_counts"`
+	// WaitSeconds and TalkedSeconds are the summed durations.
+	WaitSeconds   float64 `json:"wait_seconds"`
+	TalkedSeconds float64 `json:"talked_seconds"`
+	// Answered counts the calls with talk time.
+	Answered int `json:"answered"`
+}
+
+func newAggregates() Aggregates {
+	return Aggregates{
+		Version:        aggregatesVersion,
+		DayCounts:      make(map[int]int),
+		HourCounts:     make(map[int]int),
+		HalfHourCounts: make(map[int]int),
+		DateCounts:     make(map[string]int),
+	}
+}
+
+// aggregateRecords builds the aggregates of a set of records.
+func aggregateRecords(records []Record) Aggregates {
+	agg := newAggregates()
+	for _, rec := range records {
+		agg.Rows++
+		agg.DayCounts[rec.CalledTime.Day()]++
+		agg.HourCounts[rec.CalledTime.Hour()]++
+		agg.HalfHourCounts[rec.CalledTime.Hour()*2+rec.CalledTime.Minute()/30]++
+		agg.DateCounts[rec.CalledTime.Format("2006-01-02")]++
+		agg.WaitSeconds += rec.WaitDuration
+		agg.TalkedSeconds += rec.TalkedDuration
+		if rec.TalkedDuration > 0 {
+			agg.Answered++
+		}
+	}
+	return agg
+}
+
+// merge adds other into a.
+func (a *Aggregates) merge(other Aggregates) {
+	a.Rows += other.Rows
+	a.Rejects = append(a.Rejects, other.Rejects...)
+	a.Duplicates += other.Duplicates
+	a.Overlapping += other.Overlapping
+	for k, v := range other.DayCounts {
+		a.DayCounts[k] += v
+	}
+	for k, v := range other.HourCounts {
+		a.HourCounts[k] += v
+	}
+	for k, v := range other.HalfHourCounts {
+		a.HalfHourCounts[k] += v
+	}
+	for k, v := range other.DateCounts {
+		a.DateCounts[k] += v
+	}
+	a.WaitSeconds += other.WaitSeconds
+	a.TalkedSeconds += other.TalkedSeconds
+	a.Answered += other.Answered
+}
+
+func aggregateCacheDir() string {
+	return filepath.Join(dataDir(), "cache", "aggregates")
+}
+
+// fileHash returns the hex SHA-256 of a file's contents.
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening CSV file: %w", err)
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("error reading CSV file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadAggregates returns the combined aggregates of the inputs, with
+// duplicate records removed using the given strategy. Inputs are files read
+// with mapping, or API sources (see parseAPISource), which are fetched on
+// every run and deduplicated on their own.
+func loadAggregates(ctx context.Context, inputs []string, dedup string, mapping ColumnMapping) (Aggregates, error) {
+	if err := validDedupStrategy(dedup); err != nil {
+		return Aggregates{}, err
+	}
+
+	var paths []string
+	var sources []apiSource
+	for _, input := range inputs {
+		src, ok, err := parseAPISource(input)
+		if err != nil {
+			return Aggregates{}, err
+		}
+		if ok {
+			sources = append(sources, src)
+		} else {
+			paths = append(paths, input)
+		}
+	}
+
+	total := newAggregates()
+	if len(paths) > 0 {
+		agg, err := loadFileAggregates(paths, dedup, mapping)
+		if err != nil {
+			return total, err
+		}
+		total.merge(agg)
+	}
+	for _, src := range sources {
+		records, err := src.fetch(ctx)
+		if err != nil {
+			return total, err
+		}
+		if err := appendDemand(src.Connector, "", records); err != nil {
+			log.Printf("Error adding %s records to the demand store: %v", src.Connector, err)
+		}
+		log.Printf("Fetched %d call records from %s", len(records), src.Connector)
+		records, exact, overlapping := dedupRecords(records, dedup)
+		agg := aggregateRecords(records)
+		agg.Duplicates, agg.Overlapping = exact, overlapping
+		total.merge(agg)
+	}
+	return total, nil
+}
+
+// loadFileAggregates returns the combined aggregates of the input files.
+// Aggregates are cached by content hash, mapping, and strategy, so files
+// seen before are not parsed again.
+//
+// Exports with overlapping date ranges repeat calls across files, so when
+// deduplicating several files they are parsed and cached together; adding a
+// file then re-reads the set. With dedup set to "none" every file is cached
+// on its own and only new or changed files are parsed.
+func loadFileAggregates(paths []string, dedup string, mapping ColumnMapping) (Aggregates, error) {
+	hashes := make([]string, len(paths))
+	for i, path := range paths {
+		hash, err := fileHash(path)
+		if err != nil {
+			return Aggregates{}, err
+		}
+		hashes[i] = hash
+	}
+
+	suffix := "-" + mapping.fingerprint() + "-" + dedup
+	if dedup != dedupNone && len(paths) > 1 {
+		sorted := append([]string(nil), hashes...)
+		sort.Strings(sorted)
+		sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+		return cachedAggregates(hex.EncodeToString(sum[:])+suffix, strings.Join(paths, ", "), func() ([]Record, []rejectedRow, error) {
+			var all []Record
+			var rejects []rejectedRow
+			for i, path := range paths {
+				records, rejected, err := ingestFile(path, hashes[i], mapping)
+				if err != nil {
+					return nil, nil, err
+				}
+				all = append(all, records...)
+				rejects = append(rejects, rejected...)
+			}
+			return all, rejects, nil
+		}, dedup)
+	}
+
+	total := newAggregates()
+	for i, path := range paths {
+		agg, err := cachedAggregates(hashes[i]+suffix, path, func() ([]Record, []rejectedRow, error) {
+			return ingestFile(path, hashes[i], mapping)
+		}, dedup)
+		if err != nil {
+			return total, err
+		}
+		total.merge(agg)
+	}
+	return total, nil
+}
+
+// cachedAggregates returns the aggregates stored under key, or parses the
+// records with load, deduplicates them, and stores the result.
+func cachedAggregates(key, name string, load func() ([]Record, []rejectedRow, error), dedup string) (Aggregates, error) {
+	cachePath := filepath.Join(aggregateCacheDir(), key+".json")
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var agg Aggregates
+		if err := json.Unmarshal(data, &agg); err == nil && agg.Version == aggregatesVersion {
+			log.Printf("Using cached aggregates for %s", name)
+			return agg, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error reading aggregate cache: %v", err)
+	}
+
+	records, rejects, err := load()
+	if err != nil {
+		return Aggregates{}, err
+	}
+	records, exact, overlapping := dedupRecords(records, dedup)
+	if exact+overlapping > 0 {
+		log.Printf("Dropped %d duplicate and %d overlapping records from %s", exact, overlapping, name)
+	}
+	agg := aggregateRecords(records)
+	agg.Rejects = rejects
+	agg.Duplicates, agg.Overlapping = exact, overlapping
+
+	// A cache that can't be written only costs time on the next run.
+	if err := os.MkdirAll(aggregateCacheDir(), 0o755); err != nil {
+		log.Printf("Error creating aggregate cache: %v", err)
+		return agg, nil
+	}
+	data, err := json.Marshal(agg)
+	if err != nil {
+		return agg, nil
+	}
+	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+		log.Printf("Error writing aggregate cache: %v", err)
+	}
+	return agg, nil
+}
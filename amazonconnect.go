@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConnectConfig holds the Amazon Connect instance and AWS credentials. The
+// standard AWS_* variables and CONNECT_INSTANCE_ID take precedence over the
+// config file.
+type ConnectConfig struct {
+	InstanceID      string `json:"instance_id"`
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token,omitempty"`
+	// Endpoint overrides https://connect.<region>.amazonaws.com.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func connectSettings(cfg Config) ConnectConfig {
+	var cc ConnectConfig
+	if cfg.AmazonConnect != nil {
+		cc = *cfg.AmazonConnect
+	}
+	for _, v := range []struct {
+		field *string
+		env   []string
+	}{
+		{&cc.InstanceID, []string{"CONNECT_INSTANCE_ID"}},
+		{&cc.Region, []string{"AWS_REGION", "AWS_DEFAULT_REGION"}},
+		{&cc.AccessKeyID, []string{"AWS_ACCESS_KEY_ID"}},
+		{&cc.SecretAccessKey, []string{"AWS_SECRET_ACCESS_KEY"}},
+		{&cc.SessionToken, []string{"AWS_SESSION_TOKEN"}},
+	} {
+		for _, name := range v.env {
+			if value := os.Getenv(name); value != "" {
+				*v.field = value
+				break
+			}
+		}
+	}
+	if cc.Endpoint == "" && cc.Region != "" {
+		cc.Endpoint = fmt.Sprintf("https://connect.%s.amazonaws.com", cc.Region)
+	}
+	return cc
+}
+
+// searchContactsPage is the part of a SearchContacts response that is used.
+// Timestamps are epoch seconds.
+type searchContactsPage struct {
+	Contacts []struct {
+		ID                  string   `json:"Id"`
+		InitiationTimestamp float64  `json:"InitiationTimestamp"`
+		DisconnectTimestamp *float64 `json:"DisconnectTimestamp"`
+		QueueInfo           *struct {
+			EnqueueTimestamp *float64 `json:"EnqueueTimestamp"`
+		} `json:"QueueInfo"`
+		AgentInfo *struct {
+			ConnectedToAgentTimestamp *float64 `json:"ConnectedToAgentTimestamp"`
+		} `json:"AgentInfo"`
+	} `json:"Contacts"`
+	NextToken string `json:"NextToken"`
+}
+
+func epochTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+}
+
+// fetchConnectContacts lists the voice contacts initiated in [start, end)
+// through the Amazon Connect SearchContacts API.
+func fetchConnectContacts(ctx context.Context, cfg Config, start, end time.Time) ([]Record, error) {
+	cc := connectSettings(cfg)
+	if cc.InstanceID == "" || cc.Region == "" || cc.AccessKeyID == "" || cc.SecretAccessKey == "" {
+		return nil, errors.New("CONNECT_INSTANCE_ID, AWS_REGION, AWS_ACCESS_KEY_ID, and AWS_SECRET_ACCESS_KEY not set")
+	}
+
+	var records []Record
+	nextToken := ""
+	for {
+		body := map[string]any{
+			"InstanceId": cc.InstanceID,
+			"TimeRange": map[string]any{
+				"Type":      "INITIATION_TIMESTAMP",
+				"StartTime": start.Unix(),
+				"EndTime":   end.Unix(),
+			},
+			"SearchCriteria": map[string]any{"Channels": []string{"VOICE"}},
+			"MaxResults":     100,
+		}
+		if nextToken != "" {
+			body["NextToken"] = nextToken
+		}
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.Endpoint+"/search-contacts", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		signAWSRequest(req, payload, cc, "connect", time.Now())
+
+		var page searchContactsPage
+		if err := doJSON(connectorClient, req, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contacts {
+			rec := Record{CallID: c.ID, CalledTime: epochTime(c.InitiationTimestamp)}
+			if c.DisconnectTimestamp != nil {
+				rec.HangupTime = epochTime(*c.DisconnectTimestamp)
+			}
+			if c.AgentInfo != nil && c.AgentInfo.ConnectedToAgentTimestamp != nil {
+				rec.AnsweredTime = epochTime(*c.AgentInfo.ConnectedToAgentTimestamp)
+				if c.QueueInfo != nil && c.QueueInfo.EnqueueTimestamp != nil {
+					rec.WaitDuration = rec.AnsweredTime.Sub(epochTime(*c.QueueInfo.EnqueueTimestamp)).Seconds()
+				}
+				if !rec.HangupTime.IsZero() {
+					rec.TalkedDuration = rec.HangupTime.Sub(rec.AnsweredTime).Seconds()
+				}
+			}
+			records = append(records, rec)
+		}
+		if page.NextToken == "" {
+			return records, nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers to req.
+func signAWSRequest(req *http.Request, payload []byte, cc ConnectConfig, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if cc.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cc.SessionToken)
+	}
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, cc.Region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+	key := hmacSHA256([]byte("AWS4"+cc.SecretAccessKey), date)
+	key = hmacSHA256(key, cc.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cc.AccessKeyID, scope, signedHeaders, signature))
+}
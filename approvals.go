@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// slackSectionLimit is the longest text Slack shows in a section block.
+const slackSectionLimit = 3000
+
+// ApprovalConfig lets planners approve planned runs from Slack instead of
+// running apply themselves.
+type ApprovalConfig struct {
+	// SlackWebhook is where plans are posted with Approve and Reject
+	// buttons.
+	SlackWebhook string `json:"slack_webhook,omitempty"`
+	// Approvers are the Slack member IDs allowed to press them, such as
+	// "U024BE7LH".
+	Approvers []string `json:"approvers,omitempty"`
+}
+
+// requestApproval posts the plan of a run to the approvals channel, if one
+// is configured. Failures are logged, since the plan can still be applied
+// from the command line.
+func requestApproval(run *Run, plan string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config for run %s approval: %v", run.ID, err)
+		return
+	}
+	if cfg.Approvals.SlackWebhook == "" {
+		return
+	}
+	if err := postSlack(cfg.Approvals.SlackWebhook, planSlackMessage(run.ID, plan)); err != nil {
+		log.Printf("Error posting run %s for approval: %v", run.ID, err)
+		return
+	}
+	log.Printf("Run %s posted to Slack for approval", run.ID)
+}
+
+// planSlackMessage builds the approval request of a plan, with Approve and
+// Reject buttons.
+func planSlackMessage(runID, plan string) map[string]any {
+	title := fmt.Sprintf("Schedule plan %s is ready for review", runID)
+	// Leave room for the title and code fences.
+	body := strings.TrimSpace(plan)
+	if limit := slackSectionLimit - len(title) - 16; len(body) > limit {
+		cut := strings.LastIndex(body[:limit], "\n")
+		if cut < 0 {
+			cut = limit
+		}
+		body = body[:cut] + "\n…"
+	}
+	return map[string]any{
+		"text": title,
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": title + "\n```" + body + "```"}},
+			map[string]any{"type": "actions", "elements": []any{
+				map[string]any{
+					"type":      "button",
+					"action_id": "approve_plan",
+					"style":     "primary",
+					"text":      map[string]string{"type": "plain_text", "text": "Approve"},
+					"value":     runID,
+				},
+				map[string]any{
+					"type":      "button",
+					"action_id": "reject_plan",
+					"style":     "danger",
+					"text":      map[string]string{"type": "plain_text", "text": "Reject"},
+					"value":     runID,
+				},
+			}},
+		},
+	}
+}
+
+// reviewPlan applies or rejects a planned run for the Slack user who
+// pressed its button, and returns the reply and whether it replaces the
+// plan's message for everyone.
+func reviewPlan(runID, userID string, approve bool) (string, bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Sprintf("Could not review run %s: %v", runID, err), false
+	}
+	if !slices.Contains(cfg.Approvals.Approvers, userID) {
+		return "You aren't one of the approvers of schedule plans.", false
+	}
+	by := userID
+	if name, ok := slackEmployee(userID); ok {
+		by = name
+	}
+	if !approve {
+		if err := rejectRun(runID, by); err != nil {
+			return fmt.Sprintf("Could not reject run %s: %v", runID, err), false
+		}
+		log.Printf("Run %s rejected by %s", runID, by)
+		return fmt.Sprintf("Schedule plan %s rejected by <@%s>.", runID, userID), true
+	}
+	if err := applyRun(context.Background(), runID, by); err != nil {
+		return fmt.Sprintf("Could not apply run %s: %v", runID, err), false
+	}
+	log.Printf("Run %s approved by %s", runID, by)
+	return fmt.Sprintf("Schedule plan %s approved by <@%s> and published.", runID, userID), true
+}
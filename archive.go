@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Archive formats for run artifacts.
+const (
+	archiveZip   = "zip"
+	archiveTarGz = "tar.gz"
+)
+
+// runArchiveSummary is the name of the run record inside a run archive.
+const runArchiveSummary = "run.json"
+
+// archiveRun bundles the files of a run's folder with its run record, which
+// carries the forecast, prompt, and model response, into one archive named
+// by the run ID next to the run folders. It returns the archive's path.
+func archiveRun(run *Run, format string) (string, error) {
+	if run.Folder == "" {
+		return "", fmt.Errorf("run %s has not been published", run.ID)
+	}
+	var ext string
+	switch format {
+	case archiveZip:
+		ext = ".zip"
+	case archiveTarGz:
+		ext = ".tar.gz"
+	default:
+		return "", fmt.Errorf("unknown archive format %q (want %s or %s)", format, archiveZip, archiveTarGz)
+	}
+
+	entries, err := os.ReadDir(run.Folder)
+	if err != nil {
+		return "", fmt.Errorf("error reading run folder: %w", err)
+	}
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(run.Folder, e.Name()))
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %w", e.Name(), err)
+		}
+		files[e.Name()] = data
+	}
+	summary, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	files[runArchiveSummary] = summary
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	path := filepath.Join(run.outputDir(), run.ID+ext)
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return "", fmt.Errorf("error creating archive: %w", err)
+	}
+	defer os.Remove(tmp)
+	if format == archiveZip {
+		err = writeZipArchive(out, run.ID, names, files)
+	} else {
+		err = writeTarGzArchive(out, run.ID, names, files)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("error writing archive: %w", err)
+	}
+	return path, nil
+}
+
+// writeZipArchive writes files under a top-level folder named dir.
+func writeZipArchive(w io.Writer, dir string, names []string, files map[string][]byte) error {
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: dir + "/" + name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGzArchive writes files under a top-level folder named dir.
+func writeTarGzArchive(w io.Writer, dir string, names []string, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		if err := writeTarFile(tw, dir+"/"+name, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// runArchive bundles the artifacts of an exported run after the fact.
+func runArchive(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	format := fs.String("format", archiveZip, "archive format: zip or tar.gz")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: archive [-format zip|tar.gz] <run-id>")
+	}
+	run, err := loadRun(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	path, err := archiveRun(run, *format)
+	if err != nil {
+		return err
+	}
+	log.Printf("Archived run %s to %s", run.ID, path)
+	return nil
+}
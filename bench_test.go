@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// benchRows is the size of the generated call-record dataset. The budgets in
+// the README's Performance section are stated for this size.
+const benchRows = 1_000_000
+
+var benchCSVPath string
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if benchCSVPath != "" {
+		os.RemoveAll(filepath.Dir(benchCSVPath))
+	}
+	os.Exit(code)
+}
+
+// quietLogs discards log output until the benchmark ends. Parse failures
+// are logged per row; this keeps benchmark output readable.
+func quietLogs(b *testing.B) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(out) })
+}
+
+// benchCSV writes the benchmark dataset once per test binary and returns its
+// path.
+func benchCSV(b *testing.B) string {
+	b.Helper()
+	if benchCSVPath != "" {
+		return benchCSVPath
+	}
+	dir, err := os.MkdirTemp("", "scheduler-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(dir, "calls.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "called_time;answered_time;hangup_time;event_timestamp;wait_duration;talked_duration")
+	start := time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC)
+	for i := 0; i < benchRows; i++ {
+		called := start.Add(time.Duration(i%(31*24*60)) * time.Minute)
+		answered := called.Add(time.Minute)
+		hangup := answered.Add(4 * time.Minute)
+		fmt.Fprintf(w, "%s;%s;%s;%s;%d;%d\n",
+			called.Format("2006/01/02 15:04"), answered.Format("2006/01/02 15:04"),
+			hangup.Format("2006/01/02 15:04"), hangup.Format("2006/01/02 15:04"),
+			i%90, 60+i%600)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		b.Fatal(err)
+	}
+	benchCSVPath = path
+	return path
+}
+
+func benchRecords(b *testing.B) []Record {
+	b.Helper()
+	records, _, err := getRecords(benchCSV(b), ColumnMapping{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return records
+}
+
+func BenchmarkGetRecords(b *testing.B) {
+	quietLogs(b)
+	path := benchCSV(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		records, _, err := getRecords(path, ColumnMapping{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(records) != benchRows {
+			b.Fatalf("got %d records, want %d", len(records), benchRows)
+		}
+	}
+}
+
+func BenchmarkHighVolumeDays(b *testing.B) {
+	quietLogs(b)
+	records := benchRecords(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getHighVolumeDayNumbers(records, 75)
+	}
+}
+
+// benchTeams builds five-week schedules for teams of 100 employees, with one
+// shared employee per team so conflict detection has work to report.
+func benchTeams(teams, employees, weeks int) map[string][]FlatSchedule {
+	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	out := make(map[string][]FlatSchedule, teams)
+	for t := 0; t < teams; t++ {
+		team := fmt.Sprintf("Team %d", t+1)
+		for w := 0; w < weeks; w++ {
+			for e := 0; e < employees; e++ {
+				name := fmt.Sprintf("%s Employee %d", team, e)
+				if e == 0 {
+					name = "Shared"
+				}
+				entry := FlatSchedule{"Week": fmt.Sprintf("Week %d", w+1), "Employee": name}
+				for d, day := range days {
+					value := schedule.DefaultShifts[(e+w)%len(schedule.DefaultShifts)].Name
+					if (d+e)%7 >= 5 {
+						value = "Off"
+					}
+					entry[fmt.Sprintf("%s (%d)", day, w*7+d+1)] = value
+				}
+				out[team] = append(out[team], entry)
+			}
+		}
+	}
+	return out
+}
+
+func BenchmarkDetectConflicts(b *testing.B) {
+	quietLogs(b)
+	teams := benchTeams(3, 100, 8)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(detectConflicts(teams, schedule.DefaultShifts, maxWeeklyHours, time.Now())) == 0 {
+			b.Fatal("expected conflicts for the shared employee")
+		}
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	quietLogs(b)
+	var objs []map[string]string
+	for _, entry := range benchTeams(1, 100, 8)["Team 1"] {
+		objs = append(objs, entry)
+	}
+	sch, err := schedule.Parse(objs, schedule.DefaultShifts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	v := validator.New(validator.DefaultRules())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.Validate(sch)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalendarConfig holds the secret calendar feed URLs are signed with.
+// CALENDAR_FEED_SECRET takes precedence over the config file.
+type CalendarConfig struct {
+	Secret string `json:"secret,omitempty"`
+}
+
+func calendarSecret(cfg Config) string {
+	if v := os.Getenv("CALENDAR_FEED_SECRET"); v != "" {
+		return v
+	}
+	return cfg.Calendar.Secret
+}
+
+// Kinds of calendar feeds.
+const (
+	feedEmployee = "employees"
+	feedTeam     = "teams"
+)
+
+// feedToken signs a feed, so its URL can be handed to a calendar app that
+// can't send credentials. Changing the secret revokes every feed URL.
+func feedToken(secret, kind, name string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(kind + "/" + name))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// icsText escapes a value for an iCalendar text property.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folded at 75 octets as iCalendar
+// requires.
+func writeICSLine(w io.Writer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		fmt.Fprintf(w, "%s\r\n", line[:cut])
+		line = " " + line[cut:]
+	}
+	fmt.Fprintf(w, "%s\r\n", line)
+}
+
+// writeICS writes shifts as an iCalendar calendar. Times are floating local
+// times, as in the schedule. Each event's UID is stable for the employee and
+// day, so calendars update it when a newer schedule changes the shift.
+// withNames puts the employee's name in each event's summary, for team
+// calendars.
+func writeICS(w io.Writer, name string, shifts []exportedShift, withNames bool, now time.Time) {
+	writeICSLine(w, "BEGIN:VCALENDAR")
+	writeICSLine(w, "VERSION:2.0")
+	writeICSLine(w, "PRODID:-//employee-scheduler//schedule//EN")
+	writeICSLine(w, "CALSCALE:GREGORIAN")
+	writeICSLine(w, "X-WR-CALNAME:"+icsText(name))
+	for _, s := range shifts {
+		summary := s.Shift + " shift"
+		if withNames {
+			summary = s.Employee + ": " + summary
+		}
+		writeICSLine(w, "BEGIN:VEVENT")
+		writeICSLine(w, fmt.Sprintf("UID:%s-%s@employee-scheduler", url.PathEscape(s.Employee), s.Start.Format("20060102")))
+		writeICSLine(w, "DTSTAMP:"+now.UTC().Format("20060102T150405Z"))
+		writeICSLine(w, "DTSTART:"+s.Start.Format("20060102T150405"))
+		writeICSLine(w, "DTEND:"+s.End.Format("20060102T150405"))
+		writeICSLine(w, "SUMMARY:"+icsText(summary))
+		if s.Note != "" {
+			writeICSLine(w, "DESCRIPTION:"+icsText("Handover note: "+s.Note))
+		}
+		writeICSLine(w, "END:VEVENT")
+	}
+	writeICSLine(w, "END:VCALENDAR")
+}
+
+// icsExporter writes every employee's shifts to schedule.ics.
+type icsExporter struct{}
+
+func (icsExporter) Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error) {
+	var entries []FlatSchedule
+	for _, week := range sortedWeekNames(weeks) {
+		entries = append(entries, weeks[week]...)
+	}
+	filename := filepath.Join(opts.Dir, "schedule.ics")
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	shifts := exportedShifts(entries, opts.Shifts, time.Now())
+	if err := attachHandoverNotes(shifts); err != nil {
+		file.Close()
+		return nil, err
+	}
+	writeICS(file, "Schedule", shifts, true, time.Now())
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return []string{filename}, nil
+}
+
+// publishedShifts returns the shifts of the given employees across the
+// published schedules. Each day is taken from the newest schedule covering
+// it, so feeds follow every newly published version.
+func (q *jobQueue) publishedShifts(employees map[string]bool, now time.Time) ([]exportedShift, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	claimed := make(map[time.Time]bool)
+	var out []exportedShift
+	for _, ps := range q.publishedSchedules() {
+		covered := make(map[time.Time]bool)
+		for _, obj := range ps.Entries {
+			for key := range obj {
+				if d, ok := labelDate(key, now); ok {
+					covered[d] = true
+				}
+			}
+		}
+		for _, s := range exportedShifts(ps.Entries, shifts, now) {
+			day := time.Date(s.Start.Year(), s.Start.Month(), s.Start.Day(), 0, 0, 0, 0, s.Start.Location())
+			if employees[s.Employee] && !claimed[day] {
+				out = append(out, s)
+			}
+		}
+		for d := range covered {
+			claimed[d] = true
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	if err := attachHandoverNotes(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// feedMembers returns the roster employees of a feed, none when it names
+// no one on the roster.
+func feedMembers(kind, name string) (map[string]bool, error) {
+	roster, err := loadRoster()
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]bool)
+	for _, e := range roster {
+		if (kind == feedEmployee && e.Name == name) || (kind == feedTeam && e.Team == name) {
+			members[e.Name] = true
+		}
+	}
+	return members, nil
+}
+
+// handleCalendarFeed serves an employee's or team's shifts as an ICS feed.
+// Feeds are authenticated by the token in their URL.
+func (s *server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	secret := calendarSecret(cfg)
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "calendar feed secret not configured")
+		return
+	}
+	kind := r.PathValue("kind")
+	name, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok || (kind != feedEmployee && kind != feedTeam) {
+		writeError(w, http.StatusNotFound, "no such feed")
+		return
+	}
+	if !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(feedToken(secret, kind, name))) {
+		writeError(w, http.StatusUnauthorized, "invalid feed token")
+		return
+	}
+	members, err := feedMembers(kind, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(members) == 0 {
+		writeError(w, http.StatusNotFound, "no such feed")
+		return
+	}
+	now := time.Now()
+	shifts, err := s.queue.publishedShifts(members, now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	writeICS(w, name, shifts, kind == feedTeam, now)
+}
+
+// handleCalendarFeeds lists the feed URLs of every employee and team on the
+// roster.
+func (s *server) handleCalendarFeeds(w http.ResponseWriter, r *http.Request) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	secret := calendarSecret(cfg)
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "calendar feed secret not configured")
+		return
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	feedURL := func(kind, name string) string {
+		return fmt.Sprintf("%s://%s/calendar/%s/%s.ics?token=%s", scheme, r.Host, kind, url.PathEscape(name), feedToken(secret, kind, name))
+	}
+	feeds := map[string]map[string]string{feedEmployee: {}, feedTeam: {}}
+	for _, e := range roster {
+		feeds[feedEmployee][e.Name] = feedURL(feedEmployee, e.Name)
+		if e.Team != "" {
+			feeds[feedTeam][e.Team] = feedURL(feedTeam, e.Team)
+		}
+	}
+	writeJSON(w, http.StatusOK, feeds)
+}
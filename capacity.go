@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// CapacityProfile temporarily limits an employee's work from Start to End,
+// such as a student working at most 20 hours a week and no lates during
+// exams.
+type CapacityProfile struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// MaxWeeklyHours caps the hours worked within the period in a week.
+	MaxWeeklyHours float64 `json:"max_weekly_hours,omitempty"`
+	// NoShifts are the shifts the employee can't work within the period.
+	NoShifts []string `json:"no_shifts,omitempty"`
+	Label    string   `json:"label,omitempty"`
+}
+
+// capacity converts a profile into the limit validation checks.
+func (c CapacityProfile) capacity(shifts []schedule.Shift) (validator.Capacity, error) {
+	start, err := time.Parse(time.DateOnly, c.Start)
+	if err != nil {
+		return validator.Capacity{}, fmt.Errorf("invalid start of capacity profile: %w", err)
+	}
+	end, err := time.Parse(time.DateOnly, c.End)
+	if err != nil {
+		return validator.Capacity{}, fmt.Errorf("invalid end of capacity profile: %w", err)
+	}
+	if end.Before(start) {
+		return validator.Capacity{}, fmt.Errorf("capacity profile from %s ends before it starts", c.Start)
+	}
+	if c.MaxWeeklyHours < 0 {
+		return validator.Capacity{}, fmt.Errorf("capacity profile from %s has negative max_weekly_hours", c.Start)
+	}
+	for _, name := range c.NoShifts {
+		if !slices.ContainsFunc(shifts, func(sh schedule.Shift) bool { return sh.Name == name }) {
+			return validator.Capacity{}, fmt.Errorf("capacity profile from %s: unknown shift %q", c.Start, name)
+		}
+	}
+	return validator.Capacity{Start: start, End: end, MaxWeeklyHours: c.MaxWeeklyHours, NoShifts: c.NoShifts, Label: c.Label}, nil
+}
+
+// describe writes the profile for the scheduling prompt.
+func (c CapacityProfile) describe() string {
+	var limits []string
+	if c.MaxWeeklyHours > 0 {
+		limits = append(limits, fmt.Sprintf("at most %g hours a week", c.MaxWeeklyHours))
+	}
+	if len(c.NoShifts) > 0 {
+		limits = append(limits, "no "+strings.Join(c.NoShifts, " or ")+" shifts")
+	}
+	if len(limits) == 0 {
+		limits = append(limits, "no extra limits")
+	}
+	text := fmt.Sprintf("%s from %s to %s", strings.Join(limits, " and "), c.Start, c.End)
+	if c.Label != "" {
+		text += " (" + c.Label + ")"
+	}
+	return text
+}
+
+// rosterCapacity returns the capacity profiles of the scheduled employees
+// that haven't ended by today, both as recorded and as limits for
+// validation.
+func rosterCapacity(roster []Employee, employees []string, shifts []schedule.Shift, today time.Time) (map[string][]CapacityProfile, map[string][]validator.Capacity, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	profiles := make(map[string][]CapacityProfile)
+	limits := make(map[string][]validator.Capacity)
+	for _, e := range roster {
+		if !scheduled[e.Name] {
+			continue
+		}
+		for _, c := range e.CapacityProfiles {
+			limit, err := c.capacity(shifts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("employee %s: %w", e.Name, err)
+			}
+			if limit.End.Before(today) {
+				continue
+			}
+			profiles[e.Name] = append(profiles[e.Name], c)
+			limits[e.Name] = append(limits[e.Name], limit)
+		}
+	}
+	return profiles, limits, nil
+}
+
+// capacityNote describes the capacity profiles for the scheduling prompt.
+func capacityNote(profiles map[string][]CapacityProfile) string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		var periods []string
+		for _, c := range profiles[name] {
+			periods = append(periods, c.describe())
+		}
+		parts = append(parts, fmt.Sprintf("%s works %s", name, strings.Join(periods, ", and ")))
+	}
+	return "Reduced-hours periods (hard limits on the days they cover): " + strings.Join(parts, "; ") +
+		". Keep their hours on those days within the weekly limit and don't assign them the excluded shifts."
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// shiftCatalog returns the shifts configured in cfg, or the default
+// catalog when none are.
+func shiftCatalog(cfg Config) ([]schedule.Shift, error) {
+	if len(cfg.Shifts) == 0 {
+		return schedule.DefaultShifts, nil
+	}
+	seen := make(map[string]bool, len(cfg.Shifts))
+	for _, sh := range cfg.Shifts {
+		if sh.Name == "" || sh.Name == schedule.Off {
+			return nil, fmt.Errorf("invalid shift name %q", sh.Name)
+		}
+		if seen[sh.Name] {
+			return nil, fmt.Errorf("shift %s is defined twice", sh.Name)
+		}
+		seen[sh.Name] = true
+		if sh.End <= sh.Start {
+			return nil, fmt.Errorf("shift %s ends before it starts", sh.Name)
+		}
+	}
+	return cfg.Shifts, nil
+}
+
+// loadShiftCatalog returns the shift catalog of the config file.
+func loadShiftCatalog() ([]schedule.Shift, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return shiftCatalog(cfg)
+}
+
+// shiftLengthLimits parses the configured limits on how many shifts of a
+// length an employee works per week, keyed by hours, e.g. {"12": 4}.
+func shiftLengthLimits(cfg Config) (map[float64]int, error) {
+	if len(cfg.ShiftLengthLimits) == 0 {
+		return nil, nil
+	}
+	limits := make(map[float64]int, len(cfg.ShiftLengthLimits))
+	for hours, max := range cfg.ShiftLengthLimits {
+		h, err := strconv.ParseFloat(hours, 64)
+		if err != nil || h <= 0 {
+			return nil, fmt.Errorf("invalid shift length %q", hours)
+		}
+		limits[h] = max
+	}
+	return limits, nil
+}
+
+// findShift returns the catalog entry with the given name.
+func findShift(shifts []schedule.Shift, name string) (schedule.Shift, bool) {
+	for _, sh := range shifts {
+		if sh.Name == name {
+			return sh, true
+		}
+	}
+	return schedule.Shift{}, false
+}
+
+// promptTime writes a time of day the way the scheduling prompt does, e.g.
+// "6 am" or "8:30 pm".
+func promptTime(c schedule.Clock) string {
+	hour, minute := int(c)/60%24, int(c)%60
+	suffix := "am"
+	if hour >= 12 {
+		suffix = "pm"
+	}
+	hour %= 12
+	if hour == 0 {
+		hour = 12
+	}
+	if minute == 0 {
+		return fmt.Sprintf("%d %s", hour, suffix)
+	}
+	return fmt.Sprintf("%d:%02d %s", hour, minute, suffix)
+}
+
+// shiftLines lists the shift catalog for the scheduling prompt.
+func shiftLines(shifts []schedule.Shift) string {
+	var b strings.Builder
+	for _, sh := range shifts {
+		article := "a"
+		if strings.ContainsRune("AEIOUaeiou", rune(sh.Name[0])) {
+			article = "an"
+		}
+		fmt.Fprintf(&b, "- %s - %s which is considered %s %q\n", promptTime(sh.Start), promptTime(sh.End), article, sh.Name+" Shift")
+	}
+	return b.String()
+}
+
+// lengthLimitNotes describes the shift length limits for the prompt.
+func lengthLimitNotes(limits map[float64]int) []string {
+	lengths := make([]float64, 0, len(limits))
+	for length := range limits {
+		lengths = append(lengths, length)
+	}
+	sort.Float64s(lengths)
+	var notes []string
+	for _, length := range lengths {
+		notes = append(notes, fmt.Sprintf("Shift lengths: an employee works at most %d shifts of %g hours per week.", limits[length], length))
+	}
+	return notes
+}
+
+// promptNotes formats extra notes as prompt list items.
+func promptNotes(notes []string) string {
+	var b strings.Builder
+	for _, note := range notes {
+		fmt.Fprintf(&b, "- %s\n", note)
+	}
+	return b.String()
+}
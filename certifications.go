@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Certification is one an employee holds, such as "emergency-line", with
+// the date it expires, if any, e.g. "2026-01-31".
+type Certification struct {
+	Name    string `json:"name"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// requiredCertifications returns the certifications each shift needs
+// everyone working it to hold, from the config.
+func requiredCertifications(cfg Config, shifts []schedule.Shift) (map[string][]string, error) {
+	for shift := range cfg.RequiredCertifications {
+		if _, ok := findShift(shifts, shift); !ok {
+			return nil, fmt.Errorf("required certifications for unknown shift %q", shift)
+		}
+	}
+	return cfg.RequiredCertifications, nil
+}
+
+// rosterCertifications returns the certifications of the scheduled
+// employees.
+func rosterCertifications(roster []Employee, employees []string) (map[string][]validator.Certification, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	certs := make(map[string][]validator.Certification)
+	for _, e := range roster {
+		if !scheduled[e.Name] {
+			continue
+		}
+		for _, c := range e.Certifications {
+			cert := validator.Certification{Name: c.Name}
+			if c.Expires != "" {
+				expires, err := time.Parse(time.DateOnly, c.Expires)
+				if err != nil {
+					return nil, fmt.Errorf("employee %s: invalid expiry of %s certification: %w", e.Name, c.Name, err)
+				}
+				cert.Expires = expires
+			}
+			certs[e.Name] = append(certs[e.Name], cert)
+		}
+	}
+	return certs, nil
+}
+
+// certificationNote tells the model which shifts only certified employees
+// may work, and who is certified until when.
+func certificationNote(required map[string][]string, certs map[string][]validator.Certification, today time.Time) string {
+	shifts := make([]string, 0, len(required))
+	for shift := range required {
+		shifts = append(shifts, shift)
+	}
+	sort.Strings(shifts)
+	holders := make(map[string][]string)
+	var needs []string
+	for _, shift := range shifts {
+		needs = append(needs, fmt.Sprintf("%s (%s)", shift, strings.Join(required[shift], ", ")))
+		for _, name := range required[shift] {
+			holders[name] = nil
+		}
+	}
+	names := make([]string, 0, len(certs))
+	for name := range certs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	for _, name := range names {
+		for _, c := range certs[name] {
+			h, ok := holders[c.Name]
+			if !ok || (!c.Expires.IsZero() && c.Expires.Before(today)) {
+				continue
+			}
+			holder := name
+			if !c.Expires.IsZero() {
+				holder += " until " + c.Expires.Format(time.DateOnly)
+			}
+			holders[c.Name] = append(h, holder)
+		}
+	}
+	certNames := make([]string, 0, len(holders))
+	for cert := range holders {
+		certNames = append(certNames, cert)
+	}
+	sort.Strings(certNames)
+	var held []string
+	for _, cert := range certNames {
+		who := "no one"
+		if len(holders[cert]) > 0 {
+			who = strings.Join(holders[cert], ", ")
+		}
+		held = append(held, fmt.Sprintf("%s: %s", cert, who))
+	}
+	return "Certified shifts: only employees holding every listed certification may work " + strings.Join(needs, "; ") +
+		". Holders: " + strings.Join(held, "; ") + ". Never assign these shifts to anyone else, or after their certification expires."
+}
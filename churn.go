@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// ChurnConfig caps how much of the published schedule regeneration may
+// change, so a small change of input doesn't reshuffle everyone's week.
+type ChurnConfig struct {
+	// Max is the share of assignments allowed to change, e.g. 0.2. Zero
+	// allows any change.
+	Max float64 `json:"max,omitempty"`
+}
+
+// assignment is one employee's day in a week of a schedule.
+type assignment struct {
+	Week, Employee, Day string
+}
+
+// weekDay is a day column of a week of a schedule.
+type weekDay struct {
+	Week, Day string
+}
+
+// sharedDays returns the days two versions of a schedule both cover, since
+// churn only makes sense where they overlap.
+func sharedDays(before, after []FlatSchedule) map[weekDay]bool {
+	days := func(objs []FlatSchedule) map[weekDay]bool {
+		out := make(map[weekDay]bool)
+		for _, obj := range objs {
+			for key := range obj {
+				if strings.Contains(key, "(") {
+					out[weekDay{obj["Week"], key}] = true
+				}
+			}
+		}
+		return out
+	}
+	shared := days(before)
+	covered := days(after)
+	for d := range shared {
+		if !covered[d] {
+			delete(shared, d)
+		}
+	}
+	return shared
+}
+
+// assignments returns the shifts of a schedule on the given days by
+// employee and day, with days off left out.
+func assignments(objs []FlatSchedule, shifts []schedule.Shift, days map[weekDay]bool) map[assignment]string {
+	out := make(map[assignment]string)
+	for _, obj := range objs {
+		for key, value := range obj {
+			if days[weekDay{obj["Week"], key}] && isWorking(shifts, value) {
+				out[assignment{obj["Week"], obj["Employee"], key}] = value
+			}
+		}
+	}
+	return out
+}
+
+// changedAssignments returns the employee days that differ between two
+// schedules, and how many employee days either has someone working.
+func changedAssignments(before, after map[assignment]string) ([]assignment, int) {
+	var changed []assignment
+	total := len(before)
+	for a, value := range before {
+		if after[a] != value {
+			changed = append(changed, a)
+		}
+	}
+	for a := range after {
+		if _, ok := before[a]; !ok {
+			changed = append(changed, a)
+			total++
+		}
+	}
+	return changed, total
+}
+
+// churnRate returns the share of assignments changed between two versions
+// of a schedule on the days both cover, and false when they share none.
+func churnRate(before, after []FlatSchedule, shifts []schedule.Shift) (float64, bool) {
+	days := sharedDays(before, after)
+	if len(days) == 0 {
+		return 0, false
+	}
+	changed, total := changedAssignments(assignments(before, shifts, days), assignments(after, shifts, days))
+	if total == 0 {
+		return 0, true
+	}
+	return float64(len(changed)) / float64(total), true
+}
+
+// flatten returns the entries of a grouped schedule.
+func flatten(weeks map[string][]FlatSchedule) []FlatSchedule {
+	var objs []FlatSchedule
+	for _, week := range sortedWeekNames(weeks) {
+		objs = append(objs, weeks[week]...)
+	}
+	return objs
+}
+
+// limitChurn restores published assignments in a regenerated schedule,
+// earliest day first, until no more than the limit's share of them change.
+// It returns the number of cells it restored.
+func limitChurn(weeks map[string][]FlatSchedule, published []FlatSchedule, shifts []schedule.Shift, limit float64, now time.Time) int {
+	current := flatten(weeks)
+	days := sharedDays(published, current)
+	old := assignments(published, shifts, days)
+	changed, total := changedAssignments(old, assignments(current, shifts, days))
+	allowed := int(limit * float64(total))
+	if len(changed) <= allowed {
+		return 0
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		a, _ := labelDate(changed[i].Day, now)
+		b, _ := labelDate(changed[j].Day, now)
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return changed[i].Employee < changed[j].Employee
+	})
+	restored := 0
+	for _, a := range changed[:len(changed)-allowed] {
+		var entry FlatSchedule
+		for _, obj := range weeks[a.Week] {
+			if obj["Employee"] == a.Employee {
+				entry = obj
+				break
+			}
+		}
+		if entry == nil {
+			entry = FlatSchedule{"Week": a.Week, "Employee": a.Employee}
+			weeks[a.Week] = append(weeks[a.Week], entry)
+		}
+		value, ok := old[a]
+		if !ok {
+			value = "Off"
+		}
+		entry[a.Day] = value
+		restored++
+	}
+	return restored
+}
+
+// lastPublished returns the schedule in dir, or in its latest run folder,
+// or nil when nothing is published there yet.
+func lastPublished(dir string) ([]FlatSchedule, error) {
+	dir = scheduleDir(dir)
+	if paths, _ := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv")); len(paths) == 0 {
+		return nil, nil
+	}
+	return loadScheduleDir(dir)
+}
+
+// checkChurn holds a regenerated schedule to the churn limit and records
+// how much it changes of the schedule published before it: the latest in
+// its output directory, else the one it was warm-started from.
+func checkChurn(run *Run, weeks map[string][]FlatSchedule, p *policy) error {
+	published, err := lastPublished(run.outputDir())
+	if err == nil && published == nil && run.Previous != "" {
+		published, err = lastPublished(run.Previous)
+	}
+	if err != nil || published == nil {
+		return err
+	}
+	if limit := p.cfg.Churn.Max; limit > 0 {
+		if restored := limitChurn(weeks, published, p.shifts, limit, time.Now()); restored > 0 {
+			log.Printf("Kept %d published assignments to stay within the %g%% churn limit", restored, limit*100)
+		}
+	}
+	if churn, ok := churnRate(published, flatten(weeks), p.shifts); ok {
+		run.Churn = &churn
+		log.Printf("Changed %.1f%% of the published assignments", churn*100)
+	}
+	return nil
+}
+
+// runChurn lists the churn between consecutive published versions in the
+// run folders of an output directory.
+func runChurn(args []string) error {
+	fs := flag.NewFlagSet("churn", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: churn <output dir>")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	folders := runFolders(fs.Arg(0))
+	if len(folders) < 2 {
+		return fmt.Errorf("%s has fewer than two published versions", fs.Arg(0))
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tPREVIOUS\tCHANGED")
+	var previous []FlatSchedule
+	var previousName string
+	for _, folder := range folders {
+		entries, err := loadScheduleDir(folder)
+		if err != nil {
+			log.Printf("Skipping %s: %v", folder, err)
+			continue
+		}
+		if previous != nil {
+			changed := "no shared days"
+			if churn, ok := churnRate(previous, entries, shifts); ok {
+				changed = fmt.Sprintf("%.1f%%", churn*100)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", filepath.Base(folder), previousName, changed)
+		}
+		previous, previousName = entries, filepath.Base(folder)
+	}
+	return tw.Flush()
+}
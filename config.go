@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"employee-schedular/schedule"
+)
+
+// Config holds the settings kept in the data directory between runs.
+type Config struct {
+	// Mapping describes the layout of the call-record exports.
+	Mapping ColumnMapping `json:"mapping"`
+	// Twilio and AmazonConnect configure the API connectors.
+	Twilio        *TwilioConfig  `json:"twilio,omitempty"`
+	AmazonConnect *ConnectConfig `json:"amazon_connect,omitempty"`
+	// TimeClock configures the time-clock API actuals are imported from.
+	TimeClock *TimeClockConfig `json:"time_clock,omitempty"`
+	// HRIS configures the leave sync.
+	HRIS *HRISConfig `json:"hris,omitempty"`
+	// Webhooks configures the inbox for HR tool webhooks.
+	Webhooks WebhookConfig `json:"webhooks"`
+	// Calendar signs the calendar feed URLs served in server mode.
+	Calendar CalendarConfig `json:"calendar"`
+	// Demand configures the demand store.
+	Demand DemandConfig `json:"demand"`
+	// Forecast configures the external forecast service and fallback.
+	Forecast ForecastConfig `json:"forecast"`
+	// Shifts replaces the default Early, Normal, and Late shift catalog.
+	Shifts []schedule.Shift `json:"shifts,omitempty"`
+	// ServiceLevel sizes the shifts to a target such as answering 80% of
+	// calls within 20 seconds.
+	ServiceLevel ServiceLevelConfig `json:"service_level"`
+	// Shrinkage is the share of scheduled time lost to meetings, breaks,
+	// absenteeism, and the like, which staffing is grossed up for.
+	Shrinkage Shrinkage `json:"shrinkage,omitempty"`
+	// Objective names the optimization preset schedules are generated
+	// under unless a run asks for another.
+	Objective string `json:"objective,omitempty"`
+	// ShiftLengthLimits caps the shifts of a length, in hours, an employee
+	// works per week, such as {"12": 4}.
+	ShiftLengthLimits map[string]int `json:"shift_length_limits,omitempty"`
+	// LeavePlan sets the coverage the annual leave planner keeps.
+	LeavePlan LeavePlanConfig `json:"leave_plan"`
+	// RequiredSkills lists the skills each shift needs covered, such as
+	// {"Early": ["forklift", "first-aid"]}.
+	RequiredSkills map[string][]string `json:"required_skills,omitempty"`
+	// TrainingHours is how long training someone in a skill takes, such as
+	// {"forklift": 16}, for cross-training suggestions. Skills not listed
+	// take 8 hours.
+	TrainingHours map[string]float64 `json:"training_hours,omitempty"`
+	// RequiredCertifications lists the certifications everyone working a
+	// shift must hold, such as {"Late": ["emergency-line"]}.
+	RequiredCertifications map[string][]string `json:"required_certifications,omitempty"`
+	// Rotation sets the consecutive same-shift limit and rotation cadence.
+	Rotation RotationConfig `json:"rotation"`
+	// NightShifts caps the night shifts per employee and month.
+	NightShifts NightShiftConfig `json:"night_shifts"`
+	// Staggers lists staggered start times per shift, such as
+	// {"Early": ["06:00", "07:00"]}.
+	Staggers map[string][]string `json:"staggers,omitempty"`
+	// Freeze keeps the published assignments of the coming days when a
+	// schedule is regenerated.
+	Freeze FreezeConfig `json:"freeze"`
+	// Churn caps how much of the published schedule regeneration changes.
+	Churn ChurnConfig `json:"churn"`
+	// Notice requires reason codes for changes to published shifts inside
+	// the notice period of a collective agreement.
+	Notice NoticeConfig `json:"notice"`
+	// Holidays lists the public holidays, such as ["2025-12-25"], and
+	// LateShifts the shifts counted as lates, by default those ending
+	// latest, for sharing them out fairly over time.
+	Holidays   []string `json:"holidays,omitempty"`
+	LateShifts []string `json:"late_shifts,omitempty"`
+	// SpecialDays gives employees their birthdays and work anniversaries
+	// off where coverage permits.
+	SpecialDays SpecialDaysConfig `json:"special_days"`
+	// Minors sets the limits for employees under 18.
+	Minors MinorsConfig `json:"minors"`
+	// Register sets the breaks of the working-time register.
+	Register RegisterConfig `json:"register"`
+	// Reminders configures publish deadline reminders in server mode.
+	Reminders ReminderConfig `json:"reminders"`
+	// ShiftReminders configures the reminders employees get ahead of their
+	// shifts in server mode.
+	ShiftReminders ShiftReminderConfig `json:"shift_reminders"`
+	// SMS configures the text messages sent on publication and urgent
+	// schedule changes.
+	SMS SMSConfig `json:"sms"`
+	// Acknowledgments configures how employees acknowledge published
+	// schedules.
+	Acknowledgments AcknowledgmentConfig `json:"acknowledgments"`
+	// OpenShifts configures how uncovered slots are given out.
+	OpenShifts OpenShiftConfig `json:"open_shifts"`
+	// Notifications lists the team channels told about scheduling events.
+	Notifications []NotificationChannel `json:"notifications,omitempty"`
+	// Hooks run commands and call webhooks on lifecycle events.
+	Hooks Hooks `json:"hooks,omitempty"`
+	// Approvals lets planners approve planned runs from Slack.
+	Approvals ApprovalConfig `json:"approvals"`
+	// Overtime caps the overtime employees take on through offers.
+	Overtime OvertimeConfig `json:"overtime"`
+	// LaborCost sets the pay rates and monthly budgets schedules are priced
+	// against.
+	LaborCost LaborCostConfig `json:"labor_cost"`
+	// ExportTemplates adds layouts for the export command.
+	ExportTemplates map[string]ExportTemplate `json:"export_templates,omitempty"`
+	// Exports lists the output formats written besides the weekly CSV
+	// files, such as ["xlsx", "json"].
+	Exports []string `json:"exports,omitempty"`
+	// CSV formats the CSV files written for people, such as for Excel.
+	CSV CSVConfig `json:"csv"`
+	// Reports are Go templates rendered into every run folder.
+	Reports []ReportConfig `json:"reports,omitempty"`
+	// Archive bundles every exported run into one archive named by its run
+	// ID, in zip or tar.gz format.
+	Archive string `json:"archive,omitempty"`
+	// Language is the planner's language the model writes the schedule in,
+	// such as pt-BR. English by default.
+	Language string `json:"language,omitempty"`
+	// Packs pins the prompt template and rule pack versions.
+	Packs PackConfig `json:"packs"`
+}
+
+func configPath() string {
+	if path := os.Getenv("SCHEDULER_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(dataDir(), "config.json")
+}
+
+// loadConfig reads the config file. A missing file yields the defaults.
+func loadConfig() (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(configPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("error reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing config: %w", err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file.
+func saveConfig(cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding config: %w", err)
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	if err := os.WriteFile(configPath(), data, 0o644); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	return nil
+}
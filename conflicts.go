@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// maxWeeklyHours is the weekly cap stated in the scheduling prompt.
+const maxWeeklyHours = 45
+
+// Conflict describes an employee who is over-committed across the schedules
+// of several teams drawing from a shared pool.
+type Conflict struct {
+	Employee string
+	Week     string
+	Day      string // Empty for combined-hours conflicts.
+	Teams    []string
+	Hours    float64
+	Reason   string
+}
+
+func (c Conflict) String() string {
+	if c.Day != "" {
+		return fmt.Sprintf("%s, %s %s: %s (%s)", c.Employee, c.Week, c.Day, c.Reason, strings.Join(c.Teams, ", "))
+	}
+	return fmt.Sprintf("%s, %s: %s, %.0f hours (%s)", c.Employee, c.Week, c.Reason, c.Hours, strings.Join(c.Teams, ", "))
+}
+
+// weekdayOf returns the weekday name at the start of a day column such as
+// "Monday (1st March)", so teams with different date labels still line up.
+func weekdayOf(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// cellHours returns the hours a schedule cell assigns, and whether it
+// assigns a shift at all. Cells with their own hours, such as "Early
+// 07:00-16:00" for a staggered start, count those hours.
+func cellHours(shifts []schedule.Shift, value string) (float64, bool) {
+	start, end, ok := cellWindow(shifts, value)
+	return float64(end-start) / 60, ok
+}
+
+// cellWindow returns the hours of day a schedule cell assigns, and whether
+// it assigns a shift at all.
+func cellWindow(shifts []schedule.Shift, value string) (schedule.Clock, schedule.Clock, bool) {
+	name, window := schedule.SplitCell(value)
+	sh, ok := findShift(shifts, name)
+	if !ok {
+		return 0, 0, false
+	}
+	if start, end, err := schedule.ParseWindow(window); err == nil {
+		return start, end, true
+	}
+	return sh.Start, sh.End, true
+}
+
+// isWorking reports whether a schedule cell assigns a shift.
+func isWorking(shifts []schedule.Shift, value string) bool {
+	_, ok := cellHours(shifts, value)
+	return ok
+}
+
+// detectConflicts finds employees booked on the same day by more than one
+// team, and employees whose combined weekly hours across teams exceed the cap.
+// Days are matched by calendar date, and hours summed by calendar week, since
+// teams published on other days number their weeks from other dates. Labels
+// without a date fall back to the week label and weekday.
+func detectConflicts(teams map[string][]FlatSchedule, shifts []schedule.Shift, weeklyCap float64, now time.Time) []Conflict {
+	type slot struct {
+		employee  string
+		date      time.Time
+		week, day string
+	}
+	type weekKey struct {
+		employee string
+		monday   time.Time
+		week     string
+	}
+
+	bookings := make(map[slot][]string)
+	// labels keeps the week and day a slot was first seen under.
+	labels := make(map[slot][2]string)
+	hours := make(map[weekKey]float64)
+	hourTeams := make(map[weekKey]map[string]struct{})
+	weekLabels := make(map[weekKey]string)
+
+	teamNames := make([]string, 0, len(teams))
+	for name := range teams {
+		teamNames = append(teamNames, name)
+	}
+	sort.Strings(teamNames)
+
+	for _, team := range teamNames {
+		for _, entry := range teams[team] {
+			employee, week := entry["Employee"], entry["Week"]
+			if employee == "" || week == "" {
+				continue
+			}
+			for key, value := range entry {
+				length, working := cellHours(shifts, value)
+				if !strings.Contains(key, "(") || !working {
+					continue
+				}
+				s := slot{employee: employee, week: week, day: weekdayOf(key)}
+				wk := weekKey{employee: employee, week: week}
+				if date, ok := labelDate(key, now); ok {
+					s = slot{employee: employee, date: date}
+					wk = weekKey{employee: employee, monday: date.AddDate(0, 0, -(int(date.Weekday())+6)%7)}
+				}
+				if _, ok := labels[s]; !ok {
+					labels[s] = [2]string{week, key}
+				}
+				if _, ok := weekLabels[wk]; !ok {
+					weekLabels[wk] = week
+				}
+				bookings[s] = append(bookings[s], team)
+				hours[wk] += length
+				if hourTeams[wk] == nil {
+					hourTeams[wk] = make(map[string]struct{})
+				}
+				hourTeams[wk][team] = struct{}{}
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for s, booked := range bookings {
+		if len(booked) > 1 {
+			conflicts = append(conflicts, Conflict{
+				Employee: s.employee,
+				Week:     labels[s][0],
+				Day:      labels[s][1],
+				Teams:    booked,
+				Reason:   "double-booked",
+			})
+		}
+	}
+	for wk, total := range hours {
+		if len(hourTeams[wk]) > 1 && total > weeklyCap {
+			var names []string
+			for team := range hourTeams[wk] {
+				names = append(names, team)
+			}
+			sort.Strings(names)
+			conflicts = append(conflicts, Conflict{
+				Employee: wk.employee,
+				Week:     weekLabels[wk],
+				Teams:    names,
+				Hours:    total,
+				Reason:   fmt.Sprintf("combined hours exceed %.0f", weeklyCap),
+			})
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.Employee != b.Employee {
+			return a.Employee < b.Employee
+		}
+		if a.Week != b.Week {
+			return a.Week < b.Week
+		}
+		return a.Day < b.Day
+	})
+	return conflicts
+}
+
+// loadScheduleDir reads every weekly schedule CSV written by generate from dir.
+func loadScheduleDir(dir string) ([]FlatSchedule, error) {
+	dir = scheduleDir(dir)
+	paths, err := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no schedule CSV files found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var entries []FlatSchedule
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening schedule file: %w", err)
+		}
+		rows, err := readCSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schedule file %s: %w", path, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		header := rows[0]
+		first := len(entries)
+		for _, row := range rows[1:] {
+			entry := make(FlatSchedule, len(header))
+			for i, key := range header {
+				if i < len(row) {
+					entry[key] = row[i]
+				}
+			}
+			entries = append(entries, entry)
+		}
+		if err := validateScheduleJSON(entries[first:]); err != nil {
+			return nil, fmt.Errorf("error reading schedule file %s: %w", path, err)
+		}
+	}
+	return entries, nil
+}
+
+// loadTeamSchedules loads the schedule of every team=dir argument and returns
+// the schedules and directories keyed by team name.
+func loadTeamSchedules(args []string) (map[string][]FlatSchedule, map[string]string, error) {
+	teams := make(map[string][]FlatSchedule)
+	dirs := make(map[string]string)
+	for _, arg := range args {
+		team, dir, ok := strings.Cut(arg, "=")
+		if !ok || team == "" || dir == "" {
+			return nil, nil, fmt.Errorf("invalid team argument %q, expected team=dir", arg)
+		}
+		entries, err := loadScheduleDir(dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading schedule for team %s: %w", team, err)
+		}
+		teams[team] = entries
+		dirs[team] = scheduleDir(dir)
+	}
+	return teams, dirs, nil
+}
+
+// runConflicts checks the schedules of several teams for shared-pool
+// conflicts before they are published. Each argument has the form team=dir.
+func runConflicts(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: conflicts <team>=<schedule dir> <team>=<schedule dir> ...")
+	}
+
+	teams, _, err := loadTeamSchedules(args)
+	if err != nil {
+		return err
+	}
+
+	shifts, err := loadShiftCatalog()
+	if err != nil {
+		return err
+	}
+	conflicts := detectConflicts(teams, shifts, maxWeeklyHours, time.Now())
+	if len(conflicts) == 0 {
+		fmt.Println("No conflicts found.")
+		return nil
+	}
+	for _, c := range conflicts {
+		fmt.Println(c)
+	}
+	return fmt.Errorf("%d conflicts found", len(conflicts))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"employee-schedular/schedule"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	now := day(10, 14)
+	early := []string{"Early", "Early", "Early", "Off", "Off", "Off", "Off"}
+	late := []string{"Off", "Off", "Off", "Late", "Late", "Late", "Off"}
+	tests := []struct {
+		name  string
+		teams map[string][]FlatSchedule
+		// want lists the reasons of the conflicts found, in order.
+		want []string
+	}{
+		{
+			name: "same date under other week labels",
+			teams: map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 19), map[string][]string{"Pat": early}),
+				"B": weekRows("Week 2", day(10, 19), map[string][]string{"Pat": early}),
+			},
+			want: []string{"combined hours exceed 45", "double-booked", "double-booked", "double-booked"},
+		},
+		{
+			name: "same week label on other dates",
+			teams: map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 19), map[string][]string{"Pat": early}),
+				"B": weekRows("Week 1", day(10, 26), map[string][]string{"Pat": early}),
+			},
+		},
+		{
+			name: "combined hours over a calendar week",
+			teams: map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 19), map[string][]string{"Pat": early}),
+				"B": weekRows("Week 2", day(10, 19), map[string][]string{"Pat": late}),
+			},
+			want: []string{"combined hours exceed 45"},
+		},
+		{
+			name: "labels without dates",
+			teams: map[string][]FlatSchedule{
+				"A": {{"Week": "Week 1", "Employee": "Pat", "Monday (1)": "Early"}},
+				"B": {{"Week": "Week 1", "Employee": "Pat", "Monday (8)": "Late"}},
+			},
+			want: []string{"double-booked"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := detectConflicts(tt.teams, schedule.DefaultShifts, maxWeeklyHours, now)
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("got %d conflicts, want %d: %v", len(conflicts), len(tt.want), conflicts)
+			}
+			for i, c := range conflicts {
+				if c.Reason != tt.want[i] {
+					t.Errorf("conflict %d: %v, want %s", i, c, tt.want[i])
+				}
+			}
+		})
+	}
+}
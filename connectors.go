@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiSource is an input pulled from a contact-center API rather than read
+// from a file. It is written "<connector>:<start>/<end>" with inclusive
+// dates, for example "twilio:2025-03-01/2025-03-31".
+type apiSource struct {
+	Connector  string
+	Start, End time.Time
+}
+
+// connectors fetch the call records of a date range. end is exclusive.
+var connectors = map[string]func(ctx context.Context, cfg Config, start, end time.Time) ([]Record, error){
+	"twilio":  fetchTwilioCalls,
+	"connect": fetchConnectContacts,
+}
+
+var connectorClient = &http.Client{Timeout: 30 * time.Second}
+
+// parseAPISource reports whether input names an API source and parses it.
+func parseAPISource(input string) (apiSource, bool, error) {
+	name, rng, found := strings.Cut(input, ":")
+	if _, ok := connectors[name]; !found || !ok {
+		return apiSource{}, false, nil
+	}
+	from, to, found := strings.Cut(rng, "/")
+	if !found {
+		return apiSource{}, true, fmt.Errorf("invalid input %q: want %s:<start>/<end>", input, name)
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return apiSource{}, true, fmt.Errorf("invalid start date in %q: %w", input, err)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return apiSource{}, true, fmt.Errorf("invalid end date in %q: %w", input, err)
+	}
+	if end.Before(start) {
+		return apiSource{}, true, fmt.Errorf("invalid input %q: end is before start", input)
+	}
+	return apiSource{Connector: name, Start: start, End: end}, true, nil
+}
+
+// fetch pulls the source's call records.
+func (s apiSource) fetch(ctx context.Context) ([]Record, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	records, err := connectors[s.Connector](ctx, cfg, s.Start, s.End.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s call records: %w", s.Connector, err)
+	}
+	return records, nil
+}
+
+// TwilioConfig holds the Twilio credentials. TWILIO_ACCOUNT_SID and
+// TWILIO_AUTH_TOKEN take precedence over the config file.
+type TwilioConfig struct {
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	// From is the number text messages are sent from.
+	From string `json:"from,omitempty"`
+	// Endpoint overrides https://api.twilio.com.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func twilioSettings(cfg Config) TwilioConfig {
+	var tc TwilioConfig
+	if cfg.Twilio != nil {
+		tc = *cfg.Twilio
+	}
+	if v := os.Getenv("TWILIO_ACCOUNT_SID"); v != "" {
+		tc.AccountSID = v
+	}
+	if v := os.Getenv("TWILIO_AUTH_TOKEN"); v != "" {
+		tc.AuthToken = v
+	}
+	if tc.Endpoint == "" {
+		tc.Endpoint = "https://api.twilio.com"
+	}
+	return tc
+}
+
+type twilioCallPage struct {
+	Calls []struct {
+		SID       string `json:"sid"`
+		Direction string `json:"direction"`
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+		Duration  string `json:"duration"`
+		QueueTime string `json:"queue_time"`
+	} `json:"calls"`
+	NextPageURI string `json:"next_page_uri"`
+}
+
+// fetchTwilioCalls lists the inbound calls started in [start, end) through
+// the Twilio Calls API.
+func fetchTwilioCalls(ctx context.Context, cfg Config, start, end time.Time) ([]Record, error) {
+	tc := twilioSettings(cfg)
+	if tc.AccountSID == "" || tc.AuthToken == "" {
+		return nil, errors.New("TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN not set")
+	}
+	query := url.Values{
+		"StartTime>": {start.Format("2006-01-02")},
+		"StartTime<": {end.AddDate(0, 0, -1).Format("2006-01-02")},
+		"PageSize":   {"1000"},
+	}
+	next := fmt.Sprintf("/2010-04-01/Accounts/%s/Calls.json?%s", url.PathEscape(tc.AccountSID), query.Encode())
+
+	var records []Record
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tc.Endpoint+next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(tc.AccountSID, tc.AuthToken)
+		var page twilioCallPage
+		if err := doJSON(connectorClient, req, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Calls {
+			if c.Direction != "inbound" {
+				continue
+			}
+			called, err := time.Parse(time.RFC1123Z, c.StartTime)
+			if err != nil {
+				continue
+			}
+			rec := Record{CallID: c.SID, CalledTime: called}
+			if t, err := time.Parse(time.RFC1123Z, c.EndTime); err == nil {
+				rec.HangupTime = t
+			}
+			rec.TalkedDuration, _ = strconv.ParseFloat(c.Duration, 64)
+			if ms, err := strconv.ParseFloat(c.QueueTime, 64); err == nil {
+				rec.WaitDuration = ms / 1000
+			}
+			records = append(records, rec)
+		}
+		next = page.NextPageURI
+	}
+	return records, nil
+}
+
+// doJSON sends req with client and decodes a successful JSON response
+// into v.
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
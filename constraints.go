@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Constraints holds the scheduling rules between employees, kept in
+// data/constraints.json.
+type Constraints struct {
+	// Pairings keep an employee, such as a trainee, on the same shift as
+	// another, such as their mentor, whenever they work.
+	Pairings []validator.Pairing `json:"pairings,omitempty"`
+	// Separations keep two employees off the same shift.
+	Separations []validator.Separation `json:"separations,omitempty"`
+	// Safety lists policies such as no one working a Late shift alone, or
+	// at least two people on site after 18:00.
+	Safety []validator.SafetyRule `json:"safety,omitempty"`
+}
+
+func constraintsPath() string {
+	return filepath.Join(dataDir(), "constraints.json")
+}
+
+// validate checks that the constraints refer to shifts of the catalog.
+func (c Constraints) validate(shifts []schedule.Shift) error {
+	for _, rule := range c.Safety {
+		if rule.MinStaff < 1 {
+			return errors.New("safety rule without min_staff")
+		}
+		if rule.Shift == "" && rule.After == nil {
+			return errors.New("safety rule needs a shift or an after time")
+		}
+		if _, ok := findShift(shifts, rule.Shift); rule.Shift != "" && !ok {
+			return fmt.Errorf("safety rule for unknown shift %q", rule.Shift)
+		}
+	}
+	return nil
+}
+
+// loadConstraints reads the constraints file. A missing file has none.
+func loadConstraints() (Constraints, error) {
+	var c Constraints
+	data, err := os.ReadFile(constraintsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return c, fmt.Errorf("error reading constraints: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("error parsing constraints: %w", err)
+	}
+	return c, nil
+}
+
+// forEmployees keeps the constraints that involve only scheduled employees.
+func (c Constraints) forEmployees(employees []string) Constraints {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	out := Constraints{Safety: c.Safety}
+	for _, p := range c.Pairings {
+		if scheduled[p.Employee] && scheduled[p.With] {
+			out.Pairings = append(out.Pairings, p)
+		}
+	}
+	for _, s := range c.Separations {
+		if scheduled[s.Employees[0]] && scheduled[s.Employees[1]] {
+			out.Separations = append(out.Separations, s)
+		}
+	}
+	return out
+}
+
+// notes describes the constraints for the scheduling prompt.
+func (c Constraints) notes() []string {
+	var notes []string
+	if len(c.Pairings) > 0 {
+		var parts []string
+		for _, p := range c.Pairings {
+			parts = append(parts, fmt.Sprintf("%s works the same shift as %s", p.Employee, p.With))
+		}
+		notes = append(notes, "Pairings: whenever they work, "+strings.Join(parts, "; ")+".")
+	}
+	if len(c.Separations) > 0 {
+		var parts []string
+		for _, s := range c.Separations {
+			parts = append(parts, s.Employees[0]+" and "+s.Employees[1])
+		}
+		notes = append(notes, "Separations: never put "+strings.Join(parts, ", or ")+" on the same shift of the same day.")
+	}
+	for _, rule := range c.Safety {
+		switch {
+		case rule.Shift != "":
+			notes = append(notes, fmt.Sprintf("Safety: whenever anyone works the %s Shift, schedule at least %d employees on it.", rule.Shift, rule.MinStaff))
+		case rule.After != nil:
+			notes = append(notes, fmt.Sprintf("Safety: whenever anyone is on site after %s, at least %d employees must be on site.", rule.After, rule.MinStaff))
+		}
+	}
+	return notes
+}
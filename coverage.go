@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// maxCoverageDays caps the date range of a coverage query.
+const maxCoverageDays = 92
+
+// shiftCoverage is the staffing of one shift on one day. Gap is how many
+// more employees are required.
+type shiftCoverage struct {
+	Shift     string         `json:"shift"`
+	Start     schedule.Clock `json:"start"`
+	End       schedule.Clock `json:"end"`
+	Required  int            `json:"required"`
+	Scheduled int            `json:"scheduled"`
+	Gap       int            `json:"gap"`
+}
+
+// dayCoverage is the staffing of one day, from the newest published schedule
+// covering it. Job is empty and Shifts nil when no schedule covers the day.
+type dayCoverage struct {
+	Date   string          `json:"date"`
+	Job    string          `json:"job,omitempty"`
+	Day    string          `json:"day,omitempty"`
+	Shifts []shiftCoverage `json:"shifts"`
+}
+
+// publishedSchedule is a published job with its schedule.
+type publishedSchedule struct {
+	Job     Job
+	Entries []FlatSchedule
+}
+
+// publishedSchedules loads the schedules of the published jobs, newest
+// first. Jobs whose schedule can't be read are skipped.
+func (q *jobQueue) publishedSchedules() []publishedSchedule {
+	var out []publishedSchedule
+	for _, job := range q.list() {
+		if job.Status != jobPublished {
+			continue
+		}
+		entries, err := loadScheduleDir(filepath.Join(dataDir(), "schedules", job.ID))
+		if err != nil {
+			continue
+		}
+		out = append(out, publishedSchedule{Job: job, Entries: entries})
+	}
+	return out
+}
+
+// requiredHeadcount returns the employees required on each shift of a
+// published schedule, as validation counts them: the shift targets of the
+// run's forecast, and never fewer than the minimum per shift.
+func requiredHeadcount(ps publishedSchedule) (map[string]int, []schedule.Shift, error) {
+	names := make(map[string]bool)
+	var employees []string
+	for _, obj := range ps.Entries {
+		if !names[obj["Employee"]] {
+			names[obj["Employee"]] = true
+			employees = append(employees, obj["Employee"])
+		}
+	}
+	p, err := loadPolicy(employees)
+	if err != nil {
+		return nil, nil, err
+	}
+	run := &Run{Employees: employees}
+	if r, err := loadRun(ps.Job.RunID); err == nil {
+		run = r
+	}
+	targets := p.shiftTargets(run.Forecast, len(run.Employees))
+	min := validator.DefaultRules().MinPerShift
+	required := make(map[string]int, len(p.shifts))
+	for _, sh := range p.shifts {
+		required[sh.Name] = max(targets[sh.Name], min)
+	}
+	return required, p.shifts, nil
+}
+
+// coverage reports the required and scheduled headcount of every shift on
+// each day from from to to, inclusive.
+func (q *jobQueue) coverage(from, to time.Time) ([]dayCoverage, error) {
+	published := q.publishedSchedules()
+	type policyOf struct {
+		required map[string]int
+		shifts   []schedule.Shift
+	}
+	policies := make(map[string]policyOf)
+
+	var days []dayCoverage
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := dayCoverage{Date: date.Format(time.DateOnly)}
+		for _, ps := range published {
+			week, key, ok := scheduleDay(ps.Entries, date)
+			if !ok {
+				continue
+			}
+			pol, ok := policies[ps.Job.ID]
+			if !ok {
+				required, shifts, err := requiredHeadcount(ps)
+				if err != nil {
+					return nil, fmt.Errorf("error reading policy of job %s: %w", ps.Job.ID, err)
+				}
+				pol = policyOf{required, shifts}
+				policies[ps.Job.ID] = pol
+			}
+			scheduled := make(map[string]int)
+			for _, obj := range weekEntries(ps.Entries, week) {
+				name, _ := schedule.SplitCell(obj[key])
+				scheduled[name]++
+			}
+			day.Job, day.Day = ps.Job.ID, key
+			day.Shifts = []shiftCoverage{}
+			for _, sh := range pol.shifts {
+				c := shiftCoverage{Shift: sh.Name, Start: sh.Start, End: sh.End, Required: pol.required[sh.Name], Scheduled: scheduled[sh.Name]}
+				c.Gap = max(c.Required-c.Scheduled, 0)
+				day.Shifts = append(day.Shifts, c)
+			}
+			break
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func (s *server) handleCoverage(w http.ResponseWriter, r *http.Request) {
+	from, err := time.Parse(time.DateOnly, r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be a date written 2006-01-02")
+		return
+	}
+	to, err := time.Parse(time.DateOnly, r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be a date written 2006-01-02")
+		return
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to is before from")
+		return
+	}
+	if to.Sub(from) >= maxCoverageDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("date range longer than %d days", maxCoverageDays))
+		return
+	}
+	days, err := s.queue.coverage(from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"from": from.Format(time.DateOnly), "to": to.Format(time.DateOnly), "days": days})
+}
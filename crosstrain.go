@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// defaultTrainingHours is how long training someone in a skill takes when
+// the config doesn't say.
+const defaultTrainingHours = 8
+
+// trainingHours returns the hours it takes to train someone in a skill.
+func trainingHours(cfg Config, skill string) float64 {
+	if h := cfg.TrainingHours[skill]; h > 0 {
+		return h
+	}
+	return defaultTrainingHours
+}
+
+// skillSlot is one required skill of a shift worked on one day of a past
+// schedule, with who worked it and how many of them had the skill.
+type skillSlot struct {
+	skill   string
+	working []string
+	holders int
+}
+
+// skillRisk weighs a skill slot by its holders. An uncovered skill breaks
+// the rules, and a single holder leaves the shift short if they're out.
+func skillRisk(holders int) int {
+	switch holders {
+	case 0:
+		return 3
+	case 1:
+		return 1
+	}
+	return 0
+}
+
+// pastSkillSlots collects the skill slots of a schedule's worked shifts.
+func pastSkillSlots(entries []FlatSchedule, shifts []schedule.Shift, required, skills map[string][]string) []skillSlot {
+	weeks := make(map[string][]FlatSchedule)
+	for _, obj := range entries {
+		weeks[obj["Week"]] = append(weeks[obj["Week"]], obj)
+	}
+	var slots []skillSlot
+	now := time.Now()
+	for _, week := range sortedWeekNames(weeks) {
+		objs := weeks[week]
+		for _, key := range dayColumns(objs[0], now) {
+			for _, sh := range shifts {
+				var working []string
+				for _, obj := range objs {
+					if name, _ := schedule.SplitCell(obj[key]); name == sh.Name {
+						working = append(working, obj["Employee"])
+					}
+				}
+				if len(working) == 0 {
+					continue
+				}
+				for _, skill := range required[sh.Name] {
+					slot := skillSlot{skill: skill, working: working}
+					for _, name := range working {
+						if slices.Contains(skills[name], skill) {
+							slot.holders++
+						}
+					}
+					slots = append(slots, slot)
+				}
+			}
+		}
+	}
+	return slots
+}
+
+// trainingSuggestion is one employee to train in one skill, with the risky
+// slots of past schedules it would have covered and the risk it removes.
+type trainingSuggestion struct {
+	Employee string
+	Skill    string
+	Hours    float64
+	Slots    int
+	Risk     int
+}
+
+// crossTraining picks, one at a time, the training removing the most risk
+// per training hour from the past skill slots, counting the trainings
+// already picked, until limit are picked or no training helps.
+func crossTraining(cfg Config, slots []skillSlot, skills map[string][]string, candidates []string, limit int) []trainingSuggestion {
+	var needed []string
+	for _, slot := range slots {
+		if !slices.Contains(needed, slot.skill) {
+			needed = append(needed, slot.skill)
+		}
+	}
+	sort.Strings(needed)
+	has := make(map[string][]string, len(skills))
+	for name, s := range skills {
+		has[name] = slices.Clone(s)
+	}
+
+	var out []trainingSuggestion
+	for len(out) < limit {
+		var best trainingSuggestion
+		for _, name := range candidates {
+			for _, skill := range needed {
+				if slices.Contains(has[name], skill) {
+					continue
+				}
+				t := trainingSuggestion{Employee: name, Skill: skill, Hours: trainingHours(cfg, skill)}
+				for _, slot := range slots {
+					if slot.skill == skill && slices.Contains(slot.working, name) {
+						if gain := skillRisk(slot.holders) - skillRisk(slot.holders+1); gain > 0 {
+							t.Slots++
+							t.Risk += gain
+						}
+					}
+				}
+				if t.Risk > 0 && (best.Risk == 0 || float64(t.Risk)/t.Hours > float64(best.Risk)/best.Hours) {
+					best = t
+				}
+			}
+		}
+		if best.Risk == 0 {
+			break
+		}
+		out = append(out, best)
+		has[best.Employee] = append(has[best.Employee], best.Skill)
+		for i, slot := range slots {
+			if slot.skill == best.Skill && slices.Contains(slot.working, best.Employee) {
+				slots[i].holders++
+			}
+		}
+	}
+	return out
+}
+
+// runCrossTrain suggests whom to cross-train in what, from the recurring
+// skill gaps of past schedules.
+func runCrossTrain(args []string) error {
+	fs := flag.NewFlagSet("cross-train", flag.ContinueOnError)
+	top := fs.Int("top", 5, "number of trainings to suggest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: cross-train [-top 5] <schedule dir> ...")
+	}
+
+	var schedules [][]FlatSchedule
+	var names []string
+	for _, arg := range fs.Args() {
+		dirs := runFolders(arg)
+		if len(dirs) == 0 {
+			dirs = []string{arg}
+		}
+		for _, dir := range dirs {
+			entries, err := loadScheduleDir(dir)
+			if err != nil {
+				return err
+			}
+			for _, obj := range entries {
+				if !slices.Contains(names, obj["Employee"]) {
+					names = append(names, obj["Employee"])
+				}
+			}
+			schedules = append(schedules, entries)
+		}
+	}
+	p, err := loadPolicy(names)
+	if err != nil {
+		return err
+	}
+	if len(p.requiredSkills) == 0 {
+		return fmt.Errorf("no required_skills in %s", configPath())
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	for _, e := range roster {
+		if slices.Contains(names, e.Name) {
+			candidates = append(candidates, e.Name)
+		}
+	}
+	sort.Strings(candidates)
+
+	var slots []skillSlot
+	for _, entries := range schedules {
+		slots = append(slots, pastSkillSlots(entries, p.shifts, p.requiredSkills, p.skills)...)
+	}
+	uncovered, single := 0, 0
+	for _, slot := range slots {
+		switch slot.holders {
+		case 0:
+			uncovered++
+		case 1:
+			single++
+		}
+	}
+	fmt.Printf("%d schedules: %d required skills uncovered on a shift, %d covered by a single person\n", len(schedules), uncovered, single)
+
+	suggestions := crossTraining(p.cfg, slots, p.skills, candidates, *top)
+	if len(suggestions) == 0 {
+		fmt.Println("No training would reduce the coverage risk of these schedules.")
+		return nil
+	}
+	for i, t := range suggestions {
+		fmt.Printf("%d. Train %s in %s (%g hours): covers %d risky shifts, %.2f risk removed per training hour\n",
+			i+1, t.Employee, t.Skill, t.Hours, t.Slots, float64(t.Risk)/t.Hours)
+	}
+	return nil
+}
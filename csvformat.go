@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// utf8BOM is the byte order mark Excel looks for to read a CSV file as
+// UTF-8.
+const utf8BOM = "\ufeff"
+
+// CSVConfig formats the CSV files written for people to open: the weekly
+// schedules and the labor cost, leave plan, register, and charge-back
+// files. Excel on Windows wants a byte order mark and CRLF line endings,
+// and in locales with a decimal comma a ";" delimiter.
+type CSVConfig struct {
+	// Delimiter separates the fields, "," by default.
+	Delimiter string `json:"delimiter,omitempty"`
+	// BOM starts files with a UTF-8 byte order mark, without which Excel
+	// garbles accented names.
+	BOM bool `json:"bom,omitempty"`
+	// CRLF ends lines with \r\n instead of \n.
+	CRLF bool `json:"crlf,omitempty"`
+	// SepHint starts files with a line such as "sep=;", which tells Excel
+	// the delimiter whatever its locale.
+	SepHint bool `json:"sep_hint,omitempty"`
+}
+
+// newCSVWriter writes the byte order mark and sep= line the config asks
+// for to w and returns a CSV writer in its format.
+func newCSVWriter(w io.Writer, cc CSVConfig) (*csv.Writer, error) {
+	comma := ','
+	if cc.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(cc.Delimiter)
+		if size != len(cc.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return nil, fmt.Errorf("invalid CSV delimiter %q", cc.Delimiter)
+		}
+		comma = r
+	}
+	var head strings.Builder
+	if cc.BOM {
+		head.WriteString(utf8BOM)
+	}
+	if cc.SepHint {
+		head.WriteString("sep=" + string(comma))
+		if cc.CRLF {
+			head.WriteString("\r")
+		}
+		head.WriteString("\n")
+	}
+	if _, err := io.WriteString(w, head.String()); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	cw.UseCRLF = cc.CRLF
+	return cw, nil
+}
+
+// readCSV reads a CSV file written in any CSVConfig format. It skips a
+// byte order mark and a sep= line, and takes the delimiter from that line
+// or else guesses it from the header.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	comma := ','
+	if hint, ok := bytes.CutPrefix(line, []byte("sep=")); ok && utf8.RuneCount(hint) == 1 {
+		comma, _ = utf8.DecodeRune(hint)
+		data = rest
+	} else if d := sniffDelimiter(string(line)); strings.Count(string(line), d) > 0 {
+		comma, _ = utf8.DecodeRuneInString(d)
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	return r.ReadAll()
+}
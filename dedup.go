@@ -0,0 +1,73 @@
+package main
+
+import "fmt"
+
+// Deduplication strategies for records describing the same call.
+const (
+	dedupNone        = "none"
+	dedupKeepFirst   = "keep-first"
+	dedupKeepLast    = "keep-last"
+	dedupKeepLongest = "keep-longest"
+)
+
+// validDedupStrategy reports an error for unknown strategy names.
+func validDedupStrategy(strategy string) error {
+	switch strategy {
+	case dedupNone, dedupKeepFirst, dedupKeepLast, dedupKeepLongest:
+		return nil
+	}
+	return fmt.Errorf("unknown dedup strategy %q (want %s, %s, %s, or %s)",
+		strategy, dedupNone, dedupKeepFirst, dedupKeepLast, dedupKeepLongest)
+}
+
+// recordLength is how long a call lasted, used by keep-longest.
+func recordLength(r Record) float64 {
+	if !r.HangupTime.IsZero() {
+		return r.HangupTime.Sub(r.CalledTime).Seconds()
+	}
+	return r.WaitDuration + r.TalkedDuration
+}
+
+// dedupRecords drops exact duplicate records and, for records carrying a
+// call ID, every record but one per call; the strategy picks which one is
+// kept when overlapping exports disagree. Records without a call ID are only
+// dropped when they are exact copies. It returns the kept records and how
+// many exact duplicates and overlapping records were dropped.
+func dedupRecords(records []Record, strategy string) ([]Record, int, int) {
+	if strategy == dedupNone {
+		return records, 0, 0
+	}
+
+	seen := make(map[Record]struct{}, len(records))
+	byCall := make(map[string]int)
+	kept := make([]Record, 0, len(records))
+	exact, overlapping := 0, 0
+	for _, r := range records {
+		if _, dup := seen[r]; dup {
+			exact++
+			continue
+		}
+		seen[r] = struct{}{}
+
+		if r.CallID == "" {
+			kept = append(kept, r)
+			continue
+		}
+		i, ok := byCall[r.CallID]
+		if !ok {
+			byCall[r.CallID] = len(kept)
+			kept = append(kept, r)
+			continue
+		}
+		overlapping++
+		switch strategy {
+		case dedupKeepLast:
+			kept[i] = r
+		case dedupKeepLongest:
+			if recordLength(r) > recordLength(kept[i]) {
+				kept[i] = r
+			}
+		}
+	}
+	return kept, exact, overlapping
+}
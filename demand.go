@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The demand store keeps every call record ingested across runs, so history
+// outlives the exports it came from. Records are appended to one CSV
+// segment per calendar month, in the layout getRecords reads with
+// demandMapping. Segments are only rewritten by compaction.
+
+// Records purged by retention are summarised into the archived aggregates
+// first, so forecasts keep the history without the raw records.
+
+// demandMapping reads and writes store segments.
+var demandMapping = ColumnMapping{TimeLayout: time.RFC3339}
+
+var demandHeader = []string{roleCallID, roleCalledTime, roleAnsweredTime, roleHangupTime, roleEventTime, roleWaitDuration, roleTalkedDuration, roleAgentID}
+
+// demandMu serialises store writes between concurrent jobs.
+var demandMu sync.Mutex
+
+// DemandConfig configures the demand store.
+type DemandConfig struct {
+	// RetentionMonths drops records older than this many months on
+	// compaction. Zero keeps everything.
+	RetentionMonths int `json:"retention_months,omitempty"`
+	// AgentIDs sets what the store keeps of the agent who took each call:
+	// "drop" (the default) keeps nothing, "hash" a keyed hash that still
+	// tells agents apart, and "keep" the ID as exported.
+	AgentIDs string `json:"agent_ids,omitempty"`
+	// HashKey keys the agent ID hashes; DEMAND_HASH_KEY overrides it.
+	// Changing it makes the same agent hash differently.
+	HashKey string `json:"hash_key,omitempty"`
+}
+
+// Policies for agent IDs in the demand store.
+const (
+	agentIDsDrop = "drop"
+	agentIDsHash = "hash"
+	agentIDsKeep = "keep"
+)
+
+// hashedPrefix marks hashed agent IDs, so they aren't hashed again.
+const hashedPrefix = "sha256:"
+
+func demandHashKey(cfg DemandConfig) string {
+	if v := os.Getenv("DEMAND_HASH_KEY"); v != "" {
+		return v
+	}
+	return cfg.HashKey
+}
+
+// agentIDFilter returns what the store keeps of an agent ID under the
+// configured policy.
+func agentIDFilter(cfg DemandConfig) (func(string) string, error) {
+	switch cfg.AgentIDs {
+	case "", agentIDsDrop:
+		return func(string) string { return "" }, nil
+	case agentIDsKeep:
+		return func(id string) string { return id }, nil
+	case agentIDsHash:
+		key := demandHashKey(cfg)
+		if key == "" {
+			return nil, errors.New("hashing agent IDs needs demand.hash_key or DEMAND_HASH_KEY")
+		}
+		return func(id string) string {
+			if id == "" || strings.HasPrefix(id, hashedPrefix) {
+				return id
+			}
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(id))
+			return hashedPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown demand.agent_ids %q (want drop, hash, or keep)", cfg.AgentIDs)
+}
+
+// demandSource records an input file that was added to the store.
+type demandSource struct {
+	Name       string    `json:"name"`
+	Rows       int       `json:"rows"`
+	IngestedAt time.Time `json:"ingested_at"`
+}
+
+func demandDir() string {
+	return filepath.Join(dataDir(), "demand")
+}
+
+func demandSourcesPath() string {
+	return filepath.Join(demandDir(), "sources.json")
+}
+
+func demandSegmentPath(month string) string {
+	return filepath.Join(demandDir(), month+".csv")
+}
+
+func demandArchivePath() string {
+	return filepath.Join(demandDir(), "aggregates.json")
+}
+
+// loadDemandSources reads the index of ingested files, keyed by content hash.
+func loadDemandSources() (map[string]demandSource, error) {
+	sources := make(map[string]demandSource)
+	data, err := os.ReadFile(demandSourcesPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sources, nil
+		}
+		return nil, fmt.Errorf("error reading demand sources: %w", err)
+	}
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("error parsing demand sources: %w", err)
+	}
+	return sources, nil
+}
+
+func formatStoreTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// demandRow formats a record as a segment row.
+func demandRow(r Record) []string {
+	return []string{
+		r.CallID,
+		formatStoreTime(r.CalledTime),
+		formatStoreTime(r.AnsweredTime),
+		formatStoreTime(r.HangupTime),
+		formatStoreTime(r.EventTime),
+		strconv.FormatFloat(r.WaitDuration, 'f', -1, 64),
+		strconv.FormatFloat(r.TalkedDuration, 'f', -1, 64),
+		r.AgentID,
+	}
+}
+
+// appendDemand adds records to the store, with their agent IDs kept,
+// hashed, or dropped as configured. Files are identified by their content
+// hash and only added once; records of API sources, which have no hash, are
+// always added and deduplicated on compaction.
+func appendDemand(name, hash string, records []Record) error {
+	demandMu.Lock()
+	defer demandMu.Unlock()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	agentID, err := agentIDFilter(cfg.Demand)
+	if err != nil {
+		return err
+	}
+	sources, err := loadDemandSources()
+	if err != nil {
+		return err
+	}
+	if _, ok := sources[hash]; ok && hash != "" {
+		return nil
+	}
+	if err := os.MkdirAll(demandDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating demand store: %w", err)
+	}
+
+	byMonth := make(map[string][][]string)
+	for _, r := range records {
+		month := r.CalledTime.Format("2006-01")
+		r.AgentID = agentID(r.AgentID)
+		byMonth[month] = append(byMonth[month], demandRow(r))
+	}
+	for month, rows := range byMonth {
+		if err := appendSegment(demandSegmentPath(month), rows); err != nil {
+			return err
+		}
+	}
+
+	if hash == "" {
+		return nil
+	}
+	sources[hash] = demandSource{Name: name, Rows: len(records), IngestedAt: time.Now().UTC()}
+	data, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := demandSourcesPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing demand sources: %w", err)
+	}
+	return os.Rename(tmp, demandSourcesPath())
+}
+
+// appendSegment appends rows to a segment, writing the header first when the
+// segment is new.
+func appendSegment(path string, rows [][]string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening demand segment: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening demand segment: %w", err)
+	}
+	w := csv.NewWriter(file)
+	w.Comma = demandMapping.comma()
+	if info.Size() == 0 {
+		rows = append([][]string{demandHeader}, rows...)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("error writing demand segment: %w", err)
+	}
+	return nil
+}
+
+// demandMonths lists the months held by the store, oldest first.
+func demandMonths() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(demandDir(), "????-??.csv"))
+	if err != nil {
+		return nil, err
+	}
+	months := make([]string, 0, len(matches))
+	for _, m := range matches {
+		months = append(months, strings.TrimSuffix(filepath.Base(m), ".csv"))
+	}
+	sort.Strings(months)
+	return months, nil
+}
+
+// loadDemand returns the stored records called in [from, to), deduplicated
+// like a keep-first ingest. A zero bound leaves that side open.
+func loadDemand(from, to time.Time) ([]Record, error) {
+	months, err := demandMonths()
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, month := range months {
+		start, _ := time.Parse("2006-01", month)
+		if !from.IsZero() && !start.AddDate(0, 1, 0).After(from) {
+			continue
+		}
+		if !to.IsZero() && !start.Before(to) {
+			continue
+		}
+		segment, _, err := getRecords(demandSegmentPath(month), demandMapping)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range segment {
+			if (from.IsZero() || !r.CalledTime.Before(from)) && (to.IsZero() || r.CalledTime.Before(to)) {
+				records = append(records, r)
+			}
+		}
+	}
+	records, _, _ = dedupRecords(records, dedupKeepFirst)
+	return records, nil
+}
+
+// loadDemandArchive reads the aggregates of the records purged from the
+// store. A missing archive is empty.
+func loadDemandArchive() (Aggregates, error) {
+	agg := newAggregates()
+	data, err := os.ReadFile(demandArchivePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return agg, nil
+		}
+		return agg, fmt.Errorf("error reading demand archive: %w", err)
+	}
+	if err := json.Unmarshal(data, &agg); err != nil {
+		return agg, fmt.Errorf("error parsing demand archive: %w", err)
+	}
+	return agg, nil
+}
+
+// saveDemandArchive writes the archived aggregates, replacing them
+// atomically.
+func saveDemandArchive(agg Aggregates) error {
+	data, err := json.MarshalIndent(agg, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := demandArchivePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing demand archive: %w", err)
+	}
+	return os.Rename(tmp, demandArchivePath())
+}
+
+// loadDemandAggregates returns the aggregates of the whole demand history:
+// the stored records and those already purged.
+func loadDemandAggregates() (Aggregates, error) {
+	agg, err := loadDemandArchive()
+	if err != nil {
+		return agg, err
+	}
+	records, err := loadDemand(time.Time{}, time.Time{})
+	if err != nil {
+		return agg, err
+	}
+	agg.merge(aggregateRecords(records))
+	return agg, nil
+}
+
+// compactDemand rewrites every segment without duplicates and without
+// records older than the retention period, deleting segments left empty,
+// and applies the agent ID policy to the records kept. Purged records are
+// added to the archived aggregates before their segment is rewritten, and
+// the rejected rows and cached aggregates written before the retention
+// period are removed too. It returns how many records were dropped.
+func compactDemand(cfg DemandConfig) (int, error) {
+	demandMu.Lock()
+	defer demandMu.Unlock()
+
+	agentID, err := agentIDFilter(cfg)
+	if err != nil {
+		return 0, err
+	}
+	months, err := demandMonths()
+	if err != nil {
+		return 0, err
+	}
+	var cutoff time.Time
+	if cfg.RetentionMonths > 0 {
+		cutoff = time.Now().UTC().AddDate(0, -cfg.RetentionMonths, 0)
+	}
+	dropped := 0
+	for _, month := range months {
+		path := demandSegmentPath(month)
+		records, _, err := getRecords(path, demandMapping)
+		if err != nil {
+			return dropped, err
+		}
+		kept, _, _ := dedupRecords(records, dedupKeepFirst)
+		if !cutoff.IsZero() {
+			var recent, purged []Record
+			for _, r := range kept {
+				if r.CalledTime.Before(cutoff) {
+					purged = append(purged, r)
+				} else {
+					recent = append(recent, r)
+				}
+			}
+			if len(purged) > 0 {
+				archive, err := loadDemandArchive()
+				if err != nil {
+					return dropped, err
+				}
+				archive.merge(aggregateRecords(purged))
+				if err := saveDemandArchive(archive); err != nil {
+					return dropped, err
+				}
+			}
+			kept = recent
+		}
+		dropped += len(records) - len(kept)
+		if len(kept) == 0 {
+			if err := os.Remove(path); err != nil {
+				return dropped, fmt.Errorf("error removing demand segment: %w", err)
+			}
+			continue
+		}
+		changed := len(kept) != len(records)
+		for i, r := range kept {
+			if id := agentID(r.AgentID); id != r.AgentID {
+				kept[i].AgentID = id
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		tmp := path + ".tmp"
+		os.Remove(tmp)
+		rows := make([][]string, len(kept))
+		for i, r := range kept {
+			rows[i] = demandRow(r)
+		}
+		if err := appendSegment(tmp, rows); err != nil {
+			return dropped, err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return dropped, fmt.Errorf("error replacing demand segment: %w", err)
+		}
+	}
+	if !cutoff.IsZero() {
+		n, err := purgeRejects(cutoff)
+		if err != nil {
+			return dropped, err
+		}
+		if n > 0 {
+			log.Printf("Removed %d cached aggregates and rejects files from before %s", n, cutoff.Format(time.DateOnly))
+		}
+	}
+	return dropped, nil
+}
+
+// compactDemandEvery applies the retention policy once a day until ctx is
+// done, so raw records are purged even when no one runs compact.
+func compactDemandEvery(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		cfg, err := loadConfig()
+		if err == nil {
+			var n int
+			if n, err = compactDemand(cfg.Demand); err == nil {
+				log.Printf("Compacted demand store, dropped %d records", n)
+			}
+		}
+		if err != nil {
+			log.Printf("Error compacting demand store: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ingestFile parses an input file and adds its records to the demand store
+// the first time its contents are seen. A store that can't be written is
+// logged rather than failing the run.
+func ingestFile(path, hash string, mapping ColumnMapping) ([]Record, []rejectedRow, error) {
+	records, rejects, err := getRecords(path, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := appendDemand(path, hash, records); err != nil {
+		log.Printf("Error adding %s to the demand store: %v", path, err)
+	}
+	return records, rejects, nil
+}
+
+// parseDateRange parses the -from and -to flags of the store commands; to is
+// inclusive and returned as the start of the following day.
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.Parse("2006-01-02", from); err != nil {
+			return start, end, fmt.Errorf("invalid -from date: %w", err)
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse("2006-01-02", to); err != nil {
+			return start, end, fmt.Errorf("invalid -to date: %w", err)
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// runCompact applies the retention and agent ID policies and removes
+// duplicate records from the demand store.
+func runCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	retention := fs.Int("retention-months", cfg.Demand.RetentionMonths, "drop records older than this many months (0 keeps everything)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg.Demand.RetentionMonths = *retention
+	dropped, err := compactDemand(cfg.Demand)
+	if err != nil {
+		return err
+	}
+	log.Printf("Compacted demand store, dropped %d records", dropped)
+	return nil
+}
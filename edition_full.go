@@ -0,0 +1,6 @@
+//go:build !viewer
+
+package main
+
+// viewerEdition is unset in the full build; see edition_viewer.go.
+const viewerEdition = false
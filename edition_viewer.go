@@ -0,0 +1,8 @@
+//go:build viewer
+
+package main
+
+// viewerEdition is set in the read-only build for team leads, made with
+// "go build -tags viewer". It only runs the view command, so it can't
+// generate schedules, call the API, or change the data directory.
+const viewerEdition = true
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files holding employees' personal data, such as the roster and leave
+// types, can be encrypted at rest with AES-256-GCM under a key taken from
+// SCHEDULER_DATA_KEY. Encrypted files start with encryptedMagic; lines of
+// append-only logs are encrypted one by one behind encryptedLinePrefix.
+// Files without the marker are read as plain text, so encryption can be
+// turned on for an existing data directory.
+
+const (
+	encryptedMagic      = "scheduler-encrypted:v1\n"
+	encryptedLinePrefix = "enc:v1:"
+)
+
+// personalDataFiles lists the files, relative to the data directory, that
+// are encrypted when a data key is set.
+var personalDataFiles = []string{"roster.json", "leave.json", "pseudonyms.json", "inbox.jsonl"}
+
+// dataKey returns the AES key in SCHEDULER_DATA_KEY, or nil when
+// encryption is off. The key must be 32 random bytes, written as 64 hex
+// digits or in base64; passphrases are rejected, since nothing stretches
+// them.
+func dataKey() ([]byte, error) {
+	secret := strings.TrimSpace(os.Getenv("SCHEDULER_DATA_KEY"))
+	if secret == "" {
+		return nil, nil
+	}
+	if key, err := hex.DecodeString(secret); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(secret); err == nil && len(key) == 32 {
+			return key, nil
+		}
+	}
+	return nil, errors.New("SCHEDULER_DATA_KEY must be 32 random bytes as 64 hex digits or base64, e.g. from openssl rand -hex 32")
+}
+
+// legacyDataKey returns the key earlier versions derived from a passphrase
+// by hashing it, taken from SCHEDULER_LEGACY_DATA_KEY, or nil when that is
+// unset. Files sealed with it are read until encrypt rewrites them under
+// the data key.
+func legacyDataKey() []byte {
+	secret := strings.TrimSpace(os.Getenv("SCHEDULER_LEGACY_DATA_KEY"))
+	if secret == "" {
+		return nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	return key[:]
+}
+
+// unsealData decrypts data with the data key, falling back to the legacy
+// key for data written by earlier versions.
+func unsealData(key, data []byte) ([]byte, error) {
+	err := errors.New("set SCHEDULER_DATA_KEY")
+	if key != nil {
+		plain, kerr := unseal(key, data)
+		if kerr == nil {
+			return plain, nil
+		}
+		err = kerr
+	}
+	if legacy := legacyDataKey(); legacy != nil {
+		if plain, lerr := unseal(legacy, data); lerr == nil {
+			return plain, nil
+		}
+	}
+	return nil, err
+}
+
+func dataCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data, prefixing the random nonce.
+func seal(key, data []byte) ([]byte, error) {
+	gcm, err := dataCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// unseal decrypts data written by seal.
+func unseal(key, data []byte) ([]byte, error) {
+	gcm, err := dataCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong SCHEDULER_DATA_KEY or corrupted file")
+	}
+	return plain, nil
+}
+
+// readDataFile reads a data file, decrypting it if it was encrypted.
+func readDataFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		return data, err
+	}
+	key, err := dataKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s is encrypted: set SCHEDULER_DATA_KEY", path)
+	}
+	plain, err := unsealData(key, data[len(encryptedMagic):])
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// writeDataFile writes a data file, encrypted when a data key is set, and
+// replaces it atomically.
+func writeDataFile(path string, data []byte, perm os.FileMode) error {
+	key, err := dataKey()
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		sealed, err := seal(key, data)
+		if err != nil {
+			return err
+		}
+		data = append([]byte(encryptedMagic), sealed...)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sealLine returns a log line, encrypted when a data key is set.
+func sealLine(line []byte) ([]byte, error) {
+	key, err := dataKey()
+	if err != nil || key == nil {
+		return line, err
+	}
+	sealed, err := seal(key, line)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encryptedLinePrefix + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// openLine decrypts a log line written by sealLine; plain lines are
+// returned as they are.
+func openLine(line []byte) ([]byte, error) {
+	if !bytes.HasPrefix(line, []byte(encryptedLinePrefix)) {
+		return line, nil
+	}
+	key, err := dataKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New("encrypted line: set SCHEDULER_DATA_KEY")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(line[len(encryptedLinePrefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted line: %w", err)
+	}
+	return unsealData(key, sealed)
+}
+
+// convertDataFile rewrites a personal data file encrypted with the data
+// key, or in plain text when decrypting. A missing file is skipped.
+func convertDataFile(name string, decrypt bool) (bool, error) {
+	path := filepath.Join(dataDir(), name)
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !strings.HasSuffix(name, ".jsonl") {
+		data, err := readDataFile(path)
+		if err != nil {
+			return false, err
+		}
+		if decrypt {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, data, info.Mode().Perm()); err != nil {
+				return false, err
+			}
+			return true, os.Rename(tmp, path)
+		}
+		return true, writeDataFile(path, data, info.Mode().Perm())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line, err := openLine(scanner.Bytes())
+		if err != nil {
+			return false, err
+		}
+		if !decrypt {
+			if line, err = sealLine(line); err != nil {
+				return false, err
+			}
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// runEncrypt encrypts the personal data files with SCHEDULER_DATA_KEY, or
+// decrypts them with -decrypt, for example to edit the roster by hand.
+func runEncrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	decrypt := fs.Bool("decrypt", false, "write the files back in plain text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key, err := dataKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return errors.New("set SCHEDULER_DATA_KEY to the data key")
+	}
+	for _, name := range personalDataFiles {
+		converted, err := convertDataFile(name, *decrypt)
+		if err != nil {
+			return fmt.Errorf("error converting %s: %w", name, err)
+		}
+		if !converted {
+			continue
+		}
+		if *decrypt {
+			log.Printf("Decrypted %s", name)
+		} else {
+			log.Printf("Encrypted %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataKey(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		secret  string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "hex", secret: hexKey, wantLen: 32},
+		{name: "hex with a newline", secret: hexKey + "\n", wantLen: 32},
+		{name: "base64", secret: base64.StdEncoding.EncodeToString(make([]byte, 32)), wantLen: 32},
+		{name: "url base64", secret: base64.RawURLEncoding.EncodeToString(make([]byte, 32)), wantLen: 32},
+		{name: "passphrase", secret: "correct horse battery staple", wantErr: true},
+		{name: "short hex", secret: strings.Repeat("ab", 16), wantErr: true},
+		{name: "long base64", secret: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 48)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCHEDULER_DATA_KEY", tt.secret)
+			key, err := dataKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if len(key) != tt.wantLen {
+				t.Errorf("key of %d bytes, want %d", len(key), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestEncryptMigratesLegacyPassphrase(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	legacy := sha256.Sum256([]byte("old passphrase"))
+	sealedFile, err := seal(legacy[:], []byte(`[{"name":"Ann"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealedLine, err := seal(legacy[:], []byte(`{"id":"e1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"roster.json": append([]byte(encryptedMagic), sealedFile...),
+		"inbox.jsonl": []byte(encryptedLinePrefix + base64.StdEncoding.EncodeToString(sealedLine) + "\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dataDir(), name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name    string
+		legacy  string
+		encrypt bool
+		wantErr bool
+	}{
+		{name: "new key alone", wantErr: true},
+		{name: "migrate", legacy: "old passphrase", encrypt: true},
+		{name: "after migrating"},
+	}
+	t.Setenv("SCHEDULER_DATA_KEY", strings.Repeat("cd", 32))
+	for _, st := range steps {
+		t.Setenv("SCHEDULER_LEGACY_DATA_KEY", st.legacy)
+		if st.encrypt {
+			if err := runEncrypt(nil); err != nil {
+				t.Fatalf("%s: %v", st.name, err)
+			}
+		}
+		roster, err := readDataFile(filepath.Join(dataDir(), "roster.json"))
+		if (err != nil) != st.wantErr {
+			t.Fatalf("%s: reading the roster: error %v, want error %v", st.name, err, st.wantErr)
+		}
+		if err == nil && string(roster) != `[{"name":"Ann"}]` {
+			t.Errorf("%s: roster %q", st.name, roster)
+		}
+		data, err := os.ReadFile(filepath.Join(dataDir(), "inbox.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		line, err := openLine(bytes.TrimSpace(data))
+		if (err != nil) != st.wantErr {
+			t.Fatalf("%s: reading the inbox: error %v, want error %v", st.name, err, st.wantErr)
+		}
+		if err == nil && string(line) != `{"id":"e1"}` {
+			t.Errorf("%s: inbox line %q", st.name, line)
+		}
+	}
+}
+
+func TestWriteDataFileRejectsPassphrase(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_KEY", "passphrase")
+	path := filepath.Join(t.TempDir(), "roster.json")
+	if err := writeDataFile(path, []byte("Ann"), 0o600); err == nil {
+		t.Error("wrote with a passphrase, want an error")
+	}
+}
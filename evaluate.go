@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/forecast"
+)
+
+// evalVariant is one way of writing a schedule the evaluate command
+// compares: an engine, and for the model a prompt template.
+type evalVariant struct {
+	solver, llm, prompt string
+}
+
+func (v evalVariant) String() string {
+	if v.solver == solverLocal {
+		return solverLocal
+	}
+	name := solverOpenAI
+	if v.llm != "" {
+		name += ":" + v.llm
+	}
+	if v.prompt != "" {
+		name += " prompt " + v.prompt
+	}
+	return name
+}
+
+// evalVariants reads the engines (local, openai, or openai:<model>) and
+// the prompt template versions to try each model with.
+func evalVariants(engines, prompts string) ([]evalVariant, error) {
+	versions := []string{""}
+	if prompts != "" {
+		versions = strings.Split(prompts, ",")
+		for _, v := range versions {
+			if _, _, err := lookupPrompt(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var variants []evalVariant
+	for _, engine := range strings.Split(engines, ",") {
+		solver, llm, _ := strings.Cut(strings.TrimSpace(engine), ":")
+		switch solver {
+		case solverLocal:
+			variants = append(variants, evalVariant{solver: solverLocal})
+		case solverOpenAI:
+			if offline {
+				return nil, errors.New("the openai solver is unavailable offline")
+			}
+			for _, v := range versions {
+				variants = append(variants, evalVariant{solver: solverOpenAI, llm: llm, prompt: v})
+			}
+		default:
+			return nil, fmt.Errorf("unknown engine %q (want local, openai, or openai:<model>)", engine)
+		}
+	}
+	return variants, nil
+}
+
+// evalScenario is a horizon the variants are compared on: the forecast and
+// employees of an earlier run, or a forecast file.
+type evalScenario struct {
+	name      string
+	forecast  *forecast.Forecast
+	employees []string
+	objective string
+}
+
+// loadScenario reads a scenario from a forecast file, scheduled for
+// employees, or else from the run of that ID.
+func loadScenario(arg string, employees []string) (evalScenario, error) {
+	if _, err := os.Stat(arg); err == nil {
+		fc, err := forecast.Load(arg)
+		if err != nil {
+			return evalScenario{}, err
+		}
+		return evalScenario{name: arg, forecast: fc, employees: employees}, nil
+	}
+	run, err := loadRun(arg)
+	if err != nil {
+		return evalScenario{}, err
+	}
+	if run.Forecast == nil || len(run.Employees) == 0 {
+		return evalScenario{}, fmt.Errorf("run %s has no forecast and employees to evaluate against", run.ID)
+	}
+	return evalScenario{name: run.ID, forecast: run.Forecast, employees: run.Employees, objective: run.Objective}, nil
+}
+
+// evalResult is how one variant did on one scenario.
+type evalResult struct {
+	scenario string
+	variant  string
+	run      *Run
+	err      error
+	score    float64
+	seconds  float64
+}
+
+// evaluateOnce generates a schedule of a scenario with a variant into a
+// scratch output directory, validating and scoring it without publishing.
+func evaluateOnce(evaluation string, sc evalScenario, v evalVariant, objective string) evalResult {
+	res := evalResult{scenario: sc.name, variant: v.String()}
+	fc, err := tempForecast(sc.forecast)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer os.Remove(fc)
+	out, err := os.MkdirTemp("", "evaluate-*")
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer os.RemoveAll(out)
+
+	opts := defaultGenerateOptions()
+	opts.Forecast, opts.Employees, opts.OutputDir = fc, sc.employees, out
+	opts.Objective = objective
+	if opts.Objective == "" {
+		opts.Objective = sc.objective
+	}
+	opts.Solver, opts.LLM, opts.Prompt = v.solver, v.llm, v.prompt
+	opts.Cold, opts.Evaluate = true, true
+	run, err := newRun()
+	if err != nil {
+		res.err = err
+		return res
+	}
+	run.Evaluation = evaluation
+	res.run = run
+	start := time.Now()
+	res.err = generateRun(context.Background(), run, opts, nil)
+	res.seconds = time.Since(start).Seconds()
+	if res.err == nil && run.Penalties != nil {
+		res.score = run.Penalties.score(objectivePresets[run.Objective])
+	}
+	return res
+}
+
+// runEvaluate compares engines and prompt templates over the same
+// scenarios, generating each scenario with every variant and scoring the
+// schedules with the objective penalties, violations, coverage, and cost.
+func runEvaluate(args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	engines := fs.String("engines", "local,openai", "comma-separated engines: local, openai, or openai:<model> such as openai:gpt-4o")
+	prompts := fs.String("prompts", "", "comma-separated prompt template versions to try each model with (default the config's)")
+	repeat := fs.Int("repeat", 1, "times to run each variant on each scenario, since the model's schedules vary")
+	objective := fs.String("objective", "", "optimization preset to score under (default each scenario's)")
+	employees := fs.String("employees", "", "comma-separated employees for forecast-file scenarios (default the roster)")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	if fs.NArg() == 0 || *repeat < 1 {
+		return withExitCode(exitInput, errors.New("usage: evaluate [-engines local,openai:gpt-4o] [-prompts 1,2] [-repeat 1] [-objective name] [-employees names] <run-id | forecast file> ..."))
+	}
+	if *objective != "" {
+		if _, err := lookupObjective(*objective); err != nil {
+			return withExitCode(exitInput, err)
+		}
+	}
+	variants, err := evalVariants(*engines, *prompts)
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+	var names []string
+	if *employees != "" {
+		if names, err = readEmployees(*employees); err != nil {
+			return withExitCode(exitInput, err)
+		}
+	}
+	var scenarios []evalScenario
+	for _, arg := range fs.Args() {
+		sc, err := loadScenario(arg, names)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+		scenarios = append(scenarios, sc)
+	}
+
+	evaluation := time.Now().UTC().Format(runFolderLayout)
+	log.Printf("Evaluating %d variants on %d scenarios (evaluation %s)", len(variants), len(scenarios), evaluation)
+	var results []evalResult
+	for _, sc := range scenarios {
+		for _, v := range variants {
+			for i := 0; i < *repeat; i++ {
+				res := evaluateOnce(evaluation, sc, v, *objective)
+				if res.err != nil {
+					log.Printf("Error evaluating %s on %s: %v", v, sc.name, res.err)
+				}
+				results = append(results, res)
+			}
+		}
+	}
+	flushTracing(context.Background())
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCENARIO\tVARIANT\tRUN\tRESULT\tVIOLATIONS\tERRORS\tSCORE\tHOURS\tLABOR COST\tCOVERAGE\tSECONDS")
+	for _, r := range results {
+		id := ""
+		if r.run != nil {
+			id = r.run.ID
+		}
+		if r.err != nil {
+			kind := exitKinds[exitCode(r.err)]
+			if kind == "" {
+				kind = "error"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\t\t\t\t\t\t%.1f\n", r.scenario, r.variant, id, kind, r.seconds)
+			continue
+		}
+		t := tradeoff{}
+		if r.run.Tradeoff != nil {
+			t = *r.run.Tradeoff
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\tok\t%d\t%d\t%g\t%.1f\t%.2f\t%.1f%%\t%.1f\n", r.scenario, r.variant, id, r.run.Violations, r.run.Errors, r.score, t.Hours, t.Cost, t.Coverage*100, r.seconds)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIANT\tRUNS\tFAILED\tMEAN VIOLATIONS\tMEAN ERRORS\tMEAN SCORE\tMEAN COVERAGE\tMEAN LABOR COST\tMEAN SECONDS")
+	ok := 0
+	for _, v := range variants {
+		var runs, failed int
+		var violations, errs, score, coverage, cost, seconds float64
+		for _, r := range results {
+			if r.variant != v.String() {
+				continue
+			}
+			runs++
+			if r.err != nil {
+				failed++
+				continue
+			}
+			violations += float64(r.run.Violations)
+			errs += float64(r.run.Errors)
+			score += r.score
+			seconds += r.seconds
+			if t := r.run.Tradeoff; t != nil {
+				coverage += t.Coverage
+				cost += t.Cost
+			}
+		}
+		ok += runs - failed
+		if n := float64(runs - failed); n > 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f%%\t%.2f\t%.1f\n", v, runs, failed, violations/n, errs/n, score/n, coverage/n*100, cost/n, seconds/n)
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t\t\t\t\t\t\n", v, runs, failed)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if ok == 0 {
+		return errors.New("no variant produced a schedule")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"employee-schedular/forecast"
+)
+
+// calendarEvent is a known event in the events file. End is inclusive and
+// defaults to Start, so multi-day outage windows are a single entry.
+type calendarEvent struct {
+	Start  string  `json:"start"`
+	End    string  `json:"end,omitempty"`
+	Source string  `json:"source,omitempty"`
+	Label  string  `json:"label"`
+	Factor float64 `json:"factor,omitempty"`
+}
+
+func eventsPath() string {
+	return filepath.Join(dataDir(), "events.json")
+}
+
+func init() {
+	forecast.RegisterEnricher("calendar", calendarEnricher{})
+}
+
+// calendarEnricher attaches the events of the events file to the dates they
+// cover. A missing file has no events.
+type calendarEnricher struct{}
+
+func (calendarEnricher) Signals(dates []time.Time) ([]forecast.Signal, error) {
+	data, err := os.ReadFile(eventsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading events: %w", err)
+	}
+	var events []calendarEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("error parsing events: %w", err)
+	}
+
+	var signals []forecast.Signal
+	for _, ev := range events {
+		start, err := time.Parse(forecast.DateLayout, ev.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start of event %q: %w", ev.Label, err)
+		}
+		end := start
+		if ev.End != "" {
+			if end, err = time.Parse(forecast.DateLayout, ev.End); err != nil {
+				return nil, fmt.Errorf("invalid end of event %q: %w", ev.Label, err)
+			}
+		}
+		for _, d := range dates {
+			if d.Before(start) || d.After(end) {
+				continue
+			}
+			signals = append(signals, forecast.Signal{
+				Date:   d.Format(forecast.DateLayout),
+				Source: ev.Source,
+				Label:  ev.Label,
+				Factor: ev.Factor,
+			})
+		}
+	}
+	return signals, nil
+}
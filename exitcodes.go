@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Exit codes of the commands, stable so pipelines can branch on why a run
+// failed. Failures of any other kind exit with 1.
+const (
+	exitOK         = 0
+	exitValidation = 2
+	exitInfeasible = 3
+	exitLLM        = 4
+	exitInput      = 5
+)
+
+// exitKinds names the exit codes in error reports.
+var exitKinds = map[int]string{
+	exitValidation: "validation_failed",
+	exitInfeasible: "infeasible",
+	exitLLM:        "llm_error",
+	exitInput:      "input_error",
+}
+
+// errorFormat is how a failed command reports its error: "text", the
+// default, or "json", set with --error-format.
+var errorFormat = "text"
+
+// exitError is an error that ends the program with its code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode marks err to exit the program with code. It keeps the code
+// of an error that already has one, and returns nil for a nil err.
+func withExitCode(code int, err error) error {
+	var ee *exitError
+	if err == nil || errors.As(err, &ee) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the code err exits the program with.
+func exitCode(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return 1
+}
+
+// checkErrors fails a run whose schedule breaks error-severity rules,
+// after it was published or planned as before.
+func checkErrors(run *Run) error {
+	if run.Errors == 0 {
+		return nil
+	}
+	return withExitCode(exitValidation, fmt.Errorf("run %s has %d error violations", run.ID, run.Errors))
+}
+
+// errorReport is a failed command's error as written with
+// --error-format json.
+type errorReport struct {
+	Error   string `json:"error"`
+	Code    int    `json:"code"`
+	Kind    string `json:"kind"`
+	Command string `json:"command,omitempty"`
+}
+
+// exit reports the error a command failed with, after prefix in the text
+// format, and exits with its code.
+func exit(command, prefix string, err error) {
+	code := exitCode(err)
+	if errorFormat == "json" {
+		kind, ok := exitKinds[code]
+		if !ok {
+			kind = "error"
+		}
+		json.NewEncoder(os.Stderr).Encode(errorReport{Error: err.Error(), Code: code, Kind: kind, Command: command})
+	} else if prefix != "" {
+		log.Printf("%s: %v", prefix, err)
+	} else {
+		log.Print(err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// ExportOptions are passed to every exporter of a run.
+type ExportOptions struct {
+	// Dir is the directory the files are written to.
+	Dir        string
+	Run        string
+	Shifts     []schedule.Shift
+	Violations []validator.Violation
+	Reports    []ReportConfig
+	Labor      laborRates
+	// CSV is the configured format of the CSV files.
+	CSV CSVConfig
+}
+
+// Exporter writes a schedule, grouped by week, in one output format and
+// returns the paths of the files it wrote.
+type Exporter interface {
+	Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error)
+}
+
+// exporters are the output formats by config name. The weekly CSV files are
+// always written, since the other commands read them back.
+var exporters = map[string]Exporter{
+	"csv":    csvExporter{},
+	"ics":    icsExporter{},
+	"json":   jsonExporter{},
+	"report": reportExporter{},
+	"xlsx":   xlsxExporter{},
+}
+
+// configuredExporters returns the exporters enabled under exports in the
+// config, CSV first. Reports are enabled by configuring report templates.
+func configuredExporters(cfg Config) ([]string, error) {
+	names := []string{"csv"}
+	for _, name := range cfg.Exports {
+		name = strings.ToLower(name)
+		if _, ok := exporters[name]; !ok {
+			known := make([]string, 0, len(exporters))
+			for k := range exporters {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown export format %q (want one of %s)", name, strings.Join(known, ", "))
+		}
+		if name != "csv" && name != "report" {
+			names = append(names, name)
+		}
+	}
+	if len(cfg.Reports) > 0 {
+		names = append(names, "report")
+	}
+	return names, nil
+}
+
+// sortedWeekNames returns the week names of a schedule in order.
+func sortedWeekNames(weeks map[string][]FlatSchedule) []string {
+	names := make([]string, 0, len(weeks))
+	for week := range weeks {
+		names = append(names, week)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// csvExporter writes one CSV file per week, and the handover notes of the
+// schedule's dates to handover_notes.csv when there are any.
+type csvExporter struct{}
+
+func (csvExporter) Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error) {
+	var files []string
+	for _, week := range sortedWeekNames(weeks) {
+		filename, err := writeWeekCSV(opts.Dir, week, weeks[week], opts.CSV)
+		if err != nil {
+			return files, fmt.Errorf("error writing schedule for %s: %w", week, err)
+		}
+		files = append(files, filename)
+	}
+	notes, err := scheduleNotes(weeks, time.Now())
+	if err != nil || len(notes) == 0 {
+		return files, err
+	}
+	filename, err := writeHandoverCSV(opts.Dir, notes)
+	if err != nil {
+		return files, err
+	}
+	return append(files, filename), nil
+}
+
+// jsonExporter writes the whole schedule to schedule.json as an array of
+// the model's objects, in week order.
+type jsonExporter struct{}
+
+func (jsonExporter) Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error) {
+	entries := []FlatSchedule{}
+	for _, week := range sortedWeekNames(weeks) {
+		entries = append(entries, weeks[week]...)
+	}
+	if err := validateScheduleJSON(entries); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(opts.Dir, "schedule.json")
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return []string{filename}, nil
+}
+
+// xlsxExporter writes schedule.xlsx with a sheet per week, and a "Handover
+// notes" sheet when the schedule's dates have notes.
+type xlsxExporter struct{}
+
+func (xlsxExporter) Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error) {
+	filename := filepath.Join(opts.Dir, "schedule.xlsx")
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	notes, err := scheduleNotes(weeks, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	zw := zip.NewWriter(file)
+	names := sortedWeekNames(weeks)
+	tables := make([][][]string, 0, len(names)+1)
+	for _, week := range names {
+		objs := weeks[week]
+		tables = append(tables, buildTableForWeek(buildHeaderForWeek(objs), objs))
+	}
+	titles := make([]string, len(names))
+	for i, week := range names {
+		titles[i] = sheetName(week)
+	}
+	if len(notes) > 0 {
+		titles = append(titles, "Handover notes")
+		tables = append(tables, handoverTable(notes))
+	}
+	var sheets, rels, types strings.Builder
+	for i, title := range titles {
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlText(title), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + types.String() + `</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+	}
+	for i, table := range tables {
+		parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML(table)})
+	}
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", filename, err)
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", filename, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return []string{filename}, nil
+}
+
+// sheetXML lays out a table, such as a week with the same columns as its
+// CSV file, as a worksheet of inline strings.
+func sheetXML(table [][]string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range table {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, value := range row {
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t>%s</t></is></c>`, columnName(c), r+1, xmlText(value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnName returns the spreadsheet name of a zero-based column: A, B, ...,
+// Z, AA, and so on.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a week name fit the rules for sheet names.
+func sheetName(week string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, week)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	return name
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"employee-schedular/forecast"
+)
+
+// ForecastConfig configures forecasting. FORECAST_SERVICE_URL and
+// FORECAST_SERVICE_TOKEN take precedence over the config file.
+type ForecastConfig struct {
+	// ServiceURL is the endpoint of the external model.
+	ServiceURL string `json:"service_url,omitempty"`
+	// Token is sent as a bearer token when set.
+	Token          string `json:"token,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	// Fallback is the built-in model used when the chosen model fails.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+func forecastSettings(cfg Config) ForecastConfig {
+	fc := cfg.Forecast
+	if v := os.Getenv("FORECAST_SERVICE_URL"); v != "" {
+		fc.ServiceURL = v
+	}
+	if v := os.Getenv("FORECAST_SERVICE_TOKEN"); v != "" {
+		fc.Token = v
+	}
+	if fc.TimeoutSeconds <= 0 {
+		fc.TimeoutSeconds = 60
+	}
+	if fc.Fallback == "" {
+		fc.Fallback = "day-of-month"
+	}
+	return fc
+}
+
+func init() {
+	forecast.Register("external", func() forecast.Forecaster { return &externalModel{} })
+}
+
+// externalRequest is sent to the forecast service.
+type externalRequest struct {
+	History []externalDay `json:"history"`
+	Dates   []string      `json:"dates"`
+}
+
+// externalResponse is expected back, with a prediction for every date.
+type externalResponse struct {
+	Predictions []externalDay `json:"predictions"`
+}
+
+type externalDay struct {
+	Date  string  `json:"date"`
+	Calls float64 `json:"calls"`
+}
+
+// externalModel delegates forecasting to an HTTP service, for teams whose
+// models live in an existing data-science pipeline.
+type externalModel struct {
+	history []forecast.Observation
+}
+
+func (m *externalModel) Fit(history []forecast.Observation) error {
+	m.history = history
+	return nil
+}
+
+func (m *externalModel) Predict(dates []time.Time) ([]float64, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	settings := forecastSettings(cfg)
+	if settings.ServiceURL == "" {
+		return nil, errors.New("FORECAST_SERVICE_URL not set")
+	}
+
+	req := externalRequest{}
+	for _, o := range m.history {
+		req.History = append(req.History, externalDay{Date: o.Date.Format(forecast.DateLayout), Calls: o.Calls})
+	}
+	for _, d := range dates {
+		req.Dates = append(req.Dates, d.Format(forecast.DateLayout))
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, settings.ServiceURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if settings.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+settings.Token)
+	}
+	var resp externalResponse
+	if err := doJSON(&http.Client{Timeout: time.Duration(settings.TimeoutSeconds) * time.Second}, httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]float64, len(resp.Predictions))
+	for _, p := range resp.Predictions {
+		byDate[p.Date] = p.Calls
+	}
+	out := make([]float64, len(dates))
+	for i, d := range req.Dates {
+		calls, ok := byDate[d]
+		if !ok {
+			return nil, fmt.Errorf("forecast service returned no prediction for %s", d)
+		}
+		out[i] = calls
+	}
+	return out, nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// fairnessMu serializes changes to the fairness ledger.
+var fairnessMu sync.Mutex
+
+// fairnessLedger is the shift every employee worked on every published day,
+// kept across schedules so weekends, lates, and holidays can be shared out
+// fairly over time. Days map dates to employees to shift names.
+type fairnessLedger struct {
+	Days map[string]map[string]string `json:"days"`
+}
+
+// fairnessCounts is what an employee has worked of the unpopular days and
+// shifts.
+type fairnessCounts struct {
+	Weekends int `json:"weekends"`
+	Lates    int `json:"lates"`
+	Holidays int `json:"holidays"`
+}
+
+func fairnessPath() string {
+	return filepath.Join(dataDir(), "fairness.json")
+}
+
+// loadFairnessLedger reads the fairness ledger. A missing file yields an
+// empty ledger.
+func loadFairnessLedger() (*fairnessLedger, error) {
+	ledger := &fairnessLedger{Days: make(map[string]map[string]string)}
+	data, err := os.ReadFile(fairnessPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ledger, nil
+		}
+		return nil, fmt.Errorf("error reading fairness ledger: %w", err)
+	}
+	if err := json.Unmarshal(data, ledger); err != nil {
+		return nil, fmt.Errorf("error parsing fairness ledger: %w", err)
+	}
+	if ledger.Days == nil {
+		ledger.Days = make(map[string]map[string]string)
+	}
+	return ledger, nil
+}
+
+// save writes the fairness ledger, replacing it atomically.
+func (l *fairnessLedger) save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fairnessPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing fairness ledger: %w", err)
+	}
+	return os.Rename(tmp, fairnessPath())
+}
+
+// record replaces the days a schedule covers with its assignments, so a
+// regenerated schedule isn't counted twice.
+func (l *fairnessLedger) record(objs []FlatSchedule, shifts []schedule.Shift, now time.Time) {
+	for _, obj := range objs {
+		for _, key := range dayColumns(obj, now) {
+			date, _ := labelDate(key, now)
+			l.Days[date.Format(time.DateOnly)] = make(map[string]string)
+		}
+	}
+	for _, obj := range objs {
+		for _, key := range dayColumns(obj, now) {
+			if !isWorking(shifts, obj[key]) {
+				continue
+			}
+			date, _ := labelDate(key, now)
+			shift, _ := schedule.SplitCell(obj[key])
+			l.Days[date.Format(time.DateOnly)][obj["Employee"]] = shift
+		}
+	}
+}
+
+// lateShifts returns the catalog's shifts ending latest, which count as
+// lates unless the config names others.
+func lateShifts(cfg Config, shifts []schedule.Shift) []string {
+	if len(cfg.LateShifts) > 0 {
+		return cfg.LateShifts
+	}
+	var latest schedule.Clock
+	for _, sh := range shifts {
+		latest = max(latest, sh.End)
+	}
+	var names []string
+	for _, sh := range shifts {
+		if sh.End == latest {
+			names = append(names, sh.Name)
+		}
+	}
+	return names
+}
+
+// counts returns each employee's weekends, lates, and holidays worked. A
+// weekend counts once however many of its days were worked.
+func (l *fairnessLedger) counts(lates, holidays []string) map[string]fairnessCounts {
+	out := make(map[string]fairnessCounts)
+	weekends := make(map[string]map[string]bool)
+	for day, worked := range l.Days {
+		date, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			continue
+		}
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+		// The Saturday of the weekend names it.
+		saturday := date.AddDate(0, 0, -int(date.Weekday()+1)%7).Format(time.DateOnly)
+		for name, shift := range worked {
+			c := out[name]
+			if weekend {
+				if weekends[name] == nil {
+					weekends[name] = make(map[string]bool)
+				}
+				if !weekends[name][saturday] {
+					weekends[name][saturday] = true
+					c.Weekends++
+				}
+			}
+			if slices.Contains(lates, shift) {
+				c.Lates++
+			}
+			if slices.Contains(holidays, day) {
+				c.Holidays++
+			}
+			out[name] = c
+		}
+	}
+	return out
+}
+
+// fairnessNote describes the employees' running totals for the scheduling
+// prompt, with the holidays falling in the horizon, so the unpopular days
+// go to those who have worked fewer of them.
+func fairnessNote(counts map[string]fairnessCounts, employees, lates, holidays []string) string {
+	var totals []string
+	for _, name := range employees {
+		c := counts[name]
+		totals = append(totals, fmt.Sprintf("%s %d/%d/%d", name, c.Weekends, c.Lates, c.Holidays))
+	}
+	note := "Fairness over time: weekends/" + strings.Join(lates, " and ") + " shifts/holidays worked so far: " + strings.Join(totals, ", ") +
+		". Give weekends, those shifts, and holidays first to the employees with the lowest totals, to even them out across schedules."
+	if len(holidays) > 0 {
+		note += " Holidays in this schedule: " + strings.Join(holidays, ", ") + "."
+	}
+	return note
+}
+
+// validHolidays checks that the holidays are dates.
+func validHolidays(holidays []string) error {
+	for _, day := range holidays {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			return fmt.Errorf("invalid holiday %q: %w", day, err)
+		}
+	}
+	return nil
+}
+
+// holidaysBetween returns the configured holidays from start to end,
+// inclusive.
+func holidaysBetween(holidays []string, start, end time.Time) []string {
+	var out []string
+	for _, day := range holidays {
+		date, err := time.Parse(time.DateOnly, day)
+		if err == nil && !date.Before(start) && !date.After(end) {
+			out = append(out, day)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// recordFairness adds a published schedule to the fairness ledger.
+func recordFairness(weeks map[string][]FlatSchedule, shifts []schedule.Shift, now time.Time) error {
+	fairnessMu.Lock()
+	defer fairnessMu.Unlock()
+	ledger, err := loadFairnessLedger()
+	if err != nil {
+		return err
+	}
+	ledger.record(flatten(weeks), shifts, now)
+	return ledger.save()
+}
+
+// runFairness prints the fairness ledger's totals per employee. Given
+// schedule directories, it first records them, oldest first, to build the
+// ledger from schedules published before it existed.
+func runFairness(args []string) error {
+	fs := flag.NewFlagSet("fairness", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	for _, dir := range fs.Args() {
+		folders := runFolders(dir)
+		if len(folders) == 0 {
+			folders = []string{dir}
+		}
+		for _, folder := range folders {
+			entries, err := loadScheduleDir(folder)
+			if err != nil {
+				return err
+			}
+			if err := recordFairness(map[string][]FlatSchedule{"": entries}, shifts, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+	ledger, err := loadFairnessLedger()
+	if err != nil {
+		return err
+	}
+	lates := lateShifts(cfg, shifts)
+	counts := ledger.counts(lates, cfg.Holidays)
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "EMPLOYEE\tWEEKENDS\t%s\tHOLIDAYS\n", strings.ToUpper(strings.Join(lates, "/")))
+	for _, name := range names {
+		c := counts[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", name, c.Weekends, c.Lates, c.Holidays)
+	}
+	return tw.Flush()
+}
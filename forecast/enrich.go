@@ -0,0 +1,95 @@
+package forecast
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Signal is an external event attached to a date, such as a weather alert,
+// a sports event, or a known outage window.
+type Signal struct {
+	Date string `json:"date"`
+	// Source names the enricher that produced the signal.
+	Source string `json:"source"`
+	Label  string `json:"label"`
+	// Factor scales the expected calls of the date: 1.3 boosts demand by
+	// 30% and 0.8 dampens it by 20%. Zero only annotates the date.
+	Factor float64 `json:"factor,omitempty"`
+}
+
+// Enricher attaches external signals to the dates of a forecast.
+type Enricher interface {
+	Signals(dates []time.Time) ([]Signal, error)
+}
+
+var (
+	enrichersMu sync.RWMutex
+	enrichers   = make(map[string]Enricher)
+)
+
+// RegisterEnricher adds an enricher that is consulted for every projection.
+// Custom enrichers register themselves from an init function.
+func RegisterEnricher(name string, e Enricher) {
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+	enrichers[name] = e
+}
+
+// Enrich collects the signals of every registered enricher for the days of
+// the forecast and applies them. An enricher that fails is skipped and
+// reported in the returned errors so a broken feed never blocks a forecast.
+func (f *Forecast) Enrich() []error {
+	enrichersMu.RLock()
+	names := make([]string, 0, len(enrichers))
+	for name := range enrichers {
+		names = append(names, name)
+	}
+	enrichersMu.RUnlock()
+	sort.Strings(names)
+
+	dates := make([]time.Time, 0, len(f.Days))
+	for _, d := range f.Days {
+		t, err := time.Parse(DateLayout, d.Date)
+		if err != nil {
+			continue
+		}
+		dates = append(dates, t)
+	}
+
+	var errs []error
+	for _, name := range names {
+		enrichersMu.RLock()
+		e := enrichers[name]
+		enrichersMu.RUnlock()
+		signals, err := e.Signals(dates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error enriching forecast with %s: %w", name, err))
+			continue
+		}
+		for _, s := range signals {
+			if s.Source == "" {
+				s.Source = name
+			}
+			f.Apply(s)
+		}
+	}
+	return errs
+}
+
+// Apply attaches a signal to its day, scales the day's expected calls by
+// the signal's factor, and re-evaluates whether the day is high volume.
+func (f *Forecast) Apply(s Signal) {
+	for i := range f.Days {
+		d := &f.Days[i]
+		if d.Date != s.Date {
+			continue
+		}
+		d.Signals = append(d.Signals, s)
+		if s.Factor > 0 {
+			d.Calls *= s.Factor
+			d.HighVolume = d.Calls > f.Threshold
+		}
+	}
+}
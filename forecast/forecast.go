@@ -0,0 +1,127 @@
+// Package forecast holds the demand projection a schedule is generated
+// against, so forecasting and generation can run separately.
+package forecast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Version is the current forecast file format.
+const Version = 1
+
+// DateLayout is the layout of the dates in a forecast.
+const DateLayout = "2006-01-02"
+
+// Day is the projected demand of one calendar day.
+type Day struct {
+	Date  string  `json:"date"`
+	Calls float64 `json:"calls"`
+	// HighVolume marks days that need extra staff.
+	HighVolume bool `json:"high_volume"`
+	// Signals are the external events known for the day.
+	Signals []Signal `json:"signals,omitempty"`
+}
+
+// Forecast is a demand projection over whole weeks.
+type Forecast struct {
+	Version     int       `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Model names the model that produced the projection.
+	Model string `json:"model"`
+	// Percentile is the daily-volume percentile above which days are high
+	// volume.
+	Percentile float64 `json:"percentile"`
+	// Threshold is the daily volume at that percentile.
+	Threshold float64 `json:"threshold"`
+	Start     string  `json:"start"`
+	Weeks     int     `json:"weeks"`
+	Days      []Day   `json:"days"`
+	// HourShare is the share of a day's calls arriving in each hour of the
+	// day.
+	HourShare [24]float64 `json:"hour_share"`
+	// HalfHourShare is the share of a day's calls arriving in each half
+	// hour, from 00:00 to 23:30, when the call records gave it.
+	HalfHourShare []float64 `json:"half_hour_share,omitempty"`
+	// ShiftShare is the share of a day's calls falling to each shift.
+	ShiftShare map[string]float64 `json:"shift_share,omitempty"`
+	// HandleTime is the average talk time of an answered call, in seconds.
+	HandleTime float64 `json:"handle_time,omitempty"`
+}
+
+// Dates returns the days of n weeks starting at start.
+func Dates(start time.Time, weeks int) []time.Time {
+	dates := make([]time.Time, 0, weeks*7)
+	for i := 0; i < weeks*7; i++ {
+		dates = append(dates, start.AddDate(0, 0, i))
+	}
+	return dates
+}
+
+// HighVolumeDays returns the days of month of the high-volume days, as the
+// scheduling prompt refers to them.
+func (f *Forecast) HighVolumeDays() []int {
+	seen := make(map[int]bool)
+	var days []int
+	for _, d := range f.Days {
+		if !d.HighVolume {
+			continue
+		}
+		t, err := time.Parse(DateLayout, d.Date)
+		if err != nil || seen[t.Day()] {
+			continue
+		}
+		seen[t.Day()] = true
+		days = append(days, t.Day())
+	}
+	sort.Ints(days)
+	return days
+}
+
+// Validate checks that the forecast can be scheduled against.
+func (f *Forecast) Validate() error {
+	if f.Version != Version {
+		return fmt.Errorf("unsupported forecast version %d", f.Version)
+	}
+	if len(f.Days) == 0 {
+		return errors.New("forecast has no days")
+	}
+	for _, d := range f.Days {
+		if _, err := time.Parse(DateLayout, d.Date); err != nil {
+			return fmt.Errorf("invalid forecast date %q", d.Date)
+		}
+	}
+	return nil
+}
+
+// Load reads and validates a forecast file.
+func Load(path string) (*Forecast, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading forecast: %w", err)
+	}
+	var f Forecast
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing forecast: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Save writes the forecast as indented JSON.
+func (f *Forecast) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding forecast: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing forecast: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package forecast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Observation is the call volume of one historical day.
+type Observation struct {
+	Date  time.Time
+	Calls float64
+}
+
+// Forecaster is a demand model. Models are fitted on daily history and
+// then asked for the expected calls of future dates.
+type Forecaster interface {
+	Fit(history []Observation) error
+	Predict(dates []time.Time) ([]float64, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]func() Forecaster)
+)
+
+// Register makes a model available under name. Custom models register
+// themselves from an init function.
+func Register(name string, factory func() Forecaster) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// New returns a fresh instance of the named model.
+func New(name string) (Forecaster, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown forecast model %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(), nil
+}
+
+// Names returns the registered model names in order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Project fits model on history and projects weeks of demand from start.
+// Days whose expected calls are above the percentile of the historical
+// daily volumes are high volume.
+func Project(name string, model Forecaster, history []Observation, start time.Time, weeks int, percentile float64) (*Forecast, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no history to forecast from")
+	}
+	if err := model.Fit(history); err != nil {
+		return nil, fmt.Errorf("error fitting %s model: %w", name, err)
+	}
+	dates := Dates(start, weeks)
+	calls, err := model.Predict(dates)
+	if err != nil {
+		return nil, fmt.Errorf("error predicting with %s model: %w", name, err)
+	}
+	if len(calls) != len(dates) {
+		return nil, fmt.Errorf("%s model returned %d predictions for %d days", name, len(calls), len(dates))
+	}
+	threshold := Percentile(history, percentile)
+	f := &Forecast{
+		Version:     Version,
+		GeneratedAt: time.Now().UTC(),
+		Model:       name,
+		Percentile:  percentile,
+		Threshold:   threshold,
+		Start:       start.Format(DateLayout),
+		Weeks:       weeks,
+	}
+	for i, date := range dates {
+		f.Days = append(f.Days, Day{
+			Date:       date.Format(DateLayout),
+			Calls:      calls[i],
+			HighVolume: calls[i] > threshold,
+		})
+	}
+	return f, nil
+}
+
+// Percentile returns the daily volume at the given percentile of history,
+// picked the same way as the original high-volume day threshold.
+func Percentile(history []Observation, percentile float64) float64 {
+	values := make([]float64, len(history))
+	for i, o := range history {
+		values[i] = o.Calls
+	}
+	sort.Float64s(values)
+	index := int((percentile / 100.0) * float64(len(values)))
+	if index >= len(values) {
+		index = len(values) - 1
+	}
+	return values[index]
+}
+
+func init() {
+	Register("day-of-month", func() Forecaster { return &dayOfMonth{} })
+	Register("weekday", func() Forecaster { return &weekdayMean{} })
+}
+
+// dayOfMonth expects a date to see the average volume of its day of month,
+// falling back to the overall average for days never observed.
+type dayOfMonth struct {
+	means   map[int]float64
+	overall float64
+}
+
+func (m *dayOfMonth) Fit(history []Observation) error {
+	m.means, m.overall = meansBy(history, func(t time.Time) int { return t.Day() })
+	return nil
+}
+
+func (m *dayOfMonth) Predict(dates []time.Time) ([]float64, error) {
+	return predictBy(dates, m.means, m.overall, func(t time.Time) int { return t.Day() }), nil
+}
+
+// weekdayMean expects a date to see the average volume of its weekday,
+// which suits contact centers with a weekly rather than monthly rhythm.
+type weekdayMean struct {
+	means   map[int]float64
+	overall float64
+}
+
+func (m *weekdayMean) Fit(history []Observation) error {
+	m.means, m.overall = meansBy(history, func(t time.Time) int { return int(t.Weekday()) })
+	return nil
+}
+
+func (m *weekdayMean) Predict(dates []time.Time) ([]float64, error) {
+	return predictBy(dates, m.means, m.overall, func(t time.Time) int { return int(t.Weekday()) }), nil
+}
+
+// meansBy averages the history per key, and overall.
+func meansBy(history []Observation, key func(time.Time) int) (map[int]float64, float64) {
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	total := 0.0
+	for _, o := range history {
+		k := key(o.Date)
+		sums[k] += o.Calls
+		counts[k]++
+		total += o.Calls
+	}
+	means := make(map[int]float64, len(sums))
+	for k, sum := range sums {
+		means[k] = sum / float64(counts[k])
+	}
+	overall := 0.0
+	if len(history) > 0 {
+		overall = total / float64(len(history))
+	}
+	return means, overall
+}
+
+func predictBy(dates []time.Time, means map[int]float64, overall float64, key func(time.Time) int) []float64 {
+	out := make([]float64, len(dates))
+	for i, d := range dates {
+		if mean, ok := means[key(d)]; ok {
+			out[i] = mean
+		} else {
+			out[i] = overall
+		}
+	}
+	return out
+}
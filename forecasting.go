@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+)
+
+// forecastWeeks is the horizon of a generated schedule.
+const forecastWeeks = 5
+
+// ingestInputs loads, checks, and deduplicates the call records of opts.
+// Rejected rows are written to the output directory.
+func ingestInputs(ctx context.Context, opts generateOptions) (Aggregates, error) {
+	_, ingest := startSpan(ctx, "ingest")
+	ingest.setAttr("input.files", len(opts.Inputs))
+	mapping, err := inputMapping(opts.Preset)
+	if err != nil {
+		ingest.finish(err)
+		return Aggregates{}, err
+	}
+	agg, err := loadAggregates(ctx, opts.Inputs, opts.Dedup, mapping)
+	ingest.setAttr("input.rows", agg.Rows)
+	ingest.finish(err)
+	if err != nil {
+		return agg, fmt.Errorf("error processing CSV: %w", err)
+	}
+	if len(agg.Rejects) > 0 {
+		cfg, err := loadConfig()
+		if err != nil {
+			return agg, err
+		}
+		filename, err := writeRejects(opts.OutputDir, cfg.CSV, agg.Rejects)
+		if err != nil {
+			return agg, err
+		}
+		log.Printf("Rejected rows written to %s", filename)
+	}
+	if err := checkBadRows(agg, opts); err != nil {
+		return agg, err
+	}
+	log.Printf("Processed %d records.\n", agg.Rows)
+	if agg.Duplicates+agg.Overlapping > 0 {
+		log.Printf("Deduplication (%s) dropped %d exact duplicates and %d overlapping records", opts.Dedup, agg.Duplicates, agg.Overlapping)
+	}
+	return agg, nil
+}
+
+// nextMonday returns the first Monday after t, at midnight UTC.
+func nextMonday(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := (8 - int(day.Weekday())) % 7
+	if days == 0 {
+		days = 7
+	}
+	return day.AddDate(0, 0, days)
+}
+
+// projectDemand fits the named model on the daily volumes of the
+// aggregates and projects weeks of demand from start. When the model fails
+// the configured fallback model is used instead. The projection is then
+// adjusted by the signals of the registered enrichers.
+func projectDemand(agg Aggregates, model string, start time.Time, weeks int, percentile float64) (*forecast.Forecast, error) {
+	m, err := forecast.New(model)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]forecast.Observation, 0, len(agg.DateCounts))
+	for date, n := range agg.DateCounts {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		history = append(history, forecast.Observation{Date: t, Calls: float64(n)})
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := forecast.Project(model, m, history, start, weeks, percentile)
+	if err != nil {
+		fallback := forecastSettings(cfg).Fallback
+		if fallback == model {
+			return nil, err
+		}
+		log.Printf("Forecast model %s failed, falling back to %s: %v", model, fallback, err)
+		if m, err = forecast.New(fallback); err != nil {
+			return nil, err
+		}
+		if fc, err = forecast.Project(fallback, m, history, start, weeks, percentile); err != nil {
+			return nil, err
+		}
+	}
+	for _, err := range fc.Enrich() {
+		log.Printf("Skipping enrichment: %v", err)
+	}
+	if agg.Rows > 0 {
+		for h, n := range agg.HourCounts {
+			fc.HourShare[h] = float64(n) / float64(agg.Rows)
+		}
+		fc.ShiftShare = schedule.DemandShares(fc.HourShare, shifts)
+	}
+	// Aggregates archived before half hours were counted have none, so the
+	// half-hour shares are of the calls that have.
+	halfHours := 0
+	for _, n := range agg.HalfHourCounts {
+		halfHours += n
+	}
+	if halfHours > 0 {
+		fc.HalfHourShare = make([]float64, 48)
+		for i, n := range agg.HalfHourCounts {
+			fc.HalfHourShare[i] = float64(n) / float64(halfHours)
+		}
+	}
+	if agg.Answered > 0 {
+		fc.HandleTime = agg.TalkedSeconds / float64(agg.Answered)
+	}
+	return fc, nil
+}
+
+// loadForecast returns the forecast to schedule against: the forecast file
+// named in opts, or a projection of the inputs over the next five weeks.
+func loadForecast(ctx context.Context, opts generateOptions) (*forecast.Forecast, error) {
+	if opts.Forecast != "" {
+		fc, err := forecast.Load(opts.Forecast)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using forecast %s starting %s", opts.Forecast, fc.Start)
+		return fc, nil
+	}
+	if len(opts.Inputs) == 0 {
+		return nil, errors.New("no call records to forecast from: give -input files or a -forecast")
+	}
+	agg, err := ingestInputs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	_, sp := startSpan(ctx, "forecast")
+	sp.setAttr("forecast.model", opts.Model)
+	sp.setAttr("forecast.percentile", opts.Percentile)
+	fc, err := projectDemand(agg, opts.Model, nextMonday(time.Now()), forecastWeeks, opts.Percentile)
+	if err == nil {
+		sp.setAttr("forecast.high_volume_days", len(fc.HighVolumeDays()))
+	}
+	sp.finish(err)
+	return fc, err
+}
+
+// runForecast writes the demand projection of the inputs, or of the demand
+// store when no inputs are given, to a forecast file.
+func runForecast(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	weeks := fs.Int("weeks", forecastWeeks, "number of weeks to project")
+	out := fs.String("out", "forecast.json", "forecast file to write")
+	start := fs.String("start", "", "first day of the forecast (2006-01-02, defaults to next Monday)")
+	fs.Float64Var(&opts.Percentile, "percentile", opts.Percentile, "daily-volume percentile above which days are high volume")
+	fs.StringVar(&opts.Model, "model", opts.Model, "forecast model: "+strings.Join(forecast.Names(), ", "))
+	fs.StringVar(&opts.Dedup, "dedup", opts.Dedup, "how to drop duplicate call records: none, keep-first, keep-last, or keep-longest")
+	fs.StringVar(&opts.Preset, "preset", "", "column mapping of a known export format: "+strings.Join(presetNames(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *weeks < 1 {
+		return errors.New("weeks must be at least 1")
+	}
+	if _, err := forecast.New(opts.Model); err != nil {
+		return err
+	}
+	from := nextMonday(time.Now())
+	if *start != "" {
+		t, err := time.Parse(forecast.DateLayout, *start)
+		if err != nil {
+			return fmt.Errorf("invalid -start date: %w", err)
+		}
+		from = t
+	}
+
+	var agg Aggregates
+	if fs.NArg() > 0 {
+		opts.Inputs = fs.Args()
+		var err error
+		if agg, err = ingestInputs(context.Background(), opts); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if agg, err = loadDemandAggregates(); err != nil {
+			return err
+		}
+		if agg.Rows == 0 {
+			return errors.New("no inputs given and the demand store is empty")
+		}
+		log.Printf("Forecasting from %d records in the demand store", agg.Rows)
+	}
+
+	fc, err := projectDemand(agg, opts.Model, from, *weeks, opts.Percentile)
+	if err != nil {
+		return err
+	}
+	if err := fc.Save(*out); err != nil {
+		return err
+	}
+	log.Printf("Forecast for %d weeks from %s written to %s (high volume days: %v)", *weeks, fc.Start, *out, fc.HighVolumeDays())
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// FreezeConfig sets the freeze window: the days ahead, starting today,
+// whose published assignments regeneration keeps as they are. Changes
+// inside it go through the swap command. Zero disables the freeze.
+type FreezeConfig struct {
+	Days int `json:"days,omitempty"`
+}
+
+// labelDate returns the date of a day column such as "Monday (1st March)".
+// The labels carry no year, so the one putting the date closest to now is
+// used.
+func labelDate(label string, now time.Time) (time.Time, bool) {
+	month, ok := schedule.LabelMonth(label)
+	day := extractDayNumber(label)
+	if !ok || day == 0 {
+		return time.Time{}, false
+	}
+	var best time.Time
+	for year := now.Year() - 1; year <= now.Year()+1; year++ {
+		d := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		if best.IsZero() || d.Sub(now).Abs() < best.Sub(now).Abs() {
+			best = d
+		}
+	}
+	return best, true
+}
+
+// inWindow reports whether a date falls in the days ahead, starting today.
+func inWindow(date time.Time, days int, now time.Time) bool {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return days > 0 && !date.Before(today) && date.Before(today.AddDate(0, 0, days))
+}
+
+// frozen reports whether a day column falls inside the freeze window.
+func (f FreezeConfig) frozen(label string, now time.Time) bool {
+	date, ok := labelDate(label, now)
+	return ok && inWindow(date, f.Days, now)
+}
+
+// publishedChange is a published assignment a regenerated schedule changes.
+type publishedChange struct {
+	Employee string
+	Date     time.Time
+	// Week and Day are the date's week and column in the regenerated
+	// schedule, and row the employee's row there.
+	Week, Day     string
+	Before, After string
+	row           FlatSchedule
+}
+
+// restore puts the published assignment back in the regenerated schedule.
+func (c publishedChange) restore() {
+	c.row[c.Day] = c.Before
+}
+
+// publishedChanges compares the published assignments on the days of a
+// window with a regenerated schedule, matching them by employee and
+// calendar date: the horizon moves between runs, so the same week label
+// can stand for other dates. It returns the assignments the regenerated
+// schedule changes, by date and employee. Days it doesn't schedule are
+// left out, and employees it leaves out get a row for the weeks they have
+// published days in, so those can be restored.
+func publishedChanges(weeks map[string][]FlatSchedule, published []FlatSchedule, inside func(time.Time) bool, now time.Time) []publishedChange {
+	type place struct{ week, day string }
+	places := make(map[time.Time]place)
+	rows := make(map[string]FlatSchedule)
+	for week, objs := range weeks {
+		for _, obj := range objs {
+			rows[week+"|"+obj["Employee"]] = obj
+			for key := range obj {
+				if date, ok := labelDate(key, now); ok && strings.Contains(key, "(") {
+					places[date] = place{week, key}
+				}
+			}
+		}
+	}
+
+	var changes []publishedChange
+	for _, old := range published {
+		employee := old["Employee"]
+		for key, value := range old {
+			date, ok := labelDate(key, now)
+			if !ok || !strings.Contains(key, "(") || !inside(date) {
+				continue
+			}
+			at, ok := places[date]
+			if !ok {
+				continue
+			}
+			row := rows[at.week+"|"+employee]
+			if row == nil {
+				row = FlatSchedule{"Week": at.week, "Employee": employee}
+				rows[at.week+"|"+employee] = row
+				weeks[at.week] = append(weeks[at.week], row)
+			}
+			if row[at.day] != value {
+				changes = append(changes, publishedChange{
+					Employee: employee, Date: date, Week: at.week, Day: at.day,
+					Before: value, After: row[at.day], row: row,
+				})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].Date.Equal(changes[j].Date) {
+			return changes[i].Date.Before(changes[j].Date)
+		}
+		return changes[i].Employee < changes[j].Employee
+	})
+	return changes
+}
+
+// keepFrozen restores the published assignments of the freeze window in a
+// regenerated schedule, from the schedule files already in dir. It returns
+// the number of cells it restored.
+func keepFrozen(weeks map[string][]FlatSchedule, dir string, freeze FreezeConfig, now time.Time) (int, error) {
+	if freeze.Days <= 0 {
+		return 0, nil
+	}
+	published, err := lastPublished(dir)
+	if err != nil || published == nil {
+		return 0, err
+	}
+	inside := func(date time.Time) bool { return inWindow(date, freeze.Days, now) }
+	changes := publishedChanges(weeks, published, inside, now)
+	for _, c := range changes {
+		log.Printf("Keeping frozen assignment of %s on %s, %s: %q (regenerated as %q)", c.Employee, c.Week, c.Day, c.Before, c.After)
+		c.restore()
+	}
+	return len(changes), nil
+}
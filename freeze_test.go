@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// weekRows returns one week's rows from monday, with each employee's
+// cells in day order.
+func weekRows(week string, monday time.Time, cells map[string][]string) []FlatSchedule {
+	var rows []FlatSchedule
+	for employee, days := range cells {
+		row := FlatSchedule{"Week": week, "Employee": employee}
+		for i, cell := range days {
+			date := monday.AddDate(0, 0, i)
+			row[english.dayLabel(date.Weekday(), date.Day(), date.Month())] = cell
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// writePublished writes rows as the published schedule files of dir, one
+// per week.
+func writePublished(t *testing.T, dir string, rows []FlatSchedule) {
+	t.Helper()
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	byWeek := make(map[string][]FlatSchedule)
+	for _, row := range rows {
+		byWeek[row["Week"]] = append(byWeek[row["Week"]], row)
+	}
+	for week, rows := range byWeek {
+		if _, err := writeWeekCSV(dir, week, rows, CSVConfig{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// cell returns an employee's cell on a date in a regenerated schedule.
+func cell(weeks map[string][]FlatSchedule, employee string, date time.Time) string {
+	label := english.dayLabel(date.Weekday(), date.Day(), date.Month())
+	for _, rows := range weeks {
+		for _, row := range rows {
+			if v, ok := row[label]; ok && row["Employee"] == employee {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPublishedChanges(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	early := []string{"Early", "Early", "Early", "Early", "Early", "Off", "Off"}
+	late := []string{"Late", "Late", "Late", "Late", "Late", "Off", "Off"}
+	// Published a week earlier: Week 2 is 12-18 October and Week 3 19-25.
+	var published []FlatSchedule
+	published = append(published, weekRows("Week 2", day(10, 12), map[string][]string{"Ann": early, "Bob": early})...)
+	published = append(published, weekRows("Week 3", day(10, 19), map[string][]string{"Ann": early, "Bob": early})...)
+
+	tests := []struct {
+		name string
+		// regenerated is the new schedule, whose Week 1 is 19-25 October and
+		// Week 2 26 October to 1 November.
+		regenerated []FlatSchedule
+		days        int
+		want        map[time.Time]string
+		changes     int
+	}{
+		{
+			name: "horizon rolled forward",
+			regenerated: append(weekRows("Week 1", day(10, 19), map[string][]string{"Ann": late, "Bob": early}),
+				weekRows("Week 2", day(10, 26), map[string][]string{"Ann": late, "Bob": late})...),
+			days: 10,
+			// The freeze runs to 23 October: Ann keeps her published
+			// Early shifts there, and the new Week 2 is left alone.
+			want:    map[time.Time]string{day(10, 19): "Early", day(10, 23): "Early", day(10, 26): "Late"},
+			changes: 5,
+		},
+		{
+			name:        "employee left out",
+			regenerated: weekRows("Week 1", day(10, 19), map[string][]string{"Ann": early}),
+			days:        7,
+			want:        map[time.Time]string{day(10, 19): "Early", day(10, 20): "Early"},
+			changes:     2,
+		},
+		{
+			name:        "nothing inside the window",
+			regenerated: weekRows("Week 1", day(10, 19), map[string][]string{"Ann": late, "Bob": late}),
+			days:        3,
+			want:        map[time.Time]string{day(10, 19): "Late"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weeks := make(map[string][]FlatSchedule)
+			for _, row := range tt.regenerated {
+				weeks[row["Week"]] = append(weeks[row["Week"]], row)
+			}
+			inside := func(date time.Time) bool { return inWindow(date, tt.days, now) }
+			changes := publishedChanges(weeks, published, inside, now)
+			if len(changes) != tt.changes {
+				t.Fatalf("got %d changes, want %d: %+v", len(changes), tt.changes, changes)
+			}
+			for _, c := range changes {
+				c.restore()
+			}
+			employee := "Ann"
+			if tt.name == "employee left out" {
+				employee = "Bob"
+			}
+			for date, want := range tt.want {
+				if got := cell(weeks, employee, date); got != want {
+					t.Errorf("%s on %s: got %q, want %q", employee, date.Format(time.DateOnly), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepFrozen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	writePublished(t, dir, weekRows("Week 2", day(10, 19), map[string][]string{"Ann": {"Early", "Early", "Off", "Off", "Early", "Early", "Early"}}))
+	weeks := map[string][]FlatSchedule{
+		"Week 2": weekRows("Week 2", day(10, 26), map[string][]string{"Ann": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"}}),
+		"Week 1": weekRows("Week 1", day(10, 19), map[string][]string{"Ann": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"}}),
+	}
+	restored, err := keepFrozen(weeks, dir, FreezeConfig{Days: 8}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The window runs to 21 October: 19, 20 (Early), and 21 (Off) differ.
+	if restored != 3 {
+		t.Errorf("restored %d cells, want 3", restored)
+	}
+	for date, want := range map[time.Time]string{day(10, 19): "Early", day(10, 21): "Off", day(10, 22): "Late", day(10, 26): "Late"} {
+		if got := cell(weeks, "Ann", date); got != want {
+			t.Errorf("Ann on %s: got %q, want %q", date.Format(time.DateOnly), got, want)
+		}
+	}
+}
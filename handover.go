@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// HandoverNote is a note for whoever works a shift on a date, such as
+// "system migration tonight, expect tickets". It shows in exports, calendar
+// events, and shift reminders of the shifts it is for.
+type HandoverNote struct {
+	ID   string `json:"id"`
+	Date string `json:"date"`
+	// Shift is the shift the note is for; empty for every shift that day.
+	Shift string    `json:"shift,omitempty"`
+	Text  string    `json:"text"`
+	By    string    `json:"by,omitempty"`
+	Added time.Time `json:"added"`
+}
+
+func handoverNotesPath() string {
+	return filepath.Join(dataDir(), "handover_notes.json")
+}
+
+// loadHandoverNotes reads the handover notes file. A missing file has no
+// notes.
+func loadHandoverNotes() ([]HandoverNote, error) {
+	data, err := os.ReadFile(handoverNotesPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading handover notes: %w", err)
+	}
+	var notes []HandoverNote
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("error parsing handover notes: %w", err)
+	}
+	return notes, nil
+}
+
+// saveHandoverNotes writes the handover notes file, replacing it
+// atomically.
+func saveHandoverNotes(notes []HandoverNote) error {
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	tmp := handoverNotesPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing handover notes: %w", err)
+	}
+	return os.Rename(tmp, handoverNotesPath())
+}
+
+// attachHandoverNotes sets the note of each shift to the handover notes of
+// its date and shift, joined in the order they were added.
+func attachHandoverNotes(shifts []exportedShift) error {
+	notes, err := loadHandoverNotes()
+	if err != nil || len(notes) == 0 {
+		return err
+	}
+	for i, s := range shifts {
+		var texts []string
+		date := s.Start.Format(time.DateOnly)
+		for _, n := range notes {
+			if n.Date == date && (n.Shift == "" || strings.EqualFold(n.Shift, s.Shift)) {
+				texts = append(texts, n.Text)
+			}
+		}
+		shifts[i].Note = strings.Join(texts, "; ")
+	}
+	return nil
+}
+
+// handoverNotesFileName is the file the csv exporter writes the notes of a
+// schedule's dates to.
+const handoverNotesFileName = "handover_notes.csv"
+
+// scheduleNotes returns the handover notes falling on the dates of a
+// schedule, by date and then shift.
+func scheduleNotes(weeks map[string][]FlatSchedule, now time.Time) ([]HandoverNote, error) {
+	notes, err := loadHandoverNotes()
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	dates := make(map[string]bool)
+	for _, objs := range weeks {
+		for _, obj := range objs {
+			for key := range obj {
+				if d, ok := labelDate(key, now); ok {
+					dates[d.Format(time.DateOnly)] = true
+				}
+			}
+		}
+	}
+	var out []HandoverNote
+	for _, n := range notes {
+		if dates[n.Date] {
+			out = append(out, n)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date < out[j].Date
+		}
+		return out[i].Shift < out[j].Shift
+	})
+	return out, nil
+}
+
+// handoverTable lays out handover notes as rows of date, shift, note, and
+// author, with "All" for notes on every shift.
+func handoverTable(notes []HandoverNote) [][]string {
+	table := [][]string{{"Date", "Shift", "Note", "By"}}
+	for _, n := range notes {
+		shift := n.Shift
+		if shift == "" {
+			shift = "All"
+		}
+		table = append(table, []string{n.Date, shift, n.Text, n.By})
+	}
+	return table
+}
+
+// writeHandoverCSV writes handover notes to handover_notes.csv in dir and
+// returns the file path.
+func writeHandoverCSV(dir string, notes []HandoverNote) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(dir, handoverNotesFileName)
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	defer file.Close()
+	writer, err := newCSVWriter(file, cfg.CSV)
+	if err != nil {
+		return "", err
+	}
+	if err := writer.WriteAll(handoverTable(notes)); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return filename, nil
+}
+
+// runHandover adds, lists, and removes handover notes.
+func runHandover(args []string) error {
+	usage := errors.New("usage: handover add [-shift name] [-by name] <date> <text> | handover list [-from date] | handover remove <id>")
+	if len(args) == 0 {
+		return usage
+	}
+	notes, err := loadHandoverNotes()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("handover add", flag.ContinueOnError)
+		shift := fs.String("shift", "", "shift the note is for (default every shift that day)")
+		by := fs.String("by", os.Getenv("USER"), "who wrote the note")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() < 2 {
+			return usage
+		}
+		date, err := time.Parse(time.DateOnly, fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid date: %w", err)
+		}
+		if *shift != "" {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			catalog, err := shiftCatalog(cfg)
+			if err != nil {
+				return err
+			}
+			sh, ok := findShift(catalog, *shift)
+			if !ok {
+				return fmt.Errorf("unknown shift %q", *shift)
+			}
+			*shift = sh.Name
+		}
+		note := HandoverNote{
+			Date:  date.Format(time.DateOnly),
+			Shift: *shift,
+			Text:  strings.Join(fs.Args()[1:], " "),
+			By:    *by,
+			Added: time.Now().UTC(),
+		}
+		for n := 1; note.ID == "" || slices.ContainsFunc(notes, func(o HandoverNote) bool { return o.ID == note.ID }); n++ {
+			scope := note.Shift
+			if scope == "" {
+				scope = "all"
+			}
+			note.ID = fmt.Sprintf("%s-%s-%d", note.Date, scope, n)
+		}
+		if err := saveHandoverNotes(append(notes, note)); err != nil {
+			return err
+		}
+		fmt.Printf("Handover note %s added\n", note.ID)
+		return nil
+	case "list":
+		fs := flag.NewFlagSet("handover list", flag.ContinueOnError)
+		from := fs.String("from", time.Now().Format(time.DateOnly), "first date to list notes for")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		sort.SliceStable(notes, func(i, j int) bool { return notes[i].Date < notes[j].Date })
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tDATE\tSHIFT\tBY\tNOTE")
+		for _, n := range notes {
+			if n.Date < *from {
+				continue
+			}
+			shift := n.Shift
+			if shift == "" {
+				shift = "all"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", n.ID, n.Date, shift, n.By, n.Text)
+		}
+		return tw.Flush()
+	case "remove":
+		if len(args) != 2 {
+			return usage
+		}
+		i := slices.IndexFunc(notes, func(n HandoverNote) bool { return n.ID == args[1] })
+		if i < 0 {
+			return fmt.Errorf("no handover note %s", args[1])
+		}
+		if err := saveHandoverNotes(slices.Delete(notes, i, i+1)); err != nil {
+			return err
+		}
+		fmt.Printf("Handover note %s removed\n", args[1])
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNotes(t *testing.T) {
+	now := day(10, 14)
+	weeks := map[string][]FlatSchedule{
+		"Week 1": weekRows("Week 1", day(10, 19), map[string][]string{"Ann": {"Early", "Late"}}),
+	}
+	tests := []struct {
+		name  string
+		notes []HandoverNote
+		// want lists the IDs of the notes returned, in order.
+		want []string
+	}{
+		{name: "no notes"},
+		{
+			name: "notes on the schedule's dates",
+			notes: []HandoverNote{
+				{ID: "b", Date: "2026-10-20", Shift: "Late", Text: "launch"},
+				{ID: "a", Date: "2026-10-19", Shift: "Late", Text: "migration"},
+				{ID: "c", Date: "2026-10-19", Text: "fire drill"},
+			},
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name: "notes on other dates",
+			notes: []HandoverNote{
+				{ID: "a", Date: "2026-10-21", Text: "outside the schedule"},
+				{ID: "b", Date: "2025-10-19", Text: "a year earlier"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+			t.Setenv("SCHEDULER_CONFIG", "")
+			if tt.notes != nil {
+				if err := saveHandoverNotes(tt.notes); err != nil {
+					t.Fatal(err)
+				}
+			}
+			notes, err := scheduleNotes(weeks, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range notes {
+				got = append(got, n.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got notes %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVExporterHandoverNotes(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	today := time.Now()
+	monday := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	weeks := map[string][]FlatSchedule{
+		"Week 1": weekRows("Week 1", monday, map[string][]string{"Ann": {"Early"}}),
+	}
+	notes := []HandoverNote{{ID: "a", Date: monday.Format(time.DateOnly), Text: "migration", By: "Sam"}}
+	if err := saveHandoverNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files, err := csvExporter{}.Write(weeks, ExportOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, handoverNotesFileName)
+	if !slices.Contains(files, want) {
+		t.Fatalf("wrote %q, want %s among them", files, want)
+	}
+	data, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row := monday.Format(time.DateOnly) + ",All,migration,Sam"; !strings.Contains(string(data), row) {
+		t.Errorf("%s is %q, want a row %q", handoverNotesFileName, data, row)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// openAIModelsURL is fetched to confirm the OpenAI API is reachable and the
+// key is accepted.
+const openAIModelsURL = "https://api.openai.com/v1/models"
+
+// readinessCheck is one dependency verified by /readyz.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// checkStore verifies that the state store can be read: every persisted job
+// and run is reachable through the data directory.
+func checkStore(ctx context.Context) error {
+	for _, dir := range []string{jobsDir(), historyDir()} {
+		if _, err := os.ReadDir(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkStorageWritable writes and removes a probe file in the data directory
+// and the schedule output directory.
+func checkStorageWritable(ctx context.Context) error {
+	for _, dir := range []string{dataDir(), filepath.Join(dataDir(), "schedules")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		probe, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		probe.Close()
+		if err := os.Remove(probe.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openAIChecker checks OpenAI reachability, caching the result briefly so
+// frequent probes don't turn into a request per probe.
+type openAIChecker struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+	ttl     time.Duration
+	client  *http.Client
+}
+
+func (c *openAIChecker) check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked.IsZero() && time.Since(c.checked) < c.ttl {
+		return c.err
+	}
+	c.err = c.fetch(ctx)
+	c.checked = time.Now()
+	return c.err
+}
+
+func (c *openAIChecker) fetch(ctx context.Context) error {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return errors.New("OPENAI_API_KEY not set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, openAIModelsURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenAI returned %s", resp.Status)
+	}
+	return nil
+}
+
+// handleHealthz reports that the process is up.
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz runs every readiness check and fails if any of them does.
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]string, len(s.checks))
+	for _, c := range s.checks {
+		if err := c.check(ctx); err != nil {
+			results[c.name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			results[c.name] = "ok"
+		}
+	}
+	writeJSON(w, status, results)
+}
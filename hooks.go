@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"employee-schedular/validator"
+)
+
+// Lifecycle events published on the event bus, besides
+// eventSchedulePublished.
+const (
+	eventPreGenerate       = "pre_generate"
+	eventScheduleGenerated = "schedule_generated"
+	eventValidationFailed  = "validation_failed"
+	eventSwapApproved      = "swap_approved"
+)
+
+// Event is a lifecycle event published on the bus.
+type Event interface {
+	EventName() string
+}
+
+// PreGenerate is published before a run asks the model for a schedule.
+// Unlike other events, a failing hook stops the run.
+type PreGenerate struct {
+	RunID     string   `json:"run"`
+	OutputDir string   `json:"output_dir"`
+	Employees []string `json:"employees"`
+	Inputs    []string `json:"inputs,omitempty"`
+}
+
+// ScheduleGenerated is published when a run has written its schedule to a
+// new run folder.
+type ScheduleGenerated struct {
+	RunID      string   `json:"run"`
+	Folder     string   `json:"folder"`
+	Files      []string `json:"files"`
+	Violations int      `json:"violations"`
+}
+
+// ValidationFailed is published when a generated schedule breaks rules
+// with error severity.
+type ValidationFailed struct {
+	RunID      string                `json:"run"`
+	Violations []validator.Violation `json:"violations"`
+}
+
+// SchedulePublished is published when a server job publishes a schedule,
+// or the apply command a planned run, which leaves Job empty. Start and End are the first and last days it covers, when its run has a
+// forecast.
+type SchedulePublished struct {
+	Job    string `json:"job"`
+	RunID  string `json:"run"`
+	Folder string `json:"folder"`
+	Start  string `json:"start,omitempty"`
+	End    string `json:"end,omitempty"`
+}
+
+// SwapApproved is published when two employees' assignments on a day of a
+// published schedule are swapped.
+type SwapApproved struct {
+	Schedule   string            `json:"schedule"`
+	Week       string            `json:"week"`
+	Day        string            `json:"day"`
+	Employees  []string          `json:"employees"`
+	Before     map[string]string `json:"before"`
+	After      map[string]string `json:"after"`
+	By         string            `json:"by,omitempty"`
+	ReasonCode string            `json:"reason_code,omitempty"`
+}
+
+func (PreGenerate) EventName() string       { return eventPreGenerate }
+func (ScheduleGenerated) EventName() string { return eventScheduleGenerated }
+func (ValidationFailed) EventName() string  { return eventValidationFailed }
+func (SchedulePublished) EventName() string { return eventSchedulePublished }
+func (SwapApproved) EventName() string      { return eventSwapApproved }
+
+// schedulePublishedEvent describes a job's newly published schedule.
+func schedulePublishedEvent(job Job) SchedulePublished {
+	ev := SchedulePublished{Job: job.ID, RunID: job.RunID}
+	if run, err := loadRun(job.RunID); err == nil {
+		ev = runPublishedEvent(run)
+		ev.Job = job.ID
+	}
+	return ev
+}
+
+// runPublishedEvent describes a run's newly published schedule.
+func runPublishedEvent(run *Run) SchedulePublished {
+	ev := SchedulePublished{RunID: run.ID, Folder: run.Folder}
+	if run.Forecast != nil && len(run.Forecast.Days) > 0 {
+		ev.Start, ev.End = run.Forecast.Days[0].Date, run.Forecast.Days[len(run.Forecast.Days)-1].Date
+	}
+	return ev
+}
+
+// eventBus hands events to the handlers subscribed in process and then to
+// the hooks configured for them.
+type eventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]func(Event)
+}
+
+// bus is the process's event bus.
+var bus = &eventBus{handlers: make(map[string][]func(Event))}
+
+// subscribe calls fn for every event of the named type.
+func (b *eventBus) subscribe(name string, fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], fn)
+}
+
+// publish delivers an event to its subscribers and hooks in turn. Failing
+// hooks are logged and never fail what published the event.
+func (b *eventBus) publish(ev Event) {
+	b.mu.RLock()
+	handlers := b.handlers[ev.EventName()]
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(ev)
+	}
+	if err := runHooks(ev, false); err != nil {
+		log.Printf("Error running %s hooks: %v", ev.EventName(), err)
+	}
+}
+
+// runHooks runs the hooks configured for an event in order. With stop,
+// the first failing hook stops the rest and its error is returned;
+// otherwise failures are logged and the hooks carry on.
+func runHooks(ev Event, stop bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	for _, h := range cfg.Hooks {
+		if h.Event != ev.EventName() && h.Event != "*" {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(hookPayload{Event: ev.EventName(), Time: time.Now().UTC(), Data: ev}); err != nil {
+				return fmt.Errorf("error encoding %s event: %w", ev.EventName(), err)
+			}
+		}
+		if err := h.run(ev, payload); err != nil {
+			if stop {
+				return err
+			}
+			log.Printf("Error running %s hook: %v", ev.EventName(), err)
+		}
+	}
+	return nil
+}
+
+// hookStages maps the stage names of the shorthand hooks form to events.
+// A run's schedule is published when its run folder is written.
+var hookStages = map[string]string{
+	"pre_generate": eventPreGenerate,
+	"post_publish": eventScheduleGenerated,
+}
+
+// Hooks are the configured hooks. Besides a list of HookConfig, the config
+// takes a shorthand object of commands by stage or event, such as
+// {"post_publish": "./sync-to-intranet.sh {{.RunID}}"}.
+type Hooks []HookConfig
+
+func (h *Hooks) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return json.Unmarshal(data, (*[]HookConfig)(h))
+	}
+	var byStage map[string]string
+	if err := json.Unmarshal(data, &byStage); err != nil {
+		return fmt.Errorf("hooks: %w", err)
+	}
+	stages := make([]string, 0, len(byStage))
+	for stage := range byStage {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	*h = nil
+	for _, stage := range stages {
+		event := stage
+		if e, ok := hookStages[stage]; ok {
+			event = e
+		}
+		*h = append(*h, HookConfig{Event: event, Command: byStage[stage]})
+	}
+	return nil
+}
+
+// HookConfig runs a command or calls a webhook on a lifecycle event:
+// pre_generate, schedule_generated, validation_failed, schedule_published,
+// swap_approved, or "*" for all of them.
+type HookConfig struct {
+	Event string `json:"event"`
+	// Command is a shell command line, expanded as a Go template of the
+	// event first, such as "./sync-to-intranet.sh {{.RunID}}". Every value
+	// is quoted as a shell word, and lists as a word per item. It runs
+	// like Exec.
+	Command string `json:"command,omitempty"`
+	// Exec is the command and its arguments. It gets the event as JSON on
+	// stdin and its name in SCHEDULER_EVENT.
+	Exec []string `json:"exec,omitempty"`
+	// Webhook is posted the event as JSON, signed with Secret like HR
+	// webhooks when one is set.
+	Webhook string `json:"webhook,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+	// TimeoutSeconds bounds the hook, 30 by default.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// shq quotes a value as a word for sh. Each item of a list is quoted as a
+// word of its own.
+func shq(v any) string {
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+	if list, ok := v.([]string); ok {
+		words := make([]string, len(list))
+		for i, s := range list {
+			words[i] = quote(s)
+		}
+		return strings.Join(words, " ")
+	}
+	return quote(fmt.Sprint(v))
+}
+
+// quoteActions pipes the value of every action printing into a command
+// line through shq, so event fields such as day labels and names reach the
+// shell as single words.
+func quoteActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			quoteActions(tree, c)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			ident := parse.NewIdentifier("shq").SetTree(tree).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{ident}})
+		}
+	case *parse.IfNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	case *parse.WithNode:
+		quoteActions(tree, n.List)
+		quoteActions(tree, n.ElseList)
+	}
+}
+
+// hookPayload is what hooks receive.
+type hookPayload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  Event     `json:"data"`
+}
+
+var hookClient = &http.Client{}
+
+// run executes the hook's commands and calls its webhook with the payload.
+func (h HookConfig) run(ev Event, payload []byte) error {
+	event := ev.EventName()
+	timeout := time.Duration(h.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := h.Exec
+	if h.Command != "" {
+		tmpl, err := template.New(event).Option("missingkey=error").Funcs(template.FuncMap{"shq": shq}).Parse(h.Command)
+		if err != nil {
+			return fmt.Errorf("invalid hook command %q: %w", h.Command, err)
+		}
+		for _, t := range tmpl.Templates() {
+			quoteActions(t.Tree, t.Tree.Root)
+		}
+		var line strings.Builder
+		if err := tmpl.Execute(&line, ev); err != nil {
+			return fmt.Errorf("error expanding hook command %q: %w", h.Command, err)
+		}
+		args = []string{"sh", "-c", line.String()}
+	}
+	if len(args) > 0 {
+		name := args[0]
+		if h.Command != "" {
+			name = args[2]
+		}
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(payload)
+		cmd.Env = append(os.Environ(), "SCHEDULER_EVENT="+event)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if out = bytes.TrimSpace(out); len(out) > 0 {
+				return fmt.Errorf("%s: %w: %s", name, err, out)
+			}
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if h.Webhook != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Webhook, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if h.Secret != "" {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set("X-Signature-Timestamp", timestamp)
+			req.Header.Set("X-Signature-256", signBody(h.Secret, timestamp, payload))
+		}
+		resp, err := hookClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("error calling %s: %w", h.Webhook, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("error calling %s: %s", h.Webhook, resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHookCommandQuoting(t *testing.T) {
+	ev := SwapApproved{
+		Schedule:  "out/run 1",
+		Day:       "Monday (1st March)",
+		Employees: []string{"Ann", "O'Brien"},
+		By:        "x; touch pwned",
+	}
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{name: "day label", command: "{{.Day}}", want: []string{"Monday (1st March)"}},
+		{name: "name with a quote", command: "{{.By}} {{index .Employees 1}}", want: []string{"x; touch pwned", "O'Brien"}},
+		{name: "list", command: "{{.Employees}}", want: []string{"Ann", "O'Brien"}},
+		{name: "range", command: "{{range .Employees}}{{.}} {{end}}", want: []string{"Ann", "O'Brien"}},
+		{name: "variable", command: "{{$d := .Day}}{{if $d}}{{$d}}{{end}}", want: []string{"Monday (1st March)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			out := filepath.Join(dir, "args")
+			h := HookConfig{Event: eventSwapApproved, Command: "cd " + dir + " && printf '%s\\n' " + tt.command + " > " + out}
+			if err := h.run(ev, nil); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); !slices.Equal(got, tt.want) {
+				t.Errorf("arguments %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+				t.Error("a field ran as a command")
+			}
+		})
+	}
+}
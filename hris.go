@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// HRISConfig configures the HRIS approved leave is synced from.
+// HRIS_API_KEY and HRIS_PASSWORD take precedence over the config file.
+type HRISConfig struct {
+	// Provider is bamboohr or workday.
+	Provider string `json:"provider"`
+	// Subdomain and APIKey are the BambooHR company domain and API key.
+	Subdomain string `json:"subdomain,omitempty"`
+	APIKey    string `json:"api_key,omitempty"`
+	// ReportURL is a Workday report (RaaS) listing time-off requests, read
+	// with Username and Password.
+	ReportURL string `json:"report_url,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	// Endpoint overrides https://api.bamboohr.com.
+	Endpoint string `json:"endpoint,omitempty"`
+	// DaysAhead is how far ahead leave is synced, 90 days by default.
+	DaysAhead int `json:"days_ahead,omitempty"`
+	// SyncMinutes is how often the server syncs, every hour by default.
+	SyncMinutes int `json:"sync_minutes,omitempty"`
+}
+
+func hrisSettings(cfg Config) HRISConfig {
+	var hc HRISConfig
+	if cfg.HRIS != nil {
+		hc = *cfg.HRIS
+	}
+	if v := os.Getenv("HRIS_API_KEY"); v != "" {
+		hc.APIKey = v
+	}
+	if v := os.Getenv("HRIS_PASSWORD"); v != "" {
+		hc.Password = v
+	}
+	if hc.Endpoint == "" {
+		hc.Endpoint = "https://api.bamboohr.com"
+	}
+	if hc.DaysAhead <= 0 {
+		hc.DaysAhead = 90
+	}
+	if hc.SyncMinutes <= 0 {
+		hc.SyncMinutes = 60
+	}
+	return hc
+}
+
+// hrisLeave is an approved leave request as an HRIS reports it.
+type hrisLeave struct {
+	ID         string
+	EmployeeID string
+	Name       string
+	Start, End string
+	Type       string
+}
+
+// leaveConnectors fetch the approved leave overlapping an inclusive date
+// range.
+var leaveConnectors = map[string]func(ctx context.Context, hc HRISConfig, start, end time.Time) ([]hrisLeave, error){
+	"bamboohr": fetchBambooLeave,
+	"workday":  fetchWorkdayLeave,
+}
+
+func fetchBambooLeave(ctx context.Context, hc HRISConfig, start, end time.Time) ([]hrisLeave, error) {
+	if hc.Subdomain == "" || hc.APIKey == "" {
+		return nil, errors.New("BambooHR subdomain and HRIS_API_KEY not set")
+	}
+	query := url.Values{
+		"start":  {start.Format(time.DateOnly)},
+		"end":    {end.Format(time.DateOnly)},
+		"status": {"approved"},
+	}
+	u := fmt.Sprintf("%s/api/gateway.php/%s/v1/time_off/requests/?%s", hc.Endpoint, url.PathEscape(hc.Subdomain), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(hc.APIKey, "x")
+	req.Header.Set("Accept", "application/json")
+	var requests []struct {
+		ID         string `json:"id"`
+		EmployeeID string `json:"employeeId"`
+		Name       string `json:"name"`
+		Start      string `json:"start"`
+		End        string `json:"end"`
+		Status     struct {
+			Status string `json:"status"`
+		} `json:"status"`
+		Type struct {
+			Name string `json:"name"`
+		} `json:"type"`
+	}
+	if err := doJSON(connectorClient, req, &requests); err != nil {
+		return nil, err
+	}
+	var leave []hrisLeave
+	for _, r := range requests {
+		if r.Status.Status != "approved" {
+			continue
+		}
+		leave = append(leave, hrisLeave{ID: r.ID, EmployeeID: r.EmployeeID, Name: r.Name, Start: r.Start, End: r.End, Type: r.Type.Name})
+	}
+	return leave, nil
+}
+
+func fetchWorkdayLeave(ctx context.Context, hc HRISConfig, start, end time.Time) ([]hrisLeave, error) {
+	if hc.ReportURL == "" {
+		return nil, errors.New("Workday report URL not set")
+	}
+	u, err := url.Parse(hc.ReportURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Workday report URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("Start_Date", start.Format(time.DateOnly))
+	query.Set("End_Date", end.Format(time.DateOnly))
+	query.Set("format", "json")
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if hc.Username != "" {
+		req.SetBasicAuth(hc.Username, hc.Password)
+	}
+	var report struct {
+		Entries []struct {
+			RequestID   string `json:"Request_ID"`
+			WorkerID    string `json:"Worker_ID"`
+			Worker      string `json:"Worker"`
+			StartDate   string `json:"Start_Date"`
+			EndDate     string `json:"End_Date"`
+			TimeOffType string `json:"Time_Off_Type"`
+			Status      string `json:"Status"`
+		} `json:"Report_Entry"`
+	}
+	if err := doJSON(connectorClient, req, &report); err != nil {
+		return nil, err
+	}
+	var leave []hrisLeave
+	for _, e := range report.Entries {
+		if !strings.EqualFold(e.Status, "approved") {
+			continue
+		}
+		leave = append(leave, hrisLeave{ID: e.RequestID, EmployeeID: e.WorkerID, Name: e.Worker, Start: e.StartDate, End: e.EndDate, Type: e.TimeOffType})
+	}
+	return leave, nil
+}
+
+// hrisEmployee returns the roster name of an HRIS employee, matched on the
+// roster's hris_id and then on the name.
+func hrisEmployee(roster []Employee, l hrisLeave) (string, bool) {
+	for _, e := range roster {
+		if e.HRISID != "" && e.HRISID == l.EmployeeID {
+			return e.Name, true
+		}
+	}
+	for _, e := range roster {
+		if strings.EqualFold(e.Name, l.Name) {
+			return e.Name, true
+		}
+	}
+	return "", false
+}
+
+// syncLeave replaces the synced leave overlapping the sync window with the
+// approved leave the HRIS reports now, so cancelled requests disappear.
+// Leave maintained by hand and past leave are kept. It returns the number of
+// entries synced.
+func syncLeave(ctx context.Context, cfg Config, now time.Time) (int, error) {
+	hc := hrisSettings(cfg)
+	fetch, ok := leaveConnectors[hc.Provider]
+	if !ok {
+		return 0, fmt.Errorf("unknown HRIS provider %q", hc.Provider)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, hc.DaysAhead)
+	fetched, err := fetch(ctx, hc, start, end)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching leave from %s: %w", hc.Provider, err)
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return 0, err
+	}
+	current, err := loadLeave()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []LeaveEntry
+	for _, l := range current {
+		if l.Source == hc.Provider {
+			if from, to, err := l.dates(); err == nil && !to.Before(start) && !from.After(end) {
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	synced := 0
+	for _, l := range fetched {
+		name, ok := hrisEmployee(roster, l)
+		if !ok {
+			log.Printf("Skipping leave of %s (%s): not on the roster", l.Name, l.EmployeeID)
+			continue
+		}
+		entry := LeaveEntry{Employee: name, Start: l.Start, End: l.End, Type: l.Type, Source: hc.Provider, ID: l.ID}
+		if _, _, err := entry.dates(); err != nil {
+			log.Printf("Skipping leave request %s: %v", l.ID, err)
+			continue
+		}
+		kept = append(kept, entry)
+		synced++
+	}
+	if err := saveLeave(kept); err != nil {
+		return 0, err
+	}
+	return synced, nil
+}
+
+// syncLeaveEvery syncs leave straight away and then at every interval until
+// ctx is done, reading the config afresh each time.
+func syncLeaveEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cfg, err := loadConfig()
+		if err == nil {
+			var n int
+			if n, err = syncLeave(ctx, cfg, time.Now()); err == nil {
+				log.Printf("Synced %d approved leave requests", n)
+			}
+		}
+		if err != nil {
+			log.Printf("Error syncing leave: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runLeaveSync pulls approved leave from the configured HRIS once.
+func runLeaveSync(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: leave-sync")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.HRIS == nil {
+		return errors.New("no HRIS configured")
+	}
+	n, err := syncLeave(context.Background(), cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Printf("Synced %d approved leave requests from %s to %s", n, cfg.HRIS.Provider, leavePath())
+	return nil
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"employee-schedular/validator"
+)
+
+// WebhookConfig holds the secret external HR tools sign their webhooks
+// with. HR_WEBHOOK_SECRET takes precedence over the config file.
+type WebhookConfig struct {
+	Secret string `json:"secret,omitempty"`
+}
+
+func webhookSecret(cfg Config) string {
+	if v := os.Getenv("HR_WEBHOOK_SECRET"); v != "" {
+		return v
+	}
+	return cfg.Webhooks.Secret
+}
+
+// Kinds of HR events.
+const (
+	hrEventLeave = "leave"
+	hrEventSwap  = "swap"
+)
+
+// hrEvent is an approval announced by an external HR tool: new leave for
+// Employee from Start to End, or a swap of Employee's and With's shifts on
+// Date.
+type hrEvent struct {
+	ID        string `json:"id,omitempty"`
+	Type      string `json:"type"`
+	Employee  string `json:"employee"`
+	Start     string `json:"start,omitempty"`
+	End       string `json:"end,omitempty"`
+	LeaveType string `json:"leave_type,omitempty"`
+	With      string `json:"with,omitempty"`
+	Date      string `json:"date,omitempty"`
+	// ReasonCode is required for swaps inside the notice period.
+	ReasonCode string `json:"reason_code,omitempty"`
+}
+
+// replacement suggests who could cover a shift left open by leave.
+type replacement struct {
+	Week       string   `json:"week"`
+	Day        string   `json:"day"`
+	Shift      string   `json:"shift"`
+	Candidates []string `json:"candidates"`
+}
+
+// inboxEntry records a received event and what processing it found.
+type inboxEntry struct {
+	ReceivedAt time.Time `json:"received_at"`
+	Event      hrEvent   `json:"event"`
+	// Job is the published schedule the event affects, if any.
+	Job          string                `json:"job,omitempty"`
+	Violations   []validator.Violation `json:"violations,omitempty"`
+	Replacements []replacement         `json:"replacements,omitempty"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// inboxMu serialises processing webhooks, so an event delivered twice at
+// once is still applied once.
+var inboxMu sync.Mutex
+
+func inboxPath() string {
+	return filepath.Join(dataDir(), "inbox.jsonl")
+}
+
+// appendInbox adds an entry to the inbox.
+func appendInbox(entry inboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if data, err = sealLine(data); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(inboxPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening inbox: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing inbox: %w", err)
+	}
+	return nil
+}
+
+// loadInbox reads the inbox, oldest entry first.
+func loadInbox() ([]inboxEntry, error) {
+	f, err := os.Open(inboxPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error opening inbox: %w", err)
+	}
+	defer f.Close()
+	var entries []inboxEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line, err := openLine(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("error reading inbox: %w", err)
+		}
+		var entry inboxEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("error parsing inbox: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// signatureWindow is how far the timestamp of a signed webhook may be from
+// now, so a captured request can't be replayed later.
+const signatureWindow = 5 * time.Minute
+
+// signBody returns the X-Signature-256 header of a webhook sent at a Unix
+// timestamp: "sha256=<hex HMAC of the timestamp, a dot, and the body>".
+func signBody(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature checks the X-Signature-256 header of a webhook against its
+// X-Signature-Timestamp, rejecting requests signed more than five minutes
+// from now.
+func validSignature(secret string, body []byte, timestamp, header string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || now.Sub(time.Unix(ts, 0)).Abs() > signatureWindow {
+		return false
+	}
+	return hmac.Equal([]byte(header), []byte(signBody(secret, timestamp, body)))
+}
+
+// processedEvent returns the inbox entry of an event already processed
+// without error, found by its ID.
+func processedEvent(id string) (inboxEntry, bool, error) {
+	entries, err := loadInbox()
+	if err != nil {
+		return inboxEntry{}, false, err
+	}
+	for _, e := range entries {
+		if e.Event.ID == id && e.Error == "" {
+			return e, true, nil
+		}
+	}
+	return inboxEntry{}, false, nil
+}
+
+// scheduleDay locates a date in a schedule and returns its week and day
+// column.
+func scheduleDay(entries []FlatSchedule, date time.Time) (string, string, bool) {
+	for _, obj := range entries {
+		for key := range obj {
+			if d, ok := labelDate(key, date); ok && d.Equal(date) {
+				return obj["Week"], key, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// latestSchedule returns the newest published job whose schedule covers
+// date, with the schedule's entries.
+func (q *jobQueue) latestSchedule(date time.Time) (Job, []FlatSchedule, bool) {
+	for _, ps := range q.publishedSchedules() {
+		if _, _, ok := scheduleDay(ps.Entries, date); ok {
+			return ps.Job, ps.Entries, true
+		}
+	}
+	return Job{}, nil, false
+}
+
+// weekEntries returns the rows of one week.
+func weekEntries(entries []FlatSchedule, week string) []FlatSchedule {
+	var objs []FlatSchedule
+	for _, obj := range entries {
+		if obj["Week"] == week {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// replacementCandidates lists the employees off on a day who could take a
+// shift there without going over the weekly cap, leaving their transport
+// cutoffs, or working through leave.
+func replacementCandidates(objs []FlatSchedule, key, cell string, p *policy, date time.Time) []string {
+	hours, _ := cellHours(p.shifts, cell)
+	start, end, _ := cellWindow(p.shifts, cell)
+	var out []string
+	for _, obj := range objs {
+		name := obj["Employee"]
+		if isWorking(p.shifts, obj[key]) || onLeave(p.leaveEntries, name, date) {
+			continue
+		}
+		weekly := 0.0
+		for k, v := range obj {
+			if strings.Contains(k, "(") {
+				h, _ := cellHours(p.shifts, v)
+				weekly += h
+			}
+		}
+		if weekly+hours > maxWeeklyHours {
+			continue
+		}
+		if wh, ok := p.workHours[name]; ok && (start < wh.EarliestStart || end > wh.LatestEnd) {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// onLeave reports whether an employee has leave on a date.
+func onLeave(leave []LeaveEntry, employee string, date time.Time) bool {
+	for _, l := range leave {
+		if l.Employee != employee {
+			continue
+		}
+		if start, end, err := l.dates(); err == nil && !date.Before(start) && !date.After(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// processHREvent applies an event and checks the schedule it affects. New
+// leave is recorded and every shift it falls on gets replacement
+// suggestions; an approved swap is made in the schedule and audited.
+func (q *jobQueue) processHREvent(ev hrEvent) inboxEntry {
+	entry := inboxEntry{ReceivedAt: time.Now().UTC(), Event: ev}
+	if err := q.applyHREvent(ev, &entry); err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+func (q *jobQueue) applyHREvent(ev hrEvent, entry *inboxEntry) error {
+	if ev.Employee == "" {
+		return errors.New("event without an employee")
+	}
+	var dates []time.Time
+	switch ev.Type {
+	case hrEventLeave:
+		leave := LeaveEntry{Employee: ev.Employee, Start: ev.Start, End: ev.End, Type: ev.LeaveType, Source: "webhook", ID: ev.ID}
+		start, end, err := leave.dates()
+		if err != nil {
+			return err
+		}
+		all, err := loadLeave()
+		if err != nil {
+			return err
+		}
+		// A retry of an event that failed after saving its leave keeps it.
+		if !slices.ContainsFunc(all, func(l LeaveEntry) bool { return l.Source == leave.Source && l.ID == leave.ID }) {
+			if err := saveLeave(append(all, leave)); err != nil {
+				return err
+			}
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			dates = append(dates, d)
+		}
+	case hrEventSwap:
+		if ev.With == "" {
+			return errors.New("swap without a second employee")
+		}
+		date, err := time.Parse(time.DateOnly, ev.Date)
+		if err != nil {
+			return fmt.Errorf("invalid swap date: %w", err)
+		}
+		dates = append(dates, date)
+	default:
+		return fmt.Errorf("unknown event type %q", ev.Type)
+	}
+
+	for _, date := range dates {
+		job, entries, ok := q.latestSchedule(date)
+		if !ok {
+			continue
+		}
+		entry.Job = job.ID
+		week, key, _ := scheduleDay(entries, date)
+		objs := weekEntries(entries, week)
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj["Employee"])
+		}
+		p, err := loadPolicy(names)
+		if err != nil {
+			return err
+		}
+
+		if ev.Type == hrEventSwap {
+			if err := p.cfg.Notice.check(key, ev.ReasonCode, time.Now()); err != nil {
+				return err
+			}
+			before, after, err := swapCells(objs, key, ev.Employee, ev.With)
+			if err != nil {
+				return err
+			}
+			annotateHours(objs, p.shifts)
+			dir := scheduleDir(filepath.Join(dataDir(), "schedules", job.ID))
+			if _, err := writeWeekCSV(dir, week, objs, p.cfg.CSV); err != nil {
+				return fmt.Errorf("error writing schedule for %s: %w", week, err)
+			}
+			if err := refreshManifest(dir); err != nil {
+				return err
+			}
+			textUrgentChange(key, before, after)
+			if err := appendAudit(auditEntry{
+				Time: time.Now().UTC(), Action: "webhook-swap", By: "webhook", Week: week, Day: key,
+				Before: before, After: after, Frozen: p.cfg.Freeze.frozen(key, time.Now()), Reason: ev.ID,
+				Notice: p.cfg.Notice.within(key, time.Now()), ReasonCode: ev.ReasonCode,
+			}); err != nil {
+				return err
+			}
+			bus.publish(SwapApproved{Schedule: dir, Week: week, Day: key, Employees: []string{ev.Employee, ev.With}, Before: before, After: after, By: "webhook", ReasonCode: ev.ReasonCode})
+		} else {
+			for _, obj := range objs {
+				if obj["Employee"] == ev.Employee && isWorking(p.shifts, obj[key]) {
+					entry.Replacements = append(entry.Replacements, replacement{
+						Week:       week,
+						Day:        key,
+						Shift:      obj[key],
+						Candidates: replacementCandidates(objs, key, obj[key], p, date),
+					})
+				}
+			}
+		}
+
+		violations, err := validateWeeks(map[string][]FlatSchedule{week: objs}, p.shifts, p.rulePack, p.rules(&Run{Employees: names}))
+		if err != nil {
+			return err
+		}
+		for _, v := range violations {
+			if v.Day == key && (v.Employee == "" || v.Employee == ev.Employee || v.Employee == ev.With) {
+				entry.Violations = append(entry.Violations, v)
+			}
+		}
+	}
+	return nil
+}
+
+// handleHRWebhook receives a signed leave or swap approval.
+func (s *server) handleHRWebhook(w http.ResponseWriter, r *http.Request) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	secret := webhookSecret(cfg)
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "webhook secret not configured")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validSignature(secret, body, r.Header.Get("X-Signature-Timestamp"), r.Header.Get("X-Signature-256"), time.Now()) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	var ev hrEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid event: %v", err))
+		return
+	}
+	if ev.ID == "" {
+		writeError(w, http.StatusBadRequest, "event without an id")
+		return
+	}
+
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+	if done, ok, err := processedEvent(ev.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if ok {
+		log.Printf("Ignoring %s event %s, already processed", ev.Type, ev.ID)
+		writeJSON(w, http.StatusOK, done)
+		return
+	}
+	entry := s.queue.processHREvent(ev)
+	if err := appendInbox(entry); err != nil {
+		log.Printf("Error recording webhook: %v", err)
+	}
+	if entry.Error != "" {
+		log.Printf("Error processing %s event for %s: %s", ev.Type, ev.Employee, entry.Error)
+		writeJSON(w, http.StatusUnprocessableEntity, entry)
+		return
+	}
+	log.Printf("Processed %s event for %s: %d violations, %d shifts to cover", ev.Type, ev.Employee, len(entry.Violations), len(entry.Replacements))
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *server) handleInbox(w http.ResponseWriter, r *http.Request) {
+	entries, err := loadInbox()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidSignature(t *testing.T) {
+	now := time.Unix(1_790_000_000, 0)
+	body := []byte(`{"type":"leave","id":"ev-1"}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		header    string
+		want      bool
+	}{
+		{name: "valid", timestamp: ts, header: signBody("secret", ts, body), want: true},
+		{name: "within the window", timestamp: "1789999760", header: signBody("secret", "1789999760", body), want: true},
+		{name: "stale", timestamp: "1789999000", header: signBody("secret", "1789999000", body)},
+		{name: "from the future", timestamp: "1790001000", header: signBody("secret", "1790001000", body)},
+		{name: "timestamp changed", timestamp: "1790000001", header: signBody("secret", ts, body)},
+		{name: "body changed", body: `{"type":"leave","id":"ev-2"}`, timestamp: ts, header: signBody("secret", ts, body)},
+		{name: "other secret", timestamp: ts, header: signBody("other", ts, body)},
+		{name: "no timestamp", header: signBody("secret", "", body)},
+		{name: "no prefix", timestamp: ts, header: strings.TrimPrefix(signBody("secret", ts, body), "sha256=")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := body
+			if tt.body != "" {
+				b = []byte(tt.body)
+			}
+			if got := validSignature("secret", b, tt.timestamp, tt.header, now); got != tt.want {
+				t.Errorf("validSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleHRWebhook(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	t.Setenv("HR_WEBHOOK_SECRET", "secret")
+	q, err := newJobQueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := (&server{queue: q}).handleHRWebhook
+	leave := `{"type":"leave","employee":"Ann","start":"2026-10-19","end":"2026-10-20","id":"ev-1"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	// The steps run in order against the same data directory.
+	steps := []struct {
+		name      string
+		body      string
+		timestamp string
+		want      int
+		leave     int
+	}{
+		{name: "first delivery", body: leave, timestamp: now, want: http.StatusOK, leave: 1},
+		{name: "repeated delivery", body: leave, timestamp: now, want: http.StatusOK, leave: 1},
+		{name: "replayed after the window", body: leave, timestamp: stale, want: http.StatusUnauthorized, leave: 1},
+		{name: "without an id", body: `{"type":"leave","employee":"Bob","start":"2026-10-19","end":"2026-10-19"}`, timestamp: now, want: http.StatusBadRequest, leave: 1},
+		{name: "another event", body: strings.Replace(leave, "ev-1", "ev-2", 1), timestamp: now, want: http.StatusOK, leave: 2},
+	}
+	for _, st := range steps {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/hr", strings.NewReader(st.body))
+		req.Header.Set("X-Signature-Timestamp", st.timestamp)
+		req.Header.Set("X-Signature-256", signBody("secret", st.timestamp, []byte(st.body)))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != st.want {
+			t.Errorf("%s: status %d, want %d: %s", st.name, rec.Code, st.want, rec.Body)
+		}
+		all, err := loadLeave()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != st.leave {
+			t.Errorf("%s: %d leave entries, want %d", st.name, len(all), st.leave)
+		}
+	}
+	entries, err := loadInbox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d inbox entries, want one per event", len(entries))
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// inspectSampleRows is how many rows inspect reads to guess column roles.
+const inspectSampleRows = 20
+
+// timeLayouts are the timestamp formats inspect recognises.
+var timeLayouts = []string{
+	defaultTimeLayout,
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006 15:04:05",
+	"01/02/2006 3:04:05 PM",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+}
+
+// roleNames are the normalised header names recognised for each role.
+var roleNames = map[string][]string{
+	roleCallID:         {"call id", "callid", "id", "uniqueid", "unique id", "call sid", "sid", "contact id", "conversation id", "interaction id", "session id"},
+	roleCalledTime:     {"called time", "start", "start time", "call start", "calldate", "call date", "timestamp", "date", "date time", "initiation timestamp", "created"},
+	roleAnsweredTime:   {"answered time", "answer time", "answered", "connected", "connected time", "connected to agent timestamp"},
+	roleHangupTime:     {"hangup time", "end", "end time", "call end", "disconnect time", "disconnect timestamp", "ended"},
+	roleEventTime:      {"event timestamp", "event time"},
+	roleWaitDuration:   {"wait duration", "wait", "wait time", "queue time", "queue duration", "hold time", "ring time", "speed of answer"},
+	roleTalkedDuration: {"talked duration", "talk", "talk time", "talk duration", "billsec", "duration", "handle time", "agent interaction duration"},
+	roleAgentID:        {"agent id", "agentid", "agent", "agent name", "agent username", "user", "user id", "extension"},
+}
+
+// normalizeColumn lower-cases a header name and turns separators into
+// single spaces, so "Start_Time" and "start-time" compare equal.
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', '/':
+			return ' '
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// sniffDelimiter picks the most frequent separator on the header line.
+func sniffDelimiter(line string) string {
+	best, count := ";", 0
+	for _, d := range []string{";", ",", "\t", "|"} {
+		if n := strings.Count(line, d); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// detectLayout returns the known layout that parses most of the values, so
+// a stray bad row doesn't hide the format, or "" if none parses any.
+func detectLayout(values []string) string {
+	best, bestCount := "", 0
+	for _, layout := range timeLayouts {
+		count := 0
+		for _, v := range values {
+			if _, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = layout, count
+		}
+	}
+	return best
+}
+
+func isNumeric(values []string) bool {
+	seen := false
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// guessRoles assigns roles to columns, first by header name and then, for
+// the called time, by the first column holding timestamps. columns[i] holds
+// the sampled values of header[i].
+func guessRoles(header []string, columns [][]string) map[string]string {
+	roles := make(map[string]string)
+	taken := make(map[int]bool)
+	for _, role := range recordRoles {
+		for i, name := range header {
+			if taken[i] {
+				continue
+			}
+			if containsString(roleNames[role], normalizeColumn(name)) || normalizeColumn(name) == normalizeColumn(role) {
+				roles[role] = name
+				taken[i] = true
+				break
+			}
+		}
+	}
+	if _, ok := roles[roleCalledTime]; !ok {
+		for i, name := range header {
+			if !taken[i] && !isNumeric(columns[i]) && detectLayout(columns[i]) != "" {
+				roles[roleCalledTime] = name
+				break
+			}
+		}
+	}
+	return roles
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// inferMapping reads the header and the first rows of a CSV export and
+// guesses its mapping. It also returns the header and the sampled columns.
+func inferMapping(path, delimiter string) (ColumnMapping, []string, [][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ColumnMapping{}, nil, nil, fmt.Errorf("error opening CSV file: %w", err)
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	if delimiter == "" {
+		first, err := br.Peek(4096)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+			return ColumnMapping{}, nil, nil, fmt.Errorf("error reading CSV file: %w", err)
+		}
+		line, _, _ := strings.Cut(string(first), "\n")
+		delimiter = sniffDelimiter(line)
+	}
+	mapping := ColumnMapping{Delimiter: delimiter}
+
+	reader := csv.NewReader(br)
+	reader.Comma = mapping.comma()
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return ColumnMapping{}, nil, nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	columns := make([][]string, len(header))
+	for n := 0; n < inspectSampleRows; n++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		for i := range header {
+			if i < len(row) {
+				columns[i] = append(columns[i], row[i])
+			}
+		}
+	}
+
+	roles := guessRoles(header, columns)
+	mapping.Columns = make(map[string]string)
+	for role, name := range roles {
+		if name != role {
+			mapping.Columns[role] = name
+		}
+	}
+	if name, ok := roles[roleCalledTime]; ok {
+		for i, h := range header {
+			if h == name {
+				if layout := detectLayout(columns[i]); layout != defaultTimeLayout {
+					mapping.TimeLayout = layout
+				}
+			}
+		}
+	}
+	for i, h := range header {
+		if h == roles[roleWaitDuration] || h == roles[roleTalkedDuration] {
+			for _, v := range columns[i] {
+				if strings.Contains(v, ":") {
+					mapping.DurationFormat = durationClock
+				}
+			}
+		}
+	}
+	if mapping.Delimiter == ";" {
+		mapping.Delimiter = ""
+	}
+	return mapping, header, columns, nil
+}
+
+// printInspection lists the columns with sample values and their roles.
+func printInspection(w io.Writer, header []string, columns [][]string, mapping ColumnMapping) {
+	roleOf := make(map[string]string)
+	for _, role := range recordRoles {
+		roleOf[strings.ToLower(mapping.column(role))] = role
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tROLE\tSAMPLES")
+	for i, name := range header {
+		samples := columns[i]
+		if len(samples) > 3 {
+			samples = samples[:3]
+		}
+		role := roleOf[strings.ToLower(name)]
+		if role == "" {
+			role = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, role, strings.Join(samples, " | "))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nDelimiter: %q\nTime layout: %s\n", string(mapping.comma()), mapping.layout())
+	if mapping.DurationFormat != "" {
+		fmt.Fprintf(w, "Duration format: %s\n", mapping.DurationFormat)
+	}
+}
+
+// editMapping asks for the column of every role, keeping the guess on an
+// empty answer and unmapping the role on "-".
+func editMapping(in *bufio.Reader, out io.Writer, header []string, mapping ColumnMapping) (ColumnMapping, error) {
+	known := make(map[string]string)
+	for _, name := range header {
+		known[strings.ToLower(name)] = name
+	}
+	columns := make(map[string]string)
+	for _, role := range recordRoles {
+		current := "-"
+		if name, ok := known[mapping.column(role)]; ok {
+			current = name
+		}
+		for {
+			fmt.Fprintf(out, "%s [%s]: ", role, current)
+			answer, err := in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return mapping, fmt.Errorf("error reading answer: %w", err)
+			}
+			answer = strings.TrimSpace(answer)
+			if answer == "" {
+				answer = current
+			}
+			if answer == "-" {
+				if role == roleCalledTime {
+					fmt.Fprintln(out, "called_time is required.")
+					if errors.Is(err, io.EOF) {
+						return mapping, errors.New("no called_time column chosen")
+					}
+					continue
+				}
+				columns[role] = ""
+				break
+			}
+			name, ok := known[strings.ToLower(answer)]
+			if !ok {
+				fmt.Fprintf(out, "No column named %q.\n", answer)
+				if errors.Is(err, io.EOF) {
+					return mapping, fmt.Errorf("no column named %q", answer)
+				}
+				continue
+			}
+			columns[role] = name
+			break
+		}
+	}
+	mapping.Columns = make(map[string]string)
+	for role, name := range columns {
+		if name != role {
+			mapping.Columns[role] = name
+		}
+	}
+
+	fmt.Fprintf(out, "time layout [%s]: ", mapping.layout())
+	answer, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return mapping, fmt.Errorf("error reading answer: %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer != "" {
+		mapping.TimeLayout = answer
+	}
+	return mapping, nil
+}
+
+// runInspect shows how a call-record export would be read and offers to
+// save the mapping to the config file.
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	delimiter := fs.String("delimiter", "", "column separator (detected from the header when empty)")
+	save := fs.Bool("save", false, "save the guessed mapping without asking")
+	preset := fs.String("preset", "", "show the file read with a built-in mapping instead of guessing: "+strings.Join(presetNames(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: inspect [-delimiter ;] [-preset name] [-save] <data.csv>")
+	}
+
+	var presetMapping ColumnMapping
+	if *preset != "" {
+		m, err := lookupPreset(*preset)
+		if err != nil {
+			return err
+		}
+		presetMapping = m
+		*delimiter = string(m.comma())
+	}
+	mapping, header, columns, err := inferMapping(fs.Arg(0), *delimiter)
+	if err != nil {
+		return err
+	}
+	if *preset != "" {
+		mapping = presetMapping
+	}
+	printInspection(os.Stdout, header, columns, mapping)
+	if _, ok := mapping.Columns[roleCalledTime]; !ok && !containsString(header, roleCalledTime) {
+		fmt.Println("\nNo called_time column was recognised.")
+	}
+
+	if !*save {
+		in := bufio.NewReader(os.Stdin)
+		fmt.Printf("\nSave this mapping to %s? [y/N/e(dit)]: ", configPath())
+		answer, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error reading answer: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		case "e", "edit":
+			if mapping, err = editMapping(in, os.Stdout, header, mapping); err != nil {
+				return err
+			}
+		default:
+			fmt.Println("Mapping not saved.")
+			return nil
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Mapping = mapping
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Mapping saved to %s\n", configPath())
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job statuses reported by the server.
+const (
+	jobQueued     = "queued"
+	jobRunning    = "running"
+	jobValidating = "validating"
+	jobFailed     = "failed"
+	jobPublished  = "published"
+	jobCancelled  = "cancelled"
+)
+
+var (
+	// errQueueFull is returned when no more jobs can be accepted.
+	errQueueFull = errors.New("job queue is full")
+	// errShuttingDown is returned for jobs submitted while the server drains.
+	errShuttingDown = errors.New("server is shutting down")
+)
+
+// Job is a generation request submitted to the server. Jobs are persisted in
+// the data directory so the queue survives restarts.
+type Job struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Options   generateOptions `json:"options"`
+	Client    string          `json:"client,omitempty"`
+	RunID     string          `json:"run_id,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	// ApprovedAt is set once a planner approves the published schedule.
+	ApprovedAt *time.Time `json:"approved_at,omitempty"`
+	ApprovedBy string     `json:"approved_by,omitempty"`
+	// RemindersSent lists the HoursBefore of the reminder rules that have
+	// fired for the job.
+	RemindersSent []int `json:"reminders_sent,omitempty"`
+	// Acknowledgments are the employees who acknowledged the published
+	// schedule, by name.
+	Acknowledgments map[string]Acknowledgment `json:"acknowledgments,omitempty"`
+}
+
+func (j *Job) finished() bool {
+	return j.Status == jobFailed || j.Status == jobPublished || j.Status == jobCancelled
+}
+
+func jobsDir() string {
+	return filepath.Join(dataDir(), "jobs")
+}
+
+// jobQueue runs jobs on a fixed number of workers, persisting every status
+// change.
+type jobQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	pending chan string
+	wg      sync.WaitGroup
+
+	// stopWorkers stops workers from taking new jobs; abortJobs cancels the
+	// jobs still running once the drain period is over.
+	stopWorkers context.CancelFunc
+	abortJobs   context.CancelFunc
+	draining    bool
+}
+
+// newJobQueue loads persisted jobs and re-queues any that had not finished
+// when the previous server stopped.
+func newJobQueue(capacity int) (*jobQueue, error) {
+	q := &jobQueue{
+		jobs:    make(map[string]*Job),
+		cancels: make(map[string]context.CancelFunc),
+		pending: make(chan string, capacity),
+	}
+	if err := os.MkdirAll(jobsDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating jobs directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(jobsDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var requeue []*Job
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("error parsing job %s: %w", path, err)
+		}
+		q.jobs[job.ID] = &job
+		if !job.finished() {
+			requeue = append(requeue, &job)
+		}
+	}
+	sort.Slice(requeue, func(i, j int) bool { return requeue[i].CreatedAt.Before(requeue[j].CreatedAt) })
+	for _, job := range requeue {
+		job.Status = jobQueued
+		if err := q.save(job); err != nil {
+			return nil, err
+		}
+		select {
+		case q.pending <- job.ID:
+		default:
+			return nil, errQueueFull
+		}
+	}
+	return q, nil
+}
+
+// save persists a job. The caller must hold q.mu or own the job exclusively.
+func (q *jobQueue) save(job *Job) error {
+	job.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(jobsDir(), job.ID+".json")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("error writing job %s: %w", job.ID, err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// setStatus updates and persists the status of a job.
+func (q *jobQueue) setStatus(job *Job, status, message string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// A cancelled job keeps its status even if its worker finishes later.
+	if job.Status == jobCancelled {
+		return
+	}
+	job.Status = status
+	job.Error = message
+	if err := q.save(job); err != nil {
+		log.Printf("Error saving job %s: %v", job.ID, err)
+	}
+}
+
+// submit queues a new job.
+func (q *jobQueue) submit(opts generateOptions, client string) (Job, error) {
+	run, err := newRun()
+	if err != nil {
+		return Job{}, err
+	}
+	now := time.Now().UTC()
+	job := &Job{ID: run.ID, Status: jobQueued, Options: opts, Client: client, CreatedAt: now}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.draining {
+		return Job{}, errShuttingDown
+	}
+	select {
+	case q.pending <- job.ID:
+	default:
+		return Job{}, errQueueFull
+	}
+	q.jobs[job.ID] = job
+	if err := q.save(job); err != nil {
+		return Job{}, err
+	}
+	return *job, nil
+}
+
+// get returns a copy of a job.
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// list returns copies of all jobs, newest first.
+func (q *jobQueue) list() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// previousScheduleDir returns the schedule directory of the latest
+// published job other than id, or "" when there is none.
+func (q *jobQueue) previousScheduleDir(id string) string {
+	for _, job := range q.list() {
+		if job.ID != id && job.Status == jobPublished {
+			return filepath.Join(dataDir(), "schedules", job.ID)
+		}
+	}
+	return ""
+}
+
+// cancel stops a queued or running job. It reports false if the job does not
+// exist and an error if it has already finished.
+func (q *jobQueue) cancel(id string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return false, nil
+	}
+	if job.finished() {
+		return true, fmt.Errorf("job %s already %s", id, job.Status)
+	}
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+	}
+	job.Status = jobCancelled
+	return true, q.save(job)
+}
+
+// approve marks the published schedule of a job as approved. It reports
+// false if the job does not exist and an error if it isn't published.
+func (q *jobQueue) approve(id, by string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return false, nil
+	}
+	if job.Status != jobPublished {
+		return true, fmt.Errorf("job %s is %s, not published", id, job.Status)
+	}
+	if job.ApprovedAt != nil {
+		return true, fmt.Errorf("job %s already approved", id)
+	}
+	now := time.Now().UTC()
+	job.ApprovedAt = &now
+	job.ApprovedBy = by
+	return true, q.save(job)
+}
+
+// start launches the workers. They run until shutdown is called.
+func (q *jobQueue) start(workers int) {
+	stop, stopWorkers := context.WithCancel(context.Background())
+	jobs, abortJobs := context.WithCancel(context.Background())
+	q.stopWorkers, q.abortJobs = stopWorkers, abortJobs
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				// Check for shutdown first so a busy queue can't starve it.
+				if stop.Err() != nil {
+					return
+				}
+				select {
+				case <-stop.Done():
+					return
+				case id := <-q.pending:
+					q.process(jobs, id)
+				}
+			}
+		}()
+	}
+}
+
+// shutdown stops accepting jobs and waits for running jobs to finish. Jobs
+// still running when ctx expires are cancelled and put back in the queue, so
+// the next server picks them up again, reusing any stored API response.
+func (q *jobQueue) shutdown(ctx context.Context) {
+	q.mu.Lock()
+	q.draining = true
+	q.mu.Unlock()
+	q.stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Drain period over, checkpointing running jobs")
+		q.abortJobs()
+		<-done
+	}
+}
+
+// isDraining reports whether the queue has stopped accepting jobs.
+func (q *jobQueue) isDraining() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.draining
+}
+
+// process runs one job to completion.
+func (q *jobQueue) process(ctx context.Context, id string) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok || job.Status != jobQueued {
+		q.mu.Unlock()
+		return
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q.cancels[id] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.cancels, id)
+		q.mu.Unlock()
+	}()
+
+	q.setStatus(job, jobRunning, "")
+
+	// Reuse the stored run of a job interrupted after its API call.
+	run, err := loadRun(job.ID)
+	if err != nil {
+		run, err = newRun()
+		if err != nil {
+			q.setStatus(job, jobFailed, err.Error())
+			return
+		}
+		run.ID = job.ID
+	}
+	q.mu.Lock()
+	job.RunID = run.ID
+	q.mu.Unlock()
+
+	opts := job.Options
+	opts.OutputDir = filepath.Join(dataDir(), "schedules", job.ID)
+	opts.WarmStart = q.previousScheduleDir(job.ID)
+	err = generateRun(jobCtx, run, opts, func(stage string) {
+		q.setStatus(job, jobValidating, "")
+	})
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Job %s interrupted by shutdown, re-queued", job.ID)
+		q.setStatus(job, jobQueued, "")
+		return
+	}
+	if err != nil {
+		log.Printf("Job %s failed: %v", job.ID, err)
+		q.setStatus(job, jobFailed, err.Error())
+		return
+	}
+	q.setStatus(job, jobPublished, "")
+	log.Printf("Job %s published to %s", job.ID, run.Folder)
+	if published, ok := q.get(job.ID); ok && published.Status == jobPublished {
+		textPublished(published)
+		requestAcknowledgments(published)
+		bus.publish(schedulePublishedEvent(published))
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// LaborCostConfig prices schedules and attendance for finance.
+type LaborCostConfig struct {
+	Currency string `json:"currency,omitempty"`
+	// HourlyRate is the pay per hour of employees without a rate in the
+	// roster.
+	HourlyRate float64 `json:"hourly_rate,omitempty"`
+	// OvertimeMultiplier scales the rate of overtime shifts, 1.5 by
+	// default.
+	OvertimeMultiplier float64 `json:"overtime_multiplier,omitempty"`
+	// Budget is the labor budget per month, such as {"2025-03": 42000}.
+	Budget map[string]float64 `json:"budget,omitempty"`
+}
+
+// laborRates prices the hours of the roster's employees.
+type laborRates struct {
+	cfg   LaborCostConfig
+	rates map[string]float64
+}
+
+// rosterLaborRates returns the pay rates of the roster under the config.
+func rosterLaborRates(cfg Config, roster []Employee) laborRates {
+	r := laborRates{cfg: cfg.LaborCost, rates: make(map[string]float64)}
+	if r.cfg.OvertimeMultiplier == 0 {
+		r.cfg.OvertimeMultiplier = 1.5
+	}
+	for _, e := range roster {
+		if e.HourlyRate > 0 {
+			r.rates[e.Name] = e.HourlyRate
+		}
+	}
+	return r
+}
+
+// cost prices an employee's hours.
+func (r laborRates) cost(employee string, hours float64, overtime bool) float64 {
+	rate, ok := r.rates[employee]
+	if !ok {
+		rate = r.cfg.HourlyRate
+	}
+	if overtime {
+		rate *= r.cfg.OvertimeMultiplier
+	}
+	return hours * rate
+}
+
+// laborMonth is one month's labor cost: its budget, the cost of the
+// schedule, and the cost of the hours worked.
+type laborMonth struct {
+	Month        string
+	Budget       float64
+	PlannedHours float64
+	Planned      float64
+	ActualHours  float64
+	Actual       float64
+}
+
+// laborMonths collects labor cost by month.
+type laborMonths map[string]*laborMonth
+
+func (m laborMonths) month(date time.Time, r laborRates) *laborMonth {
+	key := date.Format("2006-01")
+	if m[key] == nil {
+		m[key] = &laborMonth{Month: key, Budget: r.cfg.Budget[key]}
+	}
+	return m[key]
+}
+
+// addPlanned adds the cost of a schedule's shifts, placing day columns in
+// the year nearest now.
+func (m laborMonths) addPlanned(sch *schedule.Schedule, r laborRates, now time.Time) {
+	for _, e := range sch.Entries {
+		for _, d := range e.Days {
+			date, ok := labelDate(d.Label, now)
+			if d.Shift == "" || !ok {
+				continue
+			}
+			hours := sch.Hours(d)
+			month := m.month(date, r)
+			month.PlannedHours += hours
+			month.Planned += r.cost(e.Employee, hours, d.Overtime)
+		}
+	}
+}
+
+// addActual adds the cost of the hours worked, as reconciled against the
+// schedule. Hours worked on an overtime shift are paid as overtime.
+func (m laborMonths) addActual(variances []Variance, r laborRates) {
+	for _, v := range variances {
+		if v.Worked == 0 {
+			continue
+		}
+		month := m.month(v.Date, r)
+		month.ActualHours += v.Worked
+		month.Actual += r.cost(v.Employee, v.Worked, schedule.IsOvertime(v.Shift))
+	}
+}
+
+// sorted returns the months in order.
+func (m laborMonths) sorted() []laborMonth {
+	out := make([]laborMonth, 0, len(m))
+	for _, month := range m {
+		out = append(out, *month)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Month < out[j].Month })
+	return out
+}
+
+// writeLaborCost writes the monthly labor cost as CSV for finance.
+func writeLaborCost(path, currency string, months []laborMonth, cc CSVConfig) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating labor cost export: %w", err)
+	}
+	defer file.Close()
+	w, err := newCSVWriter(file, cc)
+	if err != nil {
+		return err
+	}
+	w.Write([]string{"Month", "Currency", "Budget", "Planned Hours", "Planned Cost", "Actual Hours", "Actual Cost", "Planned vs Budget", "Actual vs Planned"})
+	amount := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, m := range months {
+		w.Write([]string{m.Month, currency, amount(m.Budget), amount(m.PlannedHours), amount(m.Planned),
+			amount(m.ActualHours), amount(m.Actual), amount(m.Planned - m.Budget), amount(m.Actual - m.Planned)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error writing labor cost export: %w", err)
+	}
+	return nil
+}
+
+// runLaborCost prices schedules, and the hours worked against them, per
+// month against the budget.
+func runLaborCost(args []string) error {
+	fs := flag.NewFlagSet("labor-cost", flag.ContinueOnError)
+	punchesFrom := fs.String("punches", "", "punches.csv or timeclock:<start>/<end> to price the hours worked")
+	out := fs.String("out", "labor_cost.csv", "where to write the monthly finance export")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: labor-cost [-punches punches.csv | timeclock:<start>/<end>] [-out labor_cost.csv] <schedule dir> ...")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	rates := rosterLaborRates(cfg, roster)
+	var punches []Punch
+	if *punchesFrom != "" {
+		if punches, err = loadPunches(*punchesFrom); err != nil {
+			return err
+		}
+	}
+
+	months := make(laborMonths)
+	now := time.Now()
+	for _, dir := range fs.Args() {
+		entries, err := loadScheduleDir(scheduleDir(dir))
+		if err != nil {
+			return err
+		}
+		objs := make([]map[string]string, len(entries))
+		for i, obj := range entries {
+			objs[i] = obj
+		}
+		sch, err := schedule.Parse(objs, shifts)
+		if err != nil {
+			return fmt.Errorf("error parsing schedule %s: %w", dir, err)
+		}
+		ref := now
+		if len(punches) > 0 {
+			ref = punches[0].ClockIn
+		}
+		months.addPlanned(sch, rates, ref)
+		months.addActual(reconcile(entries, shifts, punches), rates)
+	}
+
+	sorted := months.sorted()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Month\tBudget\tPlanned\tActual\tPlanned vs Budget\tActual vs Planned\t\n")
+	for _, m := range sorted {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.2f\t%+.2f\t%+.2f\t\n", m.Month, m.Budget, m.Planned, m.Actual, m.Planned-m.Budget, m.Actual-m.Planned)
+	}
+	tw.Flush()
+	if err := writeLaborCost(*out, rates.cfg.Currency, sorted, cfg.CSV); err != nil {
+		return err
+	}
+	fmt.Printf("Labor cost export written to %s\n", *out)
+	return nil
+}
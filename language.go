@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"employee-schedular/schedule"
+)
+
+// language is a language the model can be asked to write the schedule in.
+// Its labels are translated back on parsing, so everything after the model
+// works with the English labels.
+type language struct {
+	// Name is the language's English name, for the prompt.
+	Name           string
+	Week, Employee string
+	Off            string
+	// Weekdays are indexed by time.Weekday, Sunday first.
+	Weekdays [7]string
+	Months   [12]string
+	// label writes a day column such as "Monday (1st March)".
+	label func(weekday, month string, day int) string
+}
+
+// ordinal writes a day of the month as 1st, 2nd, 3rd, and so on.
+func ordinal(day int) string {
+	suffix := "th"
+	switch {
+	case day%100 >= 11 && day%100 <= 13:
+	case day%10 == 1:
+		suffix = "st"
+	case day%10 == 2:
+		suffix = "nd"
+	case day%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(day) + suffix
+}
+
+var english = language{
+	Name: "English", Week: "Week", Employee: "Employee", Off: schedule.Off,
+	Weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	Months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	label: func(weekday, month string, day int) string {
+		return fmt.Sprintf("%s (%s %s)", weekday, ordinal(day), month)
+	},
+}
+
+// languages are the supported languages by config code.
+var languages = map[string]language{
+	"en": english,
+	"pt-BR": {
+		Name: "Brazilian Portuguese", Week: "Semana", Employee: "Funcionário", Off: "Folga",
+		Weekdays: [7]string{"Domingo", "Segunda-feira", "Terça-feira", "Quarta-feira", "Quinta-feira", "Sexta-feira", "Sábado"},
+		Months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		label: func(weekday, month string, day int) string {
+			return fmt.Sprintf("%s (%d de %s)", weekday, day, month)
+		},
+	},
+	"es": {
+		Name: "Spanish", Week: "Semana", Employee: "Empleado", Off: "Libre",
+		Weekdays: [7]string{"Domingo", "Lunes", "Martes", "Miércoles", "Jueves", "Viernes", "Sábado"},
+		Months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		label: func(weekday, month string, day int) string {
+			return fmt.Sprintf("%s (%d de %s)", weekday, day, month)
+		},
+	},
+	"fr": {
+		Name: "French", Week: "Semaine", Employee: "Employé", Off: "Repos",
+		Weekdays: [7]string{"Dimanche", "Lundi", "Mardi", "Mercredi", "Jeudi", "Vendredi", "Samedi"},
+		Months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		label: func(weekday, month string, day int) string {
+			return fmt.Sprintf("%s (%d %s)", weekday, day, month)
+		},
+	},
+	"de": {
+		Name: "German", Week: "Woche", Employee: "Mitarbeiter", Off: "Frei",
+		Weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		Months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		label: func(weekday, month string, day int) string {
+			return fmt.Sprintf("%s (%d. %s)", weekday, day, month)
+		},
+	},
+}
+
+// languageFor returns the language configured by code, English when the
+// code is empty.
+func languageFor(code string) (language, error) {
+	if code == "" {
+		return english, nil
+	}
+	for name, lang := range languages {
+		if strings.EqualFold(name, code) {
+			return lang, nil
+		}
+	}
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return language{}, fmt.Errorf("unsupported language %q (want one of %s)", code, strings.Join(names, ", "))
+}
+
+// dayLabel writes the column of a date in the language.
+func (l language) dayLabel(weekday time.Weekday, day int, month time.Month) string {
+	return l.label(l.Weekdays[weekday], l.Months[month-1], day)
+}
+
+// exampleRow writes the example schedule object shown in the prompt.
+func (l language) exampleRow() string {
+	cells := []string{"Early", "Normal", "Late", l.Off, "Early", l.Off, "Normal"}
+	parts := []string{fmt.Sprintf("%q: %q", l.Week, l.Week+" 1"), fmt.Sprintf("%q: %q", l.Employee, "Alice")}
+	// 1st March 2021 was a Monday.
+	for i, cell := range cells {
+		parts = append(parts, fmt.Sprintf("%q: %q", l.dayLabel(time.Weekday((i+1)%7), i+1, time.March), cell))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// instruction asks the model to write the schedule in the language. It is
+// empty for English.
+func (l language) instruction() string {
+	if l.Name == english.Name {
+		return ""
+	}
+	return fmt.Sprintf("Write the schedule in %s, with keys and day names as in the example and %q for days off. Keep the shift names exactly as listed.\n", l.Name, l.Off)
+}
+
+// fold lowercases s and drops everything but letters, for lenient matching
+// of the model's labels.
+func fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// canonicalLabel translates a day column written in the language, such as
+// "Segunda-feira (1 de março)", into its English form. It reports false for
+// columns that are not days.
+func (l language) canonicalLabel(key string) (string, bool) {
+	name, rest, found := strings.Cut(key, "(")
+	if !found {
+		return "", false
+	}
+	weekday := -1
+	for i, w := range l.Weekdays {
+		if fold(name) == fold(w) {
+			weekday = i
+		}
+	}
+	day := extractDayNumber("x " + strings.TrimSpace(rest))
+	month := -1
+	for _, field := range strings.Fields(rest) {
+		for i, m := range l.Months {
+			if fold(field) == fold(m) {
+				month = i
+			}
+		}
+	}
+	if weekday < 0 || month < 0 || day == 0 {
+		return "", false
+	}
+	return english.dayLabel(time.Weekday(weekday), day, time.Month(month+1)), true
+}
+
+// canonical translates a schedule object written in the language into the
+// English labels the rest of the application reads.
+func (l language) canonical(obj FlatSchedule) FlatSchedule {
+	if l.Name == english.Name {
+		return obj
+	}
+	out := make(FlatSchedule, len(obj))
+	for key, value := range obj {
+		switch {
+		case key == l.Week:
+			out["Week"] = strings.Replace(value, l.Week, "Week", 1)
+		case key == l.Employee:
+			out["Employee"] = value
+		default:
+			if label, ok := l.canonicalLabel(key); ok {
+				key = label
+				if strings.EqualFold(strings.TrimSpace(value), l.Off) {
+					value = schedule.Off
+				}
+			}
+			out[key] = value
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/validator"
+)
+
+// LeaveEntry is one period of approved leave. Entries synced from an HRIS
+// carry its name as Source; entries without one are maintained by hand.
+type LeaveEntry struct {
+	Employee string `json:"employee"`
+	// Start and End are inclusive dates written 2006-01-02.
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Type   string `json:"type,omitempty"`
+	Source string `json:"source,omitempty"`
+	// ID is the request's ID in its source.
+	ID string `json:"id,omitempty"`
+}
+
+// dates parses the entry's start and end.
+func (l LeaveEntry) dates() (time.Time, time.Time, error) {
+	start, err := time.Parse(time.DateOnly, l.Start)
+	if err != nil {
+		return start, start, fmt.Errorf("invalid start of leave for %s: %w", l.Employee, err)
+	}
+	end, err := time.Parse(time.DateOnly, l.End)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid end of leave for %s: %w", l.Employee, err)
+	}
+	if end.Before(start) {
+		return start, end, fmt.Errorf("leave for %s ends before it starts", l.Employee)
+	}
+	return start, end, nil
+}
+
+func leavePath() string {
+	return filepath.Join(dataDir(), "leave.json")
+}
+
+// loadLeave reads the leave file. A missing file has no leave.
+func loadLeave() ([]LeaveEntry, error) {
+	data, err := readDataFile(leavePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading leave: %w", err)
+	}
+	var leave []LeaveEntry
+	if err := json.Unmarshal(data, &leave); err != nil {
+		return nil, fmt.Errorf("error parsing leave: %w", err)
+	}
+	return leave, nil
+}
+
+// saveLeave writes the leave file, replacing it atomically.
+func saveLeave(leave []LeaveEntry) error {
+	data, err := json.MarshalIndent(leave, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	if err := writeDataFile(leavePath(), data, 0o644); err != nil {
+		return fmt.Errorf("error writing leave: %w", err)
+	}
+	return nil
+}
+
+// scheduledLeave returns the leave of the scheduled employees that hasn't
+// ended before today, both as recorded and as periods for validation.
+func scheduledLeave(employees []string, today time.Time) ([]LeaveEntry, map[string][]validator.Leave, error) {
+	all, err := loadLeave()
+	if err != nil {
+		return nil, nil, err
+	}
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	var entries []LeaveEntry
+	periods := make(map[string][]validator.Leave)
+	for _, l := range all {
+		if !scheduled[l.Employee] {
+			continue
+		}
+		start, end, err := l.dates()
+		if err != nil {
+			return nil, nil, err
+		}
+		if end.Before(today) {
+			continue
+		}
+		entries = append(entries, l)
+		periods[l.Employee] = append(periods[l.Employee], validator.Leave{Start: start, End: end, Label: l.Type})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Employee != entries[j].Employee {
+			return entries[i].Employee < entries[j].Employee
+		}
+		return entries[i].Start < entries[j].Start
+	})
+	return entries, periods, nil
+}
+
+// leaveNote lists approved leave for the scheduling prompt.
+func leaveNote(entries []LeaveEntry) string {
+	var parts []string
+	for _, l := range entries {
+		part := fmt.Sprintf("%s from %s to %s", l.Employee, l.Start, l.End)
+		if l.Type != "" {
+			part += " (" + l.Type + ")"
+		}
+		parts = append(parts, part)
+	}
+	return "Approved leave: " + strings.Join(parts, "; ") + ". Never schedule employees on days they are on leave."
+}
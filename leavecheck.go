@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// leaveDayImpact is what taking leave does to one day the employee is
+// scheduled to work: the headcount left on their shift against its target,
+// the required skills left uncovered or to a single holder, and who could
+// cover.
+type leaveDayImpact struct {
+	Date        string   `json:"date"`
+	Job         string   `json:"job"`
+	Day         string   `json:"day"`
+	Shift       string   `json:"shift"`
+	Required    int      `json:"required"`
+	Remaining   int      `json:"remaining"`
+	BelowTarget bool     `json:"below_target,omitempty"`
+	Uncovered   []string `json:"uncovered_skills,omitempty"`
+	Single      []string `json:"single_skills,omitempty"`
+	Candidates  []string `json:"candidates"`
+}
+
+// leaveCheck is the impact summary of a leave request for its approver.
+// Feasible is false when a day drops below target or loses a required
+// skill. Unscheduled lists the days no published schedule covers yet.
+type leaveCheck struct {
+	Employee    string           `json:"employee"`
+	Start       string           `json:"start"`
+	End         string           `json:"end"`
+	Feasible    bool             `json:"feasible"`
+	Summary     string           `json:"summary"`
+	Days        []leaveDayImpact `json:"days"`
+	Unscheduled []string         `json:"unscheduled,omitempty"`
+}
+
+// checkLeave works out what the employee taking leave from start to end,
+// inclusive, would do to the published schedules covering those days.
+func (q *jobQueue) checkLeave(employee string, start, end time.Time) (leaveCheck, error) {
+	check := leaveCheck{Employee: employee, Start: start.Format(time.DateOnly), End: end.Format(time.DateOnly), Days: []leaveDayImpact{}}
+	type policyOf struct {
+		p        *policy
+		required map[string]int
+	}
+	policies := make(map[string]policyOf)
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		job, entries, ok := q.latestSchedule(date)
+		if !ok {
+			check.Unscheduled = append(check.Unscheduled, date.Format(time.DateOnly))
+			continue
+		}
+		week, key, _ := scheduleDay(entries, date)
+		objs := weekEntries(entries, week)
+		i := slices.IndexFunc(objs, func(obj FlatSchedule) bool { return obj["Employee"] == employee })
+		if i < 0 {
+			continue
+		}
+		pol, ok := policies[job.ID]
+		if !ok {
+			var names []string
+			for _, obj := range objs {
+				names = append(names, obj["Employee"])
+			}
+			p, err := loadPolicy(names)
+			if err != nil {
+				return check, fmt.Errorf("error reading policy of job %s: %w", job.ID, err)
+			}
+			required, _, err := requiredHeadcount(publishedSchedule{Job: job, Entries: entries})
+			if err != nil {
+				return check, fmt.Errorf("error reading policy of job %s: %w", job.ID, err)
+			}
+			pol = policyOf{p, required}
+			policies[job.ID] = pol
+		}
+		if !isWorking(pol.p.shifts, objs[i][key]) {
+			continue
+		}
+		shift, _ := schedule.SplitCell(objs[i][key])
+
+		day := leaveDayImpact{Date: date.Format(time.DateOnly), Job: job.ID, Day: key, Shift: shift, Required: pol.required[shift]}
+		for _, obj := range objs {
+			if name, _ := schedule.SplitCell(obj[key]); name == shift && obj["Employee"] != employee {
+				day.Remaining++
+			}
+		}
+		day.BelowTarget = day.Remaining < day.Required
+		for _, c := range skillMatrix(objs, key, pol.p.shifts, pol.p.requiredSkills, pol.p.skills) {
+			if c.Shift != shift {
+				continue
+			}
+			for _, h := range c.Skills {
+				if !slices.Contains(h.Holders, employee) {
+					continue
+				}
+				switch len(h.Holders) {
+				case 1:
+					day.Uncovered = append(day.Uncovered, h.Skill)
+				case 2:
+					day.Single = append(day.Single, h.Skill)
+				}
+			}
+		}
+		day.Candidates = replacementCandidates(objs, key, objs[i][key], pol.p, date)
+		if day.Candidates == nil {
+			day.Candidates = []string{}
+		}
+		check.Days = append(check.Days, day)
+	}
+	check.Feasible = !slices.ContainsFunc(check.Days, func(d leaveDayImpact) bool { return d.BelowTarget || len(d.Uncovered) > 0 })
+	check.Summary = check.summary()
+	return check, nil
+}
+
+// summary writes the check in a sentence for the approver, e.g. "If Eva
+// takes 2025-03-10 to 2025-03-14 off, coverage drops below target on
+// 2025-03-12 (Early: 1 of 2)."
+func (c leaveCheck) summary() string {
+	var below, skills []string
+	for _, d := range c.Days {
+		if d.BelowTarget {
+			below = append(below, fmt.Sprintf("%s (%s: %d of %d)", d.Date, d.Shift, d.Remaining, d.Required))
+		}
+		if len(d.Uncovered) > 0 {
+			skills = append(skills, fmt.Sprintf("%s on %s", strings.Join(d.Uncovered, " and "), d.Date))
+		}
+	}
+	text := fmt.Sprintf("If %s takes %s to %s off", c.Employee, c.Start, c.End)
+	switch {
+	case len(below) > 0 && len(skills) > 0:
+		text += fmt.Sprintf(", coverage drops below target on %s, and no one left on shift has %s.", strings.Join(below, ", "), strings.Join(skills, ", "))
+	case len(below) > 0:
+		text += fmt.Sprintf(", coverage drops below target on %s.", strings.Join(below, ", "))
+	case len(skills) > 0:
+		text += fmt.Sprintf(", no one left on shift has %s.", strings.Join(skills, ", "))
+	default:
+		text += ", every shift stays at or above target."
+	}
+	if len(c.Unscheduled) > 0 {
+		text += fmt.Sprintf(" %d days aren't on a published schedule yet.", len(c.Unscheduled))
+	}
+	return text
+}
+
+func (s *server) handleCheckLeave(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Employee string `json:"employee"`
+		Start    string `json:"start"`
+		End      string `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if req.Employee == "" {
+		writeError(w, http.StatusBadRequest, "employee is required")
+		return
+	}
+	l := LeaveEntry{Employee: req.Employee, Start: req.Start, End: req.End}
+	start, end, err := l.dates()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if end.Sub(start) >= maxCoverageDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("leave longer than %d days", maxCoverageDays))
+		return
+	}
+	check, err := s.queue.checkLeave(req.Employee, start, end)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, check)
+}
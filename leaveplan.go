@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/validator"
+)
+
+// LeavePlanConfig sets the coverage the annual leave planner keeps.
+type LeavePlanConfig struct {
+	// Headcount is how many of a team must be at work in a week of average
+	// demand. It defaults to enough to staff every shift at its minimum
+	// with five-day workweeks; busier weeks need proportionally more.
+	Headcount int `json:"headcount,omitempty"`
+}
+
+// minimumHeadcount is the team size staffing every shift at its minimum
+// every day with five-day workweeks.
+func minimumHeadcount(shifts int) int {
+	return int(math.Ceil(float64(shifts*validator.DefaultRules().MinPerShift) * 7 / 5))
+}
+
+// seasonality returns the calls per day of each ISO week number relative
+// to the average week, across the years of the daily call counts.
+func seasonality(dateCounts map[string]int) map[int]float64 {
+	calls := make(map[int]int)
+	days := make(map[int]map[string]bool)
+	for date, n := range dateCounts {
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			continue
+		}
+		_, week := day.ISOWeek()
+		if days[week] == nil {
+			days[week] = make(map[string]bool)
+		}
+		calls[week] += n
+		days[week][date] = true
+	}
+	perDay := make(map[int]float64, len(calls))
+	total := 0.0
+	for week, n := range calls {
+		perDay[week] = float64(n) / float64(len(days[week]))
+		total += perDay[week]
+	}
+	index := make(map[int]float64, len(perDay))
+	for week, v := range perDay {
+		index[week] = v / (total / float64(len(perDay)))
+	}
+	return index
+}
+
+// leaveWeek is one week of the leave calendar: its demand relative to the
+// average week, how many may be off, and who is.
+type leaveWeek struct {
+	Start    time.Time
+	Demand   float64
+	Required int
+	Allowed  int
+	// Booked is who already has leave that week and Planned who the plan
+	// gives leave.
+	Booked  []string
+	Planned []string
+}
+
+func (w *leaveWeek) off() int {
+	return len(w.Booked) + len(w.Planned)
+}
+
+func (w *leaveWeek) isOff(name string) bool {
+	return slices.Contains(w.Booked, name) || slices.Contains(w.Planned, name)
+}
+
+// leaveWeeks returns the ISO weeks of a year with the headcount each
+// needs at work, given its demand.
+func leaveWeeks(year int, index map[int]float64, team, minimum, headcount int) []*leaveWeek {
+	start := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	var weeks []*leaveWeek
+	for ; ; start = start.AddDate(0, 0, 7) {
+		y, week := start.ISOWeek()
+		if y != year {
+			break
+		}
+		demand, ok := index[week]
+		if !ok {
+			demand = 1
+		}
+		required := max(minimum, int(math.Ceil(float64(headcount)*demand)))
+		weeks = append(weeks, &leaveWeek{Start: start, Demand: demand, Required: required, Allowed: max(team-required, 0)})
+	}
+	return weeks
+}
+
+// bookLeave marks the weeks whose workdays the recorded leave of the team
+// falls on.
+func bookLeave(weeks []*leaveWeek, leave []LeaveEntry, team []string) error {
+	for _, l := range leave {
+		if !slices.Contains(team, l.Employee) {
+			continue
+		}
+		start, end, err := l.dates()
+		if err != nil {
+			return err
+		}
+		for _, w := range weeks {
+			friday := w.Start.AddDate(0, 0, 4)
+			if !start.After(friday) && !end.Before(w.Start) && !w.isOff(l.Employee) {
+				w.Booked = append(w.Booked, l.Employee)
+			}
+		}
+	}
+	return nil
+}
+
+// planLeave gives out each employee's balance in blocks of workdays, one
+// block per week. Each employee's blocks aim at points spread evenly over
+// the year, staggered between employees so they don't all aim at the same
+// weeks. Every round, employees pick in order of the share of their
+// balance planned so far, each taking the week nearest their next aim
+// that still has room, the roomiest and then quietest of equally near
+// weeks first, so no week drops below its headcount. It returns the days
+// that didn't fit a block or a week.
+func planLeave(weeks []*leaveWeek, balances map[string]float64, block int) map[string]float64 {
+	owed := make(map[string]int, len(balances))
+	planned := make(map[string]int, len(balances))
+	var names []string
+	for name, days := range balances {
+		if owed[name] = int(days) / block; owed[name] > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for {
+		var order []string
+		for _, name := range names {
+			if planned[name] < owed[name] {
+				order = append(order, name)
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return float64(planned[order[i]])/float64(owed[order[i]]) < float64(planned[order[j]])/float64(owed[order[j]])
+		})
+		progress := false
+		for _, name := range order {
+			stagger := (float64(slices.Index(names, name)) + 0.5) / float64(len(names))
+			aim := float64(len(weeks)) * (float64(planned[name]) + stagger) / float64(owed[name])
+			var best *leaveWeek
+			bestDistance := 0.0
+			for i, w := range weeks {
+				if w.isOff(name) || w.off() >= w.Allowed {
+					continue
+				}
+				distance := math.Abs(float64(i) + 0.5 - aim)
+				switch {
+				case best == nil, distance < bestDistance,
+					distance == bestDistance && w.Allowed-w.off() > best.Allowed-best.off(),
+					distance == bestDistance && w.Allowed-w.off() == best.Allowed-best.off() && w.Demand < best.Demand:
+					best, bestDistance = w, distance
+				}
+			}
+			if best == nil {
+				continue
+			}
+			best.Planned = append(best.Planned, name)
+			planned[name]++
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+	left := make(map[string]float64, len(balances))
+	for name, days := range balances {
+		left[name] = days - float64(planned[name]*block)
+	}
+	return left
+}
+
+// runLeavePlan proposes a leave calendar for a year that gives out the
+// team's leave balances fairly and keeps every week at its headcount.
+func runLeavePlan(args []string) error {
+	fs := flag.NewFlagSet("leave-plan", flag.ContinueOnError)
+	year := fs.Int("year", time.Now().Year()+1, "year to plan")
+	team := fs.String("team", "", "team to plan, from the roster (default everyone)")
+	block := fs.Int("block", 5, "workdays per leave block, from Monday")
+	out := fs.String("out", "leave_plan.csv", "file to write the proposed leave to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *block < 1 || *block > 5 {
+		return errors.New("block must be 1 to 5 workdays")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	var names []string
+	balances := make(map[string]float64)
+	for _, e := range roster {
+		if *team != "" && e.Team != *team {
+			continue
+		}
+		names = append(names, e.Name)
+		if e.LeaveBalance > 0 {
+			balances[e.Name] = e.LeaveBalance
+		}
+	}
+	if len(balances) == 0 {
+		return errors.New("no leave_balance in the roster for this team")
+	}
+	demand, err := loadDemandAggregates()
+	if err != nil {
+		return err
+	}
+	minimum := minimumHeadcount(len(shifts))
+	headcount := cfg.LeavePlan.Headcount
+	if headcount == 0 {
+		headcount = minimum
+	}
+	weeks := leaveWeeks(*year, seasonality(demand.DateCounts), len(names), minimum, headcount)
+	leave, err := loadLeave()
+	if err != nil {
+		return err
+	}
+	if err := bookLeave(weeks, leave, names); err != nil {
+		return err
+	}
+	left := planLeave(weeks, balances, *block)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WEEK\tSTARTS\tDEMAND\tAT WORK\tMAY BE OFF\tOFF")
+	for _, w := range weeks {
+		_, n := w.Start.ISOWeek()
+		off := slices.Concat(w.Booked, w.Planned)
+		fmt.Fprintf(tw, "%d-W%02d\t%s\t%.2f\t%d\t%d\t%s\n", *year, n, w.Start.Format("Mon 2 Jan"), w.Demand, w.Required, w.Allowed, strings.Join(off, ", "))
+	}
+	tw.Flush()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("error creating leave plan: %w", err)
+	}
+	defer file.Close()
+	cw, err := newCSVWriter(file, cfg.CSV)
+	if err != nil {
+		return err
+	}
+	cw.Write([]string{"Employee", "Start", "End", "Days"})
+	for _, w := range weeks {
+		for _, name := range w.Planned {
+			cw.Write([]string{name, w.Start.Format(time.DateOnly), w.Start.AddDate(0, 0, *block-1).Format(time.DateOnly), strconv.Itoa(*block)})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error writing leave plan: %w", err)
+	}
+
+	fmt.Println()
+	sort.Strings(names)
+	for _, name := range names {
+		if days, ok := balances[name]; ok {
+			fmt.Printf("%s: %g of %g days planned", name, days-left[name], days)
+			if left[name] > 0 {
+				fmt.Printf(", %g left to place", left[name])
+			}
+			fmt.Println()
+		}
+	}
+	fmt.Printf("Proposed leave written to %s\n", *out)
+	return nil
+}
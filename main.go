@@ -5,148 +5,201 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+
 	"github.com/sashabaranov/go-openai"
 )
 
 // Record represents one row of the CSV (Date and TicketVolume).
 type Record struct {
+	CallID         string
 	CalledTime     time.Time
 	AnsweredTime   time.Time
 	HangupTime     time.Time
 	EventTime      time.Time
 	WaitDuration   float64
 	TalkedDuration float64
+	// AgentID identifies the agent who took the call, if the export has it.
+	AgentID string
 }
 
 type FlatSchedule map[string]string
 
-func parseTime(value string) (time.Time, error) {
-	return time.Parse("2006/01/02 15:04", value)
+func parseTime(layout, value string) (time.Time, error) {
+	return time.Parse(layout, value)
 }
 
-func getRecords(csvFilePath string) ([]Record, error) {
+// sampleRows is how many rows getRecords reads before estimating how many
+// the file holds.
+const sampleRows = 16
+
+// getRecords parses the call records of a CSV export laid out as described
+// by mapping. Rows that can't be parsed are logged and skipped; they are
+// returned as rejects.
+func getRecords(csvFilePath string, mapping ColumnMapping) ([]Record, []rejectedRow, error) {
 	file, err := os.Open(csvFilePath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening CSV file: %w", err)
+		return nil, nil, fmt.Errorf("error opening CSV file: %w", err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	reader.Comma = ';' // Adjust if your CSV uses semicolons
+	reader.Comma = mapping.comma()
 
 	// Read header row.
 	header, err := reader.Read()
 	if err != nil {
-		return nil, fmt.Errorf("error reading CSV header: %w", err)
+		return nil, nil, fmt.Errorf("error reading CSV header: %w", err)
 	}
 
 	// Map header column names (in lower case) to their indices.
 	colIdx := make(map[string]int)
 	for i, col := range header {
-		colIdx[strings.ToLower(col)] = i
+		colIdx[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
 	}
 
+	// Resolve the optional columns once; -1 marks a column that is absent.
+	optional := func(role string) int {
+		if idx, ok := colIdx[strings.TrimSpace(mapping.column(role))]; ok {
+			return idx
+		}
+		return -1
+	}
+	calledIdx := optional(roleCalledTime)
+	if calledIdx < 0 {
+		return nil, nil, fmt.Errorf("error reading CSV header: no %q column (run \"inspect %s\" to map the columns)", mapping.column(roleCalledTime), csvFilePath)
+	}
+	callIDIdx := optional(roleCallID)
+	answeredIdx := optional(roleAnsweredTime)
+	hangupIdx := optional(roleHangupTime)
+	eventIdx := optional(roleEventTime)
+	waitIdx := optional(roleWaitDuration)
+	talkedIdx := optional(roleTalkedDuration)
+	agentIdx := optional(roleAgentID)
+	layout := mapping.layout()
+
+	// Rows are copied into Records straight away, so the reader can reuse
+	// its buffers. Once a sample of rows is in, room for the rest is made
+	// from their average length.
+	reader.ReuseRecord = true
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	start := reader.InputOffset()
 	var records []Record
+	var rejects []rejectedRow
+	reject := func(row []string, reason string) {
+		log.Print(reason)
+		row = append([]string(nil), row...)
+		// Rejects are kept in rejects.csv and the aggregate cache, so they
+		// don't keep who took the call.
+		if agentIdx >= 0 && agentIdx < len(row) {
+			row[agentIdx] = ""
+		}
+		rejects = append(rejects, rejectedRow{Header: header, Row: row, Reason: reason})
+	}
 	for {
 		row, err := reader.Read()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			log.Printf("error reading row: %v", err)
+			reject(row, fmt.Sprintf("%s: error reading row: %v", csvFilePath, err))
 			continue
 		}
 
 		// Parse CalledTime (assumed to always be present)
-		calledStr := strings.TrimSpace(row[colIdx["called_time"]])
-		calledTime, err := parseTime(calledStr)
+		calledStr := strings.TrimSpace(row[calledIdx])
+		calledTime, err := parseTime(layout, calledStr)
 		if err != nil {
-			log.Printf("error parsing called_time %q: %v", calledStr, err)
+			line, _ := reader.FieldPos(calledIdx)
+			reject(row, fmt.Sprintf("%s line %d: error parsing called_time %q: %v", csvFilePath, line, calledStr, err))
 			continue
 		}
 
-		// Parse AnsweredTime if available.
-		var answeredTime time.Time
-		if idx, ok := colIdx["answered_time"]; ok {
-			answeredStr := strings.TrimSpace(row[idx])
-			if answeredStr != "" {
-				answeredTime, err = parseTime(answeredStr)
-				if err != nil {
-					log.Printf("error parsing answered_time %q: %v", answeredStr, err)
-				}
-			}
+		// Parse the optional columns if available.
+		var callID string
+		if callIDIdx >= 0 {
+			callID = strings.TrimSpace(row[callIDIdx])
 		}
-
-		// Parse HangupTime if available.
-		var hangupTime time.Time
-		if idx, ok := colIdx["hangup_time"]; ok {
-			hangupStr := strings.TrimSpace(row[idx])
-			if hangupStr != "" {
-				hangupTime, err = parseTime(hangupStr)
-				if err != nil {
-					log.Printf("error parsing hangup_time %q: %v", hangupStr, err)
-				}
-			}
-		}
-
-		// Parse EventTime if available.
-		var eventTime time.Time
-		if idx, ok := colIdx["event_timestamp"]; ok {
-			eventStr := strings.TrimSpace(row[idx])
-			if eventStr != "" {
-				eventTime, err = parseTime(eventStr)
-				if err != nil {
-					log.Printf("error parsing event_timestamp %q: %v", eventStr, err)
-				}
-			}
-		}
-
-		// Parse WaitDuration if available.
-		var waitDuration float64
-		if idx, ok := colIdx["wait_duration"]; ok {
-			waitStr := strings.TrimSpace(row[idx])
-			if waitStr != "" {
-				waitDuration, err = strconv.ParseFloat(waitStr, 64)
-				if err != nil {
-					log.Printf("error parsing wait_duration %q: %v", waitStr, err)
-				}
-			}
-		}
-
-		// Parse TalkedDuration if available.
-		var talkedDuration float64
-		if idx, ok := colIdx["talked_duration"]; ok {
-			talkedStr := strings.TrimSpace(row[idx])
-			if talkedStr != "" {
-				talkedDuration, err = strconv.ParseFloat(talkedStr, 64)
-				if err != nil {
-					log.Printf("error parsing talked_duration %q: %v", talkedStr, err)
-				}
-			}
+		var agentID string
+		if agentIdx >= 0 {
+			agentID = strings.TrimSpace(row[agentIdx])
 		}
+		answeredTime := parseOptionalTime(row, answeredIdx, layout, "answered_time")
+		hangupTime := parseOptionalTime(row, hangupIdx, layout, "hangup_time")
+		eventTime := parseOptionalTime(row, eventIdx, layout, "event_timestamp")
+		waitDuration := parseOptionalDuration(row, waitIdx, mapping, "wait_duration")
+		talkedDuration := parseOptionalDuration(row, talkedIdx, mapping, "talked_duration")
 
 		// Create the record and append it.
-		record := Record{
+		records = append(records, Record{
+			CallID:         callID,
 			CalledTime:     calledTime,
 			AnsweredTime:   answeredTime,
 			HangupTime:     hangupTime,
 			EventTime:      eventTime,
 			WaitDuration:   waitDuration,
 			TalkedDuration: talkedDuration,
+			AgentID:        agentID,
+		})
+		if len(records) == sampleRows && size > 0 {
+			read := reader.InputOffset()
+			if perRow := (read - start) / sampleRows; perRow > 0 && size > read {
+				records = slices.Grow(records, int((size-read)/perRow))
+			}
 		}
-		records = append(records, record)
 	}
 
-	return records, nil
+	return records, rejects, nil
+}
+
+// parseOptionalTime parses row[idx] as a time, returning the zero time when
+// the column is absent, empty, or invalid.
+func parseOptionalTime(row []string, idx int, layout, column string) time.Time {
+	if idx < 0 {
+		return time.Time{}
+	}
+	value := strings.TrimSpace(row[idx])
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := parseTime(layout, value)
+	if err != nil {
+		log.Printf("error parsing %s %q: %v", column, value, err)
+	}
+	return t
+}
+
+// parseOptionalDuration parses row[idx] as a duration in seconds, returning
+// zero when the column is absent, empty, or invalid.
+func parseOptionalDuration(row []string, idx int, mapping ColumnMapping, column string) float64 {
+	if idx < 0 {
+		return 0
+	}
+	value := strings.TrimSpace(row[idx])
+	if value == "" {
+		return 0
+	}
+	f, err := mapping.parseDuration(value)
+	if err != nil {
+		log.Printf("error parsing %s %q: %v", column, value, err)
+	}
+	return f
 }
 
 func computeDayCounts(records []Record) map[int]int {
@@ -168,8 +221,16 @@ func computeThreshold(values []int, percentile float64) float64 {
 }
 
 func getHighVolumeDayNumbers(records []Record, percentile float64) []int {
-	countsMap := computeDayCounts(records)
-	var counts []int
+	return highVolumeDaysFromCounts(computeDayCounts(records), percentile)
+}
+
+// highVolumeDaysFromCounts returns the days whose call count is above the
+// given percentile of all daily counts.
+func highVolumeDaysFromCounts(countsMap map[int]int, percentile float64) []int {
+	if len(countsMap) == 0 {
+		return nil
+	}
+	counts := make([]int, 0, len(countsMap))
 	for _, count := range countsMap {
 		counts = append(counts, count)
 	}
@@ -184,7 +245,10 @@ func getHighVolumeDayNumbers(records []Record, percentile float64) []int {
 	return highVolumeDays
 }
 
-func buildPrompt(employeeNames []string, highVolumeDayNumbers []int) string {
+// buildPrompt writes the scheduling prompt. notes are extra lines for the
+// shifts section, such as staffing targets and limits; lang is the language
+// the schedule is written in.
+func buildPrompt(employeeNames []string, highVolumeDayNumbers []int, shifts []schedule.Shift, rotation RotationConfig, notes []string, lang language) string {
 	var dayStrs []string
 	for _, d := range highVolumeDayNumbers {
 		dayStrs = append(dayStrs, strconv.Itoa(d))
@@ -195,47 +259,55 @@ You are a scheduling software application. Utilizing forecasted dates that exper
 High Volume Days: %s and Employees: %s
 
 Shifts: 
-- 6 am - 3 pm which is considered an "Early Shift"
-- 8 am - 5 pm which is considered a "Normal Shift"
-- 11 am - 8 pm which is considered a "Late Shift"
-- NOTE: a completed shift is when an employee has worked 5 days of the same shift before being assigned a new shift.
-
+%s- %s
+%s
 Operation Constraints **STRICT**:
 - Shift coverage: Ensure each shift has at least two employees scheduled per day when possible. Ensure every day has at least two employees per shift to avoid experiencing downtime.
-- Shift rotation: Ensure that each week employees are rotated between shifts. For example: Alice - Week 1 Early, Alice - Week 2 Normal, Alice - Week 3 Late, and so on.
+- Shift rotation: %s
 - Off Days: Try your hardest to give employees at least two weekends Saturday and Sunday off at least twice in that five-week schedule. Try your hardest to ensure that employees get two rest days before the start of a new shift if possible. Maximum of two days off per week.
 - Scheduling: I recommend grouping employees as evenly as possible and rotating the shifts between those groups.
 - Hours: On a weekly, employees can only work 45 hours per week, and in a month they can only work 225. Employees are also to be scheduled every week.
 
 Do not return any extra text. Only generate the five-week schedule. The desired output should just be a JSON array of objects and each object represents one employee schedule such as: 
-{"Week": "Week 1", "Employee": "Alice", "Monday (1st March)": "Early", "Tuesday (2nd March)": "Normal", "Wednesday (3rd March)": "Late", "Thursday (4th March)": "Off", "Friday (5th March)": "Early", "Saturday (6th March)": "Off", "Sunday (7th March)": "Normal"}
-
+%s
+%s
 If constraints cannot be met please do not proceed with providing an output. 
-`, strings.Join(dayStrs, ", "), strings.Join(employeeNames, ", "))
+`, strings.Join(dayStrs, ", "), strings.Join(employeeNames, ", "), shiftLines(shifts), completedShiftNote(rotation), promptNotes(notes), rotationRule(rotation, shifts), lang.exampleRow(), lang.instruction())
 	return prompt
 }
 
-func callChatGPT(prompt string) (string, error) {
+// callChatGPT asks an OpenAI chat model, gpt-4o-mini when model is empty,
+// for the schedule.
+func callChatGPT(ctx context.Context, prompt, model string) (response string, err error) {
+	ctx, sp := startSpan(ctx, "llm")
+	defer func() { sp.finish(err) }()
+
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return "", errors.New("OPENAI_API_KEY not set")
 	}
 
 	client := openai.NewClient(apiKey)
-	ctx := context.Background()
+	if model == "" {
+		model = openai.GPT4oMini
+	}
 
 	req := openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
+		Model:       model,
 		Temperature: 0.5,
 		Messages: []openai.ChatCompletionMessage{
 			{Role: openai.ChatMessageRoleAssistant, Content: prompt},
 		},
 	}
 
+	sp.setAttr("llm.model", req.Model)
 	resp, err := client.CreateChatCompletion(ctx, req)
 	if err != nil {
 		return "", fmt.Errorf("ChatCompletion error: %w", err)
 	}
+	sp.setAttr("llm.prompt_tokens", resp.Usage.PromptTokens)
+	sp.setAttr("llm.completion_tokens", resp.Usage.CompletionTokens)
+	sp.setAttr("llm.total_tokens", resp.Usage.TotalTokens)
 
 	if len(resp.Choices) == 0 {
 		return "", fmt.Errorf("no choices returned from API")
@@ -244,19 +316,24 @@ func callChatGPT(prompt string) (string, error) {
 	return resp.Choices[0].Message.Content, nil
 }
 
-func groupObjectsByWeek(jsonStr string) (map[string][]FlatSchedule, error) {
+// groupObjectsByWeek groups the schedule objects by week, translating
+// objects written in lang to the English labels first. The objects must
+// match the schedule schema.
+func groupObjectsByWeek(jsonStr string, lang language) (map[string][]FlatSchedule, error) {
 	var entries []FlatSchedule
 	if err := json.Unmarshal([]byte(jsonStr), &entries); err != nil {
 		return nil, fmt.Errorf("error unmarshaling JSON array: %w", err)
 	}
+	for i, entry := range entries {
+		entries[i] = lang.canonical(entry)
+	}
+	if err := validateScheduleJSON(entries); err != nil {
+		return nil, err
+	}
 
 	weeks := make(map[string][]FlatSchedule)
 	for _, entry := range entries {
-		weekKey, ok := entry["Week"]
-		if !ok {
-			continue
-		}
-		weeks[weekKey] = append(weeks[weekKey], entry)
+		weeks[entry["Week"]] = append(weeks[entry["Week"]], entry)
 	}
 	return weeks, nil
 }
@@ -330,59 +407,482 @@ func buildTableForWeek(header []string, objs []FlatSchedule) [][]string {
 	return table
 }
 
+// weekFileName returns the CSV file name used for a week's schedule.
+func weekFileName(week string) string {
+	return fmt.Sprintf("generated_schedule_%s.csv", strings.ReplaceAll(week, " ", ""))
+}
+
+// writeWeekCSV writes one week's schedule to dir in the CSV format cc and
+// returns the file path.
+func writeWeekCSV(dir, week string, objs []FlatSchedule, cc CSVConfig) (string, error) {
+	header := buildHeaderForWeek(objs)
+	table := buildTableForWeek(header, objs)
+	filename := filepath.Join(dir, weekFileName(week))
+	csvFile, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("error creating CSV file %s: %w", filename, err)
+	}
+	defer csvFile.Close()
+	writer, err := newCSVWriter(csvFile, cc)
+	if err != nil {
+		return "", err
+	}
+	if err := writer.WriteAll(table); err != nil {
+		return "", fmt.Errorf("error writing CSV data to %s: %w", filename, err)
+	}
+	return filename, nil
+}
+
+// commands maps subcommand names to their handlers. Running the binary
+// without a known subcommand falls through to schedule generation.
+var commands = map[string]func(args []string) error{
+	"actuals":      runActuals,
+	"apply":        runApply,
+	"archive":      runArchive,
+	"borrow":       runBorrow,
+	"churn":        runChurn,
+	"compact":      runCompact,
+	"compare":      runCompare,
+	"conflicts":    runConflicts,
+	"cross-train":  runCrossTrain,
+	"db":           runDB,
+	"encrypt":      runEncrypt,
+	"evaluate":     runEvaluate,
+	"export":       runExport,
+	"export-state": runExportState,
+	"fairness":     runFairness,
+	"forecast":     runForecast,
+	"generate":     generate,
+	"handover":     runHandover,
+	"import-state": runImportState,
+	"inspect":      runInspect,
+	"labor-cost":   runLaborCost,
+	"leave-plan":   runLeavePlan,
+	"leave-sync":   runLeaveSync,
+	"notice":       runNotice,
+	"packs":        runPacks,
+	"pareto":       runPareto,
+	"plan":         runPlan,
+	"reconcile":    runReconcile,
+	"redact":       runRedact,
+	"register":     runRegister,
+	"report":       runReport,
+	"resume":       runResume,
+	"run":          runTeams,
+	"scenario":     runScenario,
+	"schema":       runSchema,
+	"serve":        runServe,
+	"show":         runShow,
+	"skills":       runSkills,
+	"staffing":     runStaffing,
+	"stats":        runStats,
+	"stress":       runStress,
+	"swap":         runSwap,
+	"timesheet":    runTimesheet,
+	"view":         runView,
+}
+
 func main() {
-	csvFilePath := "" // Please set this.
-	records, err := getRecords(csvFilePath)
+	if viewerEdition {
+		runViewer()
+		return
+	}
+	args, err := parseGlobalFlags(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error processing CSV: %v", err)
+		log.Fatal(err)
+	}
+	os.Args = append(os.Args[:1], args...)
+	if offline {
+		if err := checkOffline(); err != nil {
+			exit("", "Error starting offline", withExitCode(exitInput, err))
+		}
+	}
+	// Upgrade the data directory before anything reads it; "db" reports
+	// and applies migrations itself.
+	if len(os.Args) < 2 || os.Args[1] != "db" {
+		if _, err := migrateData(); err != nil {
+			exit("", "Error upgrading data directory", err)
+		}
+	}
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			err := cmd(os.Args[2:])
+			flushTracing(context.Background())
+			if err != nil {
+				exit(os.Args[1], "Error running "+os.Args[1], err)
+			}
+			return
+		}
+	}
+	if err := generate(os.Args[1:]); err != nil {
+		exit("generate", "", err)
+	}
+}
+
+// parseGlobalFlags takes the flags that come before the command off args:
+// --offline, --error-format, and --data-dir and --config, which set
+// SCHEDULER_DATA_DIR and SCHEDULER_CONFIG for the rest of the run.
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		switch name {
+		case "offline":
+			offline = true
+			args = args[1:]
+			continue
+		case "error-format", "data-dir", "config":
+		default:
+			return args, nil
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--%s needs a value", name)
+			}
+			value, args = args[1], args[1:]
+		}
+		switch name {
+		case "error-format":
+			if value != "text" && value != "json" {
+				return nil, fmt.Errorf("invalid --error-format %q (want text or json)", value)
+			}
+			errorFormat = value
+		case "data-dir":
+			os.Setenv("SCHEDULER_DATA_DIR", value)
+		case "config":
+			os.Setenv("SCHEDULER_CONFIG", value)
+		}
+		args = args[1:]
 	}
-	log.Printf("Processed %d records.\n", len(records))
+	return args, nil
+}
 
-	// Compute high-volume day numbers.
-	highVolumeDays := getHighVolumeDayNumbers(records, 75)
-	log.Printf("High volume day numbers: %v", highVolumeDays)
+// generateOptions holds the inputs of one schedule generation.
+type generateOptions struct {
+	Inputs     []string `json:"inputs"`
+	Employees  []string `json:"employees"`
+	Percentile float64  `json:"percentile"`
+	Dedup      string   `json:"dedup"`
+	// Preset names the built-in column mapping of the inputs' vendor; the
+	// mapping in the config file is used when it is empty.
+	Preset string `json:"preset,omitempty"`
+	// Model names the registered forecast model.
+	Model string `json:"model"`
+	// Forecast is a forecast file to schedule against instead of
+	// forecasting from the inputs.
+	Forecast string `json:"forecast,omitempty"`
+	// Objective names the optimization preset, such as service-first; the
+	// config's is used when it is empty.
+	Objective string `json:"objective,omitempty"`
+	// TargetScale scales the shift targets asked for in the prompt, such
+	// as 1.1 for 10% above them; validation still measures the unscaled
+	// targets.
+	TargetScale float64 `json:"target_scale,omitempty"`
+	// Cold ignores the previous schedule instead of asking to keep its
+	// weekly rhythms. WarmStart is where the previous schedule is read
+	// from, OutputDir when empty.
+	Cold      bool   `json:"cold,omitempty"`
+	WarmStart string `json:"-"`
+	// ReasonCode allows changes inside the notice period of published
+	// shifts and is recorded with them.
+	ReasonCode string `json:"reason_code,omitempty"`
+	// Strict fails the run on any unparsable row; otherwise up to
+	// MaxBadRows rows (any number when negative) are skipped.
+	Strict     bool `json:"strict"`
+	MaxBadRows int  `json:"max_bad_rows"`
+	// Solver writes the schedule: openai, the default, or local, which
+	// needs no network. Offline runs always use local.
+	Solver string `json:"solver,omitempty"`
+	// LLM is the OpenAI chat model of the openai solver, gpt-4o-mini when
+	// empty.
+	LLM string `json:"llm,omitempty"`
+	// Repairs is how many times at most the openai solver's schedule is
+	// sent back with its error violations for a corrected one.
+	Repairs   int    `json:"repairs,omitempty"`
+	OutputDir string `json:"-"`
+	// Plan stops the run once its schedule is validated, leaving it for
+	// the apply command to publish.
+	Plan bool `json:"-"`
+	// Prompt is the prompt template version to use instead of the config's
+	// pin or the latest, and Evaluate stops the run once its schedule is
+	// validated and scored, publishing nothing. The evaluate command sets
+	// them.
+	Prompt   string `json:"-"`
+	Evaluate bool   `json:"-"`
+}
 
-	// Example employee names.
-	employeeNames := []string{"Alice", "Bob", "Charlie", "David", "Eva", "Frank", "Grace", "Hannah", "Mbuso"}
+// defaultGenerateOptions returns the inputs used when none are given. With
+// no employees listed, everyone on the roster is scheduled.
+func defaultGenerateOptions() generateOptions {
+	return generateOptions{
+		Percentile: 75,
+		Model:      "day-of-month",
+		Dedup:      dedupKeepFirst,
+		MaxBadRows: -1,
+		OutputDir:  ".",
+	}
+}
 
-	// Build the scheduling prompt.
-	prompt := buildPrompt(employeeNames, highVolumeDays)
+// checkBadRows fails when more input rows were skipped than opts allow, so a
+// broken extract can't silently skew the forecast.
+func checkBadRows(agg Aggregates, opts generateOptions) error {
+	bad := len(agg.Rejects)
+	if bad == 0 {
+		return nil
+	}
+	if opts.Strict {
+		return fmt.Errorf("%d unparsable rows in input (strict mode)", bad)
+	}
+	if opts.MaxBadRows >= 0 && bad > opts.MaxBadRows {
+		return fmt.Errorf("%d unparsable rows in input, more than the %d allowed", bad, opts.MaxBadRows)
+	}
+	log.Printf("Skipped %d unparsable rows", bad)
+	return nil
+}
 
-	// Call ChatGPT (replace this with your actual API call).
-	response, err := callChatGPT(prompt)
-	if err != nil {
-		log.Fatalf("Error calling ChatGPT: %v", err)
+// generateRun takes a run through ingest, the API call, and export. onStage,
+// if set, is told when the run moves on to validating the response. A run
+// that already holds a response skips straight to validation.
+func generateRun(ctx context.Context, run *Run, opts generateOptions, onStage func(stage string)) (err error) {
+	ctx, sp := startSpan(ctx, "generate")
+	sp.setAttr("run.id", run.ID)
+	defer func() { sp.finish(err) }()
+
+	if run.Response == "" {
+		if len(opts.Employees) == 0 {
+			if opts.Employees, err = rosterNames(); err != nil {
+				return err
+			}
+			if len(opts.Employees) == 0 {
+				return withExitCode(exitInput, errors.New("no employees to schedule: list them with -employees or in the roster"))
+			}
+		}
+		if err := runHooks(PreGenerate{RunID: run.ID, OutputDir: opts.OutputDir, Employees: opts.Employees, Inputs: opts.Inputs}, true); err != nil {
+			return fmt.Errorf("pre_generate hook failed: %w", err)
+		}
+		fc, err := loadForecast(ctx, opts)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+		highVolumeDays := fc.HighVolumeDays()
+		log.Printf("High volume day numbers: %v", highVolumeDays)
+
+		// Build the scheduling prompt.
+		p, err := loadPolicy(opts.Employees)
+		if err != nil {
+			return err
+		}
+		targets := p.shiftTargets(fc, len(opts.Employees))
+		if opts.TargetScale > 0 && opts.TargetScale != 1 {
+			targets = scaleTargets(targets, opts.TargetScale)
+		}
+		if len(targets) > 0 {
+			log.Printf("Shift headcount targets: %v", targets)
+		}
+		objective, err := runObjective(opts.Objective, p.cfg)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+		if err := p.cfg.Notice.validCode(opts.ReasonCode); err != nil {
+			return withExitCode(exitInput, err)
+		}
+		notes := append(p.notes(targets), objectiveNote(objective, objectivePresets[objective]))
+		if !opts.Cold {
+			from := opts.WarmStart
+			if from == "" {
+				from = opts.OutputDir
+			}
+			pattern, err := previousPattern(from, p.shifts)
+			if err != nil {
+				return err
+			}
+			if note := pattern.note(opts.Employees); note != "" {
+				notes = append(notes, note)
+				run.Pattern, run.Previous = pattern, from
+			}
+		}
+		ledger, err := loadFairnessLedger()
+		if err != nil {
+			return err
+		}
+		if len(ledger.Days) > 0 && len(fc.Days) > 0 {
+			first, _ := time.Parse(forecast.DateLayout, fc.Days[0].Date)
+			last, _ := time.Parse(forecast.DateLayout, fc.Days[len(fc.Days)-1].Date)
+			lates := lateShifts(p.cfg, p.shifts)
+			counts := ledger.counts(lates, p.cfg.Holidays)
+			notes = append(notes, fairnessNote(counts, opts.Employees, lates, holidaysBetween(p.cfg.Holidays, first, last)))
+		}
+		if opts.Prompt != "" {
+			if p.prompt, p.promptPack, err = lookupPrompt(opts.Prompt); err != nil {
+				return withExitCode(exitInput, err)
+			}
+		}
+		prompt := p.prompt(opts.Employees, highVolumeDays, p.shifts, p.rotation, notes, p.language)
+		run.HighVolumeDays = highVolumeDays
+		run.Forecast = fc
+		run.Employees = opts.Employees
+		run.Objective = objective
+		run.TargetScale = opts.TargetScale
+		run.ReasonCode = opts.ReasonCode
+		run.Prompt = prompt
+		run.PromptPack, run.RulePack = p.promptPack, p.rulePack.Version
+		run.Solver, run.LLM = solverOpenAI, opts.LLM
+		if offline || opts.Solver == solverLocal {
+			run.Solver, run.LLM = solverLocal, ""
+		}
+		run.OutputDir = opts.OutputDir
+		if err := saveRun(run); err != nil {
+			return fmt.Errorf("error saving run: %w", err)
+		}
+		notePackChanges(run)
+
+		var response string
+		if run.Solver == solverLocal {
+			if response, err = solveLocally(p, fc, opts.Employees, p.rules(run)); err != nil {
+				return withExitCode(exitInfeasible, fmt.Errorf("error solving locally: %w", err))
+			}
+			log.Printf("Schedule written by the local solver")
+		} else {
+			// Call ChatGPT (replace this with your actual API call).
+			if response, err = callChatGPT(ctx, prompt, run.LLM); err != nil {
+				return withExitCode(exitLLM, fmt.Errorf("error calling ChatGPT: %w", err))
+			}
+			fmt.Println("ChatGPT Response:", response)
+		}
+
+		// Store the response before anything else can fail, so the run can be
+		// resumed without another API call.
+		run.Response = response
+		run.Stage = stageResponded
+		if err := saveRun(run); err != nil {
+			return fmt.Errorf("error saving run: %w", err)
+		}
+		if run.Solver == solverOpenAI && opts.Repairs > 0 {
+			if err := repairResponse(ctx, run, p, opts.Repairs); err != nil {
+				return err
+			}
+		}
 	}
-	fmt.Println("ChatGPT Response:", response)
 
-	// --- Clean and extract the JSON part ---
-	startIndex := strings.IndexAny(response, "[{")
-	if startIndex == -1 {
-		log.Fatalf("No JSON array or object found in the response")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if onStage != nil {
+		onStage(stageValidating)
+	}
+	if opts.Plan {
+		return planRun(ctx, run, os.Stdout)
+	}
+	if opts.Evaluate {
+		if _, err := validateRun(ctx, run); err != nil {
+			return err
+		}
+		run.Stage = stageEvaluated
+		return saveRun(run)
 	}
-	jsonPart := strings.Trim(response[startIndex:], " \n`")
+	return exportRun(ctx, run)
+}
 
-	// Group objects by week.
-	weeks, err := groupObjectsByWeek(jsonPart)
+// generateFlags returns the flags of the commands generating a schedule,
+// set into opts.
+func generateFlags(name string, opts *generateOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Func("input", "call-record CSV to forecast from (repeatable; files may also follow the flags)", func(v string) error {
+		opts.Inputs = append(opts.Inputs, v)
+		return nil
+	})
+	fs.Func("employees", "employees to schedule: comma-separated names, or a file of them (default the roster)", func(v string) error {
+		names, err := readEmployees(v)
+		opts.Employees = names
+		return err
+	})
+	fs.Float64Var(&opts.Percentile, "percentile", opts.Percentile, "daily-volume percentile above which days are high volume")
+	fs.StringVar(&opts.Dedup, "dedup", opts.Dedup, "how to drop duplicate call records: none, keep-first, keep-last, or keep-longest")
+	fs.StringVar(&opts.Preset, "preset", "", "column mapping of a known export format: "+strings.Join(presetNames(), ", "))
+	fs.StringVar(&opts.Model, "model", opts.Model, "forecast model: "+strings.Join(forecast.Names(), ", "))
+	fs.StringVar(&opts.Forecast, "forecast", "", "forecast file written by the forecast command to schedule against")
+	fs.StringVar(&opts.Objective, "objective", "", "optimization preset (default from the config, else balanced): "+strings.Join(objectiveNames(), ", "))
+	fs.BoolVar(&opts.Cold, "cold", false, "ignore the previous schedule's weekly rhythms")
+	fs.StringVar(&opts.ReasonCode, "code", "", "reason code allowing changes to published shifts inside the notice period")
+	fs.BoolVar(&opts.Strict, "strict", false, "fail on any unparsable input row instead of skipping it")
+	fs.IntVar(&opts.MaxBadRows, "max-bad-rows", opts.MaxBadRows, "fail when more input rows than this can't be parsed (-1 for no limit)")
+	fs.StringVar(&opts.OutputDir, "out", opts.OutputDir, "directory to publish the schedule in")
+	fs.StringVar(&opts.LLM, "llm", "", "OpenAI chat model of the openai solver (default gpt-4o-mini)")
+	fs.IntVar(&opts.Repairs, "repair", 0, "times to send the openai solver's schedule back with its error violations for a corrected one")
+	fs.StringVar(&opts.Solver, "solver", "", "what writes the schedule: "+strings.Join(solverNames, ", ")+" (default openai, local when offline)")
+	fs.Func("engine", "llm or solver, the same as -solver openai or -solver local", func(v string) error {
+		solver, ok := engineSolvers[v]
+		if !ok {
+			return fmt.Errorf("unknown engine %q (want llm or solver)", v)
+		}
+		opts.Solver = solver
+		return nil
+	})
+	return fs
+}
+
+// checkGenerateOptions reports options naming unknown strategies, presets,
+// models, objectives, or solvers.
+func checkGenerateOptions(opts generateOptions) error {
+	if err := validSolver(opts.Solver); err != nil {
+		return err
+	}
+	if offline && opts.Solver == solverOpenAI {
+		return errors.New("the openai solver is unavailable offline")
+	}
+	if err := validDedupStrategy(opts.Dedup); err != nil {
+		return err
+	}
+	if opts.Repairs < 0 {
+		return errors.New("repair can't be negative")
+	}
+	if opts.Percentile <= 0 || opts.Percentile > 100 {
+		return fmt.Errorf("invalid percentile %g (want above 0, up to 100)", opts.Percentile)
+	}
+	if opts.Preset != "" {
+		if _, err := lookupPreset(opts.Preset); err != nil {
+			return err
+		}
+	}
+	if _, err := forecast.New(opts.Model); err != nil {
+		return err
+	}
+	if opts.Objective != "" {
+		if _, err := lookupObjective(opts.Objective); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generate runs a new schedule generation from the command line.
+func generate(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := generateFlags("generate", &opts)
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	opts.Inputs = append(opts.Inputs, fs.Args()...)
+	if err := checkGenerateOptions(opts); err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	run, err := newRun()
 	if err != nil {
-		log.Fatalf("Error grouping objects by week: %v", err)
+		return fmt.Errorf("error starting run: %w", err)
 	}
+	log.Printf("Starting run %s", run.ID)
 
-	// For each week, build a header and table, then write a CSV file.
-	for week, objs := range weeks {
-		header := buildHeaderForWeek(objs)
-		table := buildTableForWeek(header, objs)
-		filename := fmt.Sprintf("generated_schedule_%s.csv", strings.ReplaceAll(week, " ", ""))
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			log.Fatalf("Error creating CSV file %s: %v", filename, err)
-		}
-		writer := csv.NewWriter(csvFile)
-		if err := writer.WriteAll(table); err != nil {
-			log.Fatalf("Error writing CSV data to %s: %v", filename, err)
+	err = generateRun(context.Background(), run, opts, nil)
+	flushTracing(context.Background())
+	if err != nil {
+		if run.Stage == stageResponded {
+			return fmt.Errorf("error exporting run %s (retry with \"resume %s\"): %w", run.ID, run.ID, err)
 		}
-		writer.Flush()
-		csvFile.Close()
-		log.Printf("Schedule for %s saved to %s", week, filename)
+		return fmt.Errorf("error generating schedule: %w", err)
+	}
+	if err := checkErrors(run); err != nil {
+		return err
 	}
+	log.Printf("Run %s complete", run.ID)
+	return nil
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetRecordsCapacity(t *testing.T) {
+	tests := []struct {
+		name string
+		rows int
+		// pad lengthens each row, for exports with many or wide columns.
+		pad int
+	}{
+		{name: "few rows", rows: 5},
+		{name: "short rows", rows: 2000},
+		{name: "wide rows", rows: 2000, pad: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			b.WriteString("called_time;call_id;notes\n")
+			for i := range tt.rows {
+				fmt.Fprintf(&b, "2026-10-19 09:%02d:00;c%d;%s\n", i%60, i, strings.Repeat("x", tt.pad))
+			}
+			path := filepath.Join(t.TempDir(), "calls.csv")
+			if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			records, _, err := getRecords(path, ColumnMapping{TimeLayout: "2006-01-02 15:04:05"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != tt.rows {
+				t.Fatalf("got %d records, want %d", len(records), tt.rows)
+			}
+			if c := cap(records); c > 2*tt.rows+sampleRows {
+				t.Errorf("capacity %d for %d records", c, tt.rows)
+			}
+		})
+	}
+}
+
+func TestViewerArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		want          []string
+		dataDir, conf string
+	}{
+		{name: "view args only", args: []string{"-employee", "Ann", "out"}, want: []string{"-employee", "Ann", "out"}},
+		{name: "command name", args: []string{"view", "out"}, want: []string{"out"}},
+		{name: "data dir", args: []string{"--data-dir", "/srv/data", "view", "out"}, want: []string{"out"}, dataDir: "/srv/data"},
+		{name: "config without command", args: []string{"--config=/srv/c.json", "-employee", "Ann", "out"}, want: []string{"-employee", "Ann", "out"}, conf: "/srv/c.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCHEDULER_DATA_DIR", "")
+			t.Setenv("SCHEDULER_CONFIG", "")
+			got, err := viewerArgs(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args %q, want %q", got, tt.want)
+			}
+			if got := os.Getenv("SCHEDULER_DATA_DIR"); got != tt.dataDir {
+				t.Errorf("SCHEDULER_DATA_DIR %q, want %q", got, tt.dataDir)
+			}
+			if got := os.Getenv("SCHEDULER_CONFIG"); got != tt.conf {
+				t.Errorf("SCHEDULER_CONFIG %q, want %q", got, tt.conf)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Roles a call-record column can play.
+const (
+	roleCallID         = "call_id"
+	roleCalledTime     = "called_time"
+	roleAnsweredTime   = "answered_time"
+	roleHangupTime     = "hangup_time"
+	roleEventTime      = "event_timestamp"
+	roleWaitDuration   = "wait_duration"
+	roleTalkedDuration = "talked_duration"
+	roleAgentID        = "agent_id"
+)
+
+// recordRoles lists the roles in the order they are presented.
+var recordRoles = []string{roleCalledTime, roleAnsweredTime, roleHangupTime, roleEventTime, roleWaitDuration, roleTalkedDuration, roleCallID, roleAgentID}
+
+const defaultTimeLayout = "2006/01/02 15:04"
+
+// Formats of duration columns.
+const (
+	durationSeconds      = "seconds"
+	durationMilliseconds = "milliseconds"
+	durationClock        = "hh:mm:ss"
+)
+
+// ColumnMapping describes how to read a call-record export. The zero value
+// reads semicolon-separated files whose columns are named after the roles.
+type ColumnMapping struct {
+	Delimiter string `json:"delimiter,omitempty"`
+	// TimeLayout is a Go reference-time layout.
+	TimeLayout string `json:"time_layout,omitempty"`
+	// DurationFormat is seconds (the default), milliseconds, or hh:mm:ss.
+	DurationFormat string `json:"duration_format,omitempty"`
+	// Columns maps roles to header names. Roles not listed are read from
+	// the column named after the role.
+	Columns map[string]string `json:"columns,omitempty"`
+}
+
+func (m ColumnMapping) comma() rune {
+	if r, _ := utf8.DecodeRuneInString(m.Delimiter); r != utf8.RuneError {
+		return r
+	}
+	return ';'
+}
+
+func (m ColumnMapping) layout() string {
+	if m.TimeLayout != "" {
+		return m.TimeLayout
+	}
+	return defaultTimeLayout
+}
+
+// parseDuration parses a duration column value into seconds.
+func (m ColumnMapping) parseDuration(value string) (float64, error) {
+	switch m.DurationFormat {
+	case "", durationSeconds:
+		return strconv.ParseFloat(value, 64)
+	case durationMilliseconds:
+		ms, err := strconv.ParseFloat(value, 64)
+		return ms / 1000, err
+	case durationClock:
+		total := 0.0
+		for _, part := range strings.Split(value, ":") {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			total = total*60 + n
+		}
+		return total, nil
+	}
+	return 0, fmt.Errorf("unknown duration format %q", m.DurationFormat)
+}
+
+// column returns the lower-cased header name holding role.
+func (m ColumnMapping) column(role string) string {
+	if name, ok := m.Columns[role]; ok {
+		return strings.ToLower(name)
+	}
+	return role
+}
+
+// fingerprint identifies the mapping in aggregate cache keys, since the
+// same file read with another mapping yields other records.
+func (m ColumnMapping) fingerprint() string {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
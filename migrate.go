@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// migration upgrades the data directory from the previous schema version
+// to Version. Migrations ship in the binary and must be safe to run again
+// on data they already upgraded, in case one is interrupted.
+type migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+// migrations lists every schema upgrade in version order. Append new ones;
+// never renumber or remove one that has shipped.
+var migrations = []migration{
+	{Version: 1, Name: "add agent_id column to demand segments", Up: addDemandAgentColumn},
+}
+
+// migrateMu serializes upgrades of the data directory.
+var migrateMu sync.Mutex
+
+// schemaState records the schema version of the data directory and the
+// migrations that brought it there.
+type schemaState struct {
+	Version int              `json:"version"`
+	Applied []appliedVersion `json:"applied,omitempty"`
+}
+
+type appliedVersion struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func schemaPath() string {
+	return filepath.Join(dataDir(), "schema.json")
+}
+
+// latestSchema returns the schema version this binary upgrades to.
+func latestSchema() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// loadSchemaState reads the schema state. A data directory without one is
+// at version 0.
+func loadSchemaState() (schemaState, error) {
+	var state schemaState
+	data, err := os.ReadFile(schemaPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return state, fmt.Errorf("error reading schema state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error parsing schema state: %w", err)
+	}
+	return state, nil
+}
+
+func saveSchemaState(state schemaState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := schemaPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing schema state: %w", err)
+	}
+	return os.Rename(tmp, schemaPath())
+}
+
+// pendingMigrations returns the migrations not yet applied to a data
+// directory at the given version.
+func pendingMigrations(version int) []migration {
+	var pending []migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// migrateData applies the pending migrations in order, recording each one
+// as soon as it succeeds, and returns how many ran. It refuses to touch a
+// data directory upgraded by a newer version. A missing data directory is
+// left alone: there is nothing to upgrade yet.
+func migrateData() (int, error) {
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	if _, err := os.Stat(dataDir()); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	state, err := loadSchemaState()
+	if err != nil {
+		return 0, err
+	}
+	if state.Version > latestSchema() {
+		return 0, fmt.Errorf("data directory is at schema version %d, newer than the %d this version knows; upgrade the scheduler", state.Version, latestSchema())
+	}
+	applied := 0
+	for _, m := range pendingMigrations(state.Version) {
+		log.Printf("Migrating data directory to schema version %d: %s", m.Version, m.Name)
+		if err := m.Up(); err != nil {
+			return applied, fmt.Errorf("error applying migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		state.Version = m.Version
+		state.Applied = append(state.Applied, appliedVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()})
+		if err := saveSchemaState(state); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// addDemandAgentColumn appends an empty agent_id column to demand segments
+// written before the store kept agent IDs, so every segment has the same
+// header.
+func addDemandAgentColumn() error {
+	demandMu.Lock()
+	defer demandMu.Unlock()
+
+	months, err := demandMonths()
+	if err != nil {
+		return err
+	}
+	for _, month := range months {
+		path := demandSegmentPath(month)
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening demand segment: %w", err)
+		}
+		r := csv.NewReader(f)
+		r.Comma = demandMapping.comma()
+		r.FieldsPerRecord = -1
+		rows, err := r.ReadAll()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("error reading demand segment %s: %w", month, err)
+		}
+		if len(rows) == 0 || slices.Contains(rows[0], roleAgentID) {
+			continue
+		}
+		rows[0] = append(rows[0], roleAgentID)
+		for i := 1; i < len(rows); i++ {
+			rows[i] = append(rows[i], "")
+		}
+		tmp := path + ".tmp"
+		os.Remove(tmp)
+		out, err := os.Create(tmp)
+		if err != nil {
+			return fmt.Errorf("error writing demand segment: %w", err)
+		}
+		w := csv.NewWriter(out)
+		w.Comma = demandMapping.comma()
+		if err := w.WriteAll(rows); err != nil {
+			out.Close()
+			return fmt.Errorf("error writing demand segment: %w", err)
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return fmt.Errorf("error replacing demand segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// runDB reports the schema version of the data directory with "db status"
+// and applies pending migrations with "db migrate". Every other command
+// applies them on startup.
+func runDB(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: db status | db migrate")
+	}
+	switch args[0] {
+	case "status":
+		state, err := loadSchemaState()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Data directory: %s\nSchema version: %d (latest %d)\n", dataDir(), state.Version, latestSchema())
+		fmt.Println()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tMIGRATION\tAPPLIED")
+		for _, a := range state.Applied {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", a.Version, a.Name, a.AppliedAt.Local().Format("2006-01-02 15:04"))
+		}
+		for _, m := range pendingMigrations(state.Version) {
+			fmt.Fprintf(tw, "%d\t%s\tpending\n", m.Version, m.Name)
+		}
+		return tw.Flush()
+	case "migrate":
+		n, err := migrateData()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations; schema version %d\n", n, latestSchema())
+		return nil
+	}
+	return fmt.Errorf("unknown db command %q (want status or migrate)", args[0])
+}
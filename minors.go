@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// MinorsConfig sets the limits for employees under 18, marked as minors in
+// the roster.
+type MinorsConfig struct {
+	// LatestEnd is the latest a minor's shift may end, "20:00" by default.
+	LatestEnd string `json:"latest_end,omitempty"`
+	// MaxDailyHours caps a minor's hours per day, 8 by default.
+	MaxDailyHours float64 `json:"max_daily_hours,omitempty"`
+}
+
+// minorLimits returns the latest end and daily hours of minors, with the
+// defaults filled in.
+func minorLimits(cfg Config) (schedule.Clock, float64, error) {
+	latestEnd := schedule.Clock(20 * 60)
+	if cfg.Minors.LatestEnd != "" {
+		var err error
+		if latestEnd, err = schedule.ParseClock(cfg.Minors.LatestEnd); err != nil {
+			return 0, 0, fmt.Errorf("invalid latest end for minors: %w", err)
+		}
+	}
+	hours := cfg.Minors.MaxDailyHours
+	if hours <= 0 {
+		hours = 8
+	}
+	return latestEnd, hours, nil
+}
+
+// rosterMinors returns the scheduled employees marked as minors.
+func rosterMinors(roster []Employee, employees []string) []string {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	var minors []string
+	for _, e := range roster {
+		if e.Minor && scheduled[e.Name] {
+			minors = append(minors, e.Name)
+		}
+	}
+	return minors
+}
+
+// minorsNote describes the limits for minors for the scheduling prompt.
+func minorsNote(minors []string, latestEnd schedule.Clock, hours float64) string {
+	return fmt.Sprintf("Minors (under 18): %s. By law they may only work shifts ending by %s and lasting at most %g hours, and never overnight. Give them other shifts or days off.",
+		strings.Join(minors, ", "), latestEnd, hours)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// NightShiftConfig caps how many night shifts an employee works in a
+// monthly schedule.
+type NightShiftConfig struct {
+	// Shifts names the night shifts. Defaults to the latest-starting shift
+	// of the catalog.
+	Shifts []string `json:"shifts,omitempty"`
+	// MaxPerMonth is the cap for everyone without one in the roster. Zero
+	// means no cap.
+	MaxPerMonth int `json:"max_per_month,omitempty"`
+}
+
+// nightShifts returns the names of the night shifts.
+func nightShifts(cfg Config, shifts []schedule.Shift) ([]string, error) {
+	if len(cfg.NightShifts.Shifts) == 0 {
+		order := forwardOrder(shifts)
+		return []string{order[len(order)-1].Name}, nil
+	}
+	for _, name := range cfg.NightShifts.Shifts {
+		if _, ok := findShift(shifts, name); !ok {
+			return nil, fmt.Errorf("unknown night shift %q", name)
+		}
+	}
+	return cfg.NightShifts.Shifts, nil
+}
+
+// nightShiftCaps returns the night-shift cap of each scheduled employee:
+// their own from the roster, or the configured default.
+func nightShiftCaps(cfg Config, roster []Employee, employees []string) map[string]int {
+	own := make(map[string]int)
+	for _, e := range roster {
+		if e.MaxNightShifts != nil {
+			own[e.Name] = *e.MaxNightShifts
+		}
+	}
+	caps := make(map[string]int)
+	for _, name := range employees {
+		if max, ok := own[name]; ok {
+			caps[name] = max
+		} else if cfg.NightShifts.MaxPerMonth > 0 {
+			caps[name] = cfg.NightShifts.MaxPerMonth
+		}
+	}
+	return caps
+}
+
+// nightShiftNote describes the caps for the scheduling prompt.
+func nightShiftNote(night []string, caps map[string]int) string {
+	names := make([]string, 0, len(caps))
+	for name := range caps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s at most %d", name, caps[name]))
+	}
+	return fmt.Sprintf("Night shifts: in each calendar month, assign %s shifts to %s.",
+		strings.Join(night, " or "), strings.Join(parts, ", "))
+}
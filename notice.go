@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// voluntaryCode is the reason code of changes employees ask for
+// themselves, such as claiming an open shift, which the notice period
+// doesn't protect them from.
+const voluntaryCode = "voluntary"
+
+// NoticeConfig sets the notice period of a collective agreement: the days
+// ahead, starting today, in which a published shift may only be changed
+// with a reason code. Such changes are flagged in the audit log and listed
+// by the notice command. Zero disables the notice period.
+type NoticeConfig struct {
+	Days int `json:"days,omitempty"`
+	// ReasonCodes are the codes the agreement accepts, such as "sickness"
+	// or "emergency". Any code is accepted when empty.
+	ReasonCodes []string `json:"reason_codes,omitempty"`
+}
+
+// within reports whether a day column falls inside the notice period.
+func (n NoticeConfig) within(label string, now time.Time) bool {
+	date, ok := labelDate(label, now)
+	return ok && inWindow(date, n.Days, now)
+}
+
+// validCode checks that a reason code is one the agreement accepts.
+func (n NoticeConfig) validCode(code string) error {
+	if code == "" || code == voluntaryCode || len(n.ReasonCodes) == 0 || slices.Contains(n.ReasonCodes, code) {
+		return nil
+	}
+	return fmt.Errorf("unknown reason code %q (want one of %s)", code, strings.Join(n.ReasonCodes, ", "))
+}
+
+// check returns an error when a change to a day inside the notice period
+// has no valid reason code.
+func (n NoticeConfig) check(label, code string, now time.Time) error {
+	if !n.within(label, now) {
+		return nil
+	}
+	if code == "" {
+		msg := fmt.Sprintf("%s is inside the %d-day notice period: changing it needs a reason code", label, n.Days)
+		if len(n.ReasonCodes) > 0 {
+			msg += " (one of " + strings.Join(n.ReasonCodes, ", ") + ")"
+		}
+		return errors.New(msg)
+	}
+	return n.validCode(code)
+}
+
+// keepNotice finds the published assignments inside the notice period a
+// regenerated schedule changes, from the schedule files already in dir.
+// Without a reason code it restores them and returns their number; with
+// one it keeps the changes and returns their audit entries, one per day.
+func keepNotice(weeks map[string][]FlatSchedule, dir string, notice NoticeConfig, code string, now time.Time) (int, []auditEntry, error) {
+	if notice.Days <= 0 {
+		return 0, nil, nil
+	}
+	published, err := lastPublished(dir)
+	if err != nil || published == nil {
+		return 0, nil, err
+	}
+	inside := func(date time.Time) bool { return inWindow(date, notice.Days, now) }
+	restored := 0
+	var days []weekDay
+	changes := make(map[weekDay]*auditEntry)
+	for _, c := range publishedChanges(weeks, published, inside, now) {
+		if code == "" {
+			log.Printf("Keeping %s's assignment on %s, %s inside the notice period: %q (regenerated as %q)", c.Employee, c.Week, c.Day, c.Before, c.After)
+			c.restore()
+			restored++
+			continue
+		}
+		day := weekDay{c.Week, c.Day}
+		if changes[day] == nil {
+			days = append(days, day)
+			changes[day] = &auditEntry{
+				Time: now.UTC(), Action: "regenerate", Week: c.Week, Day: c.Day,
+				Before: make(map[string]string), After: make(map[string]string),
+				Notice: true, ReasonCode: code,
+			}
+		}
+		changes[day].Before[c.Employee] = c.Before
+		changes[day].After[c.Employee] = c.After
+	}
+	entries := make([]auditEntry, 0, len(days))
+	for _, day := range days {
+		entries = append(entries, *changes[day])
+	}
+	return restored, entries, nil
+}
+
+// loadAudit reads the audit log. A missing log has no entries.
+func loadAudit() ([]auditEntry, error) {
+	f, err := os.Open(auditPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error parsing audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// runNotice lists the changes made to published shifts inside the notice
+// period, with their reason codes, for compliance with the collective
+// agreement.
+func runNotice(args []string) error {
+	fs := flag.NewFlagSet("notice", flag.ContinueOnError)
+	since := fs.String("since", "", "only list changes made on or after this date, e.g. 2025-03-01")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, *since); err != nil {
+			return fmt.Errorf("invalid -since date: %w", err)
+		}
+	}
+	entries, err := loadAudit()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGED\tACTION\tBY\tWEEK\tDAY\tCHANGE\tCODE\tREASON")
+	for _, e := range entries {
+		if !e.Notice || e.Time.Before(from) {
+			continue
+		}
+		names := make([]string, 0, len(e.Before))
+		for name := range e.Before {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var change []string
+		for _, name := range names {
+			change = append(change, fmt.Sprintf("%s %s → %s", name, e.Before[name], e.After[name]))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.By, e.Week, e.Day, strings.Join(change, ", "), e.ReasonCode, e.Reason)
+	}
+	return tw.Flush()
+}
+
+// appendAudits adds entries to the audit log in order.
+func appendAudits(entries []auditEntry) error {
+	for _, entry := range entries {
+		if err := appendAudit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepNotice(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		code     string
+		restored int
+		// audited lists the days of the audit entries, in order.
+		audited []string
+		want    string
+	}{
+		{name: "without a reason code", restored: 2, want: "Early"},
+		{name: "with a reason code", code: "sickness", audited: []string{"Monday (19th October)", "Tuesday (20th October)"}, want: "Late"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Published as Week 3 a week ago; regenerated as Week 1.
+			writePublished(t, dir, weekRows("Week 3", day(10, 19), map[string][]string{
+				"Ann": {"Early", "Early", "Early", "Early", "Early", "Off", "Off"},
+				"Bob": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"},
+			}))
+			weeks := map[string][]FlatSchedule{
+				"Week 1": weekRows("Week 1", day(10, 19), map[string][]string{
+					"Ann": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"},
+					"Bob": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"},
+				}),
+				"Week 3": weekRows("Week 3", day(11, 2), map[string][]string{
+					"Ann": {"Late", "Late", "Late", "Late", "Late", "Off", "Off"},
+				}),
+			}
+			restored, entries, err := keepNotice(weeks, dir, NoticeConfig{Days: 7}, tt.code, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restored != tt.restored {
+				t.Errorf("restored %d cells, want %d", restored, tt.restored)
+			}
+			if len(entries) != len(tt.audited) {
+				t.Fatalf("got %d audit entries, want %d", len(entries), len(tt.audited))
+			}
+			for i, e := range entries {
+				if e.Day != tt.audited[i] || e.Week != "Week 1" || e.Before["Ann"] != "Early" || e.After["Ann"] != "Late" || e.ReasonCode != tt.code {
+					t.Errorf("audit entry %d = %+v", i, e)
+				}
+			}
+			if got := cell(weeks, "Ann", day(10, 20)); got != tt.want {
+				t.Errorf("Ann on 20 October: got %q, want %q", got, tt.want)
+			}
+			if got := cell(weeks, "Ann", day(11, 2)); got != "Late" {
+				t.Errorf("Ann on 2 November: got %q, want the regenerated Late", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"employee-schedular/notify"
+)
+
+// Events team channels can be notified of.
+const (
+	eventSchedulePublished = "schedule_published"
+	eventPublishDeadline   = "publish_deadline"
+	eventOvertimeOffered   = "overtime_offered"
+)
+
+// NotificationChannel is a team channel and the events it is told about.
+// Type names a channel type registered with the notify package, such as
+// "slack", "mattermost", "discord", or "email"; Settings are its own, such
+// as {"webhook": "…"}.
+type NotificationChannel struct {
+	Name     string          `json:"name,omitempty"`
+	Type     string          `json:"type"`
+	Events   []string        `json:"events,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// wants reports whether the channel is told about an event. A channel
+// listing no events is told about all of them.
+func (c NotificationChannel) wants(event string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, event)
+}
+
+func (c NotificationChannel) label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+func init() {
+	notify.Register("email", newEmailNotifier)
+	bus.subscribe(eventSchedulePublished, notifyPublished)
+}
+
+// emailNotifier mails notifications through the SMTP server of the
+// reminders.
+type emailNotifier struct {
+	To []string `json:"to"`
+}
+
+func newEmailNotifier(settings json.RawMessage) (notify.Notifier, error) {
+	n := &emailNotifier{}
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, n); err != nil {
+			return nil, fmt.Errorf("invalid email settings: %w", err)
+		}
+	}
+	if len(n.To) == 0 {
+		return nil, fmt.Errorf("email channel needs recipients in \"to\"")
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	return sendEmail(reminderSettings(cfg).SMTP, n.To, msg.Title, msg.Text)
+}
+
+// notifyChannels tells every configured channel that wants the message's
+// event about it. Failures are logged; they never fail what triggered the
+// event.
+func notifyChannels(cfg Config, msg notify.Message) {
+	for _, c := range cfg.Notifications {
+		if !c.wants(msg.Event) {
+			continue
+		}
+		n, err := notify.New(c.Type, c.Settings)
+		if err == nil {
+			err = n.Notify(context.Background(), msg)
+		}
+		if err != nil {
+			log.Printf("Error notifying %s of %s: %v", c.label(), msg.Event, err)
+		}
+	}
+}
+
+// notifyPublished tells the channels about a newly published schedule,
+// with the days it covers when known.
+func notifyPublished(ev Event) {
+	published := ev.(SchedulePublished)
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config for run %s notifications: %v", published.RunID, err)
+		return
+	}
+	source := "run " + published.RunID
+	if published.Job != "" {
+		source = "job " + published.Job
+	}
+	text := fmt.Sprintf("Schedule published (%s)", source)
+	if published.Start != "" {
+		text = fmt.Sprintf("Schedule for %s to %s published (%s)", published.Start, published.End, source)
+	}
+	notifyChannels(cfg, notify.Message{Event: eventSchedulePublished, Title: "Schedule published", Text: text})
+}
+
+// checkNotifications reports channels whose type is unknown, whose
+// settings are invalid, or which list unknown events.
+func checkNotifications(cfg Config) error {
+	known := []string{eventSchedulePublished, eventPublishDeadline, eventOvertimeOffered}
+	for _, c := range cfg.Notifications {
+		if _, err := notify.New(c.Type, c.Settings); err != nil {
+			return fmt.Errorf("notification channel %s: %w", c.label(), err)
+		}
+		for _, event := range c.Events {
+			if !slices.Contains(known, event) {
+				return fmt.Errorf("notification channel %s: unknown event %q (want one of %s)", c.label(), event, strings.Join(known, ", "))
+			}
+		}
+	}
+	return nil
+}
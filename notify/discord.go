@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// discordLimit is the longest message content Discord accepts.
+const discordLimit = 2000
+
+// discord posts to a Discord webhook.
+type discord struct {
+	webhook string
+}
+
+func init() {
+	Register("discord", func(settings json.RawMessage) (Notifier, error) {
+		webhook, err := parseWebhook("discord", settings)
+		if err != nil {
+			return nil, err
+		}
+		return &discord{webhook: webhook}, nil
+	})
+}
+
+func (d *discord) Notify(ctx context.Context, msg Message) error {
+	content := msg.Text
+	if r := []rune(content); len(r) > discordLimit {
+		content = string(r[:discordLimit-1]) + "…"
+	}
+	if err := PostJSON(ctx, d.webhook, map[string]string{"content": content}); err != nil {
+		return fmt.Errorf("error posting to Discord: %w", err)
+	}
+	return nil
+}
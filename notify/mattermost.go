@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// mattermost posts to a Mattermost incoming webhook, optionally to another
+// channel than the webhook's own.
+type mattermost struct {
+	webhook string
+	channel string
+}
+
+func init() {
+	Register("mattermost", func(settings json.RawMessage) (Notifier, error) {
+		webhook, err := parseWebhook("mattermost", settings)
+		if err != nil {
+			return nil, err
+		}
+		var s struct {
+			Channel string `json:"channel"`
+		}
+		json.Unmarshal(settings, &s)
+		return &mattermost{webhook: webhook, channel: s.Channel}, nil
+	})
+}
+
+func (m *mattermost) Notify(ctx context.Context, msg Message) error {
+	payload := map[string]string{"text": msg.Text}
+	if m.channel != "" {
+		payload["channel"] = m.channel
+	}
+	if err := PostJSON(ctx, m.webhook, payload); err != nil {
+		return fmt.Errorf("error posting to Mattermost: %w", err)
+	}
+	return nil
+}
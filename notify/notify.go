@@ -0,0 +1,114 @@
+// Package notify delivers messages about scheduling events to team
+// channels. Channel types register themselves by name, so a new one is a
+// file with an init function.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a notification about one event, such as a published schedule.
+type Message struct {
+	// Event names the event type, e.g. "schedule_published".
+	Event string
+	// Title is a one-line summary, used as the subject where the channel
+	// has one.
+	Title string
+	Text  string
+}
+
+// Notifier delivers messages to one channel.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Factory builds a notifier from the channel's settings in the config.
+type Factory func(settings json.RawMessage) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a channel type available under name. Channel types
+// register themselves from an init function.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// New returns a notifier of the named channel type.
+func New(name string, settings json.RawMessage) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notification channel type %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(settings)
+}
+
+// Names returns the registered channel types in order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client sends the requests of the built-in channels.
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+// PostJSON posts payload to an incoming webhook URL.
+func PostJSON(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookSettings are the settings of the channels posting to an incoming
+// webhook.
+type webhookSettings struct {
+	Webhook string `json:"webhook"`
+}
+
+func parseWebhook(kind string, settings json.RawMessage) (string, error) {
+	var s webhookSettings
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, &s); err != nil {
+			return "", fmt.Errorf("invalid %s settings: %w", kind, err)
+		}
+	}
+	if s.Webhook == "" {
+		return "", fmt.Errorf("%s channel needs a webhook", kind)
+	}
+	return s.Webhook, nil
+}
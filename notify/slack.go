@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// slack posts to a Slack incoming webhook.
+type slack struct {
+	webhook string
+}
+
+func init() {
+	Register("slack", func(settings json.RawMessage) (Notifier, error) {
+		webhook, err := parseWebhook("slack", settings)
+		if err != nil {
+			return nil, err
+		}
+		return &slack{webhook: webhook}, nil
+	})
+}
+
+func (s *slack) Notify(ctx context.Context, msg Message) error {
+	if err := PostJSON(ctx, s.webhook, map[string]string{"text": msg.Text}); err != nil {
+		return fmt.Errorf("error posting to Slack: %w", err)
+	}
+	return nil
+}
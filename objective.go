@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// defaultObjective is the preset used when neither the run nor the config
+// names one.
+const defaultObjective = "balanced"
+
+// objectiveWeights weighs the three groups of soft constraints a schedule
+// trades off against each other.
+type objectiveWeights struct {
+	Service   float64
+	Cost      float64
+	Wellbeing float64
+}
+
+// objectivePresets are the named priorities a schedule can be generated
+// under.
+var objectivePresets = map[string]objectiveWeights{
+	"balanced":        {Service: 1, Cost: 1, Wellbeing: 1},
+	"service-first":   {Service: 3, Cost: 1, Wellbeing: 1},
+	"cost-first":      {Service: 1, Cost: 3, Wellbeing: 1},
+	"wellbeing-first": {Service: 1, Cost: 1, Wellbeing: 3},
+}
+
+// objectiveRules assigns the warnings of the validator to the objective
+// they count against.
+var objectiveRules = map[string]string{
+	"shift-demand":      "service",
+	"skill-bus-factor":  "service",
+	"consecutive-shift": "wellbeing",
+	"rotation":          "wellbeing",
+	"volunteer":         "wellbeing",
+}
+
+// objectiveNames returns the preset names in alphabetical order.
+func objectiveNames() []string {
+	names := make([]string, 0, len(objectivePresets))
+	for name := range objectivePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupObjective returns the weights of a named preset.
+func lookupObjective(name string) (objectiveWeights, error) {
+	w, ok := objectivePresets[strings.ToLower(name)]
+	if !ok {
+		return objectiveWeights{}, fmt.Errorf("unknown objective %q (want one of %s)", name, strings.Join(objectiveNames(), ", "))
+	}
+	return w, nil
+}
+
+// runObjective returns the preset a run is generated under: the one asked
+// for, else the config's, else balanced.
+func runObjective(requested string, cfg Config) (string, error) {
+	name := requested
+	if name == "" {
+		name = cfg.Objective
+	}
+	if name == "" {
+		name = defaultObjective
+	}
+	name = strings.ToLower(name)
+	if _, err := lookupObjective(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// objectiveNote describes the priorities for the scheduling prompt.
+func objectiveNote(name string, w objectiveWeights) string {
+	goals := []struct {
+		weight float64
+		text   string
+	}{
+		{w.Service, "service (every shift at its headcount target and with its skills covered twice)"},
+		{w.Cost, "cost (no overtime and no more on a shift than its target)"},
+		{w.Wellbeing, "wellbeing (rotating shifts, few consecutive same shifts, and volunteers' wishes)"},
+	}
+	sort.SliceStable(goals, func(i, j int) bool { return goals[i].weight > goals[j].weight })
+	if goals[0].weight == goals[2].weight {
+		return fmt.Sprintf("Priorities (%s): where the preferences above conflict, weigh %s, %s, and %s equally.", name, goals[0].text, goals[1].text, goals[2].text)
+	}
+	text := fmt.Sprintf("Priorities (%s): where the preferences above conflict, favour %s first, then ", name, goals[0].text)
+	if goals[1].weight == goals[2].weight {
+		return text + goals[1].text + " and " + goals[2].text + " equally."
+	}
+	return text + goals[1].text + ", then " + goals[2].text + "."
+}
+
+// objectivePenalties counts the soft-constraint misses of a schedule by
+// objective.
+type objectivePenalties struct {
+	// Service is the shift-demand and skill-bus-factor warnings.
+	Service int `json:"service"`
+	// Cost is the overtime shifts and the heads scheduled beyond a shift's
+	// target.
+	Cost int `json:"cost"`
+	// Wellbeing is the consecutive-shift, rotation, and volunteer
+	// warnings.
+	Wellbeing int `json:"wellbeing"`
+}
+
+// score weighs the penalties; lower is better.
+func (pen objectivePenalties) score(w objectiveWeights) float64 {
+	return w.Service*float64(pen.Service) + w.Cost*float64(pen.Cost) + w.Wellbeing*float64(pen.Wellbeing)
+}
+
+// schedulePenalties counts the penalties of a validated schedule against
+// its shift targets.
+func schedulePenalties(sch *schedule.Schedule, violations []validator.Violation, targets map[string]int) objectivePenalties {
+	var pen objectivePenalties
+	for _, v := range violations {
+		if v.Severity != validator.Warning {
+			continue
+		}
+		switch objectiveRules[v.Rule] {
+		case "service":
+			pen.Service++
+		case "wellbeing":
+			pen.Wellbeing++
+		}
+	}
+	type slot struct {
+		week       int
+		day, shift string
+	}
+	counts := make(map[slot]int)
+	for _, e := range sch.Entries {
+		for _, d := range e.Days {
+			if d.Shift == "" {
+				continue
+			}
+			if d.Overtime {
+				pen.Cost++
+			}
+			counts[slot{e.Week, d.Label, d.Shift}]++
+		}
+	}
+	if len(targets) > 0 {
+		for s, n := range counts {
+			if target, ok := targets[s.shift]; ok && n > target {
+				pen.Cost += n - target
+			}
+		}
+	}
+	return pen
+}
+
+// runCompare lists runs side by side with their penalties and their score
+// under every preset, so schedules generated under different priorities
+// can be compared.
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: compare <run-id> ...")
+	}
+	names := objectiveNames()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "RUN\tOBJECTIVE\tVIOLATIONS\tSERVICE\tCOST\tWELLBEING\t%s\tHOURS\tLABOR COST\tCOVERAGE\tSTABILITY\tCHURN\tPROMPT\tRULES\n", strings.ToUpper(strings.Join(names, "\t")))
+	for _, id := range fs.Args() {
+		run, err := loadRun(id)
+		if err != nil {
+			return err
+		}
+		if run.Penalties == nil {
+			return fmt.Errorf("run %s has no penalties; it isn't exported yet or predates objectives", id)
+		}
+		pen := *run.Penalties
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d", run.ID, run.Objective, run.Violations, pen.Service, pen.Cost, pen.Wellbeing)
+		for _, name := range names {
+			fmt.Fprintf(tw, "\t%g", pen.score(objectivePresets[name]))
+		}
+		if t := run.Tradeoff; t != nil {
+			fmt.Fprintf(tw, "\t%.1f\t%.2f\t%.1f%%", t.Hours, t.Cost, t.Coverage*100)
+		} else {
+			fmt.Fprint(tw, "\t\t\t")
+		}
+		fmt.Fprint(tw, "\t")
+		if run.Stability != nil {
+			fmt.Fprintf(tw, "%.1f%%", *run.Stability*100)
+		}
+		fmt.Fprint(tw, "\t")
+		if run.Churn != nil {
+			fmt.Fprintf(tw, "%.1f%%", *run.Churn*100)
+		}
+		fmt.Fprintf(tw, "\t%s\t%s\n", run.PromptPack, run.RulePack)
+	}
+	return tw.Flush()
+}
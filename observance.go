@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Observance is a recurring religious or cultural observance during which
+// an employee is never available. It is not leave and uses no balance.
+// Without From and To the whole day is unavailable.
+type Observance struct {
+	Weekday string `json:"weekday"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Label   string `json:"label,omitempty"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// unavailability converts an observance into the window validation checks.
+func (o Observance) unavailability() (validator.Unavailability, error) {
+	weekday, ok := weekdayNames[strings.ToLower(strings.TrimSpace(o.Weekday))]
+	if !ok {
+		return validator.Unavailability{}, fmt.Errorf("invalid weekday %q", o.Weekday)
+	}
+	u := validator.Unavailability{Weekday: weekday, From: 0, To: 24 * 60, Label: o.Label}
+	var err error
+	if o.From != "" {
+		if u.From, err = schedule.ParseClock(o.From); err != nil {
+			return u, err
+		}
+	}
+	if o.To != "" {
+		if u.To, err = schedule.ParseClock(o.To); err != nil {
+			return u, err
+		}
+	}
+	if u.To <= u.From {
+		return u, fmt.Errorf("observance on %s ends before it starts", o.Weekday)
+	}
+	return u, nil
+}
+
+// describe writes the observance for the scheduling prompt.
+func (o Observance) describe() string {
+	when := o.Weekday + "s"
+	switch {
+	case o.From != "" && o.To != "":
+		when = fmt.Sprintf("%s between %s and %s", o.Weekday, o.From, o.To)
+	case o.From != "":
+		when = fmt.Sprintf("%s from %s", o.Weekday, o.From)
+	case o.To != "":
+		when = fmt.Sprintf("%s until %s", o.Weekday, o.To)
+	}
+	if o.Label != "" {
+		when += " (" + o.Label + ")"
+	}
+	return when
+}
+
+// rosterObservances returns the observances of the scheduled employees,
+// both as recorded and as windows for validation.
+func rosterObservances(roster []Employee, employees []string) (map[string][]Observance, map[string][]validator.Unavailability, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	observances := make(map[string][]Observance)
+	windows := make(map[string][]validator.Unavailability)
+	for _, e := range roster {
+		if !scheduled[e.Name] {
+			continue
+		}
+		for _, o := range e.Observances {
+			u, err := o.unavailability()
+			if err != nil {
+				return nil, nil, fmt.Errorf("employee %s: %w", e.Name, err)
+			}
+			observances[e.Name] = append(observances[e.Name], o)
+			windows[e.Name] = append(windows[e.Name], u)
+		}
+	}
+	return observances, windows, nil
+}
+
+// observanceNote describes the observances for the scheduling prompt.
+func observanceNote(observances map[string][]Observance) string {
+	names := make([]string, 0, len(observances))
+	for name := range observances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		var when []string
+		for _, o := range observances[name] {
+			when = append(when, o.describe())
+		}
+		parts = append(parts, fmt.Sprintf("%s is never available on %s", name, strings.Join(when, " or ")))
+	}
+	return "Observances (hard unavailability, not leave): " + strings.Join(parts, "; ") +
+		". Do not assign them any shift overlapping those times."
+}
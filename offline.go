@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// offline is set by --offline for air-gapped installations: schedules are
+// written by the local solver, and no command may reach the network.
+var offline bool
+
+// networkIntegrations lists the configured integrations that reach the
+// network, by config key or environment variable.
+func networkIntegrations(cfg Config) []string {
+	var found []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			found = append(found, name)
+		}
+	}
+	add(cfg.Twilio != nil, "twilio")
+	add(cfg.AmazonConnect != nil, "amazon_connect")
+	add(cfg.TimeClock != nil, "time_clock")
+	add(cfg.HRIS != nil, "hris")
+	add(cfg.Forecast.ServiceURL != "", "forecast.service_url")
+	add(len(cfg.Reminders.Rules) > 0 || cfg.Reminders.SMTP.Addr != "", "reminders")
+	add(len(cfg.ShiftReminders.HoursBefore) > 0, "shift_reminders")
+	add(cfg.SMS.Publish || cfg.SMS.UrgentHours > 0, "sms")
+	add(cfg.Acknowledgments.SlackSigningSecret != "", "acknowledgments.slack_signing_secret")
+	add(cfg.OpenShifts.SlackWebhook != "", "open_shifts.slack_webhook")
+	add(len(cfg.Notifications) > 0, "notifications")
+	add(cfg.Approvals.SlackWebhook != "", "approvals.slack_webhook")
+	for _, h := range cfg.Hooks {
+		if h.Webhook != "" {
+			found = append(found, "hooks ("+h.Event+" webhook)")
+		}
+	}
+	add(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	return found
+}
+
+// checkOffline refuses to start in offline mode while integrations that
+// reach the network are configured, rather than failing on them later.
+func checkOffline() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if found := networkIntegrations(cfg); len(found) > 0 {
+		return fmt.Errorf("network integrations are configured: %s; remove them or run without --offline", strings.Join(found, ", "))
+	}
+	return nil
+}
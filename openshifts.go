@@ -0,0 +1,526 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Ways open shifts are given out.
+const (
+	claimFirstCome = "first_come"
+	claimSeniority = "seniority"
+)
+
+// Open shift statuses.
+const (
+	openShiftOpen    = "open"
+	openShiftFilled  = "filled"
+	openShiftExpired = "expired"
+)
+
+// OpenShiftConfig sets how open shifts are given out in server mode.
+type OpenShiftConfig struct {
+	// Mode is first_come, confirming the first valid claim, or seniority,
+	// collecting claims for ClaimHours and confirming the most senior
+	// claimant's. first_come by default.
+	Mode       string `json:"mode,omitempty"`
+	ClaimHours int    `json:"claim_hours,omitempty"`
+	// SlackWebhook is where open shifts are announced, with claim buttons.
+	SlackWebhook string `json:"slack_webhook,omitempty"`
+}
+
+func openShiftSettings(cfg Config) OpenShiftConfig {
+	oc := cfg.OpenShifts
+	if oc.Mode == "" {
+		oc.Mode = claimFirstCome
+	}
+	if oc.ClaimHours <= 0 {
+		oc.ClaimHours = 24
+	}
+	return oc
+}
+
+// OpenShift is one uncovered slot of a published schedule that employees
+// can claim.
+type OpenShift struct {
+	ID       string    `json:"id"`
+	Job      string    `json:"job"`
+	Week     string    `json:"week"`
+	Day      string    `json:"day"`
+	Date     string    `json:"date"`
+	Shift    string    `json:"shift"`
+	Mode     string    `json:"mode"`
+	Status   string    `json:"status"`
+	PostedAt time.Time `json:"posted_at"`
+	// ClosesAt is when the claims of a seniority open shift are decided.
+	ClosesAt *time.Time   `json:"closes_at,omitempty"`
+	Claims   []ShiftClaim `json:"claims,omitempty"`
+	FilledBy string       `json:"filled_by,omitempty"`
+}
+
+// ShiftClaim is an employee's claim on an open shift.
+type ShiftClaim struct {
+	Employee string    `json:"employee"`
+	At       time.Time `json:"at"`
+	Via      string    `json:"via"`
+}
+
+// openShiftsMu serializes changes to the open shifts file.
+var openShiftsMu sync.Mutex
+
+func openShiftsPath() string {
+	return filepath.Join(dataDir(), "open_shifts.json")
+}
+
+// loadOpenShifts reads the open shifts file. A missing file yields none.
+func loadOpenShifts() ([]OpenShift, error) {
+	data, err := os.ReadFile(openShiftsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading open shifts: %w", err)
+	}
+	var shifts []OpenShift
+	if err := json.Unmarshal(data, &shifts); err != nil {
+		return nil, fmt.Errorf("error parsing open shifts: %w", err)
+	}
+	return shifts, nil
+}
+
+// saveOpenShifts writes the open shifts file, replacing it atomically.
+func saveOpenShifts(shifts []OpenShift) error {
+	data, err := json.MarshalIndent(shifts, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := openShiftsPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing open shifts: %w", err)
+	}
+	return os.Rename(tmp, openShiftsPath())
+}
+
+// postOpenShifts opens a shift for every slot the published schedules leave
+// uncovered from from to to, beyond those already open. It returns the new
+// open shifts.
+func (q *jobQueue) postOpenShifts(from, to time.Time, now time.Time) ([]OpenShift, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	oc := openShiftSettings(cfg)
+	days, err := q.coverage(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	openShiftsMu.Lock()
+	defer openShiftsMu.Unlock()
+	all, err := loadOpenShifts()
+	if err != nil {
+		return nil, err
+	}
+	var posted []OpenShift
+	for _, day := range days {
+		if day.Job == "" {
+			continue
+		}
+		date, _ := time.ParseInLocation(time.DateOnly, day.Date, now.Location())
+		week, _, _ := scheduleDay(q.jobEntries(day.Job), date)
+		for _, c := range day.Shifts {
+			open, n := 0, 0
+			for _, slot := range all {
+				if slot.Date == day.Date && slot.Shift == c.Shift {
+					n++
+					if slot.Status == openShiftOpen {
+						open++
+					}
+				}
+			}
+			for i := open; i < c.Gap; i++ {
+				n++
+				slot := OpenShift{
+					ID:       fmt.Sprintf("%s-%s-%d", day.Date, c.Shift, n),
+					Job:      day.Job,
+					Week:     week,
+					Day:      day.Day,
+					Date:     day.Date,
+					Shift:    c.Shift,
+					Mode:     oc.Mode,
+					Status:   openShiftOpen,
+					PostedAt: now.UTC(),
+				}
+				if oc.Mode == claimSeniority {
+					closes := now.UTC().Add(time.Duration(oc.ClaimHours) * time.Hour)
+					slot.ClosesAt = &closes
+				}
+				all = append(all, slot)
+				posted = append(posted, slot)
+			}
+		}
+	}
+	if len(posted) == 0 {
+		return nil, nil
+	}
+	if err := saveOpenShifts(all); err != nil {
+		return nil, err
+	}
+	if oc.SlackWebhook != "" {
+		for _, slot := range posted {
+			if err := postSlack(oc.SlackWebhook, openShiftSlackMessage(slot)); err != nil {
+				log.Printf("Error announcing open shift %s: %v", slot.ID, err)
+			}
+		}
+	}
+	return posted, nil
+}
+
+// jobEntries loads the schedule of a job.
+func (q *jobQueue) jobEntries(id string) []FlatSchedule {
+	entries, _ := loadScheduleDir(filepath.Join(dataDir(), "schedules", id))
+	return entries
+}
+
+// openShiftSlackMessage builds the announcement of an open shift, with a
+// claim button.
+func openShiftSlackMessage(slot OpenShift) map[string]any {
+	text := fmt.Sprintf("Open shift: %s on %s", slot.Shift, slot.Day)
+	if slot.ClosesAt != nil {
+		text += fmt.Sprintf(", given by seniority among claims made by %s UTC", slot.ClosesAt.Format("Mon 2 Jan 15:04"))
+	}
+	return map[string]any{
+		"text": text,
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			map[string]any{"type": "actions", "elements": []any{map[string]any{
+				"type":      "button",
+				"action_id": "claim_shift",
+				"text":      map[string]string{"type": "plain_text", "text": "Claim"},
+				"value":     slot.ID,
+			}}},
+		},
+	}
+}
+
+// shiftAssignment gives a shift of a published schedule's day to an
+// employee who is off that day.
+type shiftAssignment struct {
+	Job, Week, Day, Date, Shift string
+	Employee                    string
+	// Overtime assigns the shift as overtime, which the overtime caps limit
+	// instead of the weekly cap.
+	Overtime bool
+	// Action and Reason are recorded in the audit log.
+	Action, Reason string
+}
+
+// assignShift makes a shift assignment. It fails when the employee isn't off
+// that day, or the shift would take them over the weekly cap, outside their
+// transport cutoffs, or into their leave, or would break a rule such as the
+// rest between shifts. Unless dryRun is set, the schedule is written and the
+// change audited.
+func assignShift(a shiftAssignment, dryRun bool) error {
+	dir := scheduleDir(filepath.Join(dataDir(), "schedules", a.Job))
+	entries, err := loadScheduleDir(dir)
+	if err != nil {
+		return err
+	}
+	objs := weekEntries(entries, a.Week)
+	var row FlatSchedule
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj["Employee"])
+		if obj["Employee"] == a.Employee {
+			row = obj
+		}
+	}
+	if row == nil {
+		return fmt.Errorf("%s is not on the schedule for %s", a.Employee, a.Week)
+	}
+	p, err := loadPolicy(names)
+	if err != nil {
+		return err
+	}
+	date, err := time.ParseInLocation(time.DateOnly, a.Date, time.Local)
+	if err != nil {
+		return err
+	}
+	if isWorking(p.shifts, row[a.Day]) {
+		return fmt.Errorf("%s already works %s on %s", a.Employee, row[a.Day], a.Day)
+	}
+	// Overtime goes past the weekly cap by design; the validator checks
+	// the overtime caps, leave, and working hours below.
+	if !a.Overtime && !slices.Contains(replacementCandidates(objs, a.Day, a.Shift, p, date), a.Employee) {
+		return fmt.Errorf("%s can't take %s on %s without going over the weekly cap, outside their working hours, or into leave", a.Employee, a.Shift, a.Day)
+	}
+
+	rules := p.rules(&Run{Employees: names})
+	before, err := validateWeeks(map[string][]FlatSchedule{a.Week: objs}, p.shifts, p.rulePack, rules)
+	if err != nil {
+		return err
+	}
+	was := row[a.Day]
+	cell := a.Shift
+	if a.Overtime {
+		cell = schedule.OvertimePrefix + a.Shift
+	}
+	row[a.Day] = cell
+	after, err := validateWeeks(map[string][]FlatSchedule{a.Week: objs}, p.shifts, p.rulePack, rules)
+	if err != nil {
+		return err
+	}
+	for _, v := range after {
+		if v.Severity == validator.Error && v.Employee == a.Employee && !slices.Contains(before, v) {
+			return fmt.Errorf("%s can't take %s on %s: %s", a.Employee, a.Shift, a.Day, v.Message)
+		}
+	}
+	if dryRun {
+		return nil
+	}
+
+	annotateHours(objs, p.shifts)
+	if _, err := writeWeekCSV(dir, a.Week, objs, p.cfg.CSV); err != nil {
+		return fmt.Errorf("error writing schedule for %s: %w", a.Week, err)
+	}
+	if err := refreshManifest(dir); err != nil {
+		return err
+	}
+	if was == "" {
+		was = schedule.Off
+	}
+	entry := auditEntry{
+		Time: time.Now().UTC(), Action: a.Action, By: a.Employee, Week: a.Week, Day: a.Day,
+		Before: map[string]string{a.Employee: was}, After: map[string]string{a.Employee: cell},
+		Frozen: p.cfg.Freeze.frozen(a.Day, time.Now()), Reason: a.Reason,
+	}
+	if p.cfg.Notice.within(a.Day, time.Now()) {
+		// The employee took the shift themselves.
+		entry.Notice, entry.ReasonCode = true, voluntaryCode
+	}
+	return appendAudit(entry)
+}
+
+// assignOpenShift gives an open shift to an employee.
+func assignOpenShift(slot OpenShift, employee string, dryRun bool) error {
+	return assignShift(shiftAssignment{
+		Job: slot.Job, Week: slot.Week, Day: slot.Day, Date: slot.Date, Shift: slot.Shift,
+		Employee: employee, Action: "open-shift", Reason: slot.ID,
+	}, dryRun)
+}
+
+// claimOpenShift records an employee's claim on an open shift. A first-come
+// shift is given to the employee at once; a seniority shift keeps the claim
+// until it closes. Either way the claim is validated first. It reports
+// false when there's no such open shift.
+func claimOpenShift(id, employee, via string, now time.Time) (OpenShift, bool, error) {
+	openShiftsMu.Lock()
+	defer openShiftsMu.Unlock()
+	all, err := loadOpenShifts()
+	if err != nil {
+		return OpenShift{}, true, err
+	}
+	i := slices.IndexFunc(all, func(slot OpenShift) bool { return slot.ID == id })
+	if i < 0 {
+		return OpenShift{}, false, nil
+	}
+	slot := &all[i]
+	if slot.Status != openShiftOpen {
+		return *slot, true, fmt.Errorf("open shift %s is %s", id, slot.Status)
+	}
+	if slices.ContainsFunc(slot.Claims, func(c ShiftClaim) bool { return c.Employee == employee }) {
+		return *slot, true, fmt.Errorf("%s already claimed open shift %s", employee, id)
+	}
+	if err := assignOpenShift(*slot, employee, slot.Mode == claimSeniority); err != nil {
+		return *slot, true, err
+	}
+	slot.Claims = append(slot.Claims, ShiftClaim{Employee: employee, At: now.UTC(), Via: via})
+	if slot.Mode != claimSeniority {
+		slot.Status, slot.FilledBy = openShiftFilled, employee
+		log.Printf("Open shift %s filled by %s", id, employee)
+	}
+	return *slot, true, saveOpenShifts(all)
+}
+
+// seniorityOrder sorts claims by the claimants' hire dates, earliest first,
+// with claimants without one last, then by when they claimed.
+func seniorityOrder(claims []ShiftClaim, roster []Employee) []ShiftClaim {
+	hired := make(map[string]string)
+	for _, e := range roster {
+		hired[e.Name] = e.HireDate
+	}
+	out := slices.Clone(claims)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := hired[out[i].Employee], hired[out[j].Employee]
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		if a != b {
+			return a < b
+		}
+		return out[i].At.Before(out[j].At)
+	})
+	return out
+}
+
+// closeOpenShifts gives each seniority open shift whose claims have closed
+// to its most senior claimant whose claim is still valid. Shifts without one
+// go first come. Open shifts whose day has passed expire.
+func closeOpenShifts(now time.Time) {
+	openShiftsMu.Lock()
+	defer openShiftsMu.Unlock()
+	all, err := loadOpenShifts()
+	if err != nil {
+		log.Printf("Error loading open shifts: %v", err)
+		return
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		log.Printf("Error loading roster for open shifts: %v", err)
+		return
+	}
+	today := now.Format(time.DateOnly)
+	changed := false
+	for i := range all {
+		slot := &all[i]
+		if slot.Status != openShiftOpen {
+			continue
+		}
+		if slot.Date < today {
+			slot.Status = openShiftExpired
+			changed = true
+			continue
+		}
+		if slot.Mode != claimSeniority || slot.ClosesAt == nil || now.Before(*slot.ClosesAt) {
+			continue
+		}
+		for _, c := range seniorityOrder(slot.Claims, roster) {
+			if err := assignOpenShift(*slot, c.Employee, false); err != nil {
+				log.Printf("Claim of %s on open shift %s no longer valid: %v", c.Employee, slot.ID, err)
+				continue
+			}
+			slot.Status, slot.FilledBy = openShiftFilled, c.Employee
+			log.Printf("Open shift %s filled by %s", slot.ID, c.Employee)
+			break
+		}
+		if slot.Status == openShiftOpen {
+			slot.Mode, slot.ClosesAt = claimFirstCome, nil
+		}
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if err := saveOpenShifts(all); err != nil {
+		log.Printf("Error saving open shifts: %v", err)
+	}
+}
+
+// slackEmployee returns the roster employee with a Slack member ID.
+func slackEmployee(userID string) (string, bool) {
+	roster, err := loadRoster()
+	if err != nil {
+		return "", false
+	}
+	for _, e := range roster {
+		if e.SlackUser != "" && e.SlackUser == userID {
+			return e.Name, true
+		}
+	}
+	return "", false
+}
+
+func (s *server) handleOpenShifts(w http.ResponseWriter, r *http.Request) {
+	all, err := loadOpenShifts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := r.URL.Query().Get("status")
+	out := []OpenShift{}
+	for _, slot := range all {
+		if status == "" || slot.Status == status {
+			out = append(out, slot)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handlePostOpenShifts opens shifts for the coverage gaps in a date range.
+func (s *server) handlePostOpenShifts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	from, err := time.Parse(time.DateOnly, req.From)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be a date written 2006-01-02")
+		return
+	}
+	to, err := time.Parse(time.DateOnly, req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be a date written 2006-01-02")
+		return
+	}
+	if to.Before(from) || to.Sub(from) >= maxCoverageDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("date range must run forward and be at most %d days", maxCoverageDays))
+		return
+	}
+	posted, err := s.queue.postOpenShifts(from, to, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if posted == nil {
+		posted = []OpenShift{}
+	}
+	writeJSON(w, http.StatusCreated, posted)
+}
+
+func (s *server) handleClaimOpenShift(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Employee string `json:"employee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Employee == "" {
+		writeError(w, http.StatusBadRequest, `body must be {"employee": "<name>"}`)
+		return
+	}
+	slot, found, err := claimOpenShift(r.PathValue("id"), req.Employee, ackViaAPI, time.Now())
+	if !found {
+		writeError(w, http.StatusNotFound, "open shift not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, slot)
+}
+
+// claimText describes the outcome of a Slack claim.
+func claimText(slot OpenShift, employee string, err error) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("Could not claim the open shift: %v", err)
+	case slot.Status == openShiftFilled:
+		return fmt.Sprintf("%s on %s is yours, %s.", slot.Shift, slot.Day, employee)
+	default:
+		return fmt.Sprintf("Claim of %s on %s recorded, %s. It goes by seniority at %s UTC.", slot.Shift, slot.Day, employee, slot.ClosesAt.Format("Mon 2 Jan 15:04"))
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"employee-schedular/notify"
+)
+
+// Overtime offer statuses.
+const (
+	overtimeOpen    = "open"
+	overtimeFilled  = "filled"
+	overtimeClosed  = "closed"
+	overtimeExpired = "expired"
+)
+
+// OvertimeConfig caps the overtime hours an employee takes on.
+type OvertimeConfig struct {
+	// DailyHours caps the overtime of one day, 9 by default.
+	DailyHours float64 `json:"daily_hours,omitempty"`
+	// WeeklyHours caps the overtime of one week, 12 by default.
+	WeeklyHours float64 `json:"weekly_hours,omitempty"`
+}
+
+func overtimeSettings(cfg Config) OvertimeConfig {
+	oc := cfg.Overtime
+	if oc.DailyHours <= 0 {
+		oc.DailyHours = 9
+	}
+	if oc.WeeklyHours <= 0 {
+		oc.WeeklyHours = 12
+	}
+	return oc
+}
+
+// OvertimeOffer is a shift of a published schedule offered as voluntary
+// overtime, after demand rose past what the schedule covers.
+type OvertimeOffer struct {
+	ID    string `json:"id"`
+	Job   string `json:"job"`
+	Week  string `json:"week"`
+	Day   string `json:"day"`
+	Date  string `json:"date"`
+	Shift string `json:"shift"`
+	// Slots is how many employees are wanted.
+	Slots     int       `json:"slots"`
+	Status    string    `json:"status"`
+	PostedAt  time.Time `json:"posted_at"`
+	OfferedTo []string  `json:"offered_to"`
+	// Accepted lists the employees who took the offer, in order.
+	Accepted []ShiftClaim `json:"accepted,omitempty"`
+}
+
+// overtimeMu serializes changes to the overtime offers file.
+var overtimeMu sync.Mutex
+
+func overtimePath() string {
+	return filepath.Join(dataDir(), "overtime_offers.json")
+}
+
+// loadOvertimeOffers reads the overtime offers file. A missing file yields
+// none.
+func loadOvertimeOffers() ([]OvertimeOffer, error) {
+	data, err := os.ReadFile(overtimePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading overtime offers: %w", err)
+	}
+	var offers []OvertimeOffer
+	if err := json.Unmarshal(data, &offers); err != nil {
+		return nil, fmt.Errorf("error parsing overtime offers: %w", err)
+	}
+	return offers, nil
+}
+
+// saveOvertimeOffers writes the overtime offers file, replacing it
+// atomically.
+func saveOvertimeOffers(offers []OvertimeOffer) error {
+	data, err := json.MarshalIndent(offers, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := overtimePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing overtime offers: %w", err)
+	}
+	return os.Rename(tmp, overtimePath())
+}
+
+// assignment is the overtime shift an offer gives an employee.
+func (o OvertimeOffer) assignment(employee string) shiftAssignment {
+	return shiftAssignment{
+		Job: o.Job, Week: o.Week, Day: o.Day, Date: o.Date, Shift: o.Shift,
+		Employee: employee, Overtime: true, Action: "overtime", Reason: o.ID,
+	}
+}
+
+// acceptLink returns an employee's link to accept an overtime offer, or ""
+// when links aren't configured.
+func (ac AcknowledgmentConfig) acceptLink(offerID, employee string) string {
+	if ac.Secret == "" || ac.BaseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/overtime/%s/accept/%s?token=%s", ac.BaseURL, url.PathEscape(offerID), url.PathEscape(employee), feedToken(ac.Secret, "overtime/"+offerID, employee))
+}
+
+// offerOvertime offers a shift on a date as overtime to the employees of the
+// newest published schedule covering it who are off that day and could take
+// it within the overtime caps, or to those of them named in only. Each is
+// sent the offer by Slack, email, and text message, whichever they have.
+func (q *jobQueue) offerOvertime(date time.Time, shift string, slots int, only []string, now time.Time) (OvertimeOffer, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return OvertimeOffer{}, err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return OvertimeOffer{}, err
+	}
+	if _, ok := findShift(shifts, shift); !ok {
+		return OvertimeOffer{}, fmt.Errorf("unknown shift %q", shift)
+	}
+	job, entries, ok := q.latestSchedule(date)
+	if !ok {
+		return OvertimeOffer{}, fmt.Errorf("no published schedule covers %s", date.Format(time.DateOnly))
+	}
+	week, key, _ := scheduleDay(entries, date)
+
+	overtimeMu.Lock()
+	defer overtimeMu.Unlock()
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		return OvertimeOffer{}, err
+	}
+	offer := OvertimeOffer{
+		ID:        fmt.Sprintf("ot-%s-%s-%d", date.Format(time.DateOnly), shift, len(offers)+1),
+		Job:       job.ID,
+		Week:      week,
+		Day:       key,
+		Date:      date.Format(time.DateOnly),
+		Shift:     shift,
+		Slots:     slots,
+		Status:    overtimeOpen,
+		PostedAt:  now.UTC(),
+		OfferedTo: []string{},
+	}
+	for _, obj := range weekEntries(entries, week) {
+		name := obj["Employee"]
+		if len(only) > 0 && !slices.Contains(only, name) {
+			continue
+		}
+		if err := assignShift(offer.assignment(name), true); err == nil {
+			offer.OfferedTo = append(offer.OfferedTo, name)
+		}
+	}
+	if len(offer.OfferedTo) == 0 {
+		return OvertimeOffer{}, fmt.Errorf("no one off on %s can take %s as overtime", key, shift)
+	}
+	if err := saveOvertimeOffers(append(offers, offer)); err != nil {
+		return OvertimeOffer{}, err
+	}
+	log.Printf("Offered %s on %s as overtime to %d employees", shift, key, len(offer.OfferedTo))
+	broadcastOvertime(cfg, offer)
+	return offer, nil
+}
+
+// broadcastOvertime sends an overtime offer to everyone it was offered to.
+func broadcastOvertime(cfg Config, offer OvertimeOffer) {
+	roster, err := loadRoster()
+	if err != nil {
+		log.Printf("Error loading roster for overtime offer %s: %v", offer.ID, err)
+		return
+	}
+	ac := acknowledgmentSettings(cfg)
+	rc := reminderSettings(cfg)
+	date, _ := time.Parse(time.DateOnly, offer.Date)
+	text := fmt.Sprintf("Overtime offered: %s on %s, %d wanted, first come first served", offer.Shift, date.Format("Monday 2 January"), offer.Slots)
+	notifyChannels(cfg, notify.Message{Event: eventOvertimeOffered, Title: "Overtime offered", Text: text})
+	for _, e := range roster {
+		if !slices.Contains(offer.OfferedTo, e.Name) {
+			continue
+		}
+		link := ac.acceptLink(offer.ID, e.Name)
+		withLink := text
+		if link != "" {
+			withLink += ". Accept: " + link
+		}
+		var errs []error
+		if e.SlackWebhook != "" {
+			if ac.SlackSigningSecret != "" {
+				errs = append(errs, postSlack(e.SlackWebhook, overtimeSlackMessage(offer, text)))
+			} else {
+				errs = append(errs, sendSlack(e.SlackWebhook, withLink))
+			}
+		}
+		if e.Email != "" && link != "" {
+			errs = append(errs, sendEmail(rc.SMTP, []string{e.Email}, "Overtime offered", withLink))
+		}
+		if e.Phone != "" {
+			errs = append(errs, textEmployee(cfg, e, withLink))
+		}
+		if err := errors.Join(errs...); err != nil {
+			log.Printf("Error sending overtime offer %s to %s: %v", offer.ID, e.Name, err)
+		}
+	}
+}
+
+// overtimeSlackMessage builds an overtime offer with an accept button.
+func overtimeSlackMessage(offer OvertimeOffer, text string) map[string]any {
+	return map[string]any{
+		"text": text,
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			map[string]any{"type": "actions", "elements": []any{map[string]any{
+				"type":      "button",
+				"action_id": "accept_overtime",
+				"style":     "primary",
+				"text":      map[string]string{"type": "plain_text", "text": "Accept"},
+				"value":     offer.ID,
+			}}},
+		},
+	}
+}
+
+// acceptOvertime gives an open overtime offer's shift to an employee it was
+// offered to, once the assignment checks out against the overtime caps and
+// the other rules. The offer fills once enough employees accept. It reports
+// false when there's no such offer.
+func acceptOvertime(id, employee, via string, now time.Time) (OvertimeOffer, bool, error) {
+	overtimeMu.Lock()
+	defer overtimeMu.Unlock()
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		return OvertimeOffer{}, true, err
+	}
+	i := slices.IndexFunc(offers, func(o OvertimeOffer) bool { return o.ID == id })
+	if i < 0 {
+		return OvertimeOffer{}, false, nil
+	}
+	offer := &offers[i]
+	if offer.Status == overtimeOpen && offer.Date < now.Format(time.DateOnly) {
+		offer.Status = overtimeExpired
+		if err := saveOvertimeOffers(offers); err != nil {
+			return *offer, true, err
+		}
+	}
+	if offer.Status != overtimeOpen {
+		return *offer, true, fmt.Errorf("overtime offer %s is %s", id, offer.Status)
+	}
+	if !slices.Contains(offer.OfferedTo, employee) {
+		return *offer, true, fmt.Errorf("overtime offer %s wasn't made to %s", id, employee)
+	}
+	if slices.ContainsFunc(offer.Accepted, func(c ShiftClaim) bool { return c.Employee == employee }) {
+		return *offer, true, fmt.Errorf("%s already accepted overtime offer %s", employee, id)
+	}
+	if err := assignShift(offer.assignment(employee), false); err != nil {
+		return *offer, true, err
+	}
+	offer.Accepted = append(offer.Accepted, ShiftClaim{Employee: employee, At: now.UTC(), Via: via})
+	if len(offer.Accepted) >= offer.Slots {
+		offer.Status = overtimeFilled
+	}
+	log.Printf("%s accepted overtime offer %s", employee, id)
+	return *offer, true, saveOvertimeOffers(offers)
+}
+
+// closeOvertime withdraws an open overtime offer.
+func closeOvertime(id string) (OvertimeOffer, bool, error) {
+	overtimeMu.Lock()
+	defer overtimeMu.Unlock()
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		return OvertimeOffer{}, true, err
+	}
+	i := slices.IndexFunc(offers, func(o OvertimeOffer) bool { return o.ID == id })
+	if i < 0 {
+		return OvertimeOffer{}, false, nil
+	}
+	if offers[i].Status != overtimeOpen {
+		return offers[i], true, fmt.Errorf("overtime offer %s is %s", id, offers[i].Status)
+	}
+	offers[i].Status = overtimeClosed
+	return offers[i], true, saveOvertimeOffers(offers)
+}
+
+// acceptText describes the outcome of accepting an overtime offer.
+func acceptText(offer OvertimeOffer, employee string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("Could not accept the overtime: %v", err)
+	}
+	return fmt.Sprintf("Thanks, %s. %s on %s is yours as overtime.", employee, offer.Shift, offer.Day)
+}
+
+func (s *server) handleOvertimeOffers(w http.ResponseWriter, r *http.Request) {
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := r.URL.Query().Get("status")
+	out := []OvertimeOffer{}
+	for _, o := range offers {
+		if status == "" || o.Status == status {
+			out = append(out, o)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleOfferOvertime broadcasts an overtime offer for a shift.
+func (s *server) handleOfferOvertime(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Date      string   `json:"date"`
+		Shift     string   `json:"shift"`
+		Slots     int      `json:"slots"`
+		Employees []string `json:"employees"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	date, err := time.ParseInLocation(time.DateOnly, req.Date, time.Local)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be written 2006-01-02")
+		return
+	}
+	if req.Slots <= 0 {
+		req.Slots = 1
+	}
+	offer, err := s.queue.offerOvertime(date, req.Shift, req.Slots, req.Employees, time.Now())
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, offer)
+}
+
+func (s *server) handleAcceptOvertime(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Employee string `json:"employee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Employee == "" {
+		writeError(w, http.StatusBadRequest, `body must be {"employee": "<name>"}`)
+		return
+	}
+	offer, found, err := acceptOvertime(r.PathValue("id"), req.Employee, ackViaAPI, time.Now())
+	if !found {
+		writeError(w, http.StatusNotFound, "overtime offer not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, offer)
+}
+
+// confirmToken signs the form of an overtime confirmation page. It differs
+// from the link's token, so a link alone can't accept the offer, and is
+// good once: an employee accepts an offer at most once.
+func confirmToken(secret, offerID, employee string) string {
+	return feedToken(secret, "overtime-confirm/"+offerID, employee)
+}
+
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Accept overtime</title></head>
+<body>
+<p>{{.Employee}}, {{.Shift}} on {{.Day}} is offered to you as overtime.</p>
+<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Accept</button>
+</form>
+</body></html>
+`))
+
+// overtimeLinkSecret returns the acknowledgment secret links are signed
+// with, writing an error when there is none.
+func overtimeLinkSecret(w http.ResponseWriter) (string, bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return "", false
+	}
+	secret := acknowledgmentSettings(cfg).Secret
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "acknowledgment secret not configured")
+		return "", false
+	}
+	return secret, true
+}
+
+// handleAcceptOvertimeLink shows an employee who clicked their link a page
+// confirming the overtime offer. Opening it changes nothing, so link
+// previews and mail scanners can't accept for them. Links are
+// authenticated by their token.
+func (s *server) handleAcceptOvertimeLink(w http.ResponseWriter, r *http.Request) {
+	secret, ok := overtimeLinkSecret(w)
+	if !ok {
+		return
+	}
+	id, employee := r.PathValue("id"), r.PathValue("employee")
+	if !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(feedToken(secret, "overtime/"+id, employee))) {
+		writeError(w, http.StatusUnauthorized, "invalid overtime token")
+		return
+	}
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	i := slices.IndexFunc(offers, func(o OvertimeOffer) bool { return o.ID == id })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "overtime offer not found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := confirmPage.Execute(w, map[string]string{
+		"Employee": employee, "Shift": offers[i].Shift, "Day": offers[i].Day,
+		"Token": confirmToken(secret, id, employee),
+	}); err != nil {
+		log.Printf("Error writing overtime confirmation: %v", err)
+	}
+}
+
+// handleConfirmOvertimeLink accepts an overtime offer for an employee who
+// confirmed it on the page of their link.
+func (s *server) handleConfirmOvertimeLink(w http.ResponseWriter, r *http.Request) {
+	secret, ok := overtimeLinkSecret(w)
+	if !ok {
+		return
+	}
+	id, employee := r.PathValue("id"), r.PathValue("employee")
+	if !hmac.Equal([]byte(r.PostFormValue("token")), []byte(confirmToken(secret, id, employee))) {
+		writeError(w, http.StatusUnauthorized, "invalid overtime token")
+		return
+	}
+	offer, found, err := acceptOvertime(id, employee, ackViaLink, time.Now())
+	if !found {
+		writeError(w, http.StatusNotFound, "overtime offer not found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+	}
+	fmt.Fprintln(w, acceptText(offer, employee, err))
+}
+
+func (s *server) handleCloseOvertime(w http.ResponseWriter, r *http.Request) {
+	offer, found, err := closeOvertime(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, "overtime offer not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, offer)
+}
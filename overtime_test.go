@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAcceptOvertimeLink(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	t.Setenv("ACK_LINK_SECRET", "secret")
+	// A closed offer, so a valid confirmation reaches acceptOvertime and is
+	// refused there without needing a published schedule.
+	offer := OvertimeOffer{ID: "ot-1", Day: "Monday (19th October)", Shift: "Late", Slots: 1, Status: overtimeClosed, OfferedTo: []string{"Ann"}}
+	if err := saveOvertimeOffers([]OvertimeOffer{offer}); err != nil {
+		t.Fatal(err)
+	}
+	link := feedToken("secret", "overtime/ot-1", "Ann")
+	confirm := confirmToken("secret", "ot-1", "Ann")
+	s := &server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /overtime/{id}/accept/{employee}", s.handleAcceptOvertimeLink)
+	mux.HandleFunc("POST /overtime/{id}/accept/{employee}", s.handleConfirmOvertimeLink)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+		// body is text the response must contain.
+		body string
+	}{
+		{name: "link shows the confirmation", method: http.MethodGet, path: "/overtime/ot-1/accept/Ann", token: link, want: http.StatusOK, body: confirm},
+		{name: "link with a bad token", method: http.MethodGet, path: "/overtime/ot-1/accept/Ann", token: "bad", want: http.StatusUnauthorized},
+		{name: "link of another employee", method: http.MethodGet, path: "/overtime/ot-1/accept/Bob", token: link, want: http.StatusUnauthorized},
+		{name: "unknown offer", method: http.MethodGet, path: "/overtime/ot-2/accept/Ann", token: feedToken("secret", "overtime/ot-2", "Ann"), want: http.StatusNotFound},
+		{name: "posting the link token", method: http.MethodPost, path: "/overtime/ot-1/accept/Ann", token: link, want: http.StatusUnauthorized},
+		{name: "posting the confirmation", method: http.MethodPost, path: "/overtime/ot-1/accept/Ann", token: confirm, want: http.StatusConflict, body: "is closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.method == http.MethodGet {
+				req = httptest.NewRequest(tt.method, tt.path+"?token="+tt.token, nil)
+			} else {
+				req = httptest.NewRequest(tt.method, tt.path, strings.NewReader(url.Values{"token": {tt.token}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("body %q doesn't contain %q", rec.Body, tt.body)
+			}
+		})
+	}
+	offers, err := loadOvertimeOffers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers[0].Accepted) != 0 {
+		t.Errorf("offer accepted: %+v", offers[0].Accepted)
+	}
+}
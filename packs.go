@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// PackConfig pins the versions of the built-in prompt template and rule
+// pack. Unpinned, a team follows the newest of each in the binary, so an
+// upgrade can change the schedules it gets and what validation reports.
+type PackConfig struct {
+	Prompt string `json:"prompt,omitempty"`
+	Rules  string `json:"rules,omitempty"`
+}
+
+// promptTemplate writes the scheduling prompt.
+type promptTemplate func(employeeNames []string, highVolumeDayNumbers []int, shifts []schedule.Shift, rotation RotationConfig, notes []string, lang language) string
+
+// promptTemplates are the versions of the built-in prompt, oldest first.
+// Changing the wording of the prompt means adding a version, so teams
+// pinned to an older one keep getting it.
+var promptTemplates = []struct {
+	version string
+	build   promptTemplate
+}{
+	{"1", buildPrompt},
+}
+
+func promptVersions() []string {
+	versions := make([]string, len(promptTemplates))
+	for i, t := range promptTemplates {
+		versions[i] = t.version
+	}
+	return versions
+}
+
+// lookupPrompt returns the prompt template of a version, the latest when
+// version is empty, and the version found.
+func lookupPrompt(version string) (promptTemplate, string, error) {
+	if version == "" {
+		latest := promptTemplates[len(promptTemplates)-1]
+		return latest.build, latest.version, nil
+	}
+	for _, t := range promptTemplates {
+		if t.version == version {
+			return t.build, t.version, nil
+		}
+	}
+	return nil, "", fmt.Errorf("unknown prompt template %q (this version has %s)", version, strings.Join(promptVersions(), ", "))
+}
+
+// latestExportedRuns returns the latest exported run of every output
+// directory in the history, by cleaned directory.
+func latestExportedRuns() (map[string]*Run, error) {
+	paths, err := filepath.Glob(filepath.Join(historyDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	latest := make(map[string]*Run)
+	for _, path := range paths {
+		run, err := loadRun(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if run.Stage == stageExported {
+			latest[filepath.Clean(run.outputDir())] = run
+		}
+	}
+	return latest, nil
+}
+
+// notePackChanges logs when a run uses other versions than the schedule
+// last published to its output directory, as after an upgrade of an
+// unpinned team.
+func notePackChanges(run *Run) {
+	latest, err := latestExportedRuns()
+	if err != nil {
+		return
+	}
+	prev, ok := latest[filepath.Clean(run.outputDir())]
+	if !ok {
+		return
+	}
+	if prev.PromptPack != "" && prev.PromptPack != run.PromptPack {
+		log.Printf("Prompt template %s replaces %s, used by run %s; pin \"packs\": {\"prompt\": %q} to keep it", run.PromptPack, prev.PromptPack, prev.ID, prev.PromptPack)
+	}
+	if prev.RulePack != "" && prev.RulePack != run.RulePack {
+		log.Printf("Rule pack %s replaces %s, used by run %s; pin \"packs\": {\"rules\": %q} to keep it", run.RulePack, prev.RulePack, prev.ID, prev.RulePack)
+	}
+}
+
+// runPacks lists the prompt templates and rule packs of this version,
+// marking the latest and the ones the config pins.
+func runPacks(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: packs")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tVERSION\tSTATUS")
+	list := func(kind string, versions []string, pinned string) {
+		for i, v := range versions {
+			var marks []string
+			if i == len(versions)-1 {
+				marks = append(marks, "latest")
+			}
+			if v == pinned {
+				marks = append(marks, "pinned")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", kind, v, strings.Join(marks, ", "))
+		}
+	}
+	list("prompt", promptVersions(), cfg.Packs.Prompt)
+	list("rules", validator.PackVersions(), cfg.Packs.Rules)
+	return tw.Flush()
+}
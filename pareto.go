@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// tradeoff is where a schedule sits between cost and coverage: its planned
+// hours and their labor cost, and the share of the shift targets it staffs.
+type tradeoff struct {
+	Hours    float64 `json:"hours"`
+	Cost     float64 `json:"cost"`
+	Coverage float64 `json:"coverage"`
+}
+
+// scheduleTradeoff prices a schedule and measures it against its shift
+// targets, or the minimum per shift when there are none. Heads beyond a
+// target don't add coverage.
+func scheduleTradeoff(sch *schedule.Schedule, rates laborRates, targets map[string]int) tradeoff {
+	var t tradeoff
+	type slot struct {
+		week       int
+		day, shift string
+	}
+	counts := make(map[slot]int)
+	days := make(map[slot]bool)
+	for _, e := range sch.Entries {
+		for _, d := range e.Days {
+			days[slot{week: e.Week, day: d.Label}] = true
+			if d.Shift == "" {
+				continue
+			}
+			hours := sch.Hours(d)
+			t.Hours += hours
+			t.Cost += rates.cost(e.Employee, hours, d.Overtime)
+			counts[slot{e.Week, d.Label, d.Shift}]++
+		}
+	}
+	required, staffed := 0, 0
+	for day := range days {
+		for _, sh := range sch.Shifts {
+			target, ok := targets[sh.Name]
+			if !ok {
+				target = validator.DefaultRules().MinPerShift
+			}
+			required += target
+			staffed += min(counts[slot{day.week, day.day, sh.Name}], target)
+		}
+	}
+	if required > 0 {
+		t.Coverage = float64(staffed) / float64(required)
+	}
+	return t
+}
+
+// scaleTargets returns shift targets scaled by a coverage level, keeping
+// every shift at its minimum.
+func scaleTargets(targets map[string]int, scale float64) map[string]int {
+	scaled := make(map[string]int, len(targets))
+	for name, n := range targets {
+		scaled[name] = max(int(math.Round(float64(n)*scale)), validator.DefaultRules().MinPerShift)
+	}
+	return scaled
+}
+
+// dominates reports whether a is at least as cheap and as well covered as
+// b, and better at one of them.
+func (a tradeoff) dominates(b tradeoff) bool {
+	return a.Cost <= b.Cost && a.Hours <= b.Hours && a.Coverage >= b.Coverage &&
+		(a.Cost < b.Cost || a.Hours < b.Hours || a.Coverage > b.Coverage)
+}
+
+// paretoFront returns which of the tradeoffs no other one dominates.
+func paretoFront(points []tradeoff) []bool {
+	front := make([]bool, len(points))
+	for i, p := range points {
+		front[i] = true
+		for j, q := range points {
+			if i != j && q.dominates(p) {
+				front[i] = false
+				break
+			}
+		}
+	}
+	return front
+}
+
+// runPareto generates a schedule for the same horizon at several coverage
+// levels, each asking for the shift targets scaled by its level, and lists
+// them side by side with the ones no other beats on both cost and coverage
+// marked, so management can pick the operating point.
+func runPareto(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := flag.NewFlagSet("pareto", flag.ContinueOnError)
+	levels := fs.String("levels", "0.8,0.9,1,1.1,1.2", "comma-separated coverage levels to scale the shift targets by")
+	fs.StringVar(&opts.Model, "model", opts.Model, "forecast model: "+strings.Join(forecast.Names(), ", "))
+	fs.StringVar(&opts.Forecast, "forecast", "", "forecast file written by the forecast command to schedule against")
+	fs.StringVar(&opts.Objective, "objective", "", "optimization preset (default from the config, else balanced): "+strings.Join(objectiveNames(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := forecast.New(opts.Model); err != nil {
+		return err
+	}
+	if opts.Objective != "" {
+		if _, err := lookupObjective(opts.Objective); err != nil {
+			return err
+		}
+	}
+	var scales []float64
+	for _, s := range strings.Split(*levels, ",") {
+		scale, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || scale <= 0 {
+			return fmt.Errorf("invalid coverage level %q", s)
+		}
+		scales = append(scales, scale)
+	}
+	if len(scales) < 2 {
+		return errors.New("give at least two coverage levels")
+	}
+
+	var runs []*Run
+	for _, scale := range scales {
+		run, err := newRun()
+		if err != nil {
+			return err
+		}
+		log.Printf("Starting run %s at coverage level %g", run.ID, scale)
+		opts.TargetScale = scale
+		if err := generateRun(context.Background(), run, opts, nil); err != nil {
+			log.Printf("Error generating run %s at coverage level %g: %v", run.ID, scale, err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	flushTracing(context.Background())
+	if len(runs) == 0 {
+		return errors.New("no schedule was generated")
+	}
+
+	points := make([]tradeoff, len(runs))
+	for i, run := range runs {
+		if run.Tradeoff != nil {
+			points[i] = *run.Tradeoff
+		}
+	}
+	front := paretoFront(points)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LEVEL\tRUN\tHOURS\tCOST\tCOVERAGE\tVIOLATIONS\tFRONTIER")
+	for i, run := range runs {
+		mark := ""
+		if front[i] {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%g\t%s\t%.1f\t%.2f\t%.1f%%\t%d\t%s\n", run.TargetScale, run.ID, points[i].Hours, points[i].Cost, points[i].Coverage*100, run.Violations, mark)
+	}
+	return tw.Flush()
+}
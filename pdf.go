@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+)
+
+// pdfPage is one page of a PDF document of text and lines, placed in
+// points from the bottom left corner.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// text writes s at x, y in Helvetica, or Helvetica-Bold when bold.
+func (p *pdfPage) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// line draws a thin line from x1, y1 to x2, y2.
+func (p *pdfPage) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %g %g m %g %g l S\n", x1, y1, x2, y2)
+}
+
+// pdfString escapes s for a PDF string literal in WinAnsiEncoding, which
+// covers Latin-1; other characters are written as "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writePDF writes the pages as a PDF document using the standard Helvetica
+// fonts, which every reader has, so nothing needs to be embedded.
+func writePDF(w io.Writer, pages []*pdfPage) error {
+	bw := bufio.NewWriter(w)
+	var offsets []int
+	written := 0
+	put := func(format string, args ...any) {
+		n, _ := fmt.Fprintf(bw, format, args...)
+		written += n
+	}
+	object := func(body string) {
+		offsets = append(offsets, written)
+		put("%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	put("%%PDF-1.4\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, p := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+
+	xref := written
+	put("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		put("%010d 00000 n \n", off)
+	}
+	put("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%EOF\n", len(offsets)+1, xref)
+	return bw.Flush()
+}
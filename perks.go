@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// SpecialDaysConfig turns on the perks of a day off on an employee's
+// birthday and on the anniversary of their hire date.
+type SpecialDaysConfig struct {
+	Birthday    bool `json:"birthday,omitempty"`
+	Anniversary bool `json:"anniversary,omitempty"`
+}
+
+// specialDay is a yearly date an employee gets off when coverage permits.
+type specialDay struct {
+	Month  time.Month
+	Day    int
+	Reason string
+}
+
+// on reports whether the special day falls on a date. Those born on 29
+// February get 28 February outside leap years.
+func (s specialDay) on(date time.Time) bool {
+	if s.Month == time.February && s.Day == 29 && date.AddDate(0, 0, 1).Month() == time.March && date.Day() == 28 {
+		return true
+	}
+	return date.Month() == s.Month && date.Day() == s.Day
+}
+
+// parseMonthDay reads a yearly date written as "03-05" or "1990-03-05".
+func parseMonthDay(value string) (time.Month, int, error) {
+	layout := "01-02"
+	if strings.Count(value, "-") == 2 {
+		layout = time.DateOnly
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Month(), t.Day(), nil
+}
+
+// rosterSpecialDays returns the special days of the scheduled employees
+// under the perks the config turns on.
+func rosterSpecialDays(cfg Config, roster []Employee, employees []string) (map[string][]specialDay, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	days := make(map[string][]specialDay)
+	for _, e := range roster {
+		if !scheduled[e.Name] {
+			continue
+		}
+		if cfg.SpecialDays.Birthday && e.Birthday != "" {
+			month, day, err := parseMonthDay(e.Birthday)
+			if err != nil {
+				return nil, fmt.Errorf("employee %s: invalid birthday: %w", e.Name, err)
+			}
+			days[e.Name] = append(days[e.Name], specialDay{month, day, "Birthday"})
+		}
+		if cfg.SpecialDays.Anniversary && e.HireDate != "" {
+			month, day, err := parseMonthDay(e.HireDate)
+			if err != nil {
+				return nil, fmt.Errorf("employee %s: invalid hire date: %w", e.Name, err)
+			}
+			days[e.Name] = append(days[e.Name], specialDay{month, day, "Anniversary"})
+		}
+	}
+	return days, nil
+}
+
+// specialDaysNote describes the special days for the scheduling prompt.
+func specialDaysNote(days map[string][]specialDay) string {
+	var parts []string
+	for name, list := range days {
+		for _, s := range list {
+			what := "birthday"
+			if s.Reason == "Anniversary" {
+				what = "work anniversary"
+			}
+			parts = append(parts, fmt.Sprintf("%s's %s on %d %s", name, what, s.Day, s.Month))
+		}
+	}
+	sort.Strings(parts)
+	return "Special days: where the coverage minimums and shift targets still hold, give these employees the day off: " + strings.Join(parts, ", ") + "."
+}
+
+// grantSpecialDays gives employees their special days off where their
+// shift stays at its target, or its minimum without one, and marks the day
+// with its reason, as in "Off (Birthday)". Days in the freeze window are
+// left as published. It returns the number of days granted.
+func grantSpecialDays(weeks map[string][]FlatSchedule, p *policy, targets map[string]int, now time.Time) int {
+	if len(p.specialDays) == 0 {
+		return 0
+	}
+	type slot struct{ week, day, shift string }
+	counts := make(map[slot]int)
+	for week, objs := range weeks {
+		for _, obj := range objs {
+			for _, key := range dayColumns(obj, now) {
+				if isWorking(p.shifts, obj[key]) {
+					name, _ := schedule.SplitCell(obj[key])
+					counts[slot{week, key, name}]++
+				}
+			}
+		}
+	}
+	granted := 0
+	for _, week := range sortedWeekNames(weeks) {
+		for _, obj := range weeks[week] {
+			for _, s := range p.specialDays[obj["Employee"]] {
+				for _, key := range dayColumns(obj, now) {
+					date, _ := labelDate(key, now)
+					if !s.on(date) || p.cfg.Freeze.frozen(key, now) {
+						continue
+					}
+					if !isWorking(p.shifts, obj[key]) {
+						obj[key] = schedule.OffFor(s.Reason)
+						continue
+					}
+					name, _ := schedule.SplitCell(obj[key])
+					need := max(targets[name], validator.DefaultRules().MinPerShift)
+					if counts[slot{week, key, name}] <= need {
+						log.Printf("Not granting %s their %s off on %s: the %s shift would drop below %d", obj["Employee"], strings.ToLower(s.Reason), key, name, need)
+						continue
+					}
+					counts[slot{week, key, name}]--
+					obj[key] = schedule.OffFor(s.Reason)
+					granted++
+				}
+			}
+		}
+	}
+	return granted
+}
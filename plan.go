@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// latestFolder returns the latest run folder published under dir, or ""
+// when there is none.
+func latestFolder(dir string) string {
+	if folders := runFolders(dir); len(folders) > 0 {
+		return folders[len(folders)-1]
+	}
+	return ""
+}
+
+// planRun validates the stored response of a run without publishing it,
+// writes to w how it changes the published schedule, and marks the run
+// planned against the latest run folder.
+func planRun(ctx context.Context, run *Run, w io.Writer) error {
+	v, err := validateRun(ctx, run)
+	if err != nil {
+		return err
+	}
+	run.Stage = stagePlanned
+	run.PlannedAgainst = latestFolder(run.outputDir())
+	if err := saveRun(run); err != nil {
+		return err
+	}
+
+	published, err := lastPublished(run.outputDir())
+	if err == nil && published == nil && run.Previous != "" {
+		published, err = lastPublished(run.Previous)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading published schedule: %w", err)
+	}
+	var out bytes.Buffer
+	printPlan(&out, run, v, published)
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return err
+	}
+	requestApproval(run, out.String())
+	return nil
+}
+
+// printPlan writes the assignments a planned schedule adds (+), removes
+// (-), and changes (~) on the days it shares with the published one,
+// followed by its violations.
+func printPlan(w io.Writer, run *Run, v *validatedRun, published []FlatSchedule) {
+	current := flatten(v.weeks)
+	if published == nil {
+		fmt.Fprintf(w, "Run %s: nothing is published yet; applying publishes %d weeks.\n", run.ID, len(v.weeks))
+	} else {
+		days := sharedDays(published, current)
+		before := assignments(published, v.p.shifts, days)
+		after := assignments(current, v.p.shifts, days)
+		changed, total := changedAssignments(before, after)
+		now := time.Now()
+		sort.Slice(changed, func(i, j int) bool {
+			a, b := changed[i], changed[j]
+			if a.Week != b.Week {
+				return a.Week < b.Week
+			}
+			if a.Day != b.Day {
+				da, _ := labelDate(a.Day, now)
+				db, _ := labelDate(b.Day, now)
+				return da.Before(db)
+			}
+			return a.Employee < b.Employee
+		})
+		fmt.Fprintf(w, "Run %s against %s:\n", run.ID, scheduleDir(run.outputDir()))
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, a := range changed {
+			old, hadOld := before[a]
+			cell, hasNew := after[a]
+			switch {
+			case !hadOld:
+				fmt.Fprintf(tw, "  +\t%s\t%s\t%s\t%s\n", a.Week, a.Day, a.Employee, cell)
+			case !hasNew:
+				fmt.Fprintf(tw, "  -\t%s\t%s\t%s\t%s\n", a.Week, a.Day, a.Employee, old)
+			default:
+				fmt.Fprintf(tw, "  ~\t%s\t%s\t%s\t%s -> %s\n", a.Week, a.Day, a.Employee, old, cell)
+			}
+		}
+		tw.Flush()
+		if added := len(sharedDays(current, current)) - len(days); added > 0 {
+			fmt.Fprintf(w, "%d of %d assignments change, plus %d days not published before.\n", len(changed), total, added)
+		} else {
+			fmt.Fprintf(w, "%d of %d assignments change.\n", len(changed), total)
+		}
+	}
+	if len(v.violations) == 0 {
+		fmt.Fprintln(w, "No violations.")
+	} else {
+		fmt.Fprintf(w, "%d violations:\n", len(v.violations))
+		for _, violation := range v.violations {
+			fmt.Fprintf(w, "  %s\n", violation)
+		}
+	}
+	fmt.Fprintf(w, "Publish it with \"apply %s\".\n", run.ID)
+}
+
+// runPlan generates a schedule, or takes the stored response of an earlier
+// run with -run, and prints how it would change the published schedule
+// without publishing it.
+func runPlan(args []string) error {
+	opts := defaultGenerateOptions()
+	opts.Plan = true
+	fs := generateFlags("plan", &opts)
+	runID := fs.String("run", "", "plan the stored response of this run instead of generating a new one")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	opts.Inputs = append(opts.Inputs, fs.Args()...)
+	if *runID != "" {
+		run, err := loadRun(*runID)
+		if err != nil {
+			return err
+		}
+		switch run.Stage {
+		case stageCreated:
+			return fmt.Errorf("run %s has no stored response; plan a new generation instead", run.ID)
+		case stageExported:
+			return fmt.Errorf("run %s was already published", run.ID)
+		case stageEvaluated:
+			return fmt.Errorf("run %s was an evaluation and can't be published", run.ID)
+		}
+		if err := planRun(context.Background(), run, os.Stdout); err != nil {
+			return err
+		}
+		return checkErrors(run)
+	}
+	if err := checkGenerateOptions(opts); err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	run, err := newRun()
+	if err != nil {
+		return fmt.Errorf("error starting run: %w", err)
+	}
+	log.Printf("Starting run %s", run.ID)
+	err = generateRun(context.Background(), run, opts, nil)
+	flushTracing(context.Background())
+	if err != nil && run.Stage == stageResponded {
+		return fmt.Errorf("error planning run %s (retry with \"plan -run %s\"): %w", run.ID, run.ID, err)
+	}
+	if err != nil {
+		return err
+	}
+	return checkErrors(run)
+}
+
+// runApply publishes a planned run from the command line.
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: apply <run-id>")
+	}
+	if err := applyRun(context.Background(), fs.Arg(0), ""); err != nil {
+		return err
+	}
+	log.Printf("Run %s applied", fs.Arg(0))
+	run, err := loadRun(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return checkErrors(run)
+}
+
+// reviewMu keeps a plan from being applied or rejected twice at once, as
+// when two approvers press the Slack buttons together.
+var reviewMu sync.Mutex
+
+// reviewablePlan loads a planned run, refusing one that was already
+// reviewed or was planned against a schedule that has been published over
+// since.
+func reviewablePlan(id string) (*Run, error) {
+	run, err := loadRun(id)
+	if err != nil {
+		return nil, err
+	}
+	switch run.Stage {
+	case stagePlanned:
+	case stageExported:
+		return nil, fmt.Errorf("run %s was already published to %s", run.ID, run.Folder)
+	default:
+		return nil, fmt.Errorf("run %s is %s, not planned; review it with \"plan -run %s\" first", run.ID, run.Stage, run.ID)
+	}
+	if latest := latestFolder(run.outputDir()); latest != run.PlannedAgainst {
+		return nil, fmt.Errorf("%s was published after run %s was planned; review it again with \"plan -run %s\"", latest, run.ID, run.ID)
+	}
+	return run, nil
+}
+
+// applyRun publishes a planned run and notifies the channels and hooks of
+// schedule_published. by names who approved it, if anyone but the user of
+// the command line.
+func applyRun(ctx context.Context, id, by string) error {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+	run, err := reviewablePlan(id)
+	if err != nil {
+		return err
+	}
+	run.ReviewedBy = by
+	if err := exportRun(ctx, run); err != nil {
+		return err
+	}
+	bus.publish(runPublishedEvent(run))
+	return nil
+}
+
+// rejectRun marks a planned run rejected, so it can't be applied. Stale
+// plans can be rejected too.
+func rejectRun(id, by string) error {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+	run, err := loadRun(id)
+	if err != nil {
+		return err
+	}
+	if run.Stage != stagePlanned {
+		return fmt.Errorf("run %s is %s, not planned", run.ID, run.Stage)
+	}
+	run.Stage = stageRejected
+	run.ReviewedBy = by
+	return saveRun(run)
+}
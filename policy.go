@@ -0,0 +1,232 @@
+package main
+
+import (
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// policy is what a run is generated and validated against besides its
+// forecast: the config, the shift catalog, and the rules derived from them
+// and from the roster entries of the scheduled employees.
+type policy struct {
+	cfg       Config
+	shifts    []schedule.Shift
+	rotation  RotationConfig
+	limits    map[float64]int
+	workweeks map[string]workweek
+	night     []string
+	nightCaps map[string]int
+	// observances are kept as recorded for the prompt and as windows for
+	// validation.
+	observances map[string][]Observance
+	unavailable map[string][]validator.Unavailability
+	// leave is kept as recorded for the prompt and as periods for
+	// validation.
+	leaveEntries []LeaveEntry
+	leave        map[string][]validator.Leave
+	constraints  Constraints
+	workHours    map[string]validator.WorkHours
+	volunteers   map[string][]string
+	language     language
+	// requiredSkills lists the skills per shift and skills those of each
+	// employee.
+	requiredSkills map[string][]string
+	skills         map[string][]string
+	labor          laborRates
+	specialDays    map[string][]specialDay
+	// capacity is kept as recorded for the prompt and as limits for
+	// validation.
+	capacityProfiles map[string][]CapacityProfile
+	capacity         map[string][]validator.Capacity
+	// requiredCerts lists the certifications per shift and certs those of
+	// each employee.
+	requiredCerts map[string][]string
+	certs         map[string][]validator.Certification
+	// minors are held to a latest end and a daily cap on hours.
+	minors     []string
+	minorEnd   schedule.Clock
+	minorHours float64
+	// prompt is the prompt template and rulePack the checks pinned by the
+	// config, else the latest.
+	prompt     promptTemplate
+	promptPack string
+	rulePack   validator.Pack
+}
+
+// loadPolicy reads the config and roster for a run of the given employees.
+func loadPolicy(employees []string) (*policy, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	p := &policy{cfg: cfg, rotation: rotationSettings(cfg)}
+	if p.language, err = languageFor(cfg.Language); err != nil {
+		return nil, err
+	}
+	if p.shifts, err = shiftCatalog(cfg); err != nil {
+		return nil, err
+	}
+	if p.prompt, p.promptPack, err = lookupPrompt(cfg.Packs.Prompt); err != nil {
+		return nil, err
+	}
+	if p.rulePack, err = validator.LookupPack(cfg.Packs.Rules); err != nil {
+		return nil, err
+	}
+	if err := cfg.ServiceLevel.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Shrinkage.validate(); err != nil {
+		return nil, err
+	}
+	if err := validHolidays(cfg.Holidays); err != nil {
+		return nil, err
+	}
+	if p.limits, err = shiftLengthLimits(cfg); err != nil {
+		return nil, err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return nil, err
+	}
+	if p.workweeks, err = rosterWorkweeks(roster, employees); err != nil {
+		return nil, err
+	}
+	if p.night, err = nightShifts(cfg, p.shifts); err != nil {
+		return nil, err
+	}
+	p.nightCaps = nightShiftCaps(cfg, roster, employees)
+	if p.observances, p.unavailable, err = rosterObservances(roster, employees); err != nil {
+		return nil, err
+	}
+	if p.leaveEntries, p.leave, err = scheduledLeave(employees, time.Now()); err != nil {
+		return nil, err
+	}
+	if p.capacityProfiles, p.capacity, err = rosterCapacity(roster, employees, p.shifts, time.Now()); err != nil {
+		return nil, err
+	}
+	if p.workHours, err = rosterWorkHours(roster, employees); err != nil {
+		return nil, err
+	}
+	if p.volunteers, err = rosterVolunteers(roster, employees, p.shifts); err != nil {
+		return nil, err
+	}
+	if p.requiredSkills, err = requiredSkills(cfg, p.shifts); err != nil {
+		return nil, err
+	}
+	p.skills = rosterSkills(roster, employees)
+	if p.requiredCerts, err = requiredCertifications(cfg, p.shifts); err != nil {
+		return nil, err
+	}
+	if p.certs, err = rosterCertifications(roster, employees); err != nil {
+		return nil, err
+	}
+	if p.minorEnd, p.minorHours, err = minorLimits(cfg); err != nil {
+		return nil, err
+	}
+	p.minors = rosterMinors(roster, employees)
+	p.labor = rosterLaborRates(cfg, roster)
+	if p.specialDays, err = rosterSpecialDays(cfg, roster, employees); err != nil {
+		return nil, err
+	}
+	constraints, err := loadConstraints()
+	if err != nil {
+		return nil, err
+	}
+	if err := constraints.validate(p.shifts); err != nil {
+		return nil, err
+	}
+	p.constraints = constraints.forEmployees(employees)
+	return p, nil
+}
+
+// notes returns the extra prompt lines for the shifts section.
+func (p *policy) notes(targets map[string]int) []string {
+	var notes []string
+	if len(targets) > 0 && p.cfg.ServiceLevel.enabled() {
+		notes = append(notes, serviceLevelNote(p.cfg.ServiceLevel, p.cfg.Shrinkage, p.shifts, targets))
+	} else if len(targets) > 0 {
+		notes = append(notes, shiftTargetsNote(p.shifts, targets))
+	}
+	notes = append(notes, lengthLimitNotes(p.limits)...)
+	if len(p.workweeks) > 0 {
+		notes = append(notes, workweekNote(p.workweeks))
+	}
+	if len(p.nightCaps) > 0 {
+		notes = append(notes, nightShiftNote(p.night, p.nightCaps))
+	}
+	if len(p.observances) > 0 {
+		notes = append(notes, observanceNote(p.observances))
+	}
+	if len(p.leaveEntries) > 0 {
+		notes = append(notes, leaveNote(p.leaveEntries))
+	}
+	if len(p.capacityProfiles) > 0 {
+		notes = append(notes, capacityNote(p.capacityProfiles))
+	}
+	if len(p.workHours) > 0 {
+		notes = append(notes, workHoursNote(p.workHours))
+	}
+	if len(p.volunteers) > 0 {
+		notes = append(notes, volunteerNote(p.volunteers))
+	}
+	if len(p.requiredSkills) > 0 {
+		notes = append(notes, skillNote(p.requiredSkills, p.skills))
+	}
+	if len(p.requiredCerts) > 0 {
+		notes = append(notes, certificationNote(p.requiredCerts, p.certs, time.Now()))
+	}
+	if len(p.minors) > 0 {
+		notes = append(notes, minorsNote(p.minors, p.minorEnd, p.minorHours))
+	}
+	if len(p.specialDays) > 0 {
+		notes = append(notes, specialDaysNote(p.specialDays))
+	}
+	notes = append(notes, p.constraints.notes()...)
+	return notes
+}
+
+// rules returns the validation rules of a run under the policy, with the
+// shift targets of the run's forecast.
+func (p *policy) rules(run *Run) validator.Rules {
+	rules := validator.DefaultRules()
+	rules.LengthLimits = p.limits
+	rules.Workdays = workdayCounts(p.workweeks)
+	rules.MaxConsecutiveSameShift = p.rotation.MaxConsecutive
+	rules.RotationWeeks = p.rotation.Weeks
+	rules.ForwardRotation = !p.rotation.AllowBackward
+	rules.NightShifts = p.night
+	rules.NightShiftCaps = p.nightCaps
+	rules.Unavailable = p.unavailable
+	rules.Leave = p.leave
+	rules.Capacity = p.capacity
+	rules.Pairings = p.constraints.Pairings
+	rules.Separations = p.constraints.Separations
+	rules.Safety = p.constraints.Safety
+	rules.WorkHours = p.workHours
+	rules.Volunteers = p.volunteers
+	rules.RequiredSkills = p.requiredSkills
+	rules.Skills = p.skills
+	rules.RequiredCertifications = p.requiredCerts
+	rules.Certifications = p.certs
+	rules.Today = time.Now()
+	rules.Minors, rules.MinorLatestEnd, rules.MinorMaxDailyHours = p.minors, p.minorEnd, p.minorHours
+	rules.ShiftTargets = p.shiftTargets(run.Forecast, len(run.Employees))
+	overtime := overtimeSettings(p.cfg)
+	rules.MaxDailyOvertime, rules.MaxWeeklyOvertime = overtime.DailyHours, overtime.WeeklyHours
+	return rules
+}
+
+// shiftTargets returns the per-shift headcount targets of a forecast: the
+// agents the service-level target needs when one is set, and otherwise
+// each shift's share of the employees.
+func (p *policy) shiftTargets(fc *forecast.Forecast, employees int) map[string]int {
+	if sl := p.cfg.ServiceLevel; sl.enabled() {
+		if targets := sl.shiftTargets(fc, p.shifts, p.cfg.Shrinkage); targets != nil {
+			return targets
+		}
+	}
+	return forecastShiftTargets(fc, p.shifts, employees)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// ChargeBack records hours a shared-pool employee worked for a team.
+type ChargeBack struct {
+	Team     string
+	Employee string
+	Week     string
+	Hours    float64
+}
+
+// coverageGap is a day and shift of one team's week that is short of staff.
+type coverageGap struct {
+	day     string
+	shift   string
+	missing int
+}
+
+// weekGaps returns the understaffed day/shift slots of one team's week,
+// largest shortfall first. Each shift needs its target headcount, and at
+// least the minimum coverage of the rules.
+func weekGaps(objs []FlatSchedule, shifts []schedule.Shift, targets map[string]int) []coverageGap {
+	counts := make(map[string]map[string]int)
+	for _, obj := range objs {
+		for key, value := range obj {
+			if !strings.Contains(key, "(") {
+				continue
+			}
+			if counts[key] == nil {
+				counts[key] = make(map[string]int)
+			}
+			if isWorking(shifts, value) {
+				name, _ := schedule.SplitCell(value)
+				counts[key][name]++
+			}
+		}
+	}
+
+	minimum := validator.DefaultRules().MinPerShift
+	var gaps []coverageGap
+	for day, byShift := range counts {
+		for _, shift := range shifts {
+			if missing := max(targets[shift.Name], minimum) - byShift[shift.Name]; missing > 0 {
+				gaps = append(gaps, coverageGap{day: day, shift: shift.Name, missing: missing})
+			}
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].missing != gaps[j].missing {
+			return gaps[i].missing > gaps[j].missing
+		}
+		if d1, d2 := extractDayNumber(gaps[i].day), extractDayNumber(gaps[j].day); d1 != d2 {
+			return d1 < d2
+		}
+		return gaps[i].shift < gaps[j].shift
+	})
+	return gaps
+}
+
+func totalMissing(gaps []coverageGap) int {
+	total := 0
+	for _, g := range gaps {
+		total += g.missing
+	}
+	return total
+}
+
+// bookingKeys returns the day a day column books and the week its hours
+// count towards: its calendar date and the Monday of that week, since teams
+// number their weeks from other dates. Labels without a date fall back to
+// the week label and weekday.
+func bookingKeys(week, label string, now time.Time) (day, calendarWeek string) {
+	if date, ok := labelDate(label, now); ok {
+		monday := date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+		return date.Format(time.DateOnly), monday.Format(time.DateOnly)
+	}
+	return week + " " + weekdayOf(label), week
+}
+
+// bookedDays returns the days each employee already works across every
+// team, and the hours they work in each calendar week, keyed as by
+// bookingKeys.
+func bookedDays(teams map[string][]FlatSchedule, shifts []schedule.Shift, now time.Time) (map[string]map[string]bool, map[string]map[string]float64) {
+	booked := make(map[string]map[string]bool)
+	hours := make(map[string]map[string]float64)
+	for _, objs := range teams {
+		for _, obj := range objs {
+			employee := obj["Employee"]
+			for key, value := range obj {
+				if !strings.Contains(key, "(") || !isWorking(shifts, value) {
+					continue
+				}
+				if booked[employee] == nil {
+					booked[employee] = make(map[string]bool)
+					hours[employee] = make(map[string]float64)
+				}
+				day, week := bookingKeys(obj["Week"], key, now)
+				booked[employee][day] = true
+				h, _ := cellHours(shifts, value)
+				hours[employee][week] += h
+			}
+		}
+	}
+	return booked, hours
+}
+
+// teamTargets returns the per-shift headcount targets of the run a team's
+// schedule was published from, or nil when its run is no longer kept.
+func teamTargets(dir string) (map[string]int, error) {
+	m, ok, err := readManifest(dir)
+	if err != nil || !ok || m.Run == "" {
+		return nil, err
+	}
+	if _, err := os.Stat(runPath(m.Run)); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	run, err := loadRun(m.Run)
+	if err != nil {
+		return nil, err
+	}
+	p, err := loadPolicy(run.Employees)
+	if err != nil {
+		return nil, err
+	}
+	return p.shiftTargets(run.Forecast, len(run.Employees)), nil
+}
+
+// borrowFlexibleStaff assigns each shared-pool employee, week by week, to the
+// team with the largest coverage gap against its shift targets and fills
+// that team's gaps with them without exceeding the weekly hour cap, counting
+// the hours they already work for any team. The team schedules are extended
+// in place and the hours each team owes for borrowed staff are returned.
+func borrowFlexibleStaff(teams map[string][]FlatSchedule, shifts []schedule.Shift, pool []string, targets map[string]map[string]int, now time.Time) []ChargeBack {
+	weekSet := make(map[string]struct{})
+	for _, objs := range teams {
+		for _, obj := range objs {
+			weekSet[obj["Week"]] = struct{}{}
+		}
+	}
+	weeks := make([]string, 0, len(weekSet))
+	for week := range weekSet {
+		weeks = append(weeks, week)
+	}
+	sort.Strings(weeks)
+
+	teamNames := make([]string, 0, len(teams))
+	for name := range teams {
+		teamNames = append(teamNames, name)
+	}
+	sort.Strings(teamNames)
+
+	booked, worked := bookedDays(teams, shifts, now)
+	var charges []ChargeBack
+	for _, week := range weeks {
+		for _, employee := range pool {
+			// Pick the team that is furthest below coverage this week.
+			bestTeam, bestGaps := "", []coverageGap(nil)
+			var dayKeys []string
+			for _, team := range teamNames {
+				var objs []FlatSchedule
+				for _, obj := range teams[team] {
+					if obj["Week"] == week {
+						objs = append(objs, obj)
+					}
+				}
+				gaps := weekGaps(objs, shifts, targets[team])
+				if totalMissing(gaps) > totalMissing(bestGaps) {
+					bestTeam, bestGaps = team, gaps
+					dayKeys = dayKeys[:0]
+					for _, key := range buildHeaderForWeek(objs) {
+						if strings.Contains(key, "(") {
+							dayKeys = append(dayKeys, key)
+						}
+					}
+				}
+			}
+			if bestTeam == "" {
+				break
+			}
+
+			row := FlatSchedule{"Week": week, "Employee": employee}
+			for _, key := range dayKeys {
+				row[key] = "Off"
+			}
+			if booked[employee] == nil {
+				booked[employee] = make(map[string]bool)
+				worked[employee] = make(map[string]float64)
+			}
+			hours := 0.0
+			for _, gap := range bestGaps {
+				sh, _ := findShift(shifts, gap.shift)
+				length := sh.Hours()
+				day, calendarWeek := bookingKeys(week, gap.day, now)
+				if booked[employee][day] || row[gap.day] != "Off" || worked[employee][calendarWeek]+length > maxWeeklyHours {
+					continue
+				}
+				row[gap.day] = gap.shift
+				hours += length
+				booked[employee][day] = true
+				worked[employee][calendarWeek] += length
+			}
+			if hours == 0 {
+				continue
+			}
+			teams[bestTeam] = append(teams[bestTeam], row)
+			charges = append(charges, ChargeBack{Team: bestTeam, Employee: employee, Week: week, Hours: hours})
+		}
+	}
+	return charges
+}
+
+// writeChargeBackReport writes borrowed hours per team, employee, and week,
+// followed by a total line per team.
+func writeChargeBackReport(path string, charges []ChargeBack) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating charge-back report: %w", err)
+	}
+	defer file.Close()
+
+	totals := make(map[string]float64)
+	table := [][]string{{"Team", "Employee", "Week", "Hours"}}
+	for _, c := range charges {
+		table = append(table, []string{c.Team, c.Employee, c.Week, strconv.FormatFloat(c.Hours, 'f', -1, 64)})
+		totals[c.Team] += c.Hours
+	}
+	var teams []string
+	for team := range totals {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	for _, team := range teams {
+		table = append(table, []string{team, "Total", "", strconv.FormatFloat(totals[team], 'f', -1, 64)})
+	}
+
+	writer, err := newCSVWriter(file, cfg.CSV)
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteAll(table); err != nil {
+		return fmt.Errorf("error writing charge-back report: %w", err)
+	}
+	return nil
+}
+
+// runBorrow fills coverage gaps in the given team schedules with flexible
+// employees from the roster, rewrites the schedules, and writes chargeback.csv.
+// Each argument has the form team=dir.
+func runBorrow(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: borrow <team>=<schedule dir> ...")
+	}
+
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	pool := flexibleEmployees(roster)
+	if len(pool) == 0 {
+		return fmt.Errorf("no flexible employees in %s", rosterPath())
+	}
+
+	teams, dirs, err := loadTeamSchedules(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]map[string]int, len(dirs))
+	for team, dir := range dirs {
+		if targets[team], err = teamTargets(dir); err != nil {
+			return fmt.Errorf("error reading shift targets of team %s: %w", team, err)
+		}
+	}
+	charges := borrowFlexibleStaff(teams, shifts, pool, targets, time.Now())
+	if len(charges) == 0 {
+		fmt.Println("No coverage gaps could be filled from the shared pool.")
+		return nil
+	}
+
+	for team, entries := range teams {
+		weeks := make(map[string][]FlatSchedule)
+		for _, entry := range entries {
+			weeks[entry["Week"]] = append(weeks[entry["Week"]], entry)
+		}
+		for week, objs := range weeks {
+			annotateHours(objs, shifts)
+			if _, err := writeWeekCSV(dirs[team], week, objs, cfg.CSV); err != nil {
+				return fmt.Errorf("error writing schedule for team %s: %w", team, err)
+			}
+		}
+		if err := refreshManifest(dirs[team]); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range charges {
+		fmt.Printf("%s lends %s to %s for %.0f hours\n", c.Week, c.Employee, c.Team, c.Hours)
+	}
+	return writeChargeBackReport("chargeback.csv", charges)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"employee-schedular/schedule"
+)
+
+func TestWeekGaps(t *testing.T) {
+	shifts := []schedule.Shift{{Name: "Early", Start: 6 * 60, End: 15 * 60}}
+	objs := weekRows("Week 1", day(10, 19), map[string][]string{
+		"Ann": {"Early"},
+		"Bob": {"Early"},
+	})
+	tests := []struct {
+		name    string
+		targets map[string]int
+		missing int
+	}{
+		{name: "no targets", missing: 0},
+		{name: "target above the minimum", targets: map[string]int{"Early": 4}, missing: 2},
+		{name: "target below the minimum", targets: map[string]int{"Early": 1}, missing: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := totalMissing(weekGaps(objs, shifts, tt.targets)); got != tt.missing {
+				t.Errorf("missing %d, want %d", got, tt.missing)
+			}
+		})
+	}
+}
+
+func TestBorrowFlexibleStaff(t *testing.T) {
+	shifts := []schedule.Shift{{Name: "Early", Start: 6 * 60, End: 15 * 60}}
+	off := []string{"Off", "Off", "Off", "Off", "Off", "Off", "Off"}
+	tests := []struct {
+		name string
+		// worked is the pool employee's own week with team A.
+		worked []string
+		hours  float64
+	}{
+		{name: "free all week", worked: off, hours: 45},
+		{name: "three days with another team", worked: []string{"Early", "Early", "Early", "Off", "Off", "Off", "Off"}, hours: 18},
+		{name: "at the cap already", worked: []string{"Early", "Early", "Early", "Early", "Early", "Off", "Off"}, hours: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teams := map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 19), map[string][]string{"Pat": tt.worked, "Ann": off}),
+				"B": weekRows("Week 1", day(10, 19), map[string][]string{"Bob": off}),
+			}
+			// Both teams are short every day; team B, with a target of three,
+			// is furthest behind.
+			targets := map[string]map[string]int{"B": {"Early": 3}}
+			charges := borrowFlexibleStaff(teams, shifts, []string{"Pat"}, targets, day(10, 14))
+			hours := 0.0
+			for _, c := range charges {
+				if c.Team != "B" {
+					t.Errorf("lent to team %s, want B", c.Team)
+				}
+				hours += c.Hours
+			}
+			if hours != tt.hours {
+				t.Errorf("lent for %g hours, want %g", hours, tt.hours)
+			}
+		})
+	}
+}
+
+func TestBorrowAcrossTeams(t *testing.T) {
+	early := []schedule.Shift{{Name: "Early", Start: 6 * 60, End: 15 * 60}}
+	long := []schedule.Shift{{Name: "Long", Start: 6 * 60, End: 16 * 60}, {Name: "Short", Start: 8 * 60, End: 12 * 60}}
+	off := []string{"Off", "Off", "Off", "Off", "Off", "Off", "Off"}
+	var teamB []FlatSchedule
+	teamB = append(teamB, weekRows("Week 1", day(10, 19), map[string][]string{"Bob": off})...)
+	teamB = append(teamB, weekRows("Week 2", day(10, 26), map[string][]string{"Bob": off})...)
+	tests := []struct {
+		name   string
+		shifts []schedule.Shift
+		teams  map[string][]FlatSchedule
+		// want is the hours lent to team B by week label.
+		want map[string]float64
+	}{
+		{
+			// Team A's Week 1 is team B's Week 2, which Pat already works
+			// in full.
+			name:   "weeks numbered from other dates",
+			shifts: early,
+			teams: map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 26), map[string][]string{"Pat": {"Early", "Early", "Early", "Early", "Early", "Off", "Off"}, "Ann": off}),
+				"B": teamB,
+			},
+			want: map[string]float64{"Week 1": 45},
+		},
+		{
+			// 40 hours with team A leave room for a short shift but no long
+			// one, and long gaps come first.
+			name:   "shorter gap after one over the cap",
+			shifts: long,
+			teams: map[string][]FlatSchedule{
+				"A": weekRows("Week 1", day(10, 19), map[string][]string{"Pat": {"Long", "Long", "Long", "Long", "Off", "Off", "Off"}, "Ann": off}),
+				"B": weekRows("Week 1", day(10, 19), map[string][]string{"Bob": off}),
+			},
+			want: map[string]float64{"Week 1": 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]float64)
+			for _, c := range borrowFlexibleStaff(tt.teams, tt.shifts, []string{"Pat"}, nil, day(10, 14)) {
+				if c.Team != "B" {
+					t.Errorf("lent to team %s, want B", c.Team)
+				}
+				got[c.Week] += c.Hours
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("lent %v, want %v", got, tt.want)
+			}
+			if conflicts := detectConflicts(tt.teams, tt.shifts, maxWeeklyHours, day(10, 14)); len(conflicts) > 0 {
+				t.Errorf("borrowing left conflicts: %v", conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mappingPresets are the column mappings of the default call-record
+// exports of common ACD and telephony systems.
+var mappingPresets = map[string]ColumnMapping{
+	// Genesys Cloud conversation detail export.
+	"genesys": {
+		Delimiter:      ",",
+		TimeLayout:     "2006-01-02T15:04:05.000Z",
+		DurationFormat: durationMilliseconds,
+		Columns: map[string]string{
+			roleCallID:         "Conversation ID",
+			roleCalledTime:     "Conversation Start",
+			roleHangupTime:     "Conversation End",
+			roleWaitDuration:   "Total Queue",
+			roleTalkedDuration: "Total Talk",
+		},
+	},
+	// Five9 call log report.
+	"five9": {
+		Delimiter:      ",",
+		TimeLayout:     "Mon, 02 Jan 2006 15:04:05",
+		DurationFormat: durationClock,
+		Columns: map[string]string{
+			roleCallID:         "CALL ID",
+			roleCalledTime:     "TIMESTAMP",
+			roleWaitDuration:   "QUEUE WAIT TIME",
+			roleTalkedDuration: "TALK TIME",
+		},
+	},
+	// 3CX call log export.
+	"3cx": {
+		Delimiter:      ",",
+		TimeLayout:     "01/02/2006 15:04:05",
+		DurationFormat: durationClock,
+		Columns: map[string]string{
+			roleCallID:         "Call ID",
+			roleCalledTime:     "Call Time",
+			roleWaitDuration:   "Ringing",
+			roleTalkedDuration: "Talking",
+		},
+	},
+	// Asterisk CDR as exported from the cdr database table.
+	"asterisk": {
+		Delimiter:  ",",
+		TimeLayout: "2006-01-02 15:04:05",
+		Columns: map[string]string{
+			roleCallID:         "uniqueid",
+			roleCalledTime:     "calldate",
+			roleTalkedDuration: "billsec",
+		},
+	},
+	// Twilio call log export, as used by Twilio Flex.
+	"twilio-flex": {
+		Delimiter:  ",",
+		TimeLayout: "2006-01-02 15:04:05 MST",
+		Columns: map[string]string{
+			roleCallID:         "Call Sid",
+			roleCalledTime:     "Start Time",
+			roleHangupTime:     "End Time",
+			roleTalkedDuration: "Duration",
+		},
+	},
+}
+
+// presetNames returns the preset names in order.
+func presetNames() []string {
+	names := make([]string, 0, len(mappingPresets))
+	for name := range mappingPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupPreset returns the mapping of a named preset.
+func lookupPreset(name string) (ColumnMapping, error) {
+	m, ok := mappingPresets[strings.ToLower(name)]
+	if !ok {
+		return ColumnMapping{}, fmt.Errorf("unknown preset %q (want one of %s)", name, strings.Join(presetNames(), ", "))
+	}
+	return m, nil
+}
+
+// inputMapping returns the mapping inputs are read with: the preset when
+// one is named, else the mapping saved in the config file.
+func inputMapping(preset string) (ColumnMapping, error) {
+	if preset != "" {
+		return lookupPreset(preset)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return ColumnMapping{}, err
+	}
+	return cfg.Mapping, nil
+}
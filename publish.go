@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// manifestName is the file listing what a run folder holds.
+const manifestName = "manifest.json"
+
+// runFolderLayout names run folders by their UTC publish time, so they sort
+// in publish order.
+const runFolderLayout = "20060102T150405Z"
+
+// manifestFile is one file of a published run folder.
+type manifestFile struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// manifest lists the files of a published run folder.
+type manifest struct {
+	Run         string         `json:"run,omitempty"`
+	PublishedAt time.Time      `json:"published_at"`
+	UpdatedAt   *time.Time     `json:"updated_at,omitempty"`
+	Files       []manifestFile `json:"files"`
+}
+
+// writeManifest lists the files in dir with their sizes and checksums.
+func writeManifest(dir string, m manifest) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	m.Files = nil
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == manifestName {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("error opening %s: %w", e.Name(), err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", e.Name(), err)
+		}
+		m.Files = append(m.Files, manifestFile{Name: e.Name(), Bytes: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestName))
+}
+
+// readManifest reads the manifest of a run folder. It reports false for
+// directories without one.
+func readManifest(dir string) (manifest, bool, error) {
+	var m manifest
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, false, nil
+	}
+	if err != nil {
+		return m, false, fmt.Errorf("error reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, false, fmt.Errorf("error parsing manifest: %w", err)
+	}
+	return m, true, nil
+}
+
+// refreshManifest lists the files of a run folder again after one of them
+// was edited in place, such as by a swap. Folders without a manifest are
+// left alone.
+func refreshManifest(dir string) error {
+	m, ok, err := readManifest(dir)
+	if err != nil || !ok {
+		return err
+	}
+	now := time.Now().UTC()
+	m.UpdatedAt = &now
+	return writeManifest(dir, m)
+}
+
+// publishRunFolder writes a run's files into a temporary directory under
+// parent and, once they are all written, moves it into place as a new
+// timestamped run folder, so readers never see a half-written run and
+// earlier runs are never overwritten. It returns the folder and the names of
+// the files written.
+func publishRunFolder(parent, runID string, now time.Time, write func(dir string) ([]string, error)) (string, []string, error) {
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".partial-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return "", nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	files, err := write(tmp)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := writeManifest(tmp, manifest{Run: runID, PublishedAt: now.UTC()}); err != nil {
+		return "", nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = filepath.Base(f)
+	}
+
+	base := now.UTC().Format(runFolderLayout)
+	for i := 1; ; i++ {
+		folder := filepath.Join(parent, base)
+		if i > 1 {
+			folder += "-" + strconv.Itoa(i)
+		}
+		if _, err := os.Lstat(folder); err == nil {
+			continue
+		}
+		// Run folders are never empty, so the rename fails rather than
+		// replacing one published by a run in the same second.
+		err := os.Rename(tmp, folder)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("error publishing run folder: %w", err)
+		}
+		return folder, names, nil
+	}
+}
+
+// runFolders returns the published run folders under dir, oldest first.
+func runFolders(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var folders []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), manifestName)); err == nil {
+			folders = append(folders, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// publishedTime returns when the schedule in dir was last published or
+// updated, from its manifest, or else the newest modification time of its
+// CSV files.
+func publishedTime(dir string) time.Time {
+	if m, ok, err := readManifest(dir); err == nil && ok {
+		if m.UpdatedAt != nil {
+			return *m.UpdatedAt
+		}
+		return m.PublishedAt
+	}
+	var latest time.Time
+	paths, _ := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv"))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// scheduleDir resolves a schedule directory given on the command line: a
+// directory of schedule CSV files is used as is, and an output directory of
+// run folders resolves to its latest run.
+func scheduleDir(dir string) string {
+	if paths, _ := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv")); len(paths) > 0 {
+		return dir
+	}
+	if folders := runFolders(dir); len(folders) > 0 {
+		return folders[len(folders)-1]
+	}
+	return dir
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIClient is one entry of the server's client file. Each client
+// authenticates with its token and gets its own limits.
+type APIClient struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// RatePerMinute limits requests of any kind; zero means unlimited.
+	RatePerMinute float64 `json:"rate_per_minute,omitempty"`
+	// MonthlyQuota limits generation jobs per calendar month; zero means
+	// unlimited.
+	MonthlyQuota int `json:"monthly_quota,omitempty"`
+}
+
+func clientsPath() string {
+	return filepath.Join(dataDir(), "clients.json")
+}
+
+func quotaPath() string {
+	return filepath.Join(dataDir(), "quota.json")
+}
+
+// loadClients reads the client file. Without one the server is open and
+// unlimited, as before clients were introduced.
+func loadClients() ([]APIClient, error) {
+	data, err := os.ReadFile(clientsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading clients: %w", err)
+	}
+	var clients []APIClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("error parsing clients: %w", err)
+	}
+	for _, c := range clients {
+		if c.Name == "" || c.Token == "" {
+			return nil, errors.New("every client needs a name and a token")
+		}
+	}
+	return clients, nil
+}
+
+// tokenBucket allows bursts up to one minute's worth of requests.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientLimiter enforces per-client rate limits and monthly generation
+// quotas. Quota usage is persisted so restarts don't reset it.
+type clientLimiter struct {
+	mu      sync.Mutex
+	clients []APIClient
+	buckets map[string]*tokenBucket
+	// usage maps client name to month ("2006-01") to generations used.
+	usage map[string]map[string]int
+	now   func() time.Time
+}
+
+func newClientLimiter(clients []APIClient) (*clientLimiter, error) {
+	l := &clientLimiter{
+		clients: clients,
+		buckets: make(map[string]*tokenBucket),
+		usage:   make(map[string]map[string]int),
+		now:     time.Now,
+	}
+	data, err := os.ReadFile(quotaPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading quota usage: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &l.usage); err != nil {
+			return nil, fmt.Errorf("error parsing quota usage: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// authenticate returns the client owning the bearer token of r.
+func (l *clientLimiter) authenticate(r *http.Request) (*APIClient, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	for i := range l.clients {
+		if subtle.ConstantTimeCompare([]byte(l.clients[i].Token), []byte(token)) == 1 {
+			return &l.clients[i], true
+		}
+	}
+	return nil, false
+}
+
+// allow takes one request from the client's bucket. When the bucket is
+// empty it returns how long to wait for the next token.
+func (l *clientLimiter) allow(c *APIClient) (bool, time.Duration) {
+	if c.RatePerMinute <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[c.Name]
+	if !ok {
+		b = &tokenBucket{tokens: c.RatePerMinute, last: now}
+		l.buckets[c.Name] = b
+	}
+	perSecond := c.RatePerMinute / 60
+	b.tokens = math.Min(c.RatePerMinute, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// useQuota counts n generations against the client's monthly quota,
+// reporting false if they don't fit in what is left of it. It returns the
+// month they were counted in, for refundQuota.
+func (l *clientLimiter) useQuota(c *APIClient, n int) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	month := l.now().UTC().Format("2006-01")
+	if c.MonthlyQuota > 0 && l.usage[c.Name][month]+n > c.MonthlyQuota {
+		return month, false, nil
+	}
+	if l.usage[c.Name] == nil {
+		l.usage[c.Name] = make(map[string]int)
+	}
+	l.usage[c.Name][month] += n
+	return month, true, l.saveUsage()
+}
+
+// refundQuota gives back n generations counted by useQuota in month for a
+// job that couldn't be queued, even if the month has turned since.
+func (l *clientLimiter) refundQuota(c *APIClient, n int, month string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.usage[c.Name][month] <= 0 {
+		return nil
+	}
+	l.usage[c.Name][month] = max(l.usage[c.Name][month]-n, 0)
+	return l.saveUsage()
+}
+
+// saveUsage persists the quota usage. The caller must hold l.mu.
+func (l *clientLimiter) saveUsage() error {
+	data, err := json.MarshalIndent(l.usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(quotaPath(), data, 0o644); err != nil {
+		return fmt.Errorf("error writing quota usage: %w", err)
+	}
+	return nil
+}
+
+type clientContextKey struct{}
+
+// clientFromContext returns the authenticated client of a request, if any.
+func clientFromContext(ctx context.Context) *APIClient {
+	c, _ := ctx.Value(clientContextKey{}).(*APIClient)
+	return c
+}
+
+// middleware authenticates every request and applies the client's rate
+// limit. It is a no-op when no clients are configured.
+func (l *clientLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(l.clients) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c, ok := l.authenticate(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		if ok, wait := l.allow(c); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientContextKey{}, c)))
+	})
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+)
+
+// declaredState is the state kept in a git-tracked directory: roster.json,
+// constraints.json, and shifts.json, the shift catalog. A file left out of
+// the directory isn't managed by it, and the others are kept as written.
+type declaredState struct {
+	roster          []Employee
+	rosterData      []byte
+	constraints     Constraints
+	constraintsData []byte
+	shifts          []schedule.Shift
+	hasShifts       bool
+}
+
+// loadDeclaredState reads and checks the declared state in dir.
+func loadDeclaredState(dir string) (*declaredState, error) {
+	var d declaredState
+	read := func(name string, v any) ([]byte, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading declared %s: %w", name, err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("error parsing declared %s: %w", name, err)
+		}
+		return data, nil
+	}
+	var err error
+	if d.rosterData, err = read("roster.json", &d.roster); err != nil {
+		return nil, err
+	}
+	if d.constraintsData, err = read("constraints.json", &d.constraints); err != nil {
+		return nil, err
+	}
+	shifts, err := read("shifts.json", &d.shifts)
+	if err != nil {
+		return nil, err
+	}
+	d.hasShifts = shifts != nil
+	if d.rosterData == nil && d.constraintsData == nil && !d.hasShifts {
+		return nil, fmt.Errorf("%s declares none of roster.json, constraints.json, and shifts.json", dir)
+	}
+	catalog, err := shiftCatalog(Config{Shifts: d.shifts})
+	if err != nil {
+		return nil, fmt.Errorf("declared shifts: %w", err)
+	}
+	if d.constraintsData != nil {
+		if !d.hasShifts {
+			if catalog, err = loadShiftCatalog(); err != nil {
+				return nil, err
+			}
+		}
+		if err := d.constraints.validate(catalog); err != nil {
+			return nil, fmt.Errorf("declared constraints: %w", err)
+		}
+	}
+	return &d, nil
+}
+
+// drift is the difference between the declared and the stored state.
+type drift struct {
+	// Roster lists the employees added (+), removed (-), or changed (~).
+	Roster      []string
+	Constraints bool
+	// Shifts lists the shifts added, removed, or changed the same way.
+	Shifts []string
+	// affected are the employees whose roster entry was removed or
+	// changed.
+	affected map[string]bool
+	removed  map[string]bool
+}
+
+func (d drift) empty() bool {
+	return len(d.Roster) == 0 && !d.Constraints && len(d.Shifts) == 0
+}
+
+// diffNamed lists the entries added, removed, or changed between two lists
+// keyed by name, sorted by name.
+func diffNamed[T any](stored, declared []T, name func(T) string) (lines []string, removed, changed []string) {
+	old := make(map[string]T, len(stored))
+	for _, v := range stored {
+		old[name(v)] = v
+	}
+	seen := make(map[string]bool, len(declared))
+	for _, v := range declared {
+		n := name(v)
+		seen[n] = true
+		prev, ok := old[n]
+		switch {
+		case !ok:
+			lines = append(lines, "+ "+n)
+		case !reflect.DeepEqual(prev, v):
+			lines = append(lines, "~ "+n)
+			changed = append(changed, n)
+		}
+	}
+	for n := range old {
+		if !seen[n] {
+			lines = append(lines, "- "+n)
+			removed = append(removed, n)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	return lines, removed, changed
+}
+
+// detectDrift compares the declared state with the data directory.
+func detectDrift(d *declaredState) (drift, error) {
+	dr := drift{affected: make(map[string]bool), removed: make(map[string]bool)}
+	if d.rosterData != nil {
+		roster, err := loadRoster()
+		if err != nil {
+			return dr, err
+		}
+		lines, removed, changed := diffNamed(roster, d.roster, func(e Employee) string { return e.Name })
+		dr.Roster = lines
+		for _, n := range removed {
+			dr.removed[n], dr.affected[n] = true, true
+		}
+		for _, n := range changed {
+			dr.affected[n] = true
+		}
+	}
+	if d.constraintsData != nil {
+		stored, err := loadConstraints()
+		if err != nil {
+			return dr, err
+		}
+		dr.Constraints = normalized(stored) != normalized(d.constraints)
+	}
+	if d.hasShifts {
+		cfg, err := loadConfig()
+		if err != nil {
+			return dr, err
+		}
+		dr.Shifts, _, _ = diffNamed(cfg.Shifts, d.shifts, func(s schedule.Shift) string { return s.Name })
+		if len(dr.Shifts) == 0 && !slices.Equal(cfg.Shifts, d.shifts) {
+			dr.Shifts = []string{"~ order"}
+		}
+	}
+	return dr, nil
+}
+
+// normalized returns constraints as they round-trip through JSON, so empty
+// and missing lists compare equal.
+func normalized(c Constraints) string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// printDrift writes the drift found, or that there is none.
+func printDrift(w io.Writer, dr drift) {
+	if dr.empty() {
+		fmt.Fprintln(w, "No drift: the stored state matches the declared state.")
+		return
+	}
+	for _, line := range dr.Roster {
+		fmt.Fprintf(w, "roster       %s\n", line)
+	}
+	if dr.Constraints {
+		fmt.Fprintln(w, "constraints  ~ changed")
+	}
+	for _, line := range dr.Shifts {
+		fmt.Fprintf(w, "shifts       %s\n", line)
+	}
+}
+
+// applyDeclaredState writes the drifted parts of the declared state to the
+// data directory.
+func applyDeclaredState(d *declaredState, dr drift) error {
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	if len(dr.Roster) > 0 {
+		if err := writeDataFile(rosterPath(), d.rosterData, 0o644); err != nil {
+			return fmt.Errorf("error writing roster: %w", err)
+		}
+	}
+	if dr.Constraints {
+		if err := os.WriteFile(constraintsPath(), d.constraintsData, 0o644); err != nil {
+			return fmt.Errorf("error writing constraints: %w", err)
+		}
+	}
+	if len(dr.Shifts) > 0 {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		cfg.Shifts = d.shifts
+		if err := saveConfig(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// impactedRuns returns the latest published run of every output directory
+// whose horizon hasn't ended by now and which the drift touches: all of
+// them when the constraints or shifts changed, else those scheduling an
+// employee whose roster entry was changed or removed.
+func impactedRuns(dr drift, now time.Time) ([]*Run, error) {
+	latest, err := latestExportedRuns()
+	if err != nil {
+		return nil, err
+	}
+	today := now.Format(forecast.DateLayout)
+	var runs []*Run
+	for _, run := range latest {
+		if run.Forecast == nil || len(run.Forecast.Days) == 0 || run.Forecast.Days[len(run.Forecast.Days)-1].Date < today {
+			continue
+		}
+		if dr.Constraints || len(dr.Shifts) > 0 || slices.ContainsFunc(run.Employees, func(e string) bool { return dr.affected[e] }) {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs, nil
+}
+
+// regenerate generates the horizon of a published run again, against its
+// forecast and without the employees removed from the roster. Published
+// shifts inside the freeze window and notice period are kept as always.
+func regenerate(prev *Run, dr drift, opts generateOptions) (*Run, error) {
+	fc, err := tempForecast(prev.Forecast)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fc)
+	opts.Forecast = fc
+	opts.Employees = slices.DeleteFunc(slices.Clone(prev.Employees), func(e string) bool { return dr.removed[e] })
+	opts.Objective = prev.Objective
+	opts.TargetScale = prev.TargetScale
+	opts.OutputDir = prev.outputDir()
+
+	run, err := newRun()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Regenerating the horizon of run %s as run %s", prev.ID, run.ID)
+	return run, generateRun(context.Background(), run, opts, nil)
+}
+
+// tempForecast saves a forecast to a temporary file for a generation to
+// read, returning its path.
+func tempForecast(fc *forecast.Forecast) (string, error) {
+	f, err := os.CreateTemp("", "forecast-*.json")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	if err := fc.Save(f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// runReconcile compares the declared state in a directory with the data
+// directory and, with -apply, brings the data directory in line and
+// regenerates the published schedules the changes touch.
+func runReconcile(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	apply := fs.Bool("apply", false, "write the declared state and regenerate the impacted schedules")
+	check := fs.Bool("check", false, "fail when the stored state has drifted, without changing it")
+	noRegenerate := fs.Bool("no-regenerate", false, "with -apply, only write the declared state")
+	fs.BoolVar(&opts.Plan, "plan", false, "plan the regenerated schedules instead of publishing them")
+	fs.StringVar(&opts.ReasonCode, "code", "", "reason code allowing regenerated changes inside the notice period")
+	fs.StringVar(&opts.Solver, "solver", "", "what writes the regenerated schedules: "+strings.Join(solverNames, ", "))
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	if fs.NArg() != 1 || (*apply && *check) {
+		return withExitCode(exitInput, errors.New("usage: reconcile [-check | -apply [-no-regenerate] [-plan] [-code reason] [-solver name]] <declared dir>"))
+	}
+	if err := checkGenerateOptions(opts); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	declared, err := loadDeclaredState(fs.Arg(0))
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+	dr, err := detectDrift(declared)
+	if err != nil {
+		return err
+	}
+	printDrift(os.Stdout, dr)
+	switch {
+	case dr.empty() || (!*apply && !*check):
+		return nil
+	case *check:
+		return errors.New("the stored state has drifted from the declared state")
+	}
+
+	runs, err := impactedRuns(dr, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := applyDeclaredState(declared, dr); err != nil {
+		return err
+	}
+	log.Printf("Applied the declared state from %s", fs.Arg(0))
+	for _, line := range dr.Roster {
+		if line[0] == '+' {
+			log.Printf("Employees added to the roster join schedules at their next generation")
+			break
+		}
+	}
+	if *noRegenerate {
+		return nil
+	}
+	if len(runs) == 0 {
+		log.Printf("No published schedule still running is affected")
+		return nil
+	}
+	var failed []error
+	for _, prev := range runs {
+		run, err := regenerate(prev, dr, opts)
+		flushTracing(context.Background())
+		if err == nil {
+			err = checkErrors(run)
+		}
+		if err != nil {
+			log.Printf("Error regenerating %s: %v", prev.outputDir(), err)
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d impacted schedules failed to regenerate: %w", len(failed), len(runs), errors.Join(failed...))
+	}
+	return nil
+}
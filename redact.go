@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// pseudonymMu serializes changes to the pseudonym key.
+var pseudonymMu sync.Mutex
+
+// pseudonymKey maps employee names to the pseudonyms that replace them in
+// redacted exports. It is kept apart from the exports, readable only by
+// its owner, so that only admins can tell who is who.
+type pseudonymKey struct {
+	Names map[string]string `json:"names"`
+}
+
+func pseudonymPath() string {
+	return filepath.Join(dataDir(), "pseudonyms.json")
+}
+
+// loadPseudonymKey reads the pseudonym key. A missing file yields an empty
+// key.
+func loadPseudonymKey() (*pseudonymKey, error) {
+	key := &pseudonymKey{Names: make(map[string]string)}
+	data, err := readDataFile(pseudonymPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return key, nil
+		}
+		return nil, fmt.Errorf("error reading pseudonym key: %w", err)
+	}
+	if info, err := os.Stat(pseudonymPath()); err == nil && info.Mode().Perm()&0o077 != 0 {
+		log.Printf("Warning: %s can be read by others; restrict it with chmod 600", pseudonymPath())
+	}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("error parsing pseudonym key: %w", err)
+	}
+	if key.Names == nil {
+		key.Names = make(map[string]string)
+	}
+	return key, nil
+}
+
+// save writes the pseudonym key, readable only by its owner, replacing it
+// atomically.
+func (k *pseudonymKey) save() error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	if err := writeDataFile(pseudonymPath(), data, 0o600); err != nil {
+		return fmt.Errorf("error writing pseudonym key: %w", err)
+	}
+	return nil
+}
+
+// newPseudonym returns a random pseudonym not yet in use, such as
+// "Employee-3fa91c". Random rather than numbered pseudonyms don't give
+// away the order employees were added in.
+func (k *pseudonymKey) newPseudonym() (string, error) {
+	taken := make(map[string]bool, len(k.Names))
+	for _, p := range k.Names {
+		taken[p] = true
+	}
+	for {
+		b := make([]byte, 3)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("error generating pseudonym: %w", err)
+		}
+		if p := "Employee-" + hex.EncodeToString(b); !taken[p] {
+			return p, nil
+		}
+	}
+}
+
+// pseudonyms returns the pseudonym of every employee of a schedule,
+// assigning new ones to employees who have none yet, so each employee
+// keeps the same pseudonym across exports.
+func pseudonyms(entries []FlatSchedule) (map[string]string, error) {
+	pseudonymMu.Lock()
+	defer pseudonymMu.Unlock()
+	key, err := loadPseudonymKey()
+	if err != nil {
+		return nil, err
+	}
+	added := false
+	for _, obj := range entries {
+		name := obj["Employee"]
+		if _, ok := key.Names[name]; ok || name == "" {
+			continue
+		}
+		if key.Names[name], err = key.newPseudonym(); err != nil {
+			return nil, err
+		}
+		added = true
+	}
+	if added {
+		if err := key.save(); err != nil {
+			return nil, err
+		}
+	}
+	return key.Names, nil
+}
+
+// redactEntries returns copies of schedule entries with the employees'
+// names replaced by their pseudonyms.
+func redactEntries(entries []FlatSchedule) ([]FlatSchedule, error) {
+	names, err := pseudonyms(entries)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FlatSchedule, len(entries))
+	for i, obj := range entries {
+		entry := make(FlatSchedule, len(obj))
+		for k, v := range obj {
+			entry[k] = v
+		}
+		entry["Employee"] = names[obj["Employee"]]
+		out[i] = entry
+	}
+	return out, nil
+}
+
+// runRedact writes a copy of a published schedule with the employees'
+// names replaced by stable pseudonyms, for sharing with vendors and
+// consultants.
+func runRedact(args []string) error {
+	fs := flag.NewFlagSet("redact", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("usage: redact <schedule dir> <output dir>")
+	}
+	entries, err := loadScheduleDir(scheduleDir(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	redacted, err := redactEntries(entries)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	out := fs.Arg(1)
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	weeks := make(map[string][]FlatSchedule)
+	for _, obj := range redacted {
+		weeks[obj["Week"]] = append(weeks[obj["Week"]], obj)
+	}
+	for _, week := range sortedWeekNames(weeks) {
+		if _, err := writeWeekCSV(out, week, weeks[week], cfg.CSV); err != nil {
+			return err
+		}
+	}
+	log.Printf("Redacted schedule written to %s; the key is in %s", out, pseudonymPath())
+	return nil
+}
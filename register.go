@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// RegisterConfig sets how the working-time register records breaks.
+type RegisterConfig struct {
+	// Breaks are the unpaid breaks recorded for scheduled days without
+	// punches: the longest whose threshold the day's span exceeds. By
+	// default 30 minutes past 6 hours and 45 past 9.
+	Breaks []BreakRule `json:"breaks,omitempty"`
+}
+
+// BreakRule is the break owed for a working day longer than AfterHours.
+type BreakRule struct {
+	AfterHours float64 `json:"after_hours"`
+	Minutes    int     `json:"minutes"`
+}
+
+// breakFor returns the break owed for a day spanning the given hours.
+func (c RegisterConfig) breakFor(span float64) time.Duration {
+	rules := c.Breaks
+	if len(rules) == 0 {
+		rules = []BreakRule{{AfterHours: 6, Minutes: 30}, {AfterHours: 9, Minutes: 45}}
+	}
+	var owed time.Duration
+	for _, r := range rules {
+		if span > r.AfterHours {
+			owed = max(owed, time.Duration(r.Minutes)*time.Minute)
+		}
+	}
+	return owed
+}
+
+// registerDay is one employee's working day in the working-time register.
+type registerDay struct {
+	Employee   string
+	Date       time.Time
+	Start, End time.Time
+	Break      time.Duration
+	// Source is "attendance" for days recorded from punches and "schedule"
+	// for days taken from the published schedule.
+	Source string
+}
+
+// hours returns the time worked, net of the break.
+func (d registerDay) hours() float64 {
+	return (d.End.Sub(d.Start) - d.Break).Hours()
+}
+
+// registerDays lists the working days of a schedule for the register. With
+// punches, the days are the ones worked, from the first punch in to the
+// last punch out, with the gaps between punches as the break; scheduled
+// days without punches were not worked. Without punches, the scheduled
+// shifts are recorded with the breaks the config owes them.
+func registerDays(entries []FlatSchedule, shifts []schedule.Shift, punches []Punch, cfg RegisterConfig, now time.Time) []registerDay {
+	var days []registerDay
+	if len(punches) > 0 {
+		for _, v := range reconcile(entries, shifts, punches) {
+			if v.Worked <= 0 {
+				continue
+			}
+			span := v.ClockOut.Sub(v.ClockIn)
+			days = append(days, registerDay{
+				Employee: v.Employee,
+				Date:     v.Date,
+				Start:    v.ClockIn,
+				End:      v.ClockOut,
+				Break:    span - time.Duration(v.Worked*float64(time.Hour)),
+				Source:   "attendance",
+			})
+		}
+		return days
+	}
+	for _, sh := range exportedShifts(entries, shifts, now) {
+		end := sh.End
+		if !end.After(sh.Start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		days = append(days, registerDay{
+			Employee: sh.Employee,
+			Date:     time.Date(sh.Start.Year(), sh.Start.Month(), sh.Start.Day(), 0, 0, 0, 0, sh.Start.Location()),
+			Start:    sh.Start,
+			End:      end,
+			Break:    cfg.breakFor(end.Sub(sh.Start).Hours()),
+			Source:   "schedule",
+		})
+	}
+	return days
+}
+
+// writeRegister writes the register as CSV, one row per working day with a
+// total row after each employee's days. ids maps employees to their IDs in
+// the HRIS; employees without one use their name.
+func writeRegister(w io.Writer, days []registerDay, ids map[string]string, cc CSVConfig) error {
+	sort.SliceStable(days, func(i, j int) bool {
+		if days[i].Employee != days[j].Employee {
+			return days[i].Employee < days[j].Employee
+		}
+		return days[i].Start.Before(days[j].Start)
+	})
+	cw, err := newCSVWriter(w, cc)
+	if err != nil {
+		return err
+	}
+	cw.Write([]string{"Employee", "Employee ID", "Date", "Start", "End", "Break Minutes", "Hours", "Source"})
+	hours := func(h float64) string { return strconv.FormatFloat(h, 'f', 2, 64) }
+	id := func(name string) string {
+		if ids[name] != "" {
+			return ids[name]
+		}
+		return name
+	}
+	var breaks time.Duration
+	var total float64
+	for i, d := range days {
+		cw.Write([]string{d.Employee, id(d.Employee), d.Date.Format(time.DateOnly), d.Start.Format("15:04"), d.End.Format("15:04"),
+			strconv.Itoa(int(d.Break.Minutes())), hours(d.hours()), d.Source})
+		breaks += d.Break
+		total += d.hours()
+		if i == len(days)-1 || days[i+1].Employee != d.Employee {
+			cw.Write([]string{d.Employee, id(d.Employee), "Total", "", "", strconv.Itoa(int(breaks.Minutes())), hours(total), ""})
+			breaks, total = 0, 0
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runRegister writes the working-time register of published schedules,
+// the record of every employee's daily start, end, breaks, and hours that
+// labor law requires, from punches when given and the schedule otherwise.
+func runRegister(args []string) error {
+	fs := flag.NewFlagSet("register", flag.ContinueOnError)
+	punchesFrom := fs.String("punches", "", "punches.csv or timeclock:<start>/<end> to record the hours worked")
+	out := fs.String("out", "working_time_register.csv", "where to write the register")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: register [-punches punches.csv | timeclock:<start>/<end>] [-out working_time_register.csv] <schedule dir> ...")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, e := range roster {
+		ids[e.Name] = e.HRISID
+	}
+	var punches []Punch
+	if *punchesFrom != "" {
+		if punches, err = loadPunches(*punchesFrom); err != nil {
+			return err
+		}
+	}
+
+	var days []registerDay
+	for _, dir := range fs.Args() {
+		entries, err := loadScheduleDir(scheduleDir(dir))
+		if err != nil {
+			return err
+		}
+		days = append(days, registerDays(entries, shifts, punches, cfg.Register, time.Now())...)
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("error creating working-time register: %w", err)
+	}
+	defer file.Close()
+	if err := writeRegister(file, days, ids, cfg.CSV); err != nil {
+		return fmt.Errorf("error writing working-time register: %w", err)
+	}
+
+	totals := make(map[string]float64)
+	var names []string
+	for _, d := range days {
+		if _, ok := totals[d.Employee]; !ok {
+			names = append(names, d.Employee)
+		}
+		totals[d.Employee] += d.hours()
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Employee\tHours\t")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%.2f\t\n", name, totals[name])
+	}
+	tw.Flush()
+	fmt.Printf("Working-time register written to %s\n", *out)
+	return nil
+}
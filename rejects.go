@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// rejectsFileName is written next to a run's schedules when input rows were
+// skipped.
+const rejectsFileName = "rejects.csv"
+
+// rejectedRow is an input row that could not be parsed.
+type rejectedRow struct {
+	// Header is the header of the file the row came from.
+	Header []string `json:"header"`
+	Row    []string `json:"row"`
+	// Reason names the file and line and says what was wrong with the row.
+	Reason string `json:"reason"`
+}
+
+// writeRejects writes rejected rows as CSV in the configured format with
+// an error_reason column appended, so the source extract can be fixed row
+// by row. The header is taken from the first row's file.
+func writeRejects(dir string, cc CSVConfig, rejects []rejectedRow) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating output directory: %w", err)
+	}
+	filename := filepath.Join(dir, rejectsFileName)
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("error creating rejects file: %w", err)
+	}
+	defer file.Close()
+
+	writer, err := newCSVWriter(file, cc)
+	if err != nil {
+		return "", err
+	}
+	table := [][]string{append(append([]string(nil), rejects[0].Header...), "error_reason")}
+	for _, r := range rejects {
+		table = append(table, append(append([]string(nil), r.Row...), r.Reason))
+	}
+	if err := writer.WriteAll(table); err != nil {
+		return "", fmt.Errorf("error writing rejects file: %w", err)
+	}
+	return filename, nil
+}
+
+// purgeRejects removes the rejected rows kept from before cutoff: cached
+// aggregates, which hold the rejects of their file, and the rejects.csv
+// files in the output directories of the runs in the history. It returns
+// how many files it removed.
+func purgeRejects(cutoff time.Time) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(aggregateCacheDir(), "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	runs, err := filepath.Glob(filepath.Join(historyDir(), "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool)
+	for _, path := range runs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("error reading run: %w", err)
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			return 0, fmt.Errorf("error parsing run %s: %w", path, err)
+		}
+		if dir := run.outputDir(); !seen[dir] {
+			seen[dir] = true
+			paths = append(paths, filepath.Join(dir, rejectsFileName))
+		}
+	}
+
+	removed := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("error removing %s: %w", path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRejectsDropAgentIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []string
+	}{
+		{
+			name: "agent column",
+			csv:  "called_time;agent_id;call_id\nyesterday;agent-7;c1\n",
+			want: []string{"yesterday", "", "c1"},
+		},
+		{
+			name: "no agent column",
+			csv:  "called_time;call_id\nyesterday;c1\n",
+			want: []string{"yesterday", "c1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "calls.csv")
+			if err := os.WriteFile(path, []byte(tt.csv), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, rejects, err := getRecords(path, ColumnMapping{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rejects) != 1 {
+				t.Fatalf("got %d rejects, want 1", len(rejects))
+			}
+			if !slices.Equal(rejects[0].Row, tt.want) {
+				t.Errorf("rejected row %q, want %q", rejects[0].Row, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeRejects(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	out := t.TempDir()
+	run := &Run{ID: "run-1", OutputDir: out}
+	if err := saveRun(run); err != nil {
+		t.Fatal(err)
+	}
+	cutoff := time.Now().AddDate(0, -18, 0)
+	files := []struct {
+		path string
+		age  time.Duration
+		kept bool
+	}{
+		{path: filepath.Join(aggregateCacheDir(), "old.json"), age: 19 * 30 * 24 * time.Hour},
+		{path: filepath.Join(aggregateCacheDir(), "new.json"), age: time.Hour, kept: true},
+		{path: filepath.Join(out, rejectsFileName), age: 19 * 30 * 24 * time.Hour},
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f.path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		at := time.Now().Add(-f.age)
+		if err := os.Chtimes(f.path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, err := purgeRejects(cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d files, want 2", removed)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.path); (err == nil) != f.kept {
+			t.Errorf("%s: kept %v, want %v", f.path, err == nil, f.kept)
+		}
+	}
+}
+
+func TestWriteRejectsFormat(t *testing.T) {
+	rejects := []rejectedRow{{Header: []string{"called_time", "call_id"}, Row: []string{"yesterday", "c1"}, Reason: "calls.csv:2: bad time"}}
+	tests := []struct {
+		name string
+		cc   CSVConfig
+		want string
+	}{
+		{name: "default", want: "called_time,call_id,error_reason\nyesterday,c1,calls.csv:2: bad time\n"},
+		{name: "excel", cc: CSVConfig{Delimiter: ";", BOM: true, CRLF: true, SepHint: true}, want: utf8BOM + "sep=;\r\ncalled_time;call_id;error_reason\r\nyesterday;c1;calls.csv:2: bad time\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename, err := writeRejects(t.TempDir(), tt.cc, rejects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
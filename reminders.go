@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/notify"
+)
+
+// ReminderConfig sets when planners are reminded of schedules awaiting
+// approval in server mode. A schedule must be approved LeadDays before the
+// first day it covers.
+type ReminderConfig struct {
+	LeadDays int `json:"lead_days,omitempty"`
+	// Rules fire once per schedule, when no more than HoursBefore hours are
+	// left until the deadline. Later rules escalate to wider audiences; a
+	// negative HoursBefore fires after the deadline has passed.
+	Rules []ReminderRule `json:"rules,omitempty"`
+	SMTP  SMTPConfig     `json:"smtp"`
+}
+
+// ReminderRule is one reminder and who receives it.
+type ReminderRule struct {
+	HoursBefore  int      `json:"hours_before"`
+	SlackWebhook string   `json:"slack_webhook,omitempty"`
+	Email        []string `json:"email,omitempty"`
+}
+
+// SMTPConfig is the mail server reminders are sent through. SMTP_PASSWORD
+// takes precedence over the config file.
+type SMTPConfig struct {
+	Addr     string `json:"addr,omitempty"`
+	From     string `json:"from,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func reminderSettings(cfg Config) ReminderConfig {
+	rc := cfg.Reminders
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		rc.SMTP.Password = v
+	}
+	if rc.LeadDays <= 0 {
+		rc.LeadDays = 14
+	}
+	return rc
+}
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// sendSlack posts a message to a Slack incoming webhook.
+func sendSlack(webhook, text string) error {
+	return postSlack(webhook, map[string]string{"text": text})
+}
+
+// postSlack posts a message payload, such as one with blocks, to a Slack
+// webhook.
+func postSlack(webhook string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := notifyClient.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting to Slack: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error posting to Slack: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendEmail sends a plain-text message through the configured mail server.
+func sendEmail(sc SMTPConfig, to []string, subject, text string) error {
+	if sc.Addr == "" || sc.From == "" {
+		return fmt.Errorf("no SMTP server configured for %s", strings.Join(to, ", "))
+	}
+	var auth smtp.Auth
+	if sc.Username != "" {
+		host, _, _ := strings.Cut(sc.Addr, ":")
+		auth = smtp.PlainAuth("", sc.Username, sc.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", sc.From, strings.Join(to, ", "), subject, text)
+	if err := smtp.SendMail(sc.Addr, auth, sc.From, to, []byte(msg)); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+// sendSMS sends a text message through the Twilio Messages API.
+func sendSMS(tc TwilioConfig, to, text string) error {
+	if tc.AccountSID == "" || tc.AuthToken == "" || tc.From == "" {
+		return fmt.Errorf("no Twilio account or sender number configured for %s", to)
+	}
+	form := url.Values{"To": {to}, "From": {tc.From}, "Body": {text}}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", tc.Endpoint, url.PathEscape(tc.AccountSID))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(tc.AccountSID, tc.AuthToken)
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending text message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("error sending text message: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// publishDeadline returns the week number and approval deadline of a job's
+// schedule. It reports false when the run has no forecast to date it by.
+func publishDeadline(job Job, leadDays int) (int, time.Time, bool) {
+	run, err := loadRun(job.RunID)
+	if err != nil || run.Forecast == nil {
+		return 0, time.Time{}, false
+	}
+	start, err := time.Parse(forecast.DateLayout, run.Forecast.Start)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	_, week := start.ISOWeek()
+	return week, start.AddDate(0, 0, -leadDays), true
+}
+
+// reminderText writes the reminder for a schedule, e.g. "Week 14 schedule
+// not yet approved, publish deadline in 48h".
+func reminderText(job Job, week int, left time.Duration) string {
+	hours := int(left.Round(time.Hour) / time.Hour)
+	when := fmt.Sprintf("publish deadline in %dh", hours)
+	if left < 0 {
+		when = fmt.Sprintf("publish deadline passed %dh ago", -hours)
+	}
+	return fmt.Sprintf("Week %d schedule not yet approved, %s (job %s)", week, when, job.ID)
+}
+
+// remindDeadlines sends the reminders that are due for published schedules
+// that haven't been approved yet. Each rule fires once per job, and rules
+// falling due together send one message to each recipient and the
+// channels notified of deadlines. When a delivery fails the rules are
+// tried again on the next pass.
+func (q *jobQueue) remindDeadlines(cfg Config, now time.Time) {
+	rc := reminderSettings(cfg)
+	if len(rc.Rules) == 0 {
+		return
+	}
+	for _, job := range q.list() {
+		if job.Status != jobPublished || job.ApprovedAt != nil {
+			continue
+		}
+		week, deadline, ok := publishDeadline(job, rc.LeadDays)
+		if !ok {
+			continue
+		}
+		left := deadline.Sub(now)
+		var due []int
+		var webhooks, emails []string
+		for _, rule := range rc.Rules {
+			if slices.Contains(job.RemindersSent, rule.HoursBefore) || left > time.Duration(rule.HoursBefore)*time.Hour {
+				continue
+			}
+			due = append(due, rule.HoursBefore)
+			if rule.SlackWebhook != "" && !slices.Contains(webhooks, rule.SlackWebhook) {
+				webhooks = append(webhooks, rule.SlackWebhook)
+			}
+			for _, to := range rule.Email {
+				if !slices.Contains(emails, to) {
+					emails = append(emails, to)
+				}
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		text := reminderText(job, week, left)
+		var failed bool
+		for _, webhook := range webhooks {
+			if err := sendSlack(webhook, text); err != nil {
+				log.Printf("Error sending reminder for job %s: %v", job.ID, err)
+				failed = true
+			}
+		}
+		if len(emails) > 0 {
+			if err := sendEmail(rc.SMTP, emails, fmt.Sprintf("Week %d schedule awaiting approval", week), text); err != nil {
+				log.Printf("Error sending reminder for job %s: %v", job.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+		log.Printf("Sent reminder: %s", text)
+		notifyChannels(cfg, notify.Message{Event: eventPublishDeadline, Title: fmt.Sprintf("Week %d schedule awaiting approval", week), Text: text})
+		q.markReminded(job.ID, due)
+	}
+}
+
+// markReminded records that reminder rules fired for a job.
+func (q *jobQueue) markReminded(id string, hoursBefore []int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return
+	}
+	job.RemindersSent = append(job.RemindersSent, hoursBefore...)
+	if err := q.save(job); err != nil {
+		log.Printf("Error saving job %s: %v", job.ID, err)
+	}
+}
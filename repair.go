@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"employee-schedular/validator"
+)
+
+// maxRepairViolations bounds the violations listed in a repair prompt.
+const maxRepairViolations = 50
+
+// responseErrors holds the stored response of a run to the rules on its
+// own, before frozen and notice-period assignments are restored: it
+// returns the error violations of the model's schedule, or why the
+// schedule couldn't be read.
+func responseErrors(run *Run, p *policy) ([]validator.Violation, error) {
+	weeks, err := parseResponse(run.Response, p.language)
+	if err != nil {
+		return nil, err
+	}
+	if err := staggerStarts(weeks, p); err != nil {
+		return nil, err
+	}
+	applyWorkweeks(weeks, p.shifts, p.workweeks)
+	violations, err := validateWeeks(weeks, p.shifts, p.rulePack, p.rules(run))
+	if err != nil {
+		return nil, err
+	}
+	var failed []validator.Violation
+	for _, v := range violations {
+		if v.Severity == validator.Error {
+			failed = append(failed, v)
+		}
+	}
+	return failed, nil
+}
+
+// repairPrompt asks the model to correct its schedule, given the prompt it
+// answered, its answer, and what was wrong with it.
+func repairPrompt(prompt, response string, unreadable error, failed []validator.Violation) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nYour previous schedule was:\n\n")
+	b.WriteString(response)
+	if unreadable != nil {
+		fmt.Fprintf(&b, "\n\nIt couldn't be read: %v.\n", unreadable)
+	} else {
+		fmt.Fprintf(&b, "\n\nIt breaks these rules (%d violations):\n", len(failed))
+		for i, v := range failed {
+			if i == maxRepairViolations {
+				fmt.Fprintf(&b, "- and %d more\n", len(failed)-i)
+				break
+			}
+			fmt.Fprintf(&b, "- %s\n", v)
+		}
+	}
+	b.WriteString("\nReturn a corrected schedule for the same weeks and employees, in the same format, that follows every rule.")
+	return b.String()
+}
+
+// repairResponse sends the model's schedule back with its error
+// violations, up to rounds times, until it follows the rules. Each
+// corrected response replaces the stored one; the last is kept even when
+// it still breaks rules, for validation to report.
+func repairResponse(ctx context.Context, run *Run, p *policy, rounds int) error {
+	for run.Repairs < rounds {
+		failed, unreadable := responseErrors(run, p)
+		if unreadable == nil && len(failed) == 0 {
+			return nil
+		}
+		if unreadable != nil {
+			log.Printf("Asking the model to correct its unreadable schedule (%d of %d): %v", run.Repairs+1, rounds, unreadable)
+		} else {
+			log.Printf("Asking the model to correct %d error violations (%d of %d)", len(failed), run.Repairs+1, rounds)
+		}
+		response, err := callChatGPT(ctx, repairPrompt(run.Prompt, run.Response, unreadable, failed), run.LLM)
+		if err != nil {
+			return withExitCode(exitLLM, fmt.Errorf("error calling ChatGPT: %w", err))
+		}
+		run.Response = response
+		run.Repairs++
+		if err := saveRun(run); err != nil {
+			return fmt.Errorf("error saving run: %w", err)
+		}
+	}
+	return nil
+}
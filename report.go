@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// ReportConfig renders a Go template into every published run folder.
+// Templates whose file name contains .html are rendered with html/template,
+// which escapes the schedule's values; any other file with text/template.
+type ReportConfig struct {
+	Template string `json:"template"`
+	// Output is the file name in the run folder, the template's name
+	// without a trailing .tmpl by default.
+	Output string `json:"output,omitempty"`
+}
+
+func (rc ReportConfig) output() string {
+	if rc.Output != "" {
+		return rc.Output
+	}
+	return strings.TrimSuffix(filepath.Base(rc.Template), ".tmpl")
+}
+
+// reportCell is one employee's day.
+type reportCell struct {
+	Label string
+	// Shift is empty on days off.
+	Shift      string
+	Start, End schedule.Clock
+	Hours      float64
+}
+
+// reportRow is one employee's week, with a cell for every day of the week.
+type reportRow struct {
+	Employee string
+	Cells    []reportCell
+	Hours    float64
+}
+
+// reportWeek is one week of the schedule. Coverage counts the employees on
+// each shift by day label.
+type reportWeek struct {
+	Number   int
+	Days     []string
+	Rows     []reportRow
+	Coverage map[string]map[string]int
+}
+
+// reportEmployee sums up an employee's schedule. WeeklyHours is aligned
+// with the report's weeks.
+type reportEmployee struct {
+	Name        string
+	Hours       float64
+	WeeklyHours []float64
+	Shifts      int
+	DaysOff     int
+}
+
+// reportData is what report templates receive.
+type reportData struct {
+	Run         string
+	GeneratedAt time.Time
+	// Schedule is the typed schedule, for templates that need more than the
+	// summaries below.
+	Schedule   *schedule.Schedule
+	Weeks      []reportWeek
+	Employees  []reportEmployee
+	Violations []validator.Violation
+	Errors     int
+	Warnings   int
+	// LaborCost is the schedule's budget and planned cost per month, in
+	// Currency.
+	LaborCost []laborMonth
+	Currency  string
+	// HandoverNotes are the handover notes on the schedule's dates.
+	HandoverNotes []HandoverNote
+}
+
+// newReportData summarizes a schedule for the report templates.
+func newReportData(runID string, sch *schedule.Schedule, violations []validator.Violation, labor laborRates, now time.Time) reportData {
+	data := reportData{Run: runID, GeneratedAt: now, Schedule: sch, Violations: violations, Currency: labor.cfg.Currency}
+	months := make(laborMonths)
+	months.addPlanned(sch, labor, now)
+	data.LaborCost = months.sorted()
+	for _, v := range violations {
+		if v.Severity == validator.Error {
+			data.Errors++
+		} else {
+			data.Warnings++
+		}
+	}
+
+	weekIndex := make(map[int]int)
+	for i, n := range sch.Weeks() {
+		weekIndex[n] = i
+		week := reportWeek{Number: n, Coverage: make(map[string]map[string]int)}
+		order := make(map[string]int)
+		for _, e := range sch.Entries {
+			if e.Week != n {
+				continue
+			}
+			for _, d := range e.Days {
+				if _, ok := order[d.Label]; !ok {
+					order[d.Label] = (int(d.Weekday) + 6) % 7
+					week.Days = append(week.Days, d.Label)
+				}
+			}
+		}
+		sort.SliceStable(week.Days, func(i, j int) bool { return order[week.Days[i]] < order[week.Days[j]] })
+		data.Weeks = append(data.Weeks, week)
+	}
+
+	employees := make(map[string]*reportEmployee)
+	for _, name := range sch.Employees() {
+		employees[name] = &reportEmployee{Name: name, WeeklyHours: make([]float64, len(data.Weeks))}
+	}
+	for _, e := range sch.Entries {
+		week := &data.Weeks[weekIndex[e.Week]]
+		byLabel := make(map[string]schedule.Day, len(e.Days))
+		for _, d := range e.Days {
+			byLabel[d.Label] = d
+		}
+		row := reportRow{Employee: e.Employee}
+		emp := employees[e.Employee]
+		for _, label := range week.Days {
+			d := byLabel[label]
+			cell := reportCell{Label: label, Shift: d.Shift}
+			if d.Shift == "" {
+				emp.DaysOff++
+			} else {
+				cell.Start, cell.End = d.Start, d.End
+				if sh, ok := sch.Shift(d.Shift); ok && d.End <= d.Start {
+					cell.Start, cell.End = sh.Start, sh.End
+				}
+				cell.Hours = sch.Hours(d)
+				row.Hours += cell.Hours
+				emp.Shifts++
+				if week.Coverage[label] == nil {
+					week.Coverage[label] = make(map[string]int)
+				}
+				week.Coverage[label][d.Shift]++
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		week.Rows = append(week.Rows, row)
+		emp.Hours += row.Hours
+		emp.WeeklyHours[weekIndex[e.Week]] += row.Hours
+	}
+	for _, name := range sch.Employees() {
+		data.Employees = append(data.Employees, *employees[name])
+	}
+	return data
+}
+
+// reportFuncs are available to every report template.
+var reportFuncs = map[string]any{
+	"hours": func(h float64) string { return strconv.FormatFloat(h, 'f', -1, 64) },
+	"date":  func(t time.Time, layout string) string { return t.Format(layout) },
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// renderReport executes a report template on data.
+func renderReport(w io.Writer, path string, data reportData) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading report template: %w", err)
+	}
+	name := filepath.Base(path)
+	if strings.Contains(strings.ToLower(name), ".html") {
+		t, err := htmltemplate.New(name).Funcs(reportFuncs).Parse(string(src))
+		if err != nil {
+			return fmt.Errorf("error parsing report template %s: %w", name, err)
+		}
+		return t.Execute(w, data)
+	}
+	t, err := texttemplate.New(name).Funcs(reportFuncs).Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("error parsing report template %s: %w", name, err)
+	}
+	return t.Execute(w, data)
+}
+
+// reportExporter renders the configured report templates.
+type reportExporter struct{}
+
+func (reportExporter) Write(weeks map[string][]FlatSchedule, opts ExportOptions) ([]string, error) {
+	if len(opts.Reports) == 0 {
+		return nil, nil
+	}
+	var objs []map[string]string
+	for _, week := range sortedWeekNames(weeks) {
+		for _, obj := range weeks[week] {
+			objs = append(objs, obj)
+		}
+	}
+	sch, err := schedule.Parse(objs, opts.Shifts)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schedule: %w", err)
+	}
+	data := newReportData(opts.Run, sch, opts.Violations, opts.Labor, time.Now())
+	if data.HandoverNotes, err = scheduleNotes(weeks, time.Now()); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, rc := range opts.Reports {
+		var buf bytes.Buffer
+		if err := renderReport(&buf, rc.Template, data); err != nil {
+			return files, err
+		}
+		filename := filepath.Join(opts.Dir, rc.output())
+		if err := os.WriteFile(filename, buf.Bytes(), 0o644); err != nil {
+			return files, fmt.Errorf("error writing report %s: %w", filename, err)
+		}
+		files = append(files, filename)
+	}
+	return files, nil
+}
+
+// runReport renders a report template for a published schedule, for trying
+// out templates without generating a schedule.
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	tmpl := fs.String("template", "", "Go template file; .html templates are HTML-escaped")
+	out := fs.String("out", "", "file to write instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *tmpl == "" {
+		return errors.New("usage: report -template file [-out file] <schedule dir>")
+	}
+	entries, err := loadScheduleDir(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, obj := range entries {
+		names[obj["Employee"]] = true
+	}
+	employees := make([]string, 0, len(names))
+	for name := range names {
+		employees = append(employees, name)
+	}
+	p, err := loadPolicy(employees)
+	if err != nil {
+		return err
+	}
+	weeks := make(map[string][]FlatSchedule)
+	var objs []map[string]string
+	for _, obj := range entries {
+		weeks[obj["Week"]] = append(weeks[obj["Week"]], obj)
+		objs = append(objs, obj)
+	}
+	violations, err := validateWeeks(weeks, p.shifts, p.rulePack, p.rules(&Run{Employees: employees}))
+	if err != nil {
+		return err
+	}
+	sch, err := schedule.Parse(objs, p.shifts)
+	if err != nil {
+		return fmt.Errorf("error parsing schedule: %w", err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("error creating report: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	// Name the report after the run, or after the directory when it isn't a
+	// run folder.
+	runID := filepath.Base(scheduleDir(fs.Arg(0)))
+	if m, ok, _ := readManifest(scheduleDir(fs.Arg(0))); ok && m.Run != "" {
+		runID = m.Run
+	}
+	data := newReportData(runID, sch, violations, p.labor, time.Now())
+	if data.HandoverNotes, err = scheduleNotes(weeks, time.Now()); err != nil {
+		return err
+	}
+	if err := renderReport(w, *tmpl, data); err != nil {
+		return err
+	}
+	if *out != "" {
+		log.Printf("Report written to %s", *out)
+	}
+	return nil
+}
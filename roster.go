@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Employee is one entry of the roster file.
+type Employee struct {
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+	// Flexible employees belong to the shared pool and can be borrowed by
+	// whichever team has a coverage gap in a given week.
+	Flexible bool `json:"flexible,omitempty"`
+	// Workweek is a compressed pattern the employee opted into, such as
+	// "4x10" or "3x12". Empty means the standard five days.
+	Workweek string `json:"workweek,omitempty"`
+	// MaxNightShifts caps the employee's night shifts per month, overriding
+	// the configured cap.
+	MaxNightShifts *int `json:"max_night_shifts,omitempty"`
+	// Observances are recurring times the employee is never available.
+	Observances []Observance `json:"observances,omitempty"`
+	// CapacityProfiles are periods of reduced capacity, such as exams.
+	CapacityProfiles []CapacityProfile `json:"capacity_profiles,omitempty"`
+	// EarliestStart and LatestEnd bound the employee's working hours, for
+	// those who rely on public transport, e.g. "07:00" and "20:00".
+	EarliestStart string `json:"earliest_start,omitempty"`
+	LatestEnd     string `json:"latest_end,omitempty"`
+	// VolunteerShifts are shifts the employee wants more of, such as Late
+	// for its premium.
+	VolunteerShifts []string `json:"volunteer_shifts,omitempty"`
+	// Skills are what the employee is qualified for, such as "forklift" or
+	// "first-aid".
+	Skills []string `json:"skills,omitempty"`
+	// Certifications gate the shifts that require them, such as
+	// "emergency-line", until they expire.
+	Certifications []Certification `json:"certifications,omitempty"`
+	// HRISID is the employee's ID in the HRIS leave is synced from, when
+	// their name there differs.
+	HRISID string `json:"hris_id,omitempty"`
+	// Email, SlackWebhook, and Phone are where the employee's shift
+	// reminders are sent. Phone is either international, e.g.
+	// "+27821234567", or local to Country, e.g. "082 123 4567".
+	Email        string `json:"email,omitempty"`
+	SlackWebhook string `json:"slack_webhook,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+	// Country is the ISO code of the employee's phone number, overriding
+	// the configured SMS country.
+	Country string `json:"country,omitempty"`
+	// SlackUser is the employee's Slack member ID, such as "U024BE7LH",
+	// identifying them when they press Slack buttons.
+	SlackUser string `json:"slack_user,omitempty"`
+	// HireDate ranks employees by seniority, e.g. "2019-04-01".
+	HireDate string `json:"hire_date,omitempty"`
+	// Birthday is the employee's birthday, as "03-05" or "1990-03-05", for
+	// the birthday day off.
+	Birthday string `json:"birthday,omitempty"`
+	// HourlyRate is the employee's pay per hour, for labor cost.
+	HourlyRate float64 `json:"hourly_rate,omitempty"`
+	// LeaveBalance is the days of leave the employee has to take in the
+	// year the leave planner plans.
+	LeaveBalance float64 `json:"leave_balance,omitempty"`
+	// NoShiftReminders opts the employee out of shift reminders.
+	NoShiftReminders bool `json:"no_shift_reminders,omitempty"`
+	// Minor marks an employee under 18, held to the limits for minors.
+	Minor bool `json:"minor,omitempty"`
+}
+
+// dataDir returns the directory holding the application state, taken from
+// SCHEDULER_DATA_DIR and defaulting to ./data.
+func dataDir() string {
+	if dir := os.Getenv("SCHEDULER_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return "data"
+}
+
+func rosterPath() string {
+	return filepath.Join(dataDir(), "roster.json")
+}
+
+// loadRoster reads the roster file. A missing roster yields an empty slice.
+func loadRoster() ([]Employee, error) {
+	data, err := readDataFile(rosterPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading roster: %w", err)
+	}
+	var roster []Employee
+	if err := json.Unmarshal(data, &roster); err != nil {
+		return nil, fmt.Errorf("error parsing roster: %w", err)
+	}
+	return roster, nil
+}
+
+// flexibleEmployees returns the names of the shared-pool employees.
+func flexibleEmployees(roster []Employee) []string {
+	var names []string
+	for _, e := range roster {
+		if e.Flexible {
+			names = append(names, e.Name)
+		}
+	}
+	return names
+}
+
+// rosterNames returns the names of everyone on the roster, the employees
+// scheduled when none are listed.
+func rosterNames() ([]string, error) {
+	roster, err := loadRoster()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(roster))
+	for i, e := range roster {
+		names[i] = e.Name
+	}
+	return names, nil
+}
+
+// readEmployees reads the -employees flag: a file of employee names, or
+// else comma-separated names. Values with a path separator or a file
+// extension are always files. A JSON file holds an array of names or of
+// roster entries, and a YAML file a list of names; any other file has a
+// name per line. Blank names, lines and # comments are skipped, and a
+// value that names no one is an error.
+func readEmployees(v string) ([]string, error) {
+	var names []string
+	data, err := os.ReadFile(v)
+	switch ext := strings.ToLower(filepath.Ext(v)); {
+	case errors.Is(err, os.ErrNotExist) && !looksLikePath(v):
+		names = strings.Split(v, ",")
+	case err != nil:
+		return nil, fmt.Errorf("error reading employees: %w", err)
+	case ext == ".json":
+		if json.Unmarshal(data, &names) == nil {
+			break
+		}
+		var entries []Employee
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("error parsing employees: %w", err)
+		}
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+	case ext == ".yaml" || ext == ".yml":
+		if names, err = yamlNames(data); err != nil {
+			return nil, fmt.Errorf("error parsing employees in %s: %w", v, err)
+		}
+	default:
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+				names = append(names, line)
+			}
+		}
+	}
+	kept := names[:0]
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			kept = append(kept, name)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no employees in %s", v)
+	}
+	return kept, nil
+}
+
+// looksLikePath reports whether an -employees value names a file rather
+// than listing names: it has a path separator or a file extension.
+func looksLikePath(v string) bool {
+	if strings.ContainsAny(v, `/\`) {
+		return true
+	}
+	ext := filepath.Ext(v)
+	return len(ext) > 1 && !strings.ContainsAny(ext, " ,")
+}
+
+// yamlNames reads a YAML list of names, one "- name" item per line, plain
+// or quoted. Anything else, such as nested lists, mappings, or anchors, is
+// an error rather than read as a name.
+func yamlNames(data []byte) ([]string, error) {
+	var names []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		item, ok := strings.CutPrefix(line, "- ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want a list item \"- name\"", i+1)
+		}
+		item = strings.TrimSpace(item)
+		if len(item) >= 2 && (item[0] == '"' || item[0] == '\'') && item[len(item)-1] == item[0] {
+			names = append(names, item[1:len(item)-1])
+			continue
+		}
+		if before, _, found := strings.Cut(item, " #"); found {
+			item = strings.TrimSpace(before)
+		}
+		if item == "" || strings.ContainsAny(item[:1], "-[]{}&*!|>%@`\"'") || strings.Contains(item, ": ") || strings.HasSuffix(item, ":") {
+			return nil, fmt.Errorf("line %d: %q isn't a plain name", i+1, item)
+		}
+		names = append(names, item)
+	}
+	return names, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadEmployees(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"team.yaml":     "# Early team\n---\n- Ann\n- \"Bob Jr\"\n- 'Cy'  \n- Di # lead\n",
+		"nested.yaml":   "- Ann\n- - Bob\n",
+		"mapping.yaml":  "- name: Ann\n",
+		"indent.yml":    "- Ann\n  - Bob\n",
+		"flow.yaml":     "[Ann, Bob]\n",
+		"team.txt":      "Ann\n\n# off\nBob Jr\n",
+		"team.json":     `["Ann", "Bob"]`,
+		"blanks.json":   `["Ann", " ", ""]`,
+		"empty.json":    `[]`,
+		"nameless.json": `[{"name": ""}, {"name": " "}]`,
+		"blank.txt":     "\n# no one\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		arg     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "names", arg: "Ann, Bob ,Cy", want: []string{"Ann", "Bob", "Cy"}},
+		{name: "name with an initial", arg: "Ann B. Smith,Cy", want: []string{"Ann B. Smith", "Cy"}},
+		{name: "yaml list", arg: filepath.Join(dir, "team.yaml"), want: []string{"Ann", "Bob Jr", "Cy", "Di"}},
+		{name: "text file", arg: filepath.Join(dir, "team.txt"), want: []string{"Ann", "Bob Jr"}},
+		{name: "json names", arg: filepath.Join(dir, "team.json"), want: []string{"Ann", "Bob"}},
+		{name: "json blank names", arg: filepath.Join(dir, "blanks.json"), want: []string{"Ann"}},
+		{name: "only commas", arg: ",", wantErr: true},
+		{name: "blank names", arg: " , ,", wantErr: true},
+		{name: "empty json", arg: filepath.Join(dir, "empty.json"), wantErr: true},
+		{name: "json entries without names", arg: filepath.Join(dir, "nameless.json"), wantErr: true},
+		{name: "blank text file", arg: filepath.Join(dir, "blank.txt"), wantErr: true},
+		{name: "nested yaml list", arg: filepath.Join(dir, "nested.yaml"), wantErr: true},
+		{name: "yaml mapping", arg: filepath.Join(dir, "mapping.yaml"), wantErr: true},
+		{name: "indented yaml item", arg: filepath.Join(dir, "indent.yml"), wantErr: true},
+		{name: "yaml flow list", arg: filepath.Join(dir, "flow.yaml"), wantErr: true},
+		{name: "missing file with an extension", arg: "employees.yaml", wantErr: true},
+		{name: "missing file in a directory", arg: filepath.Join(dir, "staff"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readEmployees(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// RotationConfig sets how employees move between shifts.
+type RotationConfig struct {
+	// MaxConsecutive is how many days in a row an employee works the same
+	// shift before moving to another one. Defaults to 5.
+	MaxConsecutive int `json:"max_consecutive_same_shift,omitempty"`
+	// Weeks is how many weeks an employee stays on a shift before rotating.
+	// Defaults to 1.
+	Weeks int `json:"rotation_weeks,omitempty"`
+	// AllowBackward turns off the forward rotation policy, which only lets
+	// employees move to a later shift, or back to the earliest shift after
+	// a day off.
+	AllowBackward bool `json:"allow_backward_rotation,omitempty"`
+}
+
+func rotationSettings(cfg Config) RotationConfig {
+	rc := cfg.Rotation
+	if rc.MaxConsecutive <= 0 {
+		rc.MaxConsecutive = 5
+	}
+	if rc.Weeks <= 0 {
+		rc.Weeks = 1
+	}
+	return rc
+}
+
+// completedShiftNote explains the consecutive same-shift rule in the prompt.
+func completedShiftNote(rc RotationConfig) string {
+	return fmt.Sprintf("NOTE: a completed shift is when an employee has worked %d days of the same shift before being assigned a new shift.", rc.MaxConsecutive)
+}
+
+// rotationRule states the rotation cadence in the prompt, with an example
+// walking Alice through the shift catalog.
+func rotationRule(rc RotationConfig, shifts []schedule.Shift) string {
+	var steps []string
+	for i, sh := range shifts {
+		first := i*rc.Weeks + 1
+		if rc.Weeks == 1 {
+			steps = append(steps, fmt.Sprintf("Alice - Week %d %s", first, sh.Name))
+		} else {
+			steps = append(steps, fmt.Sprintf("Alice - Weeks %d-%d %s", first, first+rc.Weeks-1, sh.Name))
+		}
+	}
+	example := "For example: " + strings.Join(steps, ", ") + ", and so on."
+	rule := "Ensure that each week employees are rotated between shifts. " + example
+	if rc.Weeks > 1 {
+		rule = fmt.Sprintf("Ensure that every %d weeks employees are rotated between shifts, staying on the same shift in between. %s", rc.Weeks, example)
+	}
+	if !rc.AllowBackward {
+		rule += " Rotate forward only: never move an employee to a shift that starts earlier than their previous one, except back to the " +
+			forwardOrder(shifts)[0].Name + " shift after a day off."
+	}
+	return rule
+}
+
+// forwardOrder returns the shifts sorted by start time, the order forward
+// rotation follows.
+func forwardOrder(shifts []schedule.Shift) []schedule.Shift {
+	sorted := make([]schedule.Shift, len(shifts))
+	copy(sorted, shifts)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Run stages, in the order a generation moves through them.
+const (
+	stageCreated    = "created"
+	stageResponded  = "responded"
+	stageValidating = "validating"
+	stagePlanned    = "planned"
+	stageExported   = "exported"
+	// stageRejected ends a planned run that won't be published.
+	stageRejected = "rejected"
+	// stageEvaluated ends a run of the evaluate command, validated and
+	// scored but never published.
+	stageEvaluated = "evaluated"
+)
+
+// Run is the persisted record of one schedule generation. It is saved to the
+// history directory after every stage so an interrupted run can be resumed
+// without paying for another API call.
+type Run struct {
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Stage          string    `json:"stage"`
+	HighVolumeDays []int     `json:"high_volume_days,omitempty"`
+	// Forecast is the demand projection the schedule was generated for.
+	Forecast   *forecast.Forecast `json:"forecast,omitempty"`
+	Employees  []string           `json:"employees,omitempty"`
+	Prompt     string             `json:"prompt,omitempty"`
+	Response   string             `json:"response,omitempty"`
+	Violations int                `json:"violations"`
+	// Errors is how many of the violations have error severity.
+	Errors    int    `json:"errors,omitempty"`
+	OutputDir string `json:"output_dir,omitempty"`
+	// Folder is the timestamped folder under OutputDir the run was
+	// published to.
+	Folder string   `json:"folder,omitempty"`
+	Files  []string `json:"files,omitempty"`
+	// Objective is the optimization preset the schedule was generated
+	// under, and Penalties its soft-constraint misses by objective.
+	Objective string              `json:"objective,omitempty"`
+	Penalties *objectivePenalties `json:"penalties,omitempty"`
+	// TargetScale is the coverage level the shift targets were scaled by
+	// in the prompt, and Tradeoff the schedule's cost and coverage of the
+	// unscaled targets.
+	TargetScale float64   `json:"target_scale,omitempty"`
+	Tradeoff    *tradeoff `json:"tradeoff,omitempty"`
+	// Pattern is the weekly rhythms of the previous schedule, read from
+	// Previous, the run was asked to keep, and Stability the share of days
+	// on which it kept them.
+	Pattern   schedulePattern `json:"pattern,omitempty"`
+	Previous  string          `json:"previous,omitempty"`
+	Stability *float64        `json:"stability,omitempty"`
+	// Churn is the share of the previously published assignments the
+	// schedule changes.
+	Churn *float64 `json:"churn,omitempty"`
+	// ReasonCode allows the run to change published shifts inside the
+	// notice period.
+	ReasonCode string `json:"reason_code,omitempty"`
+	// PlannedAgainst is the run folder that was the latest published when
+	// the run was planned, empty when none was.
+	PlannedAgainst string `json:"planned_against,omitempty"`
+	// ReviewedBy is who approved or rejected the plan from Slack.
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+	// PromptPack and RulePack are the versions of the prompt template and
+	// rule pack the schedule was generated and validated with.
+	PromptPack string `json:"prompt_pack,omitempty"`
+	RulePack   string `json:"rule_pack,omitempty"`
+	// Solver is what wrote the schedule, and LLM the OpenAI chat model
+	// asked for it, empty for the default.
+	Solver string `json:"solver,omitempty"`
+	LLM    string `json:"llm,omitempty"`
+	// Repairs is how many times the model was asked to correct its
+	// schedule.
+	Repairs int `json:"repairs,omitempty"`
+	// Evaluation is the evaluate command invocation the run belongs to.
+	Evaluation string `json:"evaluation,omitempty"`
+}
+
+// newRun creates a run with a fresh ID made of its start time and a random
+// suffix, so IDs sort chronologically and never collide.
+func newRun() (*Run, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("error generating run ID: %w", err)
+	}
+	now := time.Now().UTC()
+	return &Run{
+		ID:        now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		CreatedAt: now,
+		Stage:     stageCreated,
+	}, nil
+}
+
+func runPath(id string) string {
+	return filepath.Join(historyDir(), id+".json")
+}
+
+// saveRun writes the run record, replacing any earlier version atomically.
+func saveRun(run *Run) error {
+	if err := os.MkdirAll(historyDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := runPath(run.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing run %s: %w", run.ID, err)
+	}
+	return os.Rename(tmp, runPath(run.ID))
+}
+
+// loadRun reads a stored run by ID.
+func loadRun(id string) (*Run, error) {
+	if !filepath.IsLocal(id) {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+	data, err := os.ReadFile(runPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("run %s not found", id)
+		}
+		return nil, fmt.Errorf("error reading run %s: %w", id, err)
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("error parsing run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// outputDir returns where the run's schedules are written.
+func (r *Run) outputDir() string {
+	if r.OutputDir == "" {
+		return "."
+	}
+	return r.OutputDir
+}
+
+// exportRun validates the stored response of a run, writes the weekly CSV
+// files, and marks the run as exported.
+func exportRun(ctx context.Context, run *Run) error {
+	v, err := validateRun(ctx, run)
+	if err != nil {
+		return err
+	}
+	return v.publish(ctx, run)
+}
+
+// validatedRun is the schedule of a run once validated, ready to publish.
+type validatedRun struct {
+	p          *policy
+	weeks      map[string][]FlatSchedule
+	violations []validator.Violation
+	notices    []auditEntry
+}
+
+// validateRun parses the stored response of a run and holds it to the
+// published schedule and the rules, without writing anything.
+func validateRun(ctx context.Context, run *Run) (*validatedRun, error) {
+	_, validate := startSpan(ctx, "validate")
+	p, err := loadPolicy(run.Employees)
+	var weeks map[string][]FlatSchedule
+	if err == nil {
+		weeks, err = parseResponse(run.Response, p.language)
+		err = withExitCode(exitValidation, err)
+	}
+	if err == nil {
+		err = staggerStarts(weeks, p)
+	}
+	var violations []validator.Violation
+	if err == nil {
+		applyWorkweeks(weeks, p.shifts, p.workweeks)
+		var restored int
+		restored, err = keepFrozen(weeks, run.outputDir(), p.cfg.Freeze, time.Now())
+		if restored > 0 {
+			log.Printf("Kept %d frozen assignments; use the swap command to change them", restored)
+		}
+	}
+	var notices []auditEntry
+	if err == nil {
+		var restored int
+		restored, notices, err = keepNotice(weeks, run.outputDir(), p.cfg.Notice, run.ReasonCode, time.Now())
+		if restored > 0 {
+			log.Printf("Kept %d assignments inside the %d-day notice period; regenerate with a reason code to change them", restored, p.cfg.Notice.Days)
+		}
+		for i := range notices {
+			notices[i].Reason = "run " + run.ID
+		}
+	}
+	if err == nil {
+		err = checkChurn(run, weeks, p)
+	}
+	if err == nil {
+		if granted := grantSpecialDays(weeks, p, p.shiftTargets(run.Forecast, len(run.Employees)), time.Now()); granted > 0 {
+			log.Printf("Granted %d birthday and anniversary days off", granted)
+		}
+	}
+	if err == nil {
+		rules := p.rules(run)
+		pack := p.rulePack
+		if run.RulePack != "" {
+			pack, err = validator.LookupPack(run.RulePack)
+		}
+		if err == nil {
+			run.RulePack = pack.Version
+			violations, err = validateWeeks(weeks, p.shifts, pack, rules)
+			err = withExitCode(exitValidation, err)
+		}
+		if err == nil {
+			err = scoreRun(run, weeks, p, violations, rules.ShiftTargets)
+		}
+	}
+	validate.setAttr("schedule.weeks", len(weeks))
+	validate.setAttr("schedule.violations", len(violations))
+	validate.finish(err)
+	if err != nil {
+		return nil, err
+	}
+	var failed []validator.Violation
+	for _, v := range violations {
+		log.Printf("Constraint violation: %s", v)
+		if v.Severity == validator.Error {
+			failed = append(failed, v)
+		}
+	}
+	run.Violations = len(violations)
+	run.Errors = len(failed)
+	if len(failed) > 0 && run.Evaluation == "" {
+		bus.publish(ValidationFailed{RunID: run.ID, Violations: failed})
+	}
+	return &validatedRun{p: p, weeks: weeks, violations: violations, notices: notices}, nil
+}
+
+// publish writes the validated schedule of a run as a new run folder and
+// marks the run as exported.
+func (v *validatedRun) publish(ctx context.Context, run *Run) error {
+	p := v.p
+	_, export := startSpan(ctx, "export")
+	formats, err := configuredExporters(p.cfg)
+	if err == nil {
+		err = writeRunFiles(run, v.weeks, formats, ExportOptions{Run: run.ID, Shifts: p.shifts, Violations: v.violations, Reports: p.cfg.Reports, Labor: p.labor, CSV: p.cfg.CSV})
+	}
+	if err == nil {
+		err = recordFairness(v.weeks, p.shifts, time.Now())
+	}
+	if err == nil {
+		err = appendAudits(v.notices)
+	}
+	export.setAttr("export.files", len(run.Files))
+	export.finish(err)
+	if err != nil {
+		return err
+	}
+
+	run.Stage = stageExported
+	if err := saveRun(run); err != nil {
+		return err
+	}
+	if p.cfg.Archive != "" {
+		path, err := archiveRun(run, p.cfg.Archive)
+		if err != nil {
+			return err
+		}
+		log.Printf("Run artifacts archived to %s", path)
+	}
+	bus.publish(ScheduleGenerated{RunID: run.ID, Folder: run.Folder, Files: run.Files, Violations: run.Violations})
+	return nil
+}
+
+// parseResponse extracts the JSON schedule from a model response written in
+// lang and groups its entries by week.
+func parseResponse(response string, lang language) (map[string][]FlatSchedule, error) {
+	// --- Clean and extract the JSON part ---
+	startIndex := strings.IndexAny(response, "[{")
+	if startIndex == -1 {
+		return nil, errors.New("no JSON array or object found in the response")
+	}
+	jsonPart := strings.Trim(response[startIndex:], " \n`")
+
+	// Group objects by week.
+	weeks, err := groupObjectsByWeek(jsonPart, lang)
+	if err != nil {
+		return nil, fmt.Errorf("error grouping objects by week: %w", err)
+	}
+	return weeks, nil
+}
+
+// validateWeeks checks a grouped schedule against the checks of a rule pack.
+func validateWeeks(weeks map[string][]FlatSchedule, shifts []schedule.Shift, pack validator.Pack, rules validator.Rules) ([]validator.Violation, error) {
+	var objs []map[string]string
+	for _, week := range weeks {
+		for _, obj := range week {
+			objs = append(objs, obj)
+		}
+	}
+	sch, err := schedule.Parse(objs, shifts)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schedule: %w", err)
+	}
+	return validator.NewPack(rules, pack).Validate(sch), nil
+}
+
+// scoreRun records the penalties of a run's schedule by objective and
+// where it sits between cost and coverage.
+func scoreRun(run *Run, weeks map[string][]FlatSchedule, p *policy, violations []validator.Violation, targets map[string]int) error {
+	var objs []map[string]string
+	for _, week := range weeks {
+		for _, obj := range week {
+			objs = append(objs, obj)
+		}
+	}
+	sch, err := schedule.Parse(objs, p.shifts)
+	if err != nil {
+		return fmt.Errorf("error parsing schedule: %w", err)
+	}
+	pen := schedulePenalties(sch, violations, targets)
+	t := scheduleTradeoff(sch, p.labor, targets)
+	run.Penalties, run.Tradeoff = &pen, &t
+	if stability, ok := run.Pattern.stability(sch); ok {
+		run.Stability = &stability
+		log.Printf("Kept the previous schedule's rhythm on %.0f%% of days", stability*100)
+	}
+	return nil
+}
+
+// writeRunFiles writes the schedule of a run in each of the given formats
+// and publishes the files as a new run folder under its output directory.
+func writeRunFiles(run *Run, weeks map[string][]FlatSchedule, formats []string, opts ExportOptions) error {
+	for _, objs := range weeks {
+		annotateHours(objs, opts.Shifts)
+	}
+	folder, names, err := publishRunFolder(run.outputDir(), run.ID, time.Now(), func(dir string) ([]string, error) {
+		var files []string
+		opts.Dir = dir
+		for _, format := range formats {
+			written, err := exporters[format].Write(weeks, opts)
+			if err != nil {
+				return nil, fmt.Errorf("error writing %s export: %w", format, err)
+			}
+			files = append(files, written...)
+		}
+		return files, nil
+	})
+	if err != nil {
+		return err
+	}
+	run.Folder = folder
+	run.Files = nil
+	for _, name := range names {
+		run.Files = append(run.Files, filepath.Join(folder, name))
+	}
+	log.Printf("Schedule published to %s (%d files, see %s)", folder, len(names), manifestName)
+	return nil
+}
+
+// runResume continues a run that stopped after the API call, reusing its
+// stored response.
+func runResume(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: resume <run-id>")
+	}
+	run, err := loadRun(args[0])
+	if err != nil {
+		return err
+	}
+	switch run.Stage {
+	case stageCreated:
+		return fmt.Errorf("run %s has no stored response; start a new generation instead", run.ID)
+	case stagePlanned:
+		return fmt.Errorf("run %s is planned; publish it with \"apply %s\"", run.ID, run.ID)
+	case stageEvaluated:
+		return fmt.Errorf("run %s was an evaluation and can't be published", run.ID)
+	case stageExported:
+		log.Printf("Run %s was already exported; writing its schedules again", run.ID)
+	}
+	if err := exportRun(context.Background(), run); err != nil {
+		return err
+	}
+	return checkErrors(run)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// scenario is a hypothetical change to the team: hires, departures by name
+// or count from a date on, and a different shift catalog.
+type scenario struct {
+	Add       int
+	RemoveFTE int
+	Remove    []string
+	From      time.Time
+	Shifts    []schedule.Shift
+}
+
+// scenarioKPIs are the coverage measures a scenario is compared on,
+// averaged over the forecast days.
+type scenarioKPIs struct {
+	// Staff is the employees at work per day, with five-day workweeks and
+	// recorded leave.
+	Staff float64
+	// CallsPerAgent is the calls per employee at work per day, overall and
+	// on high-volume days, counting only their productive time.
+	CallsPerAgent           float64
+	HighVolumeCallsPerAgent float64
+	// PeakCallsPerAgentHour is the busiest hour's calls per productive
+	// employee on shift then.
+	PeakCallsPerAgentHour float64
+	// UncoveredCalls is the share of calls in hours no shift covers.
+	UncoveredCalls float64
+	// ShortDays is the days with too few at work to staff every shift at
+	// its minimum.
+	ShortDays int
+}
+
+// staffOn returns how many of the team are at work on a date under the
+// scenario, before five-day workweeks are counted.
+func (s scenario) staffOn(team []string, leave []LeaveEntry, date time.Time) int {
+	staff := 0
+	changed := !date.Before(s.From)
+	for _, name := range team {
+		if (changed && slices.Contains(s.Remove, name)) || onLeave(leave, name, date) {
+			continue
+		}
+		staff++
+	}
+	if changed {
+		staff += s.Add - s.RemoveFTE
+	}
+	return max(staff, 0)
+}
+
+// kpis measures the coverage of a forecast under the scenario, with the
+// shrinkage taken off the time of those at work.
+func (s scenario) kpis(fc *forecast.Forecast, team []string, leave []LeaveEntry, shrinkage Shrinkage) scenarioKPIs {
+	var k scenarioKPIs
+	shares := schedule.DemandShares(fc.HourShare, s.Shifts)
+	minimum := len(s.Shifts) * validator.DefaultRules().MinPerShift
+	for h, share := range fc.HourShare {
+		if !slices.ContainsFunc(s.Shifts, func(sh schedule.Shift) bool { return shiftCovers(sh, h) }) {
+			k.UncoveredCalls += share
+		}
+	}
+	highVolume := 0
+	for _, day := range fc.Days {
+		date, err := time.Parse(forecast.DateLayout, day.Date)
+		if err != nil {
+			continue
+		}
+		working := float64(s.staffOn(team, leave, date)) * workDaysPerWeek / 7
+		k.Staff += working
+		if working < float64(minimum) {
+			k.ShortDays++
+		}
+		productive := working * shrinkage.productive()
+		if productive == 0 {
+			continue
+		}
+		k.CallsPerAgent += day.Calls / productive
+		if day.HighVolume {
+			k.HighVolumeCallsPerAgent += day.Calls / productive
+			highVolume++
+		}
+		for h, share := range fc.HourShare {
+			onShift := 0.0
+			for _, sh := range s.Shifts {
+				if shiftCovers(sh, h) {
+					onShift += shares[sh.Name] * productive
+				}
+			}
+			if onShift > 0 {
+				k.PeakCallsPerAgentHour = max(k.PeakCallsPerAgentHour, day.Calls*share/onShift)
+			}
+		}
+	}
+	if n := float64(len(fc.Days)); n > 0 {
+		k.Staff /= n
+		k.CallsPerAgent /= n
+	}
+	if highVolume > 0 {
+		k.HighVolumeCallsPerAgent /= float64(highVolume)
+	}
+	return k
+}
+
+// shiftCovers reports whether a shift covers an hour of the day, counted
+// as demand shares count it.
+func shiftCovers(sh schedule.Shift, hour int) bool {
+	minute := schedule.Clock(hour * 60)
+	return minute >= sh.Start && minute < sh.End
+}
+
+// describe writes the scenario's changes, e.g. "+2 FTE, without Ann from
+// 2025-06-01".
+func (s scenario) describe(baseline []schedule.Shift) string {
+	var parts []string
+	if s.Add > 0 {
+		parts = append(parts, fmt.Sprintf("+%d FTE", s.Add))
+	}
+	if s.RemoveFTE > 0 {
+		parts = append(parts, fmt.Sprintf("-%d FTE", s.RemoveFTE))
+	}
+	if len(s.Remove) > 0 {
+		parts = append(parts, "without "+strings.Join(s.Remove, ", "))
+	}
+	if len(parts) > 0 {
+		parts[len(parts)-1] += " from " + s.From.Format(time.DateOnly)
+	}
+	if !slices.Equal(s.Shifts, baseline) {
+		var names []string
+		for _, sh := range s.Shifts {
+			names = append(names, fmt.Sprintf("%s %s-%s", sh.Name, sh.Start, sh.End))
+		}
+		parts = append(parts, "shifts "+strings.Join(names, ", "))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runScenario compares the coverage of the team against a what-if of
+// hires, departures, or changed shifts over the forecast weeks.
+func runScenario(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := flag.NewFlagSet("scenario", flag.ContinueOnError)
+	var s scenario
+	fs.IntVar(&s.Add, "add-fte", 0, "employees hired")
+	fs.IntVar(&s.RemoveFTE, "remove-fte", 0, "employees leaving, not named")
+	remove := fs.String("remove", "", "comma-separated employees leaving")
+	from := fs.String("from", "", "day the headcount changes (2006-01-02, defaults to the forecast start)")
+	shiftsFile := fs.String("shifts", "", "JSON file of a shift catalog to try")
+	team := fs.String("team", "", "team to plan, from the roster (default everyone)")
+	forecastFile := fs.String("forecast", "", "forecast file to use instead of projecting the demand store")
+	weeks := fs.Int("weeks", forecastWeeks, "number of weeks to project")
+	start := fs.String("start", "", "first day of the projection (2006-01-02, defaults to next Monday)")
+	fs.StringVar(&opts.Model, "model", opts.Model, "forecast model: "+strings.Join(forecast.Names(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if s.Add < 0 || s.RemoveFTE < 0 {
+		return errors.New("add-fte and remove-fte can't be negative")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Shrinkage.validate(); err != nil {
+		return err
+	}
+	baseline, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	s.Shifts = baseline
+	if *shiftsFile != "" {
+		data, err := os.ReadFile(*shiftsFile)
+		if err != nil {
+			return fmt.Errorf("error reading shifts: %w", err)
+		}
+		alt := cfg
+		if err := json.Unmarshal(data, &alt.Shifts); err != nil {
+			return fmt.Errorf("error parsing shifts: %w", err)
+		}
+		if s.Shifts, err = shiftCatalog(alt); err != nil {
+			return err
+		}
+	}
+
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range roster {
+		if *team == "" || e.Team == *team {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no employees in the roster for this team")
+	}
+	if *remove != "" {
+		for _, name := range strings.Split(*remove, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("%s isn't on the team", name)
+			}
+			s.Remove = append(s.Remove, name)
+		}
+	}
+
+	fc, err := forecastOrProjection(*forecastFile, *start, *weeks, opts)
+	if err != nil {
+		return err
+	}
+	s.From, err = time.Parse(forecast.DateLayout, fc.Start)
+	if err != nil {
+		return fmt.Errorf("invalid forecast start: %w", err)
+	}
+	if *from != "" {
+		if s.From, err = time.Parse(time.DateOnly, *from); err != nil {
+			return fmt.Errorf("invalid -from date: %w", err)
+		}
+	}
+	leave, err := loadLeave()
+	if err != nil {
+		return err
+	}
+
+	base := scenario{From: s.From, Shifts: baseline}.kpis(fc, names, leave, cfg.Shrinkage)
+	what := s.kpis(fc, names, leave, cfg.Shrinkage)
+	log.Printf("Comparing %d employees over %d forecast days from %s with %s, %s shrinkage", len(names), len(fc.Days), fc.Start, s.describe(baseline), cfg.Shrinkage)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "KPI\tBASELINE\tSCENARIO\tCHANGE\t")
+	row := func(name string, b, w float64, format string) {
+		fmt.Fprintf(tw, "%s\t"+format+"\t"+format+"\t%+.1f%%\t\n", name, b, w, percentChange(b, w))
+	}
+	row("Staff at work per day", base.Staff, what.Staff, "%.1f")
+	row("Calls per agent per day", base.CallsPerAgent, what.CallsPerAgent, "%.1f")
+	row("On high-volume days", base.HighVolumeCallsPerAgent, what.HighVolumeCallsPerAgent, "%.1f")
+	row("Peak calls per agent-hour", base.PeakCallsPerAgentHour, what.PeakCallsPerAgentHour, "%.1f")
+	row("Calls outside shift hours (%)", base.UncoveredCalls*100, what.UncoveredCalls*100, "%.1f")
+	row("Days short of shift minimums", float64(base.ShortDays), float64(what.ShortDays), "%.0f")
+	return tw.Flush()
+}
+
+// percentChange returns the change from b to w in percent, or 0 when b is.
+func percentChange(b, w float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return (w - b) / math.Abs(b) * 100
+}
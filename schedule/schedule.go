@@ -0,0 +1,361 @@
+// Package schedule holds the typed model of a generated schedule and the
+// shift catalog it refers to.
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Off is the cell value of a day without a shift.
+const Off = "Off"
+
+// IsOff reports whether a cell is a day without a shift: empty, "Off", or
+// a day off with its reason, such as "Off (Birthday)".
+func IsOff(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, Off) || strings.HasPrefix(value, Off+" (")
+}
+
+// OffFor returns the cell of a day off granted for a reason.
+func OffFor(reason string) string {
+	return Off + " (" + reason + ")"
+}
+
+// OvertimePrefix marks a cell assigning a shift as overtime, such as
+// "OT Late".
+const OvertimePrefix = "OT "
+
+// IsOvertime reports whether a cell assigns its shift as overtime.
+func IsOvertime(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), OvertimePrefix)
+}
+
+// Clock is a time of day in minutes since midnight.
+type Clock int
+
+// ParseClock parses a 24-hour "15:04" time of day.
+func ParseClock(s string) (Clock, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return Clock(t.Hour()*60 + t.Minute()), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *Clock) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseClock(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Shift is one entry of the shift catalog.
+type Shift struct {
+	Name  string `json:"name"`
+	Start Clock  `json:"start"`
+	End   Clock  `json:"end"`
+}
+
+// Hours returns the length of the shift.
+func (s Shift) Hours() float64 {
+	return float64(s.End-s.Start) / 60
+}
+
+// DefaultShifts is the catalog used unless another one is configured.
+var DefaultShifts = []Shift{
+	{Name: "Early", Start: 6 * 60, End: 15 * 60},
+	{Name: "Normal", Start: 8 * 60, End: 17 * 60},
+	{Name: "Late", Start: 11 * 60, End: 20 * 60},
+}
+
+// ParseWindow parses hours written "07:00-16:00".
+func ParseWindow(s string) (Clock, Clock, error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid hours %q: want start-end", s)
+	}
+	start, err := ParseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := ParseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("invalid hours %q: end is not after start", s)
+	}
+	return start, end, nil
+}
+
+// FormatCell writes a shift cell with the employee's own hours, such as
+// "Early 07:00-16:00".
+func FormatCell(name string, start, end Clock) string {
+	return fmt.Sprintf("%s %s-%s", name, start, end)
+}
+
+// SplitCell separates a cell such as "Early 07:00-16:00" into the shift name
+// and the hours it gives. hours is empty when the cell names only a shift.
+// The overtime prefix is dropped.
+func SplitCell(value string) (name, hours string) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), OvertimePrefix)
+	if i := strings.LastIndex(value, " "); i >= 0 && strings.Contains(value[i+1:], ":") {
+		return strings.TrimSpace(value[:i]), value[i+1:]
+	}
+	return value, ""
+}
+
+// Day is one day of an employee's week.
+type Day struct {
+	Weekday time.Weekday
+	// Label is the column the day came from, e.g. "Monday (1st March)".
+	Label      string
+	DayOfMonth int
+	// Month is zero when the label names none.
+	Month time.Month
+	// Shift is the assigned shift name, empty when the employee is off.
+	Shift string
+	// Start and End are the employee's own hours when the cell gives them,
+	// as with staggered starts. Both are zero for the shift's own hours.
+	Start, End Clock
+	// Overtime is set for shifts taken as overtime.
+	Overtime bool
+}
+
+// Entry is one employee's week.
+type Entry struct {
+	Employee string
+	Week     int
+	Days     []Day // In calendar order.
+}
+
+// Schedule is a parsed schedule together with its shift catalog.
+type Schedule struct {
+	Shifts  []Shift
+	Entries []Entry
+}
+
+// Shift returns the catalog entry with the given name.
+func (s *Schedule) Shift(name string) (Shift, bool) {
+	for _, sh := range s.Shifts {
+		if sh.Name == name {
+			return sh, true
+		}
+	}
+	return Shift{}, false
+}
+
+// Hours returns the length of a day's shift, using the employee's own
+// hours when the day has them.
+func (s *Schedule) Hours(d Day) float64 {
+	if d.End > d.Start {
+		return float64(d.End-d.Start) / 60
+	}
+	if sh, ok := s.Shift(d.Shift); ok {
+		return sh.Hours()
+	}
+	return 0
+}
+
+// Employees returns the sorted names of everyone on the schedule.
+func (s *Schedule) Employees() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, e := range s.Entries {
+		if !seen[e.Employee] {
+			seen[e.Employee] = true
+			names = append(names, e.Employee)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Weeks returns the sorted week numbers present in the schedule.
+func (s *Schedule) Weeks() []int {
+	seen := make(map[int]bool)
+	var weeks []int
+	for _, e := range s.Entries {
+		if !seen[e.Week] {
+			seen[e.Week] = true
+			weeks = append(weeks, e.Week)
+		}
+	}
+	sort.Ints(weeks)
+	return weeks
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// weekdayIndex orders weekdays Monday first, as the schedules do.
+func weekdayIndex(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// ParseWeek reads a week number from a label such as "Week 3".
+func ParseWeek(label string) (int, error) {
+	fields := strings.Fields(label)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid week %q", label)
+	}
+	n, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid week %q", label)
+	}
+	return n, nil
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March,
+	"april": time.April, "may": time.May, "june": time.June,
+	"july": time.July, "august": time.August, "september": time.September,
+	"october": time.October, "november": time.November, "december": time.December,
+}
+
+// parseDayLabel reads the weekday and day of month from a column such as
+// "Monday (1st March)". It reports false for columns that are not days.
+func parseDayLabel(label string) (time.Weekday, int, bool) {
+	name, rest, _ := strings.Cut(label, " ")
+	weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, 0, false
+	}
+	digits := ""
+	for _, r := range rest {
+		if r >= '0' && r <= '9' {
+			digits += string(r)
+		} else if digits != "" {
+			break
+		}
+	}
+	day, _ := strconv.Atoi(digits)
+	return weekday, day, true
+}
+
+// LabelMonth reads the month from a day column such as "Monday (1st
+// March)". It reports false when the column names no month.
+func LabelMonth(label string) (time.Month, bool) {
+	_, rest, _ := strings.Cut(label, "(")
+	for _, field := range strings.Fields(strings.TrimSuffix(strings.TrimSpace(rest), ")")) {
+		if m, ok := months[strings.ToLower(field)]; ok {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// Parse converts flat schedule objects, one per employee and week as returned
+// by the model, into a Schedule. Cells naming a shift outside the catalog are
+// an error; anything else that isn't a shift counts as a day off.
+func Parse(objs []map[string]string, shifts []Shift) (*Schedule, error) {
+	s := &Schedule{Shifts: shifts}
+	known := make(map[string]bool, len(shifts))
+	for _, sh := range shifts {
+		known[sh.Name] = true
+	}
+
+	for _, obj := range objs {
+		employee := strings.TrimSpace(obj["Employee"])
+		if employee == "" {
+			return nil, fmt.Errorf("schedule entry without an employee: %v", obj)
+		}
+		week, err := ParseWeek(obj["Week"])
+		if err != nil {
+			return nil, fmt.Errorf("schedule entry for %s: %w", employee, err)
+		}
+		entry := Entry{Employee: employee, Week: week}
+		for key, value := range obj {
+			weekday, dayOfMonth, ok := parseDayLabel(key)
+			if !ok {
+				continue
+			}
+			day := Day{Weekday: weekday, Label: key, DayOfMonth: dayOfMonth}
+			day.Month, _ = LabelMonth(key)
+			overtime := IsOvertime(value)
+			value, hours := SplitCell(value)
+			switch {
+			case known[value]:
+				day.Shift, day.Overtime = value, overtime
+				if hours != "" {
+					if day.Start, day.End, err = ParseWindow(hours); err != nil {
+						return nil, fmt.Errorf("%s, Week %d, %s: %w", employee, week, key, err)
+					}
+				}
+			case IsOff(value):
+			default:
+				return nil, fmt.Errorf("%s, Week %d, %s: unknown shift %q", employee, week, key, value)
+			}
+			entry.Days = append(entry.Days, day)
+		}
+		sort.Slice(entry.Days, func(i, j int) bool {
+			return weekdayIndex(entry.Days[i].Weekday) < weekdayIndex(entry.Days[j].Weekday)
+		})
+		s.Entries = append(s.Entries, entry)
+	}
+
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		if s.Entries[i].Employee != s.Entries[j].Employee {
+			return s.Entries[i].Employee < s.Entries[j].Employee
+		}
+		return s.Entries[i].Week < s.Entries[j].Week
+	})
+	return s, nil
+}
+
+// DemandShares splits the calls of a day across the shifts by when they
+// arrive: each hour's share of calls is divided evenly between the shifts
+// covering that hour. Hours no shift covers are left out, so the shares add
+// up to one whenever any call falls inside a shift.
+func DemandShares(hourShare [24]float64, shifts []Shift) map[string]float64 {
+	shares := make(map[string]float64, len(shifts))
+	total := 0.0
+	for h, share := range hourShare {
+		minute := Clock(h * 60)
+		var covering []string
+		for _, sh := range shifts {
+			if minute >= sh.Start && minute < sh.End {
+				covering = append(covering, sh.Name)
+			}
+		}
+		for _, name := range covering {
+			shares[name] += share / float64(len(covering))
+		}
+		if len(covering) > 0 {
+			total += share
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	for name := range shares {
+		shares[name] /= total
+	}
+	return shares
+}
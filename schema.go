@@ -0,0 +1,213 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scheduleSchemaJSON is the JSON Schema of the canonical schedule format:
+// the model's response, schedule.json exports, and the rows of the weekly
+// CSV files.
+//
+//go:embed schemas/schedule.schema.json
+var scheduleSchemaJSON []byte
+
+// jsonSchema is the subset of JSON Schema the schedule schema uses.
+type jsonSchema struct {
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	PatternProperties    map[string]*jsonSchema `json:"patternProperties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             int                    `json:"minItems"`
+	MinLength            int                    `json:"minLength"`
+	Pattern              string                 `json:"pattern"`
+
+	re         *regexp.Regexp
+	patternRes map[string]*regexp.Regexp
+}
+
+// compile compiles the schema's patterns, and those of its subschemas.
+func (s *jsonSchema) compile() error {
+	var err error
+	if s.Pattern != "" {
+		if s.re, err = regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	s.patternRes = make(map[string]*regexp.Regexp, len(s.PatternProperties))
+	for pattern, prop := range s.PatternProperties {
+		if s.patternRes[pattern], err = regexp.Compile(pattern); err != nil {
+			return err
+		}
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+	for _, prop := range s.Properties {
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// scheduleSchema is the parsed schedule schema.
+var scheduleSchema = func() *jsonSchema {
+	var s jsonSchema
+	if err := json.Unmarshal(scheduleSchemaJSON, &s); err != nil {
+		panic(fmt.Sprintf("invalid schedule schema: %v", err))
+	}
+	if err := s.compile(); err != nil {
+		panic(fmt.Sprintf("invalid schedule schema: %v", err))
+	}
+	return &s
+}()
+
+// maxSchemaErrors caps how many problems a failed validation lists.
+const maxSchemaErrors = 10
+
+// validate checks a decoded JSON value against the schema and returns the
+// problems found, each prefixed with the JSON pointer of the value.
+func (s *jsonSchema) validate(path string, v any) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "/"
+		}
+		errs = append(errs, where+": "+fmt.Sprintf(format, args...))
+	}
+	switch s.Type {
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			fail("expected an array")
+			return errs
+		}
+		if len(items) < s.MinItems {
+			fail("expected at least %d items", s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				errs = append(errs, s.Items.validate(path+"/"+strconv.Itoa(i), item)...)
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("expected an object")
+			return errs
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				fail("missing %q", key)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+			matched := false
+			if prop, ok := s.Properties[key]; ok {
+				matched = true
+				errs = append(errs, prop.validate(child, obj[key])...)
+			}
+			for pattern, prop := range s.PatternProperties {
+				if s.patternRes[pattern].MatchString(key) {
+					matched = true
+					errs = append(errs, prop.validate(child, obj[key])...)
+				}
+			}
+			if !matched && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("unexpected property %q", key)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("expected a string")
+			return errs
+		}
+		if len([]rune(str)) < s.MinLength {
+			fail("expected at least %d characters", s.MinLength)
+		}
+		if s.re != nil && !s.re.MatchString(str) {
+			fail("%q does not match %s", str, s.Pattern)
+		}
+	}
+	return errs
+}
+
+// validateScheduleJSON checks schedule objects against the schedule schema.
+func validateScheduleJSON(entries []FlatSchedule) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return checkSchedule(v)
+}
+
+// checkSchedule checks a decoded JSON value against the schedule schema.
+func checkSchedule(v any) error {
+	errs := scheduleSchema.validate("", v)
+	if len(errs) == 0 {
+		return nil
+	}
+	more := ""
+	if len(errs) > maxSchemaErrors {
+		more = fmt.Sprintf("\n  ... and %d more", len(errs)-maxSchemaErrors)
+		errs = errs[:maxSchemaErrors]
+	}
+	return fmt.Errorf("schedule does not match the schedule schema:\n  %s%s", strings.Join(errs, "\n  "), more)
+}
+
+// runSchema prints the schedule schema, or checks a schedule JSON file
+// against it.
+func runSchema(args []string) error {
+	switch len(args) {
+	case 0:
+		_, err := os.Stdout.Write(scheduleSchemaJSON)
+		return err
+	case 1:
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("error reading schedule: %w", err)
+		}
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("error parsing schedule: %w", err)
+		}
+		if err := checkSchedule(v); err != nil {
+			return err
+		}
+		fmt.Printf("%s matches the schedule schema\n", args[0])
+		return nil
+	default:
+		return errors.New("usage: schema [schedule.json]")
+	}
+}
+
+func (s *server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(scheduleSchemaJSON)
+}
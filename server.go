@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// server exposes the job queue over HTTP.
+type server struct {
+	queue   *jobQueue
+	limiter *clientLimiter
+	checks  []readinessCheck
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGet)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("POST /jobs/{id}/approve", s.handleApprove)
+	mux.HandleFunc("GET /jobs/{id}/acknowledgments", s.handleAcknowledgments)
+	mux.HandleFunc("POST /jobs/{id}/acknowledgments", s.handleAcknowledge)
+	mux.HandleFunc("GET /inbox", s.handleInbox)
+	mux.HandleFunc("GET /coverage", s.handleCoverage)
+	mux.HandleFunc("GET /skills", s.handleSkills)
+	mux.HandleFunc("POST /leave/check", s.handleCheckLeave)
+	mux.HandleFunc("GET /calendar", s.handleCalendarFeeds)
+	mux.HandleFunc("GET /open-shifts", s.handleOpenShifts)
+	mux.HandleFunc("POST /open-shifts", s.handlePostOpenShifts)
+	mux.HandleFunc("POST /open-shifts/{id}/claim", s.handleClaimOpenShift)
+	mux.HandleFunc("GET /overtime", s.handleOvertimeOffers)
+	mux.HandleFunc("POST /overtime", s.handleOfferOvertime)
+	mux.HandleFunc("POST /overtime/{id}/accept", s.handleAcceptOvertime)
+	mux.HandleFunc("POST /overtime/{id}/close", s.handleCloseOvertime)
+
+	// Probes bypass client authentication so Kubernetes can call them.
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", s.handleHealthz)
+	root.HandleFunc("GET /readyz", s.handleReadyz)
+	// HR tools authenticate webhooks by signing them instead.
+	root.HandleFunc("POST /webhooks/hr", s.handleHRWebhook)
+	root.HandleFunc("GET /schema/schedule.json", s.handleSchema)
+	// Calendar apps authenticate feeds by the token in their URLs.
+	root.HandleFunc("GET /calendar/{kind}/{file}", s.handleCalendarFeed)
+	// Employees acknowledge schedules and accept overtime by signed links
+	// and Slack buttons.
+	root.HandleFunc("GET /ack/{id}/{employee}", s.handleAckLink)
+	root.HandleFunc("GET /overtime/{id}/accept/{employee}", s.handleAcceptOvertimeLink)
+	root.HandleFunc("POST /overtime/{id}/accept/{employee}", s.handleConfirmOvertimeLink)
+	root.HandleFunc("POST /slack/actions", s.handleSlackActions)
+	root.Handle("/", s.limiter.middleware(mux))
+	return root
+}
+
+// maxServerRepairs caps the repair rounds a job submitted to the server
+// may ask for.
+const maxServerRepairs = 3
+
+// handleSubmit queues a generation job. The body may override any of the
+// default generation options.
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	opts := defaultGenerateOptions()
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid job request: %v", err))
+			return
+		}
+	}
+	if err := checkGenerateOptions(opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.TargetScale < 0 {
+		writeError(w, http.StatusBadRequest, "target_scale can't be negative")
+		return
+	}
+	if opts.Repairs > maxServerRepairs {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("repairs can't be above %d", maxServerRepairs))
+		return
+	}
+	// Every repair round is another model call, so it counts against the
+	// quota like a generation. The quota is counted before queueing, so
+	// concurrent requests can't overrun it, and given back when the job
+	// can't be queued.
+	cost := 1
+	if opts.Solver != solverLocal {
+		cost += opts.Repairs
+	}
+	client, month := "", ""
+	c := clientFromContext(r.Context())
+	if c != nil {
+		charged, ok, err := s.limiter.useQuota(c, cost)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusTooManyRequests, "monthly generation quota exhausted")
+			return
+		}
+		client, month = c.Name, charged
+	}
+	job, err := s.queue.submit(opts, client)
+	if err != nil {
+		if c != nil {
+			if err := s.limiter.refundQuota(c, cost, month); err != nil {
+				log.Printf("Error refunding %s's quota: %v", c.Name, err)
+			}
+		}
+		if errors.Is(err, errQueueFull) || errors.Is(err, errShuttingDown) {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.list())
+}
+
+func (s *server) handleGet(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.queue.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	found, err := s.queue.cancel(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	job, _ := s.queue.get(r.PathValue("id"))
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	by := ""
+	if c := clientFromContext(r.Context()); c != nil {
+		by = c.Name
+	}
+	found, err := s.queue.approve(r.PathValue("id"), by)
+	if !found {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	job, _ := s.queue.get(r.PathValue("id"))
+	writeJSON(w, http.StatusOK, job)
+}
+
+// runServe starts the HTTP server with its job queue.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	concurrency := fs.Int("concurrency", 2, "number of jobs generated at the same time")
+	capacity := fs.Int("queue-size", 100, "maximum number of queued jobs")
+	drainTimeout := fs.Duration("drain-timeout", 2*time.Minute, "how long to let running jobs finish on shutdown")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	queue, err := newJobQueue(*capacity)
+	if err != nil {
+		return err
+	}
+
+	clients, err := loadClients()
+	if err != nil {
+		return err
+	}
+	limiter, err := newClientLimiter(clients)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	queue.start(*concurrency)
+
+	// Ship spans of finished jobs as they complete rather than at exit.
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				flushTracing(ctx)
+			}
+		}
+	}()
+
+	// Remind planners of schedules nearing their publish deadline, and
+	// employees of their upcoming shifts, and decide seniority open shifts.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cfg, err := loadConfig()
+				if err != nil {
+					log.Printf("Error loading reminders: %v", err)
+					continue
+				}
+				queue.remindDeadlines(cfg, time.Now())
+				queue.remindShifts(cfg, time.Now())
+				closeOpenShifts(time.Now())
+			}
+		}
+	}()
+
+	// Keep approved leave current from the HRIS.
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := checkNotifications(cfg); err != nil {
+		return err
+	}
+	if cfg.HRIS != nil {
+		go syncLeaveEvery(ctx, time.Duration(hrisSettings(cfg).SyncMinutes)*time.Minute)
+	}
+	// Purge raw call records past the retention period.
+	if cfg.Demand.RetentionMonths > 0 {
+		go compactDemandEvery(ctx)
+	}
+
+	s := &server{
+		queue:   queue,
+		limiter: limiter,
+		checks: []readinessCheck{
+			{name: "database", check: checkStore},
+			{name: "storage", check: checkStorageWritable},
+			{name: "queue", check: func(context.Context) error {
+				if queue.isDraining() {
+					return errShuttingDown
+				}
+				return nil
+			}},
+		},
+	}
+	// Offline servers schedule with the local solver and never reach
+	// OpenAI.
+	if !offline {
+		s.checks = append(s.checks, readinessCheck{name: "openai", check: (&openAIChecker{ttl: 30 * time.Second, client: &http.Client{Timeout: 4 * time.Second}}).check})
+	}
+	srv := &http.Server{Addr: *addr, Handler: s.routes()}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s with %d workers", *addr, *concurrency)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Keep answering status requests while running jobs drain; new jobs are
+	// refused and /readyz fails so traffic moves elsewhere.
+	log.Printf("Shutting down, draining running jobs for up to %s", *drainTimeout)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), *drainTimeout)
+	queue.shutdown(drainCtx)
+	cancelDrain()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+	flushTracing(shutdownCtx)
+	log.Printf("Shutdown complete")
+	return nil
+}
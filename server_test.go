@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleSubmitQuota(t *testing.T) {
+	t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+	t.Setenv("SCHEDULER_CONFIG", "")
+	client := &APIClient{Name: "acme", Token: "t", MonthlyQuota: 3}
+	limiter, err := newClientLimiter([]APIClient{*client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The queue holds one job, and no worker takes it.
+	queue, err := newJobQueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{queue: queue, limiter: limiter}
+	month := limiter.now().UTC().Format("2006-01")
+
+	// The steps run in order against the same queue and quota.
+	steps := []struct {
+		name string
+		body string
+		want int
+		used int
+	}{
+		{name: "queued", body: `{}`, want: http.StatusAccepted, used: 1},
+		{name: "queue full", body: `{}`, want: http.StatusServiceUnavailable, used: 1},
+		{name: "invalid request", body: `{"percentile": 0}`, want: http.StatusBadRequest, used: 1},
+		{name: "too many repairs", body: `{"repairs": 4}`, want: http.StatusBadRequest, used: 1},
+		{name: "repairs past the quota", body: `{"repairs": 2}`, want: http.StatusTooManyRequests, used: 1},
+		{name: "repairs counted", body: `{"repairs": 1}`, want: http.StatusServiceUnavailable, used: 1},
+		{name: "local solver needs no repairs", body: `{"repairs": 3, "solver": "local"}`, want: http.StatusServiceUnavailable, used: 1},
+	}
+	for _, st := range steps {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(st.body))
+		req = req.WithContext(context.WithValue(req.Context(), clientContextKey{}, client))
+		rec := httptest.NewRecorder()
+		s.handleSubmit(rec, req)
+		if rec.Code != st.want {
+			t.Errorf("%s: status %d, want %d: %s", st.name, rec.Code, st.want, rec.Body)
+		}
+		if got := limiter.usage[client.Name][month]; got != st.used {
+			t.Errorf("%s: %d generations counted, want %d", st.name, got, st.used)
+		}
+	}
+}
+
+func TestRefundQuotaAcrossMonths(t *testing.T) {
+	tests := []struct {
+		name     string
+		charged  time.Time
+		refunded time.Time
+		usedJan  int
+		usedFeb  int
+		wantJan  int
+		wantFeb  int
+	}{
+		{name: "same month", charged: time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC), refunded: time.Date(2026, 1, 20, 12, 0, 1, 0, time.UTC), usedJan: 1, wantJan: 1},
+		{name: "month turned", charged: time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC), refunded: time.Date(2026, 2, 1, 0, 0, 1, 0, time.UTC), usedJan: 1, usedFeb: 2, wantJan: 1, wantFeb: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCHEDULER_DATA_DIR", t.TempDir())
+			t.Setenv("SCHEDULER_CONFIG", "")
+			client := &APIClient{Name: "acme", Token: "t", MonthlyQuota: 10}
+			limiter, err := newClientLimiter([]APIClient{*client})
+			if err != nil {
+				t.Fatal(err)
+			}
+			limiter.usage[client.Name] = map[string]int{"2026-01": tt.usedJan, "2026-02": tt.usedFeb}
+			limiter.now = func() time.Time { return tt.charged }
+			month, ok, err := limiter.useQuota(client, 3)
+			if err != nil || !ok {
+				t.Fatalf("useQuota: %v, %v", ok, err)
+			}
+			limiter.now = func() time.Time { return tt.refunded }
+			if err := limiter.refundQuota(client, 3, month); err != nil {
+				t.Fatal(err)
+			}
+			if got := limiter.usage[client.Name]["2026-01"]; got != tt.wantJan {
+				t.Errorf("January: %d generations counted, want %d", got, tt.wantJan)
+			}
+			if got := limiter.usage[client.Name]["2026-02"]; got != tt.wantFeb {
+				t.Errorf("February: %d generations counted, want %d", got, tt.wantFeb)
+			}
+		})
+	}
+}
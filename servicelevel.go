@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// maxAgents bounds the staffing search for an interval.
+const maxAgents = 1000
+
+// ServiceLevelConfig is a service-level target such as answering 80% of
+// calls within 20 seconds, and a cap on agent occupancy. When set, shift
+// targets are the agents the targets need instead of each shift's share of
+// the employees.
+type ServiceLevelConfig struct {
+	// Target is the share of calls to answer within Seconds, e.g. 0.8.
+	Target  float64 `json:"target,omitempty"`
+	Seconds float64 `json:"seconds,omitempty"`
+	// MaxOccupancy caps the share of their time agents spend on calls,
+	// e.g. 0.85, so they aren't run back to back all day.
+	MaxOccupancy float64 `json:"max_occupancy,omitempty"`
+	// HandleTime is the average handle time in seconds, used when the
+	// forecast has none from the call records.
+	HandleTime float64 `json:"handle_time,omitempty"`
+	// IntervalMinutes is the length of the intervals staffing is sized
+	// for, 60 or 30. Defaults to 60.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+}
+
+func (sl ServiceLevelConfig) enabled() bool {
+	return sl.Target > 0 || sl.MaxOccupancy > 0
+}
+
+// validate checks that the target can be met at all.
+func (sl ServiceLevelConfig) validate() error {
+	if sl.Target >= 1 {
+		return errors.New("service level target must be below 1, e.g. 0.8 for 80%")
+	}
+	if sl.Target > 0 && sl.Seconds <= 0 {
+		return errors.New("service level seconds must be positive")
+	}
+	if sl.MaxOccupancy < 0 || sl.MaxOccupancy > 1 {
+		return errors.New("max occupancy must be a share from 0 to 1, e.g. 0.85 for 85%")
+	}
+	if sl.IntervalMinutes != 0 && sl.IntervalMinutes != 30 && sl.IntervalMinutes != 60 {
+		return errors.New("service level interval_minutes must be 30 or 60")
+	}
+	return nil
+}
+
+// interval returns the length of the staffing intervals in minutes.
+func (sl ServiceLevelConfig) interval() int {
+	if sl.IntervalMinutes == 30 {
+		return 30
+	}
+	return 60
+}
+
+// String describes the targets, e.g. "80% answered within 20 s, at most
+// 85% occupancy".
+func (sl ServiceLevelConfig) String() string {
+	var parts []string
+	if sl.Target > 0 {
+		parts = append(parts, fmt.Sprintf("%g%% answered within %g s", sl.Target*100, sl.Seconds))
+	}
+	if sl.MaxOccupancy > 0 {
+		parts = append(parts, fmt.Sprintf("at most %g%% occupancy", sl.MaxOccupancy*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleTime returns the average handle time of a forecast, falling back to
+// the configured one.
+func (sl ServiceLevelConfig) handleTime(fc *forecast.Forecast) float64 {
+	if fc != nil && fc.HandleTime > 0 {
+		return fc.HandleTime
+	}
+	return sl.HandleTime
+}
+
+// erlangC returns the probability that a call waits, with agents serving a
+// traffic of erlangs.
+func erlangC(agents int, erlangs float64) float64 {
+	// Erlang B by its recurrence, which stays stable for large groups.
+	b := 1.0
+	for n := 1; n <= agents; n++ {
+		b = erlangs * b / (float64(n) + erlangs*b)
+	}
+	return float64(agents) * b / (float64(agents) - erlangs*(1-b))
+}
+
+// serviceLevel returns the share of calls answered within the target
+// seconds, for calls per hour with a handle time in seconds.
+func (sl ServiceLevelConfig) serviceLevel(agents int, calls, handleTime float64) float64 {
+	erlangs := calls * handleTime / 3600
+	if float64(agents) <= erlangs {
+		return 0
+	}
+	return 1 - erlangC(agents, erlangs)*math.Exp(-(float64(agents)-erlangs)*sl.Seconds/handleTime)
+}
+
+// occupancy returns the share of their time agents spend on calls.
+func occupancy(agents int, calls, handleTime float64) float64 {
+	if agents == 0 {
+		return 0
+	}
+	return min(calls*handleTime/3600/float64(agents), 1)
+}
+
+// agents returns the fewest agents meeting the targets for calls per hour.
+func (sl ServiceLevelConfig) agents(calls, handleTime float64) int {
+	if calls <= 0 || handleTime <= 0 {
+		return 0
+	}
+	erlangs := calls * handleTime / 3600
+	n := max(int(erlangs), 1)
+	if sl.MaxOccupancy > 0 {
+		// Round before ceiling so 8.5 erlangs at 85% stays 10.
+		n = max(n, int(math.Ceil(math.Round(erlangs/sl.MaxOccupancy*1e6)/1e6)))
+	}
+	for ; sl.Target > 0 && n < maxAgents; n++ {
+		if sl.serviceLevel(n, calls, handleTime) >= sl.Target {
+			return n
+		}
+	}
+	return min(n, maxAgents)
+}
+
+// intervalShares returns the share of a day's calls arriving in each
+// interval of the given minutes. Without half-hour shares in the forecast,
+// each half hour gets half of its hour's.
+func intervalShares(fc *forecast.Forecast, minutes int) []float64 {
+	if minutes == 60 {
+		return fc.HourShare[:]
+	}
+	if len(fc.HalfHourShare) == 48 {
+		return fc.HalfHourShare
+	}
+	shares := make([]float64, 48)
+	for h, share := range fc.HourShare {
+		shares[2*h], shares[2*h+1] = share/2, share/2
+	}
+	return shares
+}
+
+// intervalAgents returns the agents each interval of a day with calls
+// needs.
+func (sl ServiceLevelConfig) intervalAgents(calls float64, fc *forecast.Forecast) []int {
+	shares := intervalShares(fc, sl.interval())
+	perHour := float64(60 / sl.interval())
+	aht := sl.handleTime(fc)
+	need := make([]int, len(shares))
+	for i, share := range shares {
+		need[i] = sl.agents(calls*share*perHour, aht)
+	}
+	return need
+}
+
+// designCalls is the daily volume staffing is sized for: the high-volume
+// threshold, so days up to the forecast's percentile meet the target, or
+// the average day when the forecast has no threshold.
+func designCalls(fc *forecast.Forecast) float64 {
+	if fc.Threshold > 0 {
+		return fc.Threshold
+	}
+	total := 0.0
+	for _, d := range fc.Days {
+		total += d.Calls
+	}
+	if len(fc.Days) == 0 {
+		return 0
+	}
+	return total / float64(len(fc.Days))
+}
+
+// coverIntervals turns the requirements of the intervals of a day into
+// shift headcounts: interval by interval, any shortfall goes to the shift
+// on then that runs latest, so it also covers the intervals after. Every
+// shift gets at least min.
+func coverIntervals(need []int, shifts []schedule.Shift, min int) map[string]int {
+	targets := make(map[string]int, len(shifts))
+	for _, sh := range shifts {
+		targets[sh.Name] = min
+	}
+	length := 24 * 60 / len(need)
+	for t, n := range need {
+		onShift, latest := 0, -1
+		start := schedule.Clock(t * length)
+		for i, sh := range shifts {
+			if start >= sh.Start && start < sh.End {
+				onShift += targets[sh.Name]
+				if latest < 0 || sh.End > shifts[latest].End {
+					latest = i
+				}
+			}
+		}
+		if latest >= 0 && n > onShift {
+			targets[shifts[latest].Name] += n - onShift
+		}
+	}
+	return targets
+}
+
+// scheduledAgents grosses the agents each interval needs up for shrinkage.
+func scheduledAgents(need []int, shrinkage Shrinkage) []int {
+	scheduled := make([]int, len(need))
+	for i, n := range need {
+		scheduled[i] = shrinkage.grossUp(n)
+	}
+	return scheduled
+}
+
+// shiftTargets returns the headcount of each shift meeting the target on
+// the design day of a forecast, grossed up for shrinkage.
+func (sl ServiceLevelConfig) shiftTargets(fc *forecast.Forecast, shifts []schedule.Shift, shrinkage Shrinkage) map[string]int {
+	if fc == nil || sl.handleTime(fc) <= 0 {
+		return nil
+	}
+	need := scheduledAgents(sl.intervalAgents(designCalls(fc), fc), shrinkage)
+	return coverIntervals(need, shifts, validator.DefaultRules().MinPerShift)
+}
+
+// serviceLevelNote describes the targets for the scheduling prompt.
+func serviceLevelNote(sl ServiceLevelConfig, shrinkage Shrinkage, shifts []schedule.Shift, targets map[string]int) string {
+	var parts []string
+	for _, sh := range shifts {
+		parts = append(parts, fmt.Sprintf("%d on the %s Shift", targets[sh.Name], sh.Name))
+	}
+	allowing := ""
+	if len(shrinkage) > 0 {
+		allowing = fmt.Sprintf(" allowing for %s shrinkage,", shrinkage)
+	}
+	return fmt.Sprintf("Service level: for %s,%s schedule at least ", sl, allowing) +
+		strings.Join(parts, ", ") + " every day, more on high-volume days where possible."
+}
+
+// runStaffing prints the agents the service-level target needs per interval
+// of a forecast's design day, and the shift headcounts covering them.
+func runStaffing(args []string) error {
+	opts := defaultGenerateOptions()
+	fs := flag.NewFlagSet("staffing", flag.ContinueOnError)
+	forecastFile := fs.String("forecast", "", "forecast file to use instead of projecting the demand store")
+	weeks := fs.Int("weeks", forecastWeeks, "number of weeks to project")
+	start := fs.String("start", "", "first day of the projection (2006-01-02, defaults to next Monday)")
+	fs.StringVar(&opts.Model, "model", opts.Model, "forecast model: "+strings.Join(forecast.Names(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	sl := cfg.ServiceLevel
+	if !sl.enabled() {
+		return fmt.Errorf("no service_level in %s", configPath())
+	}
+	if err := cfg.Shrinkage.validate(); err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	fc, err := forecastOrProjection(*forecastFile, *start, *weeks, opts)
+	if err != nil {
+		return err
+	}
+	aht := sl.handleTime(fc)
+	if aht <= 0 {
+		return errors.New("no handle time in the forecast or service_level.handle_time")
+	}
+
+	calls := designCalls(fc)
+	need := sl.intervalAgents(calls, fc)
+	scheduled := scheduledAgents(need, cfg.Shrinkage)
+	fmt.Printf("Sized for a day of %.0f calls, %.0f s handle time, %s", calls, aht, sl)
+	if len(cfg.Shrinkage) > 0 {
+		fmt.Printf(", %s shrinkage", cfg.Shrinkage)
+	}
+	fmt.Print("\n\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	shares := intervalShares(fc, sl.interval())
+	perHour := float64(60 / sl.interval())
+	fmt.Fprintln(tw, "Interval\tCalls\tAgents\tService level\tOccupancy\tScheduled\t")
+	for i, n := range need {
+		if shares[i] == 0 {
+			continue
+		}
+		interval := calls * shares[i]
+		hourly := interval * perHour
+		fmt.Fprintf(tw, "%s\t%.1f\t%d\t%.1f%%\t%.1f%%\t%d\t\n", schedule.Clock(i*sl.interval()), interval, n, sl.serviceLevel(n, hourly, aht)*100, occupancy(n, hourly, aht)*100, scheduled[i])
+	}
+	tw.Flush()
+	fmt.Println()
+	targets := coverIntervals(scheduled, shifts, validator.DefaultRules().MinPerShift)
+	for _, sh := range shifts {
+		fmt.Printf("%s %s-%s: %d\n", sh.Name, sh.Start, sh.End, targets[sh.Name])
+	}
+	return nil
+}
+
+// forecastOrProjection loads a forecast file, or projects the demand store
+// over weeks from start when no file is given.
+func forecastOrProjection(file, start string, weeks int, opts generateOptions) (*forecast.Forecast, error) {
+	if file != "" {
+		return forecast.Load(file)
+	}
+	first := nextMonday(time.Now())
+	if start != "" {
+		var err error
+		if first, err = time.Parse(forecast.DateLayout, start); err != nil {
+			return nil, fmt.Errorf("invalid -start date: %w", err)
+		}
+	}
+	agg, err := loadDemandAggregates()
+	if err != nil {
+		return nil, err
+	}
+	if agg.Rows == 0 {
+		return nil, errors.New("the demand store is empty; pass -forecast")
+	}
+	return projectDemand(agg, opts.Model, first, weeks, opts.Percentile)
+}
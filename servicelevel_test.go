@@ -0,0 +1,134 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"testing"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+)
+
+func TestErlangC(t *testing.T) {
+	// Published Erlang C tables give these for 10 erlangs.
+	tests := []struct {
+		agents int
+		want   float64
+	}{
+		{agents: 11, want: 0.6821},
+		{agents: 12, want: 0.4494},
+		{agents: 13, want: 0.2853},
+		{agents: 15, want: 0.1020},
+	}
+	for _, tt := range tests {
+		if got := erlangC(tt.agents, 10); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("erlangC(%d, 10) = %.4f, want %.4f", tt.agents, got, tt.want)
+		}
+	}
+}
+
+func TestServiceLevelAgents(t *testing.T) {
+	tests := []struct {
+		name       string
+		sl         ServiceLevelConfig
+		calls      float64
+		handleTime float64
+		want       int
+	}{
+		// 200 calls an hour of 3 minutes are 10 erlangs; 13 agents answer
+		// 79.6% within 20 seconds and 14 answer 88.8%.
+		{name: "80/20", sl: ServiceLevelConfig{Target: 0.8, Seconds: 20}, calls: 200, handleTime: 180, want: 14},
+		{name: "90/20", sl: ServiceLevelConfig{Target: 0.9, Seconds: 20}, calls: 200, handleTime: 180, want: 15},
+		{name: "no calls", sl: ServiceLevelConfig{Target: 0.8, Seconds: 20}, handleTime: 180},
+		{name: "no handle time", sl: ServiceLevelConfig{Target: 0.8, Seconds: 20}, calls: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sl.agents(tt.calls, tt.handleTime); got != tt.want {
+				t.Errorf("agents = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccupancyCap(t *testing.T) {
+	tests := []struct {
+		name  string
+		sl    ServiceLevelConfig
+		calls float64
+		want  int
+	}{
+		// 170 calls of 3 minutes are 8.5 erlangs, which 10 agents take at
+		// exactly 85% occupancy.
+		{name: "occupancy only", sl: ServiceLevelConfig{MaxOccupancy: 0.85}, calls: 170, want: 10},
+		{name: "occupancy above the target", sl: ServiceLevelConfig{Target: 0.8, Seconds: 20, MaxOccupancy: 0.7}, calls: 200, want: 15},
+		{name: "target above the occupancy", sl: ServiceLevelConfig{Target: 0.8, Seconds: 20, MaxOccupancy: 0.95}, calls: 200, want: 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := tt.sl.agents(tt.calls, 180)
+			if n != tt.want {
+				t.Errorf("agents = %d, want %d", n, tt.want)
+			}
+			if occ := occupancy(n, tt.calls, 180); occ > tt.sl.MaxOccupancy+1e-9 {
+				t.Errorf("occupancy %.3f above the %.2f cap", occ, tt.sl.MaxOccupancy)
+			}
+		})
+	}
+}
+
+func TestIntervalShares(t *testing.T) {
+	fc := &forecast.Forecast{}
+	fc.HourShare[9] = 0.2
+	tests := []struct {
+		name     string
+		halfHour []float64
+		minutes  int
+		index    int
+		want     float64
+	}{
+		{name: "hours", minutes: 60, index: 9, want: 0.2},
+		{name: "half hours split from hours", minutes: 30, index: 19, want: 0.1},
+		{name: "half hours from the forecast", halfHour: func() []float64 { s := make([]float64, 48); s[19] = 0.15; return s }(), minutes: 30, index: 19, want: 0.15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc.HalfHourShare = tt.halfHour
+			shares := intervalShares(fc, tt.minutes)
+			if len(shares) != 24*60/tt.minutes {
+				t.Fatalf("%d intervals, want %d", len(shares), 24*60/tt.minutes)
+			}
+			if shares[tt.index] != tt.want {
+				t.Errorf("share %g, want %g", shares[tt.index], tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverIntervals(t *testing.T) {
+	tests := []struct {
+		name string
+		need map[int]int
+		want map[string]int
+	}{
+		{name: "quiet day", want: map[string]int{"Early": 2, "Normal": 2, "Late": 2}},
+		{
+			// At 07:00 only Early is on; at 12:00 all three are and Late
+			// runs latest; at 18:00 only Late is on.
+			name: "peaks",
+			need: map[int]int{7: 3, 12: 8, 18: 4},
+			want: map[string]int{"Early": 3, "Normal": 2, "Late": 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			need := make([]int, 24)
+			for h, n := range tt.need {
+				need[h] = n
+			}
+			if got := coverIntervals(need, schedule.DefaultShifts, 2); !maps.Equal(got, tt.want) {
+				t.Errorf("targets %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// workDaysPerWeek is how many days an employee works in a week, given the
+// two days off the prompt asks for.
+const workDaysPerWeek = 5
+
+// shiftTargets allocates the employees working on an average day across the
+// shifts by their share of demand, keeping at least min on every shift.
+func shiftTargets(shifts []schedule.Shift, shares map[string]float64, employees, min int) map[string]int {
+	if len(shares) == 0 || employees == 0 {
+		return nil
+	}
+	working := float64(employees) * workDaysPerWeek / 7
+	targets := make(map[string]int, len(shares))
+	for _, sh := range shifts {
+		n := int(math.Round(shares[sh.Name] * working))
+		if n < min {
+			n = min
+		}
+		targets[sh.Name] = n
+	}
+	return targets
+}
+
+// forecastShiftTargets returns the per-shift headcount targets of a
+// forecast. The shift shares are derived again from the hourly profile when
+// the forecast was made for a different shift catalog.
+func forecastShiftTargets(fc *forecast.Forecast, shifts []schedule.Shift, employees int) map[string]int {
+	if fc == nil {
+		return nil
+	}
+	shares := fc.ShiftShare
+	for _, sh := range shifts {
+		if _, ok := shares[sh.Name]; !ok {
+			shares = schedule.DemandShares(fc.HourShare, shifts)
+			break
+		}
+	}
+	return shiftTargets(shifts, shares, employees, validator.DefaultRules().MinPerShift)
+}
+
+// shiftTargetsNote describes the targets for the scheduling prompt.
+func shiftTargetsNote(shifts []schedule.Shift, targets map[string]int) string {
+	var parts []string
+	for _, sh := range shifts {
+		parts = append(parts, fmt.Sprintf("%d on the %s Shift", targets[sh.Name], sh.Name))
+	}
+	return "Staffing by demand: of the employees working each day, schedule about " + strings.Join(parts, ", ") +
+		", following the share of calls each shift handles."
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Channels shift reminders are sent through.
+const (
+	channelSlack = "slack"
+	channelEmail = "email"
+	channelSMS   = "sms"
+)
+
+// ShiftReminderConfig sets when employees are reminded of their upcoming
+// shifts in server mode.
+type ShiftReminderConfig struct {
+	// HoursBefore lists how long before each shift reminders go out, such
+	// as [12, 1]. Shift reminders are off when empty.
+	HoursBefore []int `json:"hours_before,omitempty"`
+	// Channels limits reminders to some of slack, email, and sms. Empty
+	// means every channel the employee has contact details for.
+	Channels []string `json:"channels,omitempty"`
+}
+
+// channels returns the channels an employee is reminded through.
+func (sc ShiftReminderConfig) channels(e Employee) []string {
+	var out []string
+	for channel, contact := range map[string]string{channelSlack: e.SlackWebhook, channelEmail: e.Email, channelSMS: e.Phone} {
+		if contact != "" && (len(sc.Channels) == 0 || slices.Contains(sc.Channels, channel)) {
+			out = append(out, channel)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func shiftRemindersPath() string {
+	return filepath.Join(dataDir(), "shift_reminders.json")
+}
+
+// loadSentShiftReminders reads the reminders already sent, keyed by
+// shiftReminderKey, with the start of the shift each was for.
+func loadSentShiftReminders() (map[string]time.Time, error) {
+	sent := make(map[string]time.Time)
+	data, err := os.ReadFile(shiftRemindersPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sent, nil
+		}
+		return nil, fmt.Errorf("error reading shift reminders: %w", err)
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return nil, fmt.Errorf("error parsing shift reminders: %w", err)
+	}
+	return sent, nil
+}
+
+// saveSentShiftReminders writes the sent reminders, replacing the file
+// atomically.
+func saveSentShiftReminders(sent map[string]time.Time) error {
+	data, err := json.MarshalIndent(sent, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := shiftRemindersPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing shift reminders: %w", err)
+	}
+	return os.Rename(tmp, shiftRemindersPath())
+}
+
+// shiftReminderKey identifies one reminder of one shift on one channel. The
+// shift's name is part of it, so a shift changed by a newer schedule is
+// reminded again.
+func shiftReminderKey(s exportedShift, hoursBefore int, channel string) string {
+	return s.Employee + "|" + s.Shift + "|" + s.Start.Format("2006-01-02T15:04") + "|" + strconv.Itoa(hoursBefore) + "|" + channel
+}
+
+// shiftReminderText writes the reminder of a shift, e.g. "Your Early shift
+// starts in 12 hours, at 06:00 on Tuesday 10 March", followed by the
+// shift's handover note, if any.
+func shiftReminderText(s exportedShift, left time.Duration) string {
+	in := fmt.Sprintf("%d hours", int(left.Round(time.Hour)/time.Hour))
+	switch {
+	case left < time.Hour:
+		in = fmt.Sprintf("%d minutes", int(left.Round(time.Minute)/time.Minute))
+	case left < 90*time.Minute:
+		in = "1 hour"
+	}
+	text := fmt.Sprintf("Your %s shift starts in %s, at %s on %s", s.Shift, in, s.Start.Format("15:04"), s.Start.Format("Monday 2 January"))
+	if s.Note != "" {
+		text += ". Handover note: " + s.Note
+	}
+	return text
+}
+
+// remindShifts sends the shift reminders that are due, from the published
+// schedules. Each reminder is sent once per shift and channel, and reminders
+// falling due together, such as after a restart, send one message. When a
+// delivery fails it is tried again on the next pass.
+func (q *jobQueue) remindShifts(cfg Config, now time.Time) {
+	sc := cfg.ShiftReminders
+	if len(sc.HoursBefore) == 0 {
+		return
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		log.Printf("Error loading roster for shift reminders: %v", err)
+		return
+	}
+	members := make(map[string]bool)
+	byName := make(map[string]Employee)
+	for _, e := range roster {
+		if !e.NoShiftReminders && len(sc.channels(e)) > 0 {
+			members[e.Name] = true
+			byName[e.Name] = e
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+	shifts, err := q.publishedShifts(members, now)
+	if err != nil {
+		log.Printf("Error loading shifts for reminders: %v", err)
+		return
+	}
+	sent, err := loadSentShiftReminders()
+	if err != nil {
+		log.Printf("Error loading shift reminders: %v", err)
+		return
+	}
+
+	changed := false
+	for key, start := range sent {
+		if start.Before(now.AddDate(0, 0, -1)) {
+			delete(sent, key)
+			changed = true
+		}
+	}
+	rc := reminderSettings(cfg)
+	for _, s := range shifts {
+		left := s.Start.Sub(now)
+		if left <= 0 {
+			continue
+		}
+		e := byName[s.Employee]
+		text := shiftReminderText(s, left)
+		for _, channel := range sc.channels(e) {
+			var due []string
+			for _, h := range sc.HoursBefore {
+				key := shiftReminderKey(s, h, channel)
+				if _, ok := sent[key]; ok || left > time.Duration(h)*time.Hour {
+					continue
+				}
+				due = append(due, key)
+			}
+			if len(due) == 0 {
+				continue
+			}
+			var err error
+			switch channel {
+			case channelSlack:
+				err = sendSlack(e.SlackWebhook, text)
+			case channelEmail:
+				err = sendEmail(rc.SMTP, []string{e.Email}, fmt.Sprintf("Your %s shift on %s", s.Shift, s.Start.Format("Monday 2 January")), text)
+			case channelSMS:
+				err = textEmployee(cfg, e, text)
+			}
+			if err != nil {
+				log.Printf("Error sending shift reminder to %s by %s: %v", e.Name, channel, err)
+				continue
+			}
+			log.Printf("Sent shift reminder to %s by %s: %s", e.Name, channel, text)
+			for _, key := range due {
+				sent[key] = s.Start
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := saveSentShiftReminders(sent); err != nil {
+		log.Printf("Error saving shift reminders: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"employee-schedular/schedule"
+)
+
+// shiftColors are the ANSI colors of the shifts in the grid, by their
+// place in the catalog.
+var shiftColors = []string{"36", "33", "35", "32", "34", "31"}
+
+// runShow prints a week of a published schedule as a grid of employees by
+// day, with each employee's hours and each day's headcount.
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	week := fs.Int("week", 1, "week of the schedule to show")
+	color := fs.String("color", "auto", "color the shifts: auto (when writing to a terminal), always, or never")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("usage: show [-week 1] [-color auto|always|never] [schedule dir]")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	var colored bool
+	switch *color {
+	case "auto":
+		colored = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		colored = true
+	case "never":
+	default:
+		return fmt.Errorf("invalid -color %q (want auto, always, or never)", *color)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	entries, err := loadScheduleDir(dir)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("Week %d", *week)
+	var rows []FlatSchedule
+	weeks := make(map[string]bool)
+	for _, obj := range entries {
+		weeks[obj["Week"]] = true
+		if obj["Week"] == name {
+			rows = append(rows, obj)
+		}
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%s has no %s (it has %d weeks)", scheduleDir(dir), name, len(weeks))
+	}
+	printGrid(os.Stdout, rows, shifts, colored, time.Now())
+	return nil
+}
+
+// printGrid writes a week's rows as an aligned table, with shift-colored
+// cells when colored. The cells are padded by hand, since the color codes
+// would throw tabwriter's widths off.
+func printGrid(w io.Writer, rows []FlatSchedule, shifts []schedule.Shift, colored bool, now time.Time) {
+	var days []string
+	for key := range rows[0] {
+		if _, ok := labelDate(key, now); ok {
+			days = append(days, key)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		a, _ := labelDate(days[i], now)
+		b, _ := labelDate(days[j], now)
+		return a.Before(b)
+	})
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["Employee"] < rows[j]["Employee"] })
+
+	header := []string{rows[0]["Week"]}
+	for _, day := range days {
+		date, _ := labelDate(day, now)
+		header = append(header, date.Format("Mon 2"))
+	}
+	header = append(header, "Hours")
+	table := [][]string{header}
+	working := make([]int, len(days))
+	for _, obj := range rows {
+		line := []string{obj["Employee"]}
+		total := 0.0
+		for i, day := range days {
+			line = append(line, obj[day])
+			if h, ok := cellHours(shifts, obj[day]); ok {
+				total += h
+				working[i]++
+			}
+		}
+		table = append(table, append(line, strconv.FormatFloat(total, 'f', -1, 64)))
+	}
+	footer := []string{"Working"}
+	for _, n := range working {
+		footer = append(footer, strconv.Itoa(n))
+	}
+	table = append(table, append(footer, ""))
+
+	widths := make([]int, len(header))
+	for _, line := range table {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	for r, line := range table {
+		var b strings.Builder
+		for i, cell := range line {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == len(line)-1 {
+				cell = pad + cell
+			} else {
+				cell += pad
+			}
+			switch {
+			case !colored:
+			case r == 0 || r == len(table)-1 || i == 0:
+				cell = "\x1b[1m" + cell + "\x1b[0m"
+			case i < len(line)-1:
+				cell = colorCell(cell, line[i], shifts)
+			}
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// colorCell colors a padded cell by the shift its value assigns, and dims
+// days off.
+func colorCell(cell, value string, shifts []schedule.Shift) string {
+	name, _ := schedule.SplitCell(value)
+	for i, sh := range shifts {
+		if strings.EqualFold(sh.Name, name) {
+			return "\x1b[" + shiftColors[i%len(shiftColors)] + "m" + cell + "\x1b[0m"
+		}
+	}
+	return "\x1b[2m" + cell + "\x1b[0m"
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
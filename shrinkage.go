@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Shrinkage is the share of scheduled time lost to each category, such as
+// {"meetings": 0.05, "breaks": 0.1, "absenteeism": 0.08}. A single
+// percentage is one category, e.g. {"total": 0.3}.
+type Shrinkage map[string]float64
+
+// total returns the share of scheduled time that isn't productive.
+func (s Shrinkage) total() float64 {
+	total := 0.0
+	for _, v := range s {
+		total += v
+	}
+	return total
+}
+
+// productive returns the share of scheduled time spent taking calls.
+func (s Shrinkage) productive() float64 {
+	return 1 - s.total()
+}
+
+// validate checks that every category is a share and that some time is
+// left productive.
+func (s Shrinkage) validate() error {
+	for category, v := range s {
+		if v < 0 || v >= 1 {
+			return fmt.Errorf("shrinkage %s must be a share from 0 to 1, e.g. 0.1 for 10%%", category)
+		}
+	}
+	if s.total() >= 1 {
+		return errors.New("shrinkage adds up to 100% or more")
+	}
+	return nil
+}
+
+// grossUp returns the agents to schedule for n to be productive at a time.
+func (s Shrinkage) grossUp(n int) int {
+	if n == 0 {
+		return 0
+	}
+	// Round before ceiling so 8 / 0.8 stays 10.
+	return int(math.Ceil(math.Round(float64(n)/s.productive()*1e6) / 1e6))
+}
+
+// String describes the shrinkage, e.g. "18% (absenteeism 8%, breaks 10%)".
+func (s Shrinkage) String() string {
+	var categories []string
+	for category := range s {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	var parts []string
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%s %g%%", category, s[category]*100))
+	}
+	text := fmt.Sprintf("%g%%", math.Round(s.total()*1000)/10)
+	if len(parts) > 1 {
+		text += " (" + strings.Join(parts, ", ") + ")"
+	}
+	return text
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// Skill coverage alerts.
+const (
+	skillUncovered = "uncovered"
+	skillSingle    = "single"
+)
+
+// requiredSkills returns the skills each shift needs, from the config.
+func requiredSkills(cfg Config, shifts []schedule.Shift) (map[string][]string, error) {
+	for shift := range cfg.RequiredSkills {
+		if _, ok := findShift(shifts, shift); !ok {
+			return nil, fmt.Errorf("required skills for unknown shift %q", shift)
+		}
+	}
+	return cfg.RequiredSkills, nil
+}
+
+// rosterSkills returns the skills of the scheduled employees.
+func rosterSkills(roster []Employee, employees []string) map[string][]string {
+	skills := make(map[string][]string)
+	for _, e := range roster {
+		if len(e.Skills) > 0 && slices.Contains(employees, e.Name) {
+			skills[e.Name] = e.Skills
+		}
+	}
+	return skills
+}
+
+// skillNote tells the model which shifts need which skills, and who has
+// them.
+func skillNote(required, skills map[string][]string) string {
+	shifts := make([]string, 0, len(required))
+	for shift := range required {
+		shifts = append(shifts, shift)
+	}
+	sort.Strings(shifts)
+	holders := make(map[string][]string)
+	var needs []string
+	for _, shift := range shifts {
+		needs = append(needs, fmt.Sprintf("%s (%s)", shift, strings.Join(required[shift], ", ")))
+		for _, skill := range required[shift] {
+			holders[skill] = nil
+		}
+	}
+	names := make([]string, 0, len(skills))
+	for name := range skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, skill := range skills[name] {
+			if h, ok := holders[skill]; ok {
+				holders[skill] = append(h, name)
+			}
+		}
+	}
+	skillNames := make([]string, 0, len(holders))
+	for skill := range holders {
+		skillNames = append(skillNames, skill)
+	}
+	sort.Strings(skillNames)
+	var has []string
+	for _, skill := range skillNames {
+		has = append(has, fmt.Sprintf("%s (%s)", skill, strings.Join(holders[skill], ", ")))
+	}
+	return "Required skills: " + strings.Join(needs, "; ") + ". Skilled employees: " + strings.Join(has, "; ") +
+		". Every day, put at least one employee with each required skill on the shift needing it, and two where possible so the shift doesn't rest on one person."
+}
+
+// skillHolders is who on a shift has one of its required skills. Alert is
+// uncovered when no one does and single when only one does.
+type skillHolders struct {
+	Skill   string   `json:"skill"`
+	Holders []string `json:"holders"`
+	Alert   string   `json:"alert,omitempty"`
+}
+
+// shiftSkills is the skill coverage of one shift on one day.
+type shiftSkills struct {
+	Shift  string         `json:"shift"`
+	Skills []skillHolders `json:"skills"`
+}
+
+// daySkills is the skill coverage of every shift of one day needing skills.
+type daySkills struct {
+	Week   string        `json:"week"`
+	Day    string        `json:"day"`
+	Date   string        `json:"date,omitempty"`
+	Job    string        `json:"job,omitempty"`
+	Shifts []shiftSkills `json:"shifts"`
+}
+
+// skillMatrix reports, for one day of a week's schedule, who covers each
+// required skill of each worked shift. Shifts no one works are left to the
+// coverage report.
+func skillMatrix(objs []FlatSchedule, key string, shifts []schedule.Shift, required, skills map[string][]string) []shiftSkills {
+	var out []shiftSkills
+	for _, sh := range shifts {
+		if len(required[sh.Name]) == 0 {
+			continue
+		}
+		var working []string
+		for _, obj := range objs {
+			if name, _ := schedule.SplitCell(obj[key]); name == sh.Name {
+				working = append(working, obj["Employee"])
+			}
+		}
+		if len(working) == 0 {
+			continue
+		}
+		c := shiftSkills{Shift: sh.Name}
+		for _, skill := range required[sh.Name] {
+			h := skillHolders{Skill: skill, Holders: []string{}}
+			for _, name := range working {
+				if slices.Contains(skills[name], skill) {
+					h.Holders = append(h.Holders, name)
+				}
+			}
+			switch len(h.Holders) {
+			case 0:
+				h.Alert = skillUncovered
+			case 1:
+				h.Alert = skillSingle
+			}
+			c.Skills = append(c.Skills, h)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// dayColumns returns the day columns of a schedule object in date order.
+func dayColumns(obj FlatSchedule, now time.Time) []string {
+	var keys []string
+	for key := range obj {
+		if _, ok := labelDate(key, now); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := labelDate(keys[i], now)
+		b, _ := labelDate(keys[j], now)
+		return a.Before(b)
+	})
+	return keys
+}
+
+// runSkills prints the skill coverage matrix of a schedule directory and
+// the alerts it raises.
+func runSkills(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: skills <schedule dir>")
+	}
+	dir := scheduleDir(args[0])
+	entries, err := loadScheduleDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, obj := range entries {
+		if !slices.Contains(names, obj["Employee"]) {
+			names = append(names, obj["Employee"])
+		}
+	}
+	p, err := loadPolicy(names)
+	if err != nil {
+		return err
+	}
+	if len(p.requiredSkills) == 0 {
+		return fmt.Errorf("no required_skills in %s", configPath())
+	}
+
+	weeks := make(map[string][]FlatSchedule)
+	for _, obj := range entries {
+		weeks[obj["Week"]] = append(weeks[obj["Week"]], obj)
+	}
+	uncovered, single := 0, 0
+	now := time.Now()
+	for _, week := range sortedWeekNames(weeks) {
+		objs := weeks[week]
+		for _, key := range dayColumns(objs[0], now) {
+			matrix := skillMatrix(objs, key, p.shifts, p.requiredSkills, p.skills)
+			if len(matrix) == 0 {
+				continue
+			}
+			fmt.Printf("%s, %s\n", week, key)
+			for _, c := range matrix {
+				var parts []string
+				for _, h := range c.Skills {
+					part := fmt.Sprintf("%s: %s", h.Skill, strings.Join(h.Holders, ", "))
+					switch h.Alert {
+					case skillUncovered:
+						part = h.Skill + ": NONE"
+						uncovered++
+					case skillSingle:
+						part += " (single)"
+						single++
+					}
+					parts = append(parts, part)
+				}
+				fmt.Printf("  %-8s %s\n", c.Shift, strings.Join(parts, "   "))
+			}
+		}
+	}
+	fmt.Printf("%d uncovered skills, %d covered by a single person\n", uncovered, single)
+	return nil
+}
+
+// skillCoverage reports the skill coverage of each day from from to to,
+// inclusive, from the newest published schedule covering it. Days without a
+// worked shift needing skills are left out.
+func (q *jobQueue) skillCoverage(from, to time.Time) ([]daySkills, error) {
+	published := q.publishedSchedules()
+	policies := make(map[string]*policy)
+	days := []daySkills{}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		for _, ps := range published {
+			week, key, ok := scheduleDay(ps.Entries, date)
+			if !ok {
+				continue
+			}
+			objs := weekEntries(ps.Entries, week)
+			p, ok := policies[ps.Job.ID]
+			if !ok {
+				var names []string
+				for _, obj := range ps.Entries {
+					if !slices.Contains(names, obj["Employee"]) {
+						names = append(names, obj["Employee"])
+					}
+				}
+				var err error
+				if p, err = loadPolicy(names); err != nil {
+					return nil, fmt.Errorf("error reading policy of job %s: %w", ps.Job.ID, err)
+				}
+				policies[ps.Job.ID] = p
+			}
+			if matrix := skillMatrix(objs, key, p.shifts, p.requiredSkills, p.skills); len(matrix) > 0 {
+				days = append(days, daySkills{Week: week, Day: key, Date: date.Format(time.DateOnly), Job: ps.Job.ID, Shifts: matrix})
+			}
+			break
+		}
+	}
+	return days, nil
+}
+
+// handleSkills serves the skill coverage matrix of a date range. With
+// alerts=true only the skills with an alert are listed.
+func (s *server) handleSkills(w http.ResponseWriter, r *http.Request) {
+	from, err := time.Parse(time.DateOnly, r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be a date written 2006-01-02")
+		return
+	}
+	to, err := time.Parse(time.DateOnly, r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be a date written 2006-01-02")
+		return
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to is before from")
+		return
+	}
+	if to.Sub(from) >= maxCoverageDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("date range longer than %d days", maxCoverageDays))
+		return
+	}
+	days, err := s.queue.skillCoverage(from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if r.URL.Query().Get("alerts") == "true" {
+		for i := range days {
+			var shifts []shiftSkills
+			for _, c := range days[i].Shifts {
+				c.Skills = slices.DeleteFunc(c.Skills, func(h skillHolders) bool { return h.Alert == "" })
+				if len(c.Skills) > 0 {
+					shifts = append(shifts, c)
+				}
+			}
+			days[i].Shifts = shifts
+		}
+		days = slices.DeleteFunc(days, func(d daySkills) bool { return len(d.Shifts) == 0 })
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"from": from.Format(time.DateOnly), "to": to.Format(time.DateOnly), "days": days})
+}
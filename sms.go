@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// SMSConfig sets the text messages sent to employees through Twilio, for
+// staff without smartphones or Slack.
+type SMSConfig struct {
+	// Country is the ISO country code, such as "ZA", of phone numbers in
+	// the roster written without an international prefix.
+	Country string `json:"country,omitempty"`
+	// Publish texts each employee their shifts when a schedule is
+	// published.
+	Publish bool `json:"publish,omitempty"`
+	// UrgentHours texts the employees whose shifts change on a published
+	// schedule when the changed day starts within this many hours.
+	UrgentHours int `json:"urgent_hours,omitempty"`
+}
+
+// dialingPlan is a country's calling code and the trunk prefix dialed
+// before national numbers, which is dropped in the international format.
+type dialingPlan struct {
+	code  string
+	trunk string
+}
+
+// dialingPlans lists the countries whose local numbers can be formatted.
+var dialingPlans = map[string]dialingPlan{
+	"AU": {"61", "0"},
+	"BR": {"55", "0"},
+	"CA": {"1", "1"},
+	"DE": {"49", "0"},
+	"ES": {"34", ""},
+	"FR": {"33", "0"},
+	"GB": {"44", "0"},
+	"IE": {"353", "0"},
+	"IN": {"91", "0"},
+	"IT": {"39", ""},
+	"KE": {"254", "0"},
+	"MX": {"52", ""},
+	"NG": {"234", "0"},
+	"NL": {"31", "0"},
+	"NZ": {"64", "0"},
+	"PT": {"351", ""},
+	"US": {"1", "1"},
+	"ZA": {"27", "0"},
+}
+
+// formatPhone returns a phone number in the E.164 format Twilio expects,
+// e.g. "082 123 4567" in ZA becomes "+27821234567". Numbers starting with
+// + or 00 are already international; others are read as local numbers of
+// country.
+func formatPhone(number, country string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()/", r) {
+			return -1
+		}
+		return r
+	}, number)
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	default:
+		plan, ok := dialingPlans[strings.ToUpper(country)]
+		if !ok {
+			if country == "" {
+				return "", fmt.Errorf("phone number %q has no country code and no country is set", number)
+			}
+			return "", fmt.Errorf("phone number %q: unknown country %q", number, country)
+		}
+		digits = plan.code + strings.TrimPrefix(digits, plan.trunk)
+	}
+	if len(digits) < 8 || len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("invalid phone number %q", number)
+	}
+	return "+" + digits, nil
+}
+
+// textEmployee sends a text message to an employee's phone.
+func textEmployee(cfg Config, e Employee, text string) error {
+	country := e.Country
+	if country == "" {
+		country = cfg.SMS.Country
+	}
+	to, err := formatPhone(e.Phone, country)
+	if err != nil {
+		return err
+	}
+	return sendSMS(twilioSettings(cfg), to, text)
+}
+
+// phoneContacts returns the roster employees with a phone number, by name.
+func phoneContacts() (map[string]Employee, error) {
+	roster, err := loadRoster()
+	if err != nil {
+		return nil, err
+	}
+	contacts := make(map[string]Employee)
+	for _, e := range roster {
+		if e.Phone != "" {
+			contacts[e.Name] = e
+		}
+	}
+	return contacts, nil
+}
+
+// dayText shortens a day column for a text message, e.g. "Monday (1st
+// March)" to "Mon 1 Mar".
+func dayText(key string, now time.Time) string {
+	if d, ok := labelDate(key, now); ok {
+		return d.Format("Mon 2 Jan")
+	}
+	return key
+}
+
+// publicationText writes the text of one employee's week, e.g. "Week 1
+// schedule published: Mon 1 Mar Early, Tue 2 Mar Early, ... Sun 7 Mar Off".
+func publicationText(obj FlatSchedule, now time.Time) string {
+	keys := dayColumns(obj, now)
+	days := make([]string, len(keys))
+	for i, key := range keys {
+		name, _ := schedule.SplitCell(obj[key])
+		if name == "" {
+			name = schedule.Off
+		}
+		days[i] = dayText(key, now) + " " + name
+	}
+	return fmt.Sprintf("%s schedule published: %s", obj["Week"], strings.Join(days, ", "))
+}
+
+// textPublished texts every employee with a phone number their shifts in a
+// newly published job's schedule, one message per week.
+func textPublished(job Job) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config for job %s texts: %v", job.ID, err)
+		return
+	}
+	if !cfg.SMS.Publish {
+		return
+	}
+	contacts, err := phoneContacts()
+	if err != nil {
+		log.Printf("Error loading roster for job %s texts: %v", job.ID, err)
+		return
+	}
+	entries, err := loadScheduleDir(filepath.Join(dataDir(), "schedules", job.ID))
+	if err != nil {
+		log.Printf("Error loading schedule for job %s texts: %v", job.ID, err)
+		return
+	}
+	ac := acknowledgmentSettings(cfg)
+	now := time.Now()
+	for _, obj := range entries {
+		e, ok := contacts[obj["Employee"]]
+		if !ok {
+			continue
+		}
+		text := publicationText(obj, now)
+		if link := ac.ackLink(job.ID, e.Name); link != "" {
+			text += ". Acknowledge: " + link
+		}
+		if err := textEmployee(cfg, e, text); err != nil {
+			log.Printf("Error texting %s the schedule of job %s: %v", e.Name, job.ID, err)
+		}
+	}
+}
+
+// textUrgentChange texts the employees whose cell on a day of a published
+// schedule changed, when the day starts within the configured urgent hours.
+func textUrgentChange(key string, before, after map[string]string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Error loading config for change texts: %v", err)
+		return
+	}
+	if cfg.SMS.UrgentHours <= 0 {
+		return
+	}
+	now := time.Now()
+	date, ok := labelDate(key, now)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !ok || date.Before(today) || date.After(now.Add(time.Duration(cfg.SMS.UrgentHours)*time.Hour)) {
+		return
+	}
+	contacts, err := phoneContacts()
+	if err != nil {
+		log.Printf("Error loading roster for change texts: %v", err)
+		return
+	}
+	for name, cell := range after {
+		e, ok := contacts[name]
+		if !ok || cell == before[name] {
+			continue
+		}
+		shift, _ := schedule.SplitCell(cell)
+		was, _ := schedule.SplitCell(before[name])
+		text := fmt.Sprintf("Schedule change: on %s you now work %s (was %s)", date.Format("Monday 2 January"), shift, was)
+		if schedule.IsOff(shift) {
+			text = fmt.Sprintf("Schedule change: on %s you are now off (was %s)", date.Format("Monday 2 January"), was)
+		}
+		if err := textEmployee(cfg, e, text); err != nil {
+			log.Printf("Error texting %s a schedule change: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// Solvers a generation can write its schedule with.
+const (
+	solverOpenAI = "openai"
+	solverLocal  = "local"
+)
+
+var solverNames = []string{solverOpenAI, solverLocal}
+
+// engineSolvers maps the names of the -engine flag to the solvers.
+var engineSolvers = map[string]string{"llm": solverOpenAI, "solver": solverLocal}
+
+// validSolver reports an unknown solver name. The empty name is the
+// default, the OpenAI model.
+func validSolver(name string) error {
+	if name == "" || name == solverOpenAI || name == solverLocal {
+		return nil
+	}
+	return fmt.Errorf("unknown solver %q (want one of %s)", name, strings.Join(solverNames, ", "))
+}
+
+// solvePlan is how the local solver places one employee.
+type solvePlan struct {
+	// rotates is whether the employee rotates with a team, starting on the
+	// shift of the team's index in forward order. Otherwise they stay on
+	// shift, or are off throughout when it is -1.
+	rotates     bool
+	team, shift int
+	// place is the employee's position in their team, or among those
+	// staying on their shift, which sets the day their cycle starts.
+	place int
+}
+
+// solveLocally writes a schedule without the model, within the limits of
+// the rules. Employees rotate forward through the shifts in teams of
+// nearly equal size, one shift every rotation period, so each shift is
+// staffed by one team at a time. Each employee works a cycle of seven
+// days: their working days, five unless their compressed workweek, the
+// weekly hours, shift lengths, or consecutive-shift limit call for fewer,
+// and then their days off. The shift only changes at the start of a cycle,
+// right after days off, so moving from the latest shift back to the
+// earliest always follows a rest. Within each team the cycles start on
+// different days so every day keeps cover. Employees who can't work every
+// shift, such as minors, stay on one they can. Days that would break
+// leave, observances, transport, certifications, a reduced-hours period,
+// or the weekly, monthly, or night shift limits are left off. The same
+// inputs always give the same schedule.
+// The schedule is written like a model response in the policy's language,
+// so the run validates it like any other; validation reports whatever
+// constraints it misses.
+func solveLocally(p *policy, fc *forecast.Forecast, employees []string, rules validator.Rules) (string, error) {
+	if fc == nil || len(fc.Days) == 0 {
+		return "", errors.New("the local solver needs a forecast with days to schedule")
+	}
+	if len(employees) == 0 || len(p.shifts) == 0 {
+		return "", errors.New("the local solver needs employees and shifts")
+	}
+	var dates []time.Time
+	for _, d := range fc.Days {
+		date, err := time.Parse(forecast.DateLayout, d.Date)
+		if err != nil {
+			return "", fmt.Errorf("invalid forecast day %q: %w", d.Date, err)
+		}
+		dates = append(dates, date)
+	}
+	order := forwardOrder(p.shifts)
+	period := max(rules.RotationWeeks, 1)
+	window := func(name string, sh schedule.Shift) (schedule.Clock, schedule.Clock, float64) {
+		if w, ok := p.workweeks[name]; ok {
+			start, end := w.day(sh.Start, sh.End)
+			return start, end, float64(end-start) / 60
+		}
+		return sh.Start, sh.End, sh.Hours()
+	}
+
+	// Those who can work every shift rotate, and the others stay on the
+	// shift they can work with the fewest staying on it.
+	plans := make(map[string]solvePlan, len(employees))
+	rotating := 0
+	staying := make([]int, len(order))
+	for _, name := range employees {
+		var can []int
+		for i, sh := range order {
+			if start, end, hours := window(name, sh); fits(rules, name, sh, start, end, hours, time.Time{}) {
+				can = append(can, i)
+			}
+		}
+		pl := solvePlan{shift: -1}
+		switch {
+		case len(can) == len(order):
+			pl.rotates, pl.team, pl.place = true, rotating%len(order), rotating/len(order)
+			rotating++
+		case len(can) > 0:
+			pl.shift = can[0]
+			for _, i := range can {
+				if staying[i] < staying[pl.shift] {
+					pl.shift = i
+				}
+			}
+			pl.place = staying[pl.shift]
+			staying[pl.shift]++
+		}
+		plans[name] = pl
+	}
+	usual := 7
+	for _, sh := range order {
+		usual = min(usual, workdays(rules, "", sh.Hours()))
+	}
+	starts := cycleStarts(usual, max(rotating/len(order)+1, slices.Max(staying)))
+
+	lang := p.language
+	cells := make(map[string][]string, len(employees))
+	for _, name := range employees {
+		pl := plans[name]
+		first := starts[pl.place]
+		weekHours := make([]float64, (len(dates)+6)/7)
+		lengths := make(map[[2]float64]int)
+		reduced := make(map[[2]int]float64)
+		total, nights := 0.0, 0
+		row := make([]string, len(dates))
+		for k, date := range dates {
+			row[k] = lang.Off
+			// Floor divisions, as k-first is at least -6.
+			cycle := (k-first+7)/7 - 1
+			shift := pl.shift
+			if pl.rotates {
+				shift = (pl.team + (cycle+period)/period - 1 + len(order)) % len(order)
+			}
+			if shift < 0 {
+				continue
+			}
+			sh := order[shift]
+			start, end, hours := window(name, sh)
+			week := k / 7
+			if k-first-7*cycle >= workdays(rules, name, hours) || !fits(rules, name, sh, start, end, hours, date) {
+				continue
+			}
+			if rules.MaxWeeklyHours > 0 && weekHours[week]+hours > rules.MaxWeeklyHours {
+				continue
+			}
+			if rules.MaxMonthlyHours > 0 && total+hours > rules.MaxMonthlyHours {
+				continue
+			}
+			if limit, ok := rules.LengthLimits[hours]; ok && lengths[[2]float64{float64(week), hours}] >= limit {
+				continue
+			}
+			night := slices.Contains(rules.NightShifts, sh.Name)
+			if limit, ok := rules.NightShiftCaps[name]; ok && night && nights >= limit {
+				continue
+			}
+			over := false
+			for i, c := range rules.Capacity[name] {
+				if c.MaxWeeklyHours > 0 && between(date, c.Start, c.End) && reduced[[2]int{i, week}]+hours > c.MaxWeeklyHours {
+					over = true
+				}
+			}
+			if over {
+				continue
+			}
+
+			row[k] = sh.Name
+			weekHours[week] += hours
+			total += hours
+			lengths[[2]float64{float64(week), hours}]++
+			if night {
+				nights++
+			}
+			for i, c := range rules.Capacity[name] {
+				if between(date, c.Start, c.End) {
+					reduced[[2]int{i, week}] += hours
+				}
+			}
+		}
+		cells[name] = row
+	}
+
+	var rows []map[string]string
+	for week := 0; week*7 < len(dates); week++ {
+		for _, name := range employees {
+			row := map[string]string{lang.Week: fmt.Sprintf("%s %d", lang.Week, week+1), lang.Employee: name}
+			for k := week * 7; k < min(week*7+7, len(dates)); k++ {
+				row[lang.dayLabel(dates[k].Weekday(), dates[k].Day(), dates[k].Month())] = cells[name][k]
+			}
+			rows = append(rows, row)
+		}
+	}
+	out, err := json.Marshal(rows)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// workdays returns how many days in each seven an employee works a shift
+// of the given hours: the days of their compressed workweek, or five,
+// fewer where the weekly hours, shift-length, or consecutive-shift limits
+// need it.
+func workdays(r validator.Rules, employee string, hours float64) int {
+	if days, ok := r.Workdays[employee]; ok {
+		return days
+	}
+	days := 5
+	if r.MaxWeeklyHours > 0 && hours > 0 {
+		days = min(days, int(r.MaxWeeklyHours/hours))
+	}
+	if limit, ok := r.LengthLimits[hours]; ok {
+		days = min(days, limit)
+	}
+	if r.MaxConsecutiveSameShift > 0 {
+		days = min(days, r.MaxConsecutiveSameShift)
+	}
+	return days
+}
+
+// cycleStarts returns the day of the week, counted from the first day
+// scheduled, each of n employees starts their seven-day cycle of the given
+// working days on, spreading their days off so as few as possible are off
+// together. Cycles only start early enough for most of the week's working
+// days to fall in the cycle starting in it, so each week is worked mostly
+// on one shift.
+func cycleStarts(workdays, n int) []int {
+	var allowed []int
+	for start := 0; start < 7; start++ {
+		if min(workdays, 7-start) > max(0, start-7+workdays) {
+			allowed = append(allowed, start)
+		}
+	}
+	if len(allowed) == 0 {
+		allowed = []int{0}
+	}
+	off := make([]int, 7)
+	starts := make([]int, n)
+	for i := range starts {
+		best, bestPeak, bestSum := -1, 0, 0
+		for _, start := range allowed {
+			peak, sum := 0, 0
+			for d := workdays; d < 7; d++ {
+				peak, sum = max(peak, off[(start+d)%7]), sum+off[(start+d)%7]
+			}
+			if best < 0 || peak < bestPeak || (peak == bestPeak && sum < bestSum) {
+				best, bestPeak, bestSum = start, peak, sum
+			}
+		}
+		starts[i] = best
+		for d := workdays; d < 7; d++ {
+			off[(best+d)%7]++
+		}
+	}
+	return starts
+}
+
+// fits reports whether an employee may work a shift from start to end on a
+// date under the rules on transport, minors, certifications, leave,
+// observances, and reduced-hours periods. Without a date, only the limits
+// that hold every day are checked.
+func fits(r validator.Rules, employee string, sh schedule.Shift, start, end schedule.Clock, hours float64, date time.Time) bool {
+	if wh, ok := r.WorkHours[employee]; ok && (start < wh.EarliestStart || end > wh.LatestEnd) {
+		return false
+	}
+	if slices.Contains(r.Minors, employee) && (end <= start || end > r.MinorLatestEnd || (r.MinorMaxDailyHours > 0 && hours > r.MinorMaxDailyHours)) {
+		return false
+	}
+	for _, name := range r.RequiredCertifications[sh.Name] {
+		valid := slices.ContainsFunc(r.Certifications[employee], func(c validator.Certification) bool {
+			return c.Name == name && (c.Expires.IsZero() || date.IsZero() || !date.After(c.Expires))
+		})
+		if !valid {
+			return false
+		}
+	}
+	if date.IsZero() {
+		return true
+	}
+	for _, l := range r.Leave[employee] {
+		if between(date, l.Start, l.End) {
+			return false
+		}
+	}
+	for _, u := range r.Unavailable[employee] {
+		if u.Weekday == date.Weekday() && start < u.To && end > u.From {
+			return false
+		}
+	}
+	for _, c := range r.Capacity[employee] {
+		if between(date, c.Start, c.End) && slices.Contains(c.NoShifts, sh.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// between reports whether a date falls from start to end, both inclusive.
+func between(date, start, end time.Time) bool {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, start.Location())
+	return !day.Before(start) && !day.After(end)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"employee-schedular/forecast"
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// solveScenario sets up a stress scenario's data directory and solves it
+// locally under its rules, adjusted by tweak, returning the policy, the
+// rules, and the response.
+func solveScenario(t *testing.T, sc stressScenario, shifts []schedule.Shift, tweak func(*validator.Rules)) (*policy, validator.Rules, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("SCHEDULER_DATA_DIR", dir)
+	t.Setenv("SCHEDULER_CONFIG", "")
+	path, err := sc.setUp(dir, Config{Shifts: shifts}, nextMonday(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err := forecast.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var employees []string
+	for _, e := range roster {
+		employees = append(employees, e.Name)
+	}
+	p, err := loadPolicy(employees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := p.rules(&Run{Forecast: fc, Employees: employees})
+	if tweak != nil {
+		tweak(&rules)
+	}
+	response, err := solveLocally(p, fc, employees, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, rules, response
+}
+
+func TestSolveLocallyFollowsRules(t *testing.T) {
+	scenario := func(name string) stressScenario {
+		return stressScenarios[slices.Index(stressScenarioNames(), name)]
+	}
+	tests := []struct {
+		name     string
+		scenario stressScenario
+		shifts   []schedule.Shift
+		rules    func(*validator.Rules)
+		// unmeetable lists the rules no schedule of the scenario can
+		// follow, whose errors are expected.
+		unmeetable []string
+	}{
+		{name: "tiny roster", scenario: scenario("tiny-roster"), unmeetable: []string{"coverage"}},
+		{name: "huge roster", scenario: scenario("huge-roster")},
+		{name: "everyone on leave", scenario: scenario("everyone-on-leave"), unmeetable: []string{"coverage"}},
+		{name: "spike day", scenario: scenario("spike-day")},
+		{
+			name:     "two-week rotation",
+			scenario: scenario("spike-day"),
+			rules:    func(r *validator.Rules) { r.RotationWeeks = 2 },
+		},
+		{
+			name:     "twelve-hour shifts",
+			scenario: stressScenario{employees: 12},
+			shifts:   []schedule.Shift{{Name: "Day", Start: 6 * 60, End: 18 * 60}, {Name: "Late", Start: 10 * 60, End: 22 * 60}},
+		},
+		{
+			name:     "minor",
+			scenario: scenario("spike-day"),
+			rules: func(r *validator.Rules) {
+				r.Minors, r.MinorLatestEnd, r.MinorMaxDailyHours = []string{"Agent 001"}, 16*60, 9
+			},
+		},
+		{
+			name:     "transport and observance",
+			scenario: scenario("spike-day"),
+			rules: func(r *validator.Rules) {
+				r.WorkHours = map[string]validator.WorkHours{"Agent 002": {EarliestStart: 8 * 60, LatestEnd: 24 * 60}}
+				r.Unavailable = map[string][]validator.Unavailability{"Agent 003": {{Weekday: time.Friday, From: 12 * 60, To: 14 * 60}}}
+			},
+		},
+		{
+			name:     "certification",
+			scenario: scenario("spike-day"),
+			rules: func(r *validator.Rules) {
+				r.RequiredCertifications = map[string][]string{"Late": {"first aid"}}
+				r.Certifications = map[string][]validator.Certification{}
+				for i := 1; i <= 10; i++ {
+					if i != 4 {
+						r.Certifications[stressAgent(i)] = []validator.Certification{{Name: "first aid"}}
+					}
+				}
+			},
+		},
+		{
+			name:     "tighter hours",
+			scenario: scenario("spike-day"),
+			rules: func(r *validator.Rules) {
+				r.MaxWeeklyHours, r.MaxMonthlyHours = 36, 150
+				r.NightShifts, r.NightShiftCaps = []string{"Late"}, map[string]int{"Agent 005": 3}
+			},
+			unmeetable: []string{"coverage"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, rules, response := solveScenario(t, tt.scenario, tt.shifts, tt.rules)
+			weeks, err := parseResponse(response, p.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations, err := validateWeeks(weeks, p.shifts, p.rulePack, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range violations {
+				if v.Severity == validator.Error && !slices.Contains(tt.unmeetable, v.Rule) {
+					t.Errorf("unexpected error: %s", v)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveLocallyIsDeterministic(t *testing.T) {
+	sc := stressScenario{employees: 10}
+	_, _, first := solveScenario(t, sc, nil, nil)
+	_, _, second := solveScenario(t, sc, nil, nil)
+	if first != second {
+		t.Error("the same inputs gave different schedules")
+	}
+}
+
+func TestCycleStarts(t *testing.T) {
+	tests := []struct {
+		workdays, n int
+		want        []int
+	}{
+		{5, 3, []int{0, 2, 4}},
+		{5, 1, []int{0}},
+		{3, 2, []int{0, 3}},
+		{0, 2, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		if got := cycleStarts(tt.workdays, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("cycleStarts(%d, %d) = %v, want %v", tt.workdays, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// staggerWindow is the hours of one staggered start group.
+type staggerWindow struct {
+	Start, End schedule.Clock
+}
+
+// staggerWindows parses the configured staggers. Each entry is a start time,
+// keeping the shift's length, or start-end hours for a group that works a
+// shorter or longer day.
+func staggerWindows(shifts []schedule.Shift, staggers map[string][]string) (map[string][]staggerWindow, error) {
+	windows := make(map[string][]staggerWindow, len(staggers))
+	for name, entries := range staggers {
+		shift, found := findShift(shifts, name)
+		if !found {
+			return nil, fmt.Errorf("stagger for unknown shift %q", name)
+		}
+		for _, entry := range entries {
+			var w staggerWindow
+			var err error
+			if strings.Contains(entry, "-") {
+				w.Start, w.End, err = schedule.ParseWindow(entry)
+			} else if w.Start, err = schedule.ParseClock(entry); err == nil {
+				w.End = w.Start + shift.End - shift.Start
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid stagger for %s shift: %w", name, err)
+			}
+			windows[name] = append(windows[name], w)
+		}
+	}
+	return windows, nil
+}
+
+// applyStaggers gives the employees on a staggered shift their start times.
+// On each day the shift's employees, in name order, are dealt across the
+// start groups, and the deal rotates every week so nobody always starts
+// earliest. An employee whose dealt group doesn't fit their working hours
+// gets the next group that does. Cells become, for example, "Early
+// 07:00-16:00".
+func applyStaggers(weeks map[string][]FlatSchedule, windows map[string][]staggerWindow, hours map[string]validator.WorkHours) {
+	if len(windows) == 0 {
+		return
+	}
+	for week, objs := range weeks {
+		rotation, _ := schedule.ParseWeek(week)
+		sorted := make([]FlatSchedule, len(objs))
+		copy(sorted, objs)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i]["Employee"] < sorted[j]["Employee"] })
+
+		for _, key := range buildHeaderForWeek(objs) {
+			if !strings.Contains(key, "(") {
+				continue
+			}
+			seen := make(map[string]int)
+			for _, obj := range sorted {
+				name := strings.TrimSpace(obj[key])
+				groups := windows[name]
+				if len(groups) == 0 {
+					continue
+				}
+				dealt := seen[name] + rotation
+				seen[name]++
+				w := groups[dealt%len(groups)]
+				if wh, ok := hours[obj["Employee"]]; ok {
+					for i := range groups {
+						g := groups[(dealt+i)%len(groups)]
+						if g.Start >= wh.EarliestStart && g.End <= wh.LatestEnd {
+							w = g
+							break
+						}
+					}
+				}
+				obj[key] = schedule.FormatCell(name, w.Start, w.End)
+			}
+		}
+	}
+}
+
+// staggerStarts applies the configured staggers to a schedule.
+func staggerStarts(weeks map[string][]FlatSchedule, p *policy) error {
+	windows, err := staggerWindows(p.shifts, p.cfg.Staggers)
+	if err != nil {
+		return err
+	}
+	applyStaggers(weeks, windows, p.workHours)
+	return nil
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateManifestName is the archive entry describing a state export.
+const stateManifestName = "state.json"
+
+// stateManifest is written at the start of every state archive.
+type stateManifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Files      []string  `json:"files"`
+	// Outside maps the archive folders holding files kept outside the data
+	// directory, the config and run folders, to where they were.
+	Outside map[string]string `json:"outside,omitempty"`
+}
+
+// outsideFile is a file kept outside the data directory that a state
+// export carries under outside/.
+type outsideFile struct {
+	entry, path string
+	mode        fs.FileMode
+}
+
+// insideDir reports whether path is within dir.
+func insideDir(dir, path string) bool {
+	absDir, err1 := filepath.Abs(dir)
+	absPath, err2 := filepath.Abs(path)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// outsideFiles returns the config when it is kept outside the data
+// directory, and the files of every run folder published outside it, such
+// as under the -out of generate. Run folders that no longer exist, or
+// whose run cannot be parsed, are logged and left out.
+func outsideFiles(root string) ([]outsideFile, map[string]string, error) {
+	var files []outsideFile
+	folders := make(map[string]string)
+	if path := configPath(); !insideDir(root, path) {
+		if info, err := os.Stat(path); err == nil {
+			entry := "outside/config/" + filepath.Base(path)
+			abs, _ := filepath.Abs(path)
+			files = append(files, outsideFile{entry: entry, path: path, mode: info.Mode().Perm()})
+			folders[entry] = abs
+		}
+	}
+	runs, err := filepath.Glob(filepath.Join(historyDir(), "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range runs {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading run: %w", err)
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			log.Printf("Skipping the folder of run %s: %v", p, err)
+			continue
+		}
+		if run.Folder == "" || insideDir(root, run.Folder) {
+			continue
+		}
+		if _, err := os.Stat(run.Folder); errors.Is(err, os.ErrNotExist) {
+			log.Printf("Run %s: folder %s no longer exists, left out", run.ID, run.Folder)
+			continue
+		}
+		prefix := "outside/runs/" + run.ID
+		abs, _ := filepath.Abs(run.Folder)
+		folders[prefix] = abs
+		err = filepath.WalkDir(run.Folder, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(run.Folder, p)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, outsideFile{entry: prefix + "/" + filepath.ToSlash(rel), path: p, mode: info.Mode().Perm()})
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading run folder %s: %w", run.Folder, err)
+		}
+	}
+	return files, folders, nil
+}
+
+func historyDir() string {
+	return filepath.Join(dataDir(), "history")
+}
+
+// exportState writes the whole data directory (roster, constraints, history,
+// schedules) to a single gzip-compressed tar archive at path, along with
+// the config and run folders kept outside it.
+func exportState(path string) (int, error) {
+	root := dataDir()
+	var files []string
+	// modes keeps each file's permissions, so private files such as the
+	// pseudonym key stay private once imported.
+	modes := make(map[string]fs.FileMode)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+			modes[filepath.ToSlash(rel)] = info.Mode().Perm()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error reading data directory: %w", err)
+	}
+	outside, folders, err := outsideFiles(root)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("error creating archive: %w", err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(stateManifest{Version: 1, ExportedAt: time.Now().UTC(), Files: files, Outside: folders}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := writeTarFile(tw, stateManifestName, manifest, 0o644); err != nil {
+		return 0, err
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return 0, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		if err := writeTarFile(tw, "data/"+name, data, int64(modes[name])); err != nil {
+			return 0, err
+		}
+	}
+	for _, f := range outside {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return 0, fmt.Errorf("error reading %s: %w", f.path, err)
+		}
+		if err := writeTarFile(tw, f.entry, data, int64(f.mode)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, fmt.Errorf("error finishing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("error finishing archive: %w", err)
+	}
+	return len(files) + len(outside), out.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("error writing archive entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("error writing archive entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// importState restores a state archive into the data directory. Any existing
+// data directory is kept alongside as a timestamped backup, whose path is
+// returned. Files the archive carries from outside the data directory are
+// restored under its restored/ folder rather than where they were, which
+// may not exist on this machine; the archive's Outside map, also returned,
+// says where that was.
+func importState(path string) (string, map[string]string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error opening archive: %w", err)
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	root := dataDir()
+	staging := root + ".import"
+	if err := os.RemoveAll(staging); err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	sawManifest := false
+	var outside map[string]string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		if hdr.Name == stateManifestName {
+			var m stateManifest
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				return "", nil, fmt.Errorf("error reading archive manifest: %w", err)
+			}
+			if m.Version != 1 {
+				return "", nil, fmt.Errorf("unsupported state archive version %d", m.Version)
+			}
+			sawManifest = true
+			outside = m.Outside
+			continue
+		}
+		name, ok := strings.CutPrefix(hdr.Name, "data/")
+		if rest, isOutside := strings.CutPrefix(hdr.Name, "outside/"); isOutside {
+			name, ok = "restored/"+rest, true
+		}
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Refuse entries that would escape the data directory.
+		if !filepath.IsLocal(name) {
+			return "", nil, fmt.Errorf("invalid archive entry %q", hdr.Name)
+		}
+		target := filepath.Join(staging, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return "", nil, err
+		}
+		mode := hdr.FileInfo().Mode().Perm()
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return "", nil, err
+		}
+		// The umask may have narrowed the mode the file was created with.
+		if err := f.Chmod(mode); err != nil {
+			f.Close()
+			return "", nil, err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return "", nil, fmt.Errorf("error extracting %s: %w", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return "", nil, err
+		}
+	}
+	if !sawManifest {
+		return "", nil, errors.New("archive is not a state export")
+	}
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		return "", nil, err
+	}
+
+	backup := ""
+	if _, err := os.Stat(root); err == nil {
+		backup = root + ".bak-" + time.Now().UTC().Format("20060102T150405Z")
+		if err := os.Rename(root, backup); err != nil {
+			return "", nil, fmt.Errorf("error backing up data directory: %w", err)
+		}
+	}
+	if err := os.Rename(staging, root); err != nil {
+		return "", nil, fmt.Errorf("error installing imported state: %w", err)
+	}
+	return backup, outside, nil
+}
+
+// runExportState implements the export-state command.
+func runExportState(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: export-state <archive.tar.gz>")
+	}
+	n, err := exportState(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d files from %s to %s\n", n, dataDir(), args[0])
+	return nil
+}
+
+// runImportState implements the import-state command.
+func runImportState(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: import-state <archive.tar.gz>")
+	}
+	backup, outside, err := importState(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported state from %s into %s\n", args[0], dataDir())
+	if backup != "" {
+		fmt.Printf("Previous state kept in %s\n", backup)
+	}
+	if len(outside) > 0 {
+		fmt.Printf("Files kept outside the data directory were restored under %s; move them back where they were:\n", filepath.Join(dataDir(), "restored"))
+		entries := make([]string, 0, len(outside))
+		for entry := range outside {
+			entries = append(entries, entry)
+		}
+		sort.Strings(entries)
+		for _, entry := range entries {
+			fmt.Printf("  %s -> %s\n", strings.Replace(entry, "outside/", "restored/", 1), outside[entry])
+		}
+	}
+	return nil
+}
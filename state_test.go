@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateKeepsPermissions(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+	t.Setenv("SCHEDULER_DATA_DIR", root)
+	t.Setenv("SCHEDULER_CONFIG", "")
+	files := []struct {
+		name string
+		mode fs.FileMode
+	}{
+		{name: "pseudonyms.json", mode: 0o600},
+		{name: "config.json", mode: 0o640},
+		{name: "history/run.json", mode: 0o644},
+	}
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f.name), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+	if _, err := exportState(archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := importState(archive); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			info, err := os.Stat(filepath.Join(root, filepath.FromSlash(f.name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != f.mode {
+				t.Errorf("mode %v, want %v", got, f.mode)
+			}
+		})
+	}
+}
+
+func TestStateIncludesOutsideFiles(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+	outside := t.TempDir()
+	config := filepath.Join(outside, "scheduler.json")
+	folder := filepath.Join(outside, "schedules", "20260105")
+	t.Setenv("SCHEDULER_DATA_DIR", root)
+	t.Setenv("SCHEDULER_CONFIG", config)
+	for path, data := range map[string]string{
+		config:                             "{}",
+		filepath.Join(folder, "week1.csv"): "Name,Mon",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := saveRun(&Run{ID: "published", OutputDir: filepath.Dir(folder), Folder: folder}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveRun(&Run{ID: "moved", Folder: filepath.Join(outside, "gone")}); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+	if _, err := exportState(archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	_, origins, err := importState(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, restored, origin, data string
+	}{
+		{name: "config", restored: "restored/config/scheduler.json", origin: config, data: "{}"},
+		{name: "run folder", restored: "restored/runs/published/week1.csv", origin: folder, data: "Name,Mon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(tt.restored)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.data {
+				t.Errorf("got %q, want %q", data, tt.data)
+			}
+			found := false
+			for _, origin := range origins {
+				found = found || origin == tt.origin
+			}
+			if !found {
+				t.Errorf("origins %v do not name %s", origins, tt.origin)
+			}
+		})
+	}
+	if _, ok := origins["outside/runs/moved"]; ok {
+		t.Error("missing run folder was archived")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// printDailyVolumes prints the calls of every day in the aggregates.
+func printDailyVolumes(w io.Writer, agg Aggregates) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "DATE\tCALLS\t")
+	dates := make([]string, 0, len(agg.DateCounts))
+	for date := range agg.DateCounts {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		fmt.Fprintf(tw, "%s\t%d\t\n", date, agg.DateCounts[date])
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d calls over %d days\n", agg.Rows, len(agg.DateCounts))
+}
+
+// weekStats sums the calls of one ISO week.
+type weekStats struct {
+	Week     string
+	Days     map[string]bool
+	Calls    int
+	Answered int
+	Talked   float64
+	Waited   float64
+}
+
+// weeklyStats groups records by ISO week, oldest first.
+func weeklyStats(records []Record) []*weekStats {
+	byWeek := make(map[string]*weekStats)
+	for _, r := range records {
+		year, week := r.CalledTime.ISOWeek()
+		key := fmt.Sprintf("%d-W%02d", year, week)
+		ws, ok := byWeek[key]
+		if !ok {
+			ws = &weekStats{Week: key, Days: make(map[string]bool)}
+			byWeek[key] = ws
+		}
+		ws.Days[r.CalledTime.Format("2006-01-02")] = true
+		ws.Calls++
+		ws.Waited += r.WaitDuration
+		if r.TalkedDuration > 0 {
+			ws.Answered++
+			ws.Talked += r.TalkedDuration
+		}
+	}
+	weeks := make([]*weekStats, 0, len(byWeek))
+	for _, ws := range byWeek {
+		weeks = append(weeks, ws)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Week < weeks[j].Week })
+	return weeks
+}
+
+// printWeeklyVolumes prints calls, average handle time (talk time per
+// answered call), and average wait per ISO week, so trends show.
+func printWeeklyVolumes(w io.Writer, weeks []*weekStats) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "WEEK\tDAYS\tCALLS\tPER DAY\tAHT (s)\tWAIT (s)\t")
+	for _, ws := range weeks {
+		aht := 0.0
+		if ws.Answered > 0 {
+			aht = ws.Talked / float64(ws.Answered)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.0f\t%.0f\t\n", ws.Week, len(ws.Days), ws.Calls,
+			float64(ws.Calls)/float64(len(ws.Days)), aht, ws.Waited/float64(ws.Calls))
+	}
+	tw.Flush()
+}
+
+// printBusiestHours prints the hours of day with the most calls.
+func printBusiestHours(w io.Writer, agg Aggregates, n int) {
+	hours := make([]int, 0, len(agg.HourCounts))
+	for h := range agg.HourCounts {
+		hours = append(hours, h)
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		if agg.HourCounts[hours[i]] != agg.HourCounts[hours[j]] {
+			return agg.HourCounts[hours[i]] > agg.HourCounts[hours[j]]
+		}
+		return hours[i] < hours[j]
+	})
+	if len(hours) > n {
+		hours = hours[:n]
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "HOUR\tCALLS\tSHARE\t")
+	for _, h := range hours {
+		fmt.Fprintf(tw, "%02d:00\t%d\t%.1f%%\t\n", h, agg.HourCounts[h], 100*float64(agg.HourCounts[h])/float64(agg.Rows))
+	}
+	tw.Flush()
+}
+
+// printPercentiles summarises the daily volumes and handle times at the
+// percentiles planners usually size for.
+func printPercentiles(w io.Writer, agg Aggregates, records []Record) {
+	daily := make([]int, 0, len(agg.DateCounts))
+	for _, n := range agg.DateCounts {
+		daily = append(daily, n)
+	}
+	var handle []int
+	for _, r := range records {
+		if r.TalkedDuration > 0 {
+			handle = append(handle, int(r.TalkedDuration))
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tP50\tP75\tP90\tP95\tMAX\t")
+	for _, row := range []struct {
+		name   string
+		values []int
+	}{{"calls per day", daily}, {"handle time (s)", handle}} {
+		if len(row.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t", row.name)
+		for _, p := range []float64{50, 75, 90, 95, 100} {
+			fmt.Fprintf(tw, "%.0f\t", computeThreshold(row.values, p))
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+// runStats summarises the demand store without generating a schedule, for
+// planners checking their assumptions: daily and weekly volumes, handle
+// time trends, busiest hours, and percentiles.
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	from := fs.String("from", "", "first day to include (2006-01-02)")
+	to := fs.String("to", "", "last day to include (2006-01-02)")
+	daily := fs.Bool("daily", true, "print the daily volume table")
+	hours := fs.Int("hours", 5, "number of busiest hours to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		return err
+	}
+	records, err := loadDemand(start, end)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("No call records in the demand store for this range.")
+		return nil
+	}
+	agg := aggregateRecords(records)
+	if *daily {
+		printDailyVolumes(os.Stdout, agg)
+		fmt.Println()
+	}
+	printWeeklyVolumes(os.Stdout, weeklyStats(records))
+	fmt.Println()
+	printBusiestHours(os.Stdout, agg, *hours)
+	fmt.Println()
+	printPercentiles(os.Stdout, agg, records)
+	return nil
+}
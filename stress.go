@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/forecast"
+)
+
+// stressScenario is a synthetic team the stress command generates a
+// schedule for, built to push the generator past what it was tuned for.
+type stressScenario struct {
+	name  string
+	about string
+	// employees is the size of the roster.
+	employees int
+	// leaveWeek puts the whole roster on leave for the first week.
+	leaveWeek bool
+	// spike multiplies the calls of the third day.
+	spike float64
+}
+
+// stressScenarios is the suite the stress command runs.
+var stressScenarios = []stressScenario{
+	{name: "tiny-roster", about: "a single employee", employees: 1},
+	{name: "huge-roster", about: "500 employees", employees: 500},
+	{name: "everyone-on-leave", about: "the whole roster on leave for the first week", employees: 10, leaveWeek: true},
+	{name: "spike-day", about: "one day with 50 times the usual calls", employees: 10, spike: 50},
+}
+
+func stressScenarioNames() []string {
+	names := make([]string, len(stressScenarios))
+	for i, sc := range stressScenarios {
+		names[i] = sc.name
+	}
+	return names
+}
+
+// stressAgent names the nth employee of a synthetic roster.
+func stressAgent(n int) string {
+	return fmt.Sprintf("Agent %03d", n)
+}
+
+// stressCalls is the daily call volume of the synthetic forecasts.
+const stressCalls = 100
+
+// setUp writes the scenario's data directory into dir: a config with the
+// shift catalog and pack pins of cfg and nothing else, so no integration
+// is reached, and the roster, leave, and forecast of the scenario. It
+// returns the path of the forecast.
+func (sc stressScenario) setUp(dir string, cfg Config, start time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	write := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+	}
+	if err := write("config.json", Config{Shifts: cfg.Shifts, Packs: cfg.Packs}); err != nil {
+		return "", err
+	}
+	roster := make([]Employee, sc.employees)
+	var leave []LeaveEntry
+	for i := range roster {
+		roster[i].Name = stressAgent(i + 1)
+		if sc.leaveWeek {
+			leave = append(leave, LeaveEntry{
+				Employee: roster[i].Name,
+				Start:    start.Format(forecast.DateLayout),
+				End:      start.AddDate(0, 0, 6).Format(forecast.DateLayout),
+				Type:     "Vacation",
+			})
+		}
+	}
+	if err := write("roster.json", roster); err != nil {
+		return "", err
+	}
+	if err := write("leave.json", leave); err != nil {
+		return "", err
+	}
+
+	fc := &forecast.Forecast{
+		Version:     forecast.Version,
+		GeneratedAt: time.Now().UTC(),
+		Model:       "stress",
+		Percentile:  75,
+		Threshold:   stressCalls,
+		Start:       start.Format(forecast.DateLayout),
+		Weeks:       forecastWeeks,
+	}
+	for i, date := range forecast.Dates(start, forecastWeeks) {
+		day := forecast.Day{Date: date.Format(forecast.DateLayout), Calls: stressCalls}
+		if i == 2 && sc.spike > 0 {
+			day.Calls *= sc.spike
+		}
+		day.HighVolume = day.Calls > fc.Threshold
+		fc.Days = append(fc.Days, day)
+	}
+	for h := 8; h < 20; h++ {
+		fc.HourShare[h] = 1.0 / 12
+	}
+	path := filepath.Join(dir, "forecast.json")
+	if err := fc.Save(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// stressResult is how the generator held up in one scenario.
+type stressResult struct {
+	scenario string
+	// graceful is whether the run succeeded, or failed with a stable exit
+	// code and an error report, in time and without panicking.
+	graceful bool
+	code     int
+	kind     string
+	detail   string
+	seconds  float64
+	log      string
+}
+
+// runStressScenario generates the scenario's schedule in a child process
+// with its data directory in dir, so a panic or hang ends only that run,
+// and judges how it ended.
+func runStressScenario(self string, sc stressScenario, dir string, cfg Config, start time.Time, timeout time.Duration, flags []string) stressResult {
+	res := stressResult{scenario: sc.name, log: filepath.Join(dir, "run.log")}
+	fail := func(err error) stressResult {
+		res.code, res.kind, res.detail = 1, "error", err.Error()
+		return res
+	}
+	fc, err := sc.setUp(dir, cfg, start)
+	if err != nil {
+		return fail(fmt.Errorf("error setting up scenario: %w", err))
+	}
+	logFile, err := os.Create(res.log)
+	if err != nil {
+		return fail(err)
+	}
+	defer logFile.Close()
+
+	args := []string{"--error-format", "json"}
+	if offline {
+		args = append(args, "--offline")
+	}
+	args = append(args, "generate", "-forecast", fc, "-out", filepath.Join(dir, "out"))
+	args = append(args, flags...)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Env = append(os.Environ(), "SCHEDULER_DATA_DIR="+dir, "SCHEDULER_CONFIG=")
+	cmd.Stdout = logFile
+	cmd.Stderr = io.MultiWriter(logFile, &stderr)
+	began := time.Now()
+	err = cmd.Run()
+	res.seconds = time.Since(began).Seconds()
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		res.code, res.kind, res.detail = -1, "timeout", fmt.Sprintf("still running after %s", timeout)
+		return res
+	case bytes.Contains(stderr.Bytes(), []byte("panic: ")):
+		res.code, res.kind = -1, "panic"
+		res.detail = string(lastPanic(stderr.Bytes()))
+		return res
+	case errors.As(err, &exitErr):
+		res.code, res.kind = exitErr.ExitCode(), "error"
+		var report errorReport
+		if json.Unmarshal(lastLine(stderr.Bytes()), &report) == nil && report.Error != "" {
+			res.kind, res.detail = report.Kind, report.Error
+		}
+		_, stable := exitKinds[res.code]
+		res.graceful = stable && res.detail != ""
+		if res.detail == "" {
+			res.detail = "no error report"
+		}
+		return res
+	case err != nil:
+		return fail(err)
+	}
+	res.kind, res.graceful = "ok", true
+	if run := newRunSince(dir, nil); run != nil {
+		res.detail = fmt.Sprintf("%d violations, %d errors", run.Violations, run.Errors)
+	}
+	return res
+}
+
+// lastPanic returns the line a panic starts with.
+func lastPanic(output []byte) []byte {
+	i := bytes.LastIndex(output, []byte("panic: "))
+	line, _, _ := bytes.Cut(output[i:], []byte("\n"))
+	return line
+}
+
+// runStress runs the generator against the synthetic scenarios and checks
+// that it degrades gracefully on each: it either writes a schedule or
+// fails with a stable exit code and a clear report, without panicking and
+// within the time limit.
+func runStress(args []string) error {
+	fs := flag.NewFlagSet("stress", flag.ContinueOnError)
+	only := fs.String("scenarios", "", "comma-separated scenarios to run (default all): "+strings.Join(stressScenarioNames(), ", "))
+	timeout := fs.Duration("timeout", 2*time.Minute, "time each scenario may take")
+	solver := fs.String("solver", solverLocal, "what writes the schedules: "+strings.Join(solverNames, ", "))
+	out := fs.String("out", "", "directory to keep each scenario's data, log, and schedule in (default a temporary one, removed afterwards)")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	if *timeout <= 0 {
+		return withExitCode(exitInput, errors.New("timeout must be positive"))
+	}
+	if err := validSolver(*solver); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	scenarios := stressScenarios
+	if *only != "" {
+		scenarios = nil
+		for _, name := range strings.Split(*only, ",") {
+			i := slices.Index(stressScenarioNames(), strings.TrimSpace(name))
+			if i < 0 {
+				return withExitCode(exitInput, fmt.Errorf("unknown stress scenario %q (want %s)", name, strings.Join(stressScenarioNames(), ", ")))
+			}
+			scenarios = append(scenarios, stressScenarios[i])
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error finding the scheduler binary: %w", err)
+	}
+	dir := *out
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "stress-*"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	start := nextMonday(time.Now())
+	flags := append([]string{"-solver", *solver}, fs.Args()...)
+	var results []stressResult
+	for _, sc := range scenarios {
+		log.Printf("Stress scenario %s: %s", sc.name, sc.about)
+		res := runStressScenario(self, sc, filepath.Join(dir, sc.name), cfg, start, *timeout, flags)
+		results = append(results, res)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCENARIO\tGRACEFUL\tRESULT\tCODE\tSECONDS\tDETAIL")
+	failed := 0
+	for _, r := range results {
+		verdict := "yes"
+		if !r.graceful {
+			verdict = "NO"
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.1f\t%s\n", r.scenario, verdict, r.kind, r.code, r.seconds, r.detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if *out != "" {
+		log.Printf("Scenario data, logs, and schedules kept in %s", *out)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stress scenarios didn't degrade gracefully", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// auditEntry is one line of the audit log, recording a change made to a
+// published schedule.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	By     string    `json:"by,omitempty"`
+	Week   string    `json:"week"`
+	Day    string    `json:"day"`
+	// Before and After are the cells of each affected employee.
+	Before map[string]string `json:"before"`
+	After  map[string]string `json:"after"`
+	Frozen bool              `json:"frozen"`
+	Reason string            `json:"reason,omitempty"`
+	// Notice marks a change inside the notice period of the collective
+	// agreement, made for ReasonCode.
+	Notice     bool   `json:"notice,omitempty"`
+	ReasonCode string `json:"reason_code,omitempty"`
+}
+
+func auditPath() string {
+	return filepath.Join(dataDir(), "audit.jsonl")
+}
+
+// appendAudit adds an entry to the audit log.
+func appendAudit(entry auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	f, err := os.OpenFile(auditPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	return nil
+}
+
+// findDayColumn returns the column of a week's schedule matching day, given
+// either as the full column label or as a weekday name.
+func findDayColumn(objs []FlatSchedule, day string) (string, bool) {
+	for _, key := range buildHeaderForWeek(objs) {
+		if !strings.Contains(key, "(") {
+			continue
+		}
+		name, _, _ := strings.Cut(key, " ")
+		if strings.EqualFold(key, day) || strings.EqualFold(name, day) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// swapCells exchanges the cells of two employees on one day of a week. When
+// the second employee is off, this hands the first one's shift over to them.
+func swapCells(objs []FlatSchedule, key, a, b string) (before, after map[string]string, err error) {
+	var rowA, rowB FlatSchedule
+	for _, obj := range objs {
+		switch obj["Employee"] {
+		case a:
+			rowA = obj
+		case b:
+			rowB = obj
+		}
+	}
+	if rowA == nil {
+		return nil, nil, fmt.Errorf("%s is not on the schedule", a)
+	}
+	if rowB == nil {
+		return nil, nil, fmt.Errorf("%s is not on the schedule", b)
+	}
+	cellA, cellB := rowA[key], rowB[key]
+	if cellA == "" {
+		cellA = schedule.Off
+	}
+	if cellB == "" {
+		cellB = schedule.Off
+	}
+	if cellA == cellB {
+		return nil, nil, fmt.Errorf("%s and %s both have %q on %s", a, b, cellA, key)
+	}
+	rowA[key], rowB[key] = cellB, cellA
+	return map[string]string{a: cellA, b: cellB}, map[string]string{a: cellB, b: cellA}, nil
+}
+
+// runSwap exchanges two employees' assignments on one day of a published
+// schedule and records the change in the audit log. It is the way to change
+// days inside the freeze window.
+func runSwap(args []string) error {
+	fs := flag.NewFlagSet("swap", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the change is made, for the audit log")
+	by := fs.String("by", os.Getenv("USER"), "who makes the change, for the audit log")
+	code := fs.String("code", "", "reason code, required inside the notice period")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 5 {
+		return errors.New("usage: swap [-reason text] [-code code] [-by name] <schedule dir> <week> <day> <employee> <other employee>")
+	}
+	dir, week, day, a, b := scheduleDir(fs.Arg(0)), fs.Arg(1), fs.Arg(2), fs.Arg(3), fs.Arg(4)
+
+	entries, err := loadScheduleDir(dir)
+	if err != nil {
+		return err
+	}
+	var objs []FlatSchedule
+	for _, obj := range entries {
+		if obj["Week"] == week {
+			objs = append(objs, obj)
+		}
+	}
+	if len(objs) == 0 {
+		return fmt.Errorf("no schedule for %s in %s", week, dir)
+	}
+	key, found := findDayColumn(objs, day)
+	if !found {
+		return fmt.Errorf("no day %q in the schedule for %s", day, week)
+	}
+	before, after, err := swapCells(objs, key, a, b)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj["Employee"])
+	}
+	p, err := loadPolicy(names)
+	if err != nil {
+		return err
+	}
+	if err := p.cfg.Notice.check(key, *code, time.Now()); err != nil {
+		return err
+	}
+	violations, err := validateWeeks(map[string][]FlatSchedule{week: objs}, p.shifts, p.rulePack, p.rules(&Run{Employees: names}))
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		if v.Employee == a || v.Employee == b || v.Day == key {
+			log.Printf("Constraint violation: %s", v)
+		}
+	}
+
+	annotateHours(objs, p.shifts)
+	filename, err := writeWeekCSV(dir, week, objs, p.cfg.CSV)
+	if err != nil {
+		return fmt.Errorf("error writing schedule for %s: %w", week, err)
+	}
+	if err := refreshManifest(dir); err != nil {
+		return err
+	}
+	entry := auditEntry{
+		Time:   time.Now().UTC(),
+		Action: "swap",
+		By:     *by,
+		Week:   week,
+		Day:    key,
+		Before: before,
+		After:  after,
+		Frozen: p.cfg.Freeze.frozen(key, time.Now()),
+		Reason: *reason,
+	}
+	if p.cfg.Notice.within(key, time.Now()) {
+		entry.Notice, entry.ReasonCode = true, *code
+	}
+	if err := appendAudit(entry); err != nil {
+		return err
+	}
+	textUrgentChange(key, before, after)
+	bus.publish(SwapApproved{Schedule: dir, Week: week, Day: key, Employees: []string{a, b}, Before: before, After: after, By: *by, ReasonCode: *code})
+	log.Printf("Swapped %s (%s) and %s (%s) on %s, %s in %s", a, before[a], b, before[b], week, key, filename)
+	return nil
+}
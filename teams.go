@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// teamResult is how the generation of one team in a batch went.
+type teamResult struct {
+	Team       string  `json:"team"`
+	Code       int     `json:"code"`
+	Kind       string  `json:"kind"`
+	Error      string  `json:"error,omitempty"`
+	RunID      string  `json:"run_id,omitempty"`
+	Stage      string  `json:"stage,omitempty"`
+	Violations int     `json:"violations"`
+	Errors     int     `json:"errors"`
+	Folder     string  `json:"folder,omitempty"`
+	Log        string  `json:"log"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// teamDirs returns the teams of a directory: its subdirectories holding a
+// config.json, each a data directory of its own.
+func teamDirs(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading teams: %w", err)
+	}
+	teams := make(map[string]string)
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(path, "config.json")); e.IsDir() && err == nil {
+			teams[e.Name()] = path
+		}
+	}
+	if len(teams) == 0 {
+		return nil, fmt.Errorf("no team directories with a config.json in %s", dir)
+	}
+	return teams, nil
+}
+
+// runTeams generates a schedule for every team in a directory, running
+// this program once per team with the team's directory as its data
+// directory, so no state or output is shared between them.
+func runTeams(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	allTeams := fs.Bool("all-teams", false, "generate for every team directory")
+	parallel := fs.Int("parallel", 4, "teams to generate at once")
+	out := fs.String("out", ".", "directory to write each team's schedules under, in a folder named after the team")
+	plan := fs.Bool("plan", false, "plan each team's schedule instead of publishing it")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(exitInput, err)
+	}
+	if !*allTeams || fs.NArg() < 1 {
+		return withExitCode(exitInput, errors.New("usage: run -all-teams [-parallel 4] [-out dir] [-plan] <teams dir> [generate flags]"))
+	}
+	if *parallel < 1 {
+		return withExitCode(exitInput, errors.New("parallel must be at least 1"))
+	}
+	teams, err := teamDirs(fs.Arg(0))
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error finding the scheduler binary: %w", err)
+	}
+	names := make([]string, 0, len(teams))
+	for name := range teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]teamResult, len(names))
+	slots := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = runTeam(self, name, teams[name], filepath.Join(*out, name), *plan, fs.Args()[1:])
+			log.Printf("Team %s: %s", name, results[i].Kind)
+		}()
+	}
+	wg.Wait()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEAM\tRESULT\tRUN\tVIOLATIONS\tERRORS\tFOLDER")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", r.Team, r.Kind, r.RunID, r.Violations, r.Errors, r.Folder)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	summary := filepath.Join(*out, "teams_summary.json")
+	if err := os.WriteFile(summary, data, 0o644); err != nil {
+		return fmt.Errorf("error writing summary: %w", err)
+	}
+	log.Printf("Summary written to %s", summary)
+
+	failed, code := 0, 0
+	for _, r := range results {
+		if r.Code != exitOK {
+			failed++
+			if code == 0 || code == r.Code {
+				code = r.Code
+			} else {
+				code = 1
+			}
+		}
+	}
+	if failed > 0 {
+		return withExitCode(code, fmt.Errorf("%d of %d teams failed", failed, len(results)))
+	}
+	return nil
+}
+
+// runTeam generates one team's schedule in a child process, logging to
+// run.log in its output directory, and reads back the run it made.
+func runTeam(self, name, dataDir, outDir string, plan bool, flags []string) teamResult {
+	result := teamResult{Team: name, Log: filepath.Join(outDir, "run.log")}
+	fail := func(err error) teamResult {
+		result.Code, result.Kind, result.Error = 1, "error", err.Error()
+		return result
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fail(err)
+	}
+	logFile, err := os.Create(result.Log)
+	if err != nil {
+		return fail(err)
+	}
+	defer logFile.Close()
+
+	args := []string{"--error-format", "json"}
+	if offline {
+		args = append(args, "--offline")
+	}
+	if plan {
+		args = append(args, "plan")
+	}
+	args = append(args, "-out", outDir)
+	args = append(args, flags...)
+	var stderr bytes.Buffer
+	cmd := exec.Command(self, args...)
+	cmd.Env = append(os.Environ(), "SCHEDULER_DATA_DIR="+dataDir, "SCHEDULER_CONFIG=")
+	cmd.Stdout = logFile
+	cmd.Stderr = io.MultiWriter(logFile, &stderr)
+	before := historyFiles(dataDir)
+	start := time.Now()
+	err = cmd.Run()
+	result.Seconds = time.Since(start).Seconds()
+
+	result.Kind = "ok"
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.Code, result.Kind = exitErr.ExitCode(), "error"
+		var report errorReport
+		if json.Unmarshal(lastLine(stderr.Bytes()), &report) == nil && report.Error != "" {
+			result.Kind, result.Error = report.Kind, report.Error
+		}
+	} else if err != nil {
+		return fail(err)
+	}
+	if run := newRunSince(dataDir, before); run != nil {
+		result.RunID, result.Stage = run.ID, run.Stage
+		result.Violations, result.Errors, result.Folder = run.Violations, run.Errors, run.Folder
+	}
+	return result
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(output []byte) []byte {
+	var last []byte
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	return last
+}
+
+// newRunSince reads the newest run in a data directory's history that isn't
+// among before, or returns nil when there is none.
+func newRunSince(dataDir string, before map[string]bool) *Run {
+	paths, _ := filepath.Glob(filepath.Join(dataDir, "history", "*.json"))
+	sort.Strings(paths)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if before[paths[i]] {
+			continue
+		}
+		data, err := os.ReadFile(paths[i])
+		if err != nil {
+			return nil
+		}
+		var run Run
+		if json.Unmarshal(data, &run) != nil {
+			return nil
+		}
+		return &run
+	}
+	return nil
+}
+
+// historyFiles returns the set of run files in a data directory's history.
+func historyFiles(dataDir string) map[string]bool {
+	paths, _ := filepath.Glob(filepath.Join(dataDir, "history", "*.json"))
+	files := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		files[path] = true
+	}
+	return files
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// TimeClockConfig points at a time-clock API. TIME_CLOCK_URL and
+// TIME_CLOCK_TOKEN take precedence over the config file.
+type TimeClockConfig struct {
+	// URL is called with start and end dates as query parameters and
+	// returns {"punches": [{"employee", "clock_in", "clock_out"}]}.
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+func timeClockSettings(cfg Config) TimeClockConfig {
+	var tc TimeClockConfig
+	if cfg.TimeClock != nil {
+		tc = *cfg.TimeClock
+	}
+	if v := os.Getenv("TIME_CLOCK_URL"); v != "" {
+		tc.URL = v
+	}
+	if v := os.Getenv("TIME_CLOCK_TOKEN"); v != "" {
+		tc.Token = v
+	}
+	return tc
+}
+
+// Punch is one clock-in/clock-out pair.
+type Punch struct {
+	Employee string    `json:"employee"`
+	ClockIn  time.Time `json:"clock_in"`
+	ClockOut time.Time `json:"clock_out"`
+}
+
+// punchLayouts are the timestamp layouts accepted in punch files.
+var punchLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+func parsePunchTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range punchLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// readPunches reads a CSV export with employee, clock_in, and clock_out
+// columns.
+func readPunches(path string) ([]Punch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening punches: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading punches header: %w", err)
+	}
+	idx := make(map[string]int)
+	for i, col := range header {
+		idx[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+	for _, col := range []string{"employee", "clock_in", "clock_out"} {
+		if _, ok := idx[col]; !ok {
+			return nil, fmt.Errorf("error reading punches header: no %q column", col)
+		}
+	}
+
+	var punches []Punch
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading punches: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		p := Punch{Employee: strings.TrimSpace(row[idx["employee"]])}
+		if p.ClockIn, err = parsePunchTime(row[idx["clock_in"]]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		if p.ClockOut, err = parsePunchTime(row[idx["clock_out"]]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		if !p.ClockOut.After(p.ClockIn) {
+			return nil, fmt.Errorf("%s line %d: clock-out is not after clock-in", path, line)
+		}
+		punches = append(punches, p)
+	}
+	return punches, nil
+}
+
+// fetchPunches pulls the punches of an inclusive date range from the
+// time-clock API.
+func fetchPunches(cfg Config, start, end time.Time) ([]Punch, error) {
+	tc := timeClockSettings(cfg)
+	if tc.URL == "" {
+		return nil, errors.New("TIME_CLOCK_URL not set")
+	}
+	query := url.Values{"start": {start.Format("2006-01-02")}, "end": {end.Format("2006-01-02")}}
+	req, err := http.NewRequest(http.MethodGet, tc.URL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if tc.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.Token)
+	}
+	var resp struct {
+		Punches []Punch `json:"punches"`
+	}
+	if err := doJSON(connectorClient, req, &resp); err != nil {
+		return nil, fmt.Errorf("error fetching punches: %w", err)
+	}
+	return resp.Punches, nil
+}
+
+// loadPunches reads punches from a CSV file, or from the time-clock API when
+// the input is written "timeclock:<start>/<end>".
+func loadPunches(input string) ([]Punch, error) {
+	rng, ok := strings.CutPrefix(input, "timeclock:")
+	if !ok {
+		return readPunches(input)
+	}
+	from, to, found := strings.Cut(rng, "/")
+	start, err1 := time.Parse("2006-01-02", from)
+	end, err2 := time.Parse("2006-01-02", to)
+	if !found || err1 != nil || err2 != nil || end.Before(start) {
+		return nil, fmt.Errorf("invalid input %q: want timeclock:<start>/<end>", input)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return fetchPunches(cfg, start, end)
+}
+
+// Variance compares one employee's scheduled and worked hours on one day.
+type Variance struct {
+	Employee string
+	Date     time.Time
+	// Shift is the schedule cell, empty for unscheduled work.
+	Shift          string
+	ScheduledStart schedule.Clock
+	ScheduledEnd   schedule.Clock
+	// ClockIn and ClockOut are the first and last punches of the day.
+	ClockIn, ClockOut time.Time
+	Scheduled, Worked float64
+}
+
+// Difference is the worked hours less the scheduled hours.
+func (v Variance) Difference() float64 {
+	return v.Worked - v.Scheduled
+}
+
+// reconcile compares punches with a schedule, day by day. Punches count
+// toward the day they start on, and only days the schedule covers are
+// reported.
+func reconcile(entries []FlatSchedule, shifts []schedule.Shift, punches []Punch) []Variance {
+	if len(punches) == 0 {
+		return nil
+	}
+	// The day columns carry no year; take the one nearest the punches.
+	ref := punches[0].ClockIn
+
+	type key struct {
+		employee string
+		date     time.Time
+	}
+	days := make(map[key]*Variance)
+	for _, obj := range entries {
+		for label, value := range obj {
+			date, ok := labelDate(label, ref)
+			if !ok {
+				continue
+			}
+			v := &Variance{Employee: obj["Employee"], Date: date}
+			days[key{v.Employee, date}] = v
+			start, end, found := cellWindow(shifts, value)
+			if !found {
+				continue
+			}
+			v.Shift = value
+			v.ScheduledStart, v.ScheduledEnd = start, end
+			v.Scheduled = float64(v.ScheduledEnd-v.ScheduledStart) / 60
+		}
+	}
+
+	for _, p := range punches {
+		date := time.Date(p.ClockIn.Year(), p.ClockIn.Month(), p.ClockIn.Day(), 0, 0, 0, 0, ref.Location())
+		v, ok := days[key{p.Employee, date}]
+		if !ok {
+			continue
+		}
+		if v.ClockIn.IsZero() || p.ClockIn.Before(v.ClockIn) {
+			v.ClockIn = p.ClockIn
+		}
+		if p.ClockOut.After(v.ClockOut) {
+			v.ClockOut = p.ClockOut
+		}
+		v.Worked += p.ClockOut.Sub(p.ClockIn).Hours()
+	}
+
+	var out []Variance
+	for _, v := range days {
+		if v.Shift != "" || v.Worked > 0 {
+			out = append(out, *v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Employee != out[j].Employee {
+			return out[i].Employee < out[j].Employee
+		}
+		return out[i].Date.Before(out[j].Date)
+	})
+	return out
+}
+
+// writeVarianceReport writes the day-by-day variances as CSV, for payroll
+// and adherence analytics.
+func writeVarianceReport(path string, variances []Variance) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating variance report: %w", err)
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.Write([]string{"Employee", "Date", "Shift", "Scheduled Start", "Scheduled End", "Clock In", "Clock Out", "Scheduled Hours", "Worked Hours", "Variance"})
+	punch := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("15:04")
+	}
+	hours := func(h float64) string { return strconv.FormatFloat(h, 'f', 2, 64) }
+	for _, v := range variances {
+		start, end := "", ""
+		if v.Shift != "" {
+			start, end = v.ScheduledStart.String(), v.ScheduledEnd.String()
+		}
+		w.Write([]string{v.Employee, v.Date.Format("2006-01-02"), v.Shift, start, end, punch(v.ClockIn), punch(v.ClockOut), hours(v.Scheduled), hours(v.Worked), hours(v.Difference())})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error writing variance report: %w", err)
+	}
+	return nil
+}
+
+// printVarianceSummary prints each employee's scheduled and worked hours,
+// with the shifts they missed and the days they worked unscheduled.
+func printVarianceSummary(w io.Writer, variances []Variance) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Employee\tScheduled\tWorked\tVariance\tMissed\tUnscheduled\t")
+	var (
+		name                      string
+		scheduled, worked         float64
+		missed, unscheduled, days int
+	)
+	flush := func() {
+		if days > 0 {
+			fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%+.2f\t%d\t%d\t\n", name, scheduled, worked, worked-scheduled, missed, unscheduled)
+		}
+	}
+	for _, v := range variances {
+		if v.Employee != name {
+			flush()
+			name, scheduled, worked, missed, unscheduled, days = v.Employee, 0, 0, 0, 0, 0
+		}
+		days++
+		scheduled += v.Scheduled
+		worked += v.Worked
+		switch {
+		case v.Shift != "" && v.Worked == 0:
+			missed++
+		case v.Shift == "" && v.Worked > 0:
+			unscheduled++
+		}
+	}
+	flush()
+	tw.Flush()
+}
+
+// runActuals reconciles time-clock punches against a published schedule.
+func runActuals(args []string) error {
+	fs := flag.NewFlagSet("actuals", flag.ContinueOnError)
+	out := fs.String("out", "variance.csv", "where to write the day-by-day variance report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("usage: actuals [-out variance.csv] <schedule dir> <punches.csv | timeclock:<start>/<end>>")
+	}
+	entries, err := loadScheduleDir(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	shifts, err := loadShiftCatalog()
+	if err != nil {
+		return err
+	}
+	punches, err := loadPunches(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	variances := reconcile(entries, shifts, punches)
+	if err := writeVarianceReport(*out, variances); err != nil {
+		return err
+	}
+	printVarianceSummary(os.Stdout, variances)
+	fmt.Printf("Variance report written to %s\n", *out)
+	return nil
+}
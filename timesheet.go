@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// timesheetDay is one day on an employee's monthly timesheet.
+type timesheetDay struct {
+	Date  time.Time
+	Shift string
+	// Start and End are the scheduled hours, empty for unscheduled work.
+	Start, End string
+	// In and Out are the first and last punches of the day, zero without
+	// attendance.
+	In, Out time.Time
+	Break   time.Duration
+	Hours   float64
+}
+
+// timesheet is one employee's days of a month, for HR to file once signed.
+type timesheet struct {
+	Employee string
+	ID       string
+	Month    time.Time
+	// Attendance is whether the hours come from punches rather than the
+	// schedule.
+	Attendance bool
+	Days       []timesheetDay
+}
+
+func (t *timesheet) hours() float64 {
+	var total float64
+	for _, d := range t.Days {
+		total += d.Hours
+	}
+	return total
+}
+
+// timesheetDays lists each employee's days of the schedule falling in the
+// month. With punches, the hours are those worked, from the first punch in
+// to the last punch out less the gaps between punches; scheduled days
+// without punches count no hours. Without punches, the scheduled shifts
+// count net of the breaks the config owes them, as in the register.
+func timesheetDays(entries []FlatSchedule, shifts []schedule.Shift, punches []Punch, cfg RegisterConfig, month time.Time) map[string][]timesheetDay {
+	inMonth := func(t time.Time) bool { return t.Year() == month.Year() && t.Month() == month.Month() }
+	days := make(map[string][]timesheetDay)
+	if len(punches) > 0 {
+		for _, v := range reconcile(entries, shifts, punches) {
+			if !inMonth(v.Date) {
+				continue
+			}
+			d := timesheetDay{Date: v.Date, In: v.ClockIn, Out: v.ClockOut, Hours: v.Worked}
+			if v.Shift != "" {
+				d.Shift, _ = schedule.SplitCell(v.Shift)
+				d.Start, d.End = v.ScheduledStart.String(), v.ScheduledEnd.String()
+			}
+			if v.Worked > 0 {
+				d.Break = v.ClockOut.Sub(v.ClockIn) - time.Duration(v.Worked*float64(time.Hour))
+			}
+			days[v.Employee] = append(days[v.Employee], d)
+		}
+		return days
+	}
+	// The day columns carry no year; take the one nearest the month.
+	for _, sh := range exportedShifts(entries, shifts, month.AddDate(0, 0, 14)) {
+		if !inMonth(sh.Start) {
+			continue
+		}
+		end := sh.End
+		if !end.After(sh.Start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		span := end.Sub(sh.Start)
+		brk := cfg.breakFor(span.Hours())
+		days[sh.Employee] = append(days[sh.Employee], timesheetDay{
+			Date:  time.Date(sh.Start.Year(), sh.Start.Month(), sh.Start.Day(), 0, 0, 0, 0, sh.Start.Location()),
+			Shift: sh.Shift,
+			Start: sh.Start.Format("15:04"),
+			End:   sh.End.Format("15:04"),
+			Break: brk,
+			Hours: (span - brk).Hours(),
+		})
+	}
+	return days
+}
+
+// clockTime formats a punch as hours and minutes, or empty when missing.
+func clockTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("15:04")
+}
+
+// timesheetSource says where a timesheet's hours come from.
+func timesheetSource(t *timesheet) string {
+	if t.Attendance {
+		return "Hours are from recorded attendance, net of the gaps between punches."
+	}
+	return "Hours are from the published schedule, net of the breaks owed."
+}
+
+// writeTimesheetCSV writes a timesheet as CSV: the employee and month, a
+// row per day with a total, and lines for the employee's and supervisor's
+// signatures.
+func writeTimesheetCSV(w io.Writer, t *timesheet, cc CSVConfig) error {
+	cw, err := newCSVWriter(w, cc)
+	if err != nil {
+		return err
+	}
+	hours := func(h float64) string { return strconv.FormatFloat(h, 'f', 2, 64) }
+	cw.Write([]string{"Employee", t.Employee})
+	cw.Write([]string{"Employee ID", t.ID})
+	cw.Write([]string{"Month", t.Month.Format("2006-01")})
+	cw.Write(nil)
+	cw.Write([]string{"Date", "Day", "Shift", "Scheduled Start", "Scheduled End", "Clock In", "Clock Out", "Break Minutes", "Hours"})
+	for _, d := range t.Days {
+		cw.Write([]string{d.Date.Format(time.DateOnly), d.Date.Weekday().String(), d.Shift, d.Start, d.End,
+			clockTime(d.In), clockTime(d.Out), strconv.Itoa(int(d.Break.Minutes())), hours(d.Hours)})
+	}
+	cw.Write([]string{"Total", "", "", "", "", "", "", "", hours(t.hours())})
+	cw.Write(nil)
+	cw.Write([]string{timesheetSource(t)})
+	cw.Write(nil)
+	cw.Write([]string{"Employee signature", "", "Date", ""})
+	cw.Write([]string{"Supervisor signature", "", "Date", ""})
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTimesheetPDF writes a timesheet as a one-page PDF laid out like the
+// CSV, with lines to sign on.
+func writeTimesheetPDF(w io.Writer, t *timesheet) error {
+	const left, size, lineHeight = 50, 9, 14
+	p := &pdfPage{}
+	y := float64(pdfPageHeight - 60)
+	p.text(left, y, 16, true, "Timesheet "+t.Month.Format("January 2006"))
+	y -= 26
+	p.text(left, y, 10, false, "Employee: "+t.Employee)
+	if t.ID != "" && t.ID != t.Employee {
+		p.text(300, y, 10, false, "Employee ID: "+t.ID)
+	}
+
+	columns := []struct {
+		x     float64
+		title string
+	}{{left, "Date"}, {115, "Day"}, {175, "Shift"}, {245, "Start"}, {290, "End"}, {340, "Clock In"}, {395, "Clock Out"}, {455, "Break"}, {505, "Hours"}}
+	y -= 30
+	for _, c := range columns {
+		p.text(c.x, y, size, true, c.title)
+	}
+	p.line(left, y-4, pdfPageWidth-left, y-4)
+	for _, d := range t.Days {
+		y -= lineHeight
+		cells := []string{d.Date.Format(time.DateOnly), d.Date.Weekday().String(), d.Shift, d.Start, d.End,
+			clockTime(d.In), clockTime(d.Out), fmt.Sprintf("%d min", int(d.Break.Minutes())), fmt.Sprintf("%.2f", d.Hours)}
+		for i, c := range columns {
+			p.text(c.x, y, size, false, cells[i])
+		}
+	}
+	p.line(left, y-6, pdfPageWidth-left, y-6)
+	y -= lineHeight + 6
+	p.text(left, y, size, true, fmt.Sprintf("Total: %d days", len(t.Days)))
+	p.text(columns[len(columns)-1].x, y, size, true, fmt.Sprintf("%.2f", t.hours()))
+	y -= lineHeight * 2
+	p.text(left, y, size, false, timesheetSource(t))
+
+	for _, signer := range []string{"Employee signature", "Supervisor signature"} {
+		y -= 50
+		p.line(left, y, 300, y)
+		p.line(360, y, pdfPageWidth-left, y)
+		p.text(left, y-12, size, false, signer)
+		p.text(360, y-12, size, false, "Date")
+	}
+	return writePDF(w, []*pdfPage{p})
+}
+
+// timesheetFileName returns the file name of an employee's timesheet.
+func timesheetFileName(month time.Time, employee, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, employee)
+	return fmt.Sprintf("timesheet_%s_%s.%s", month.Format("2006-01"), name, ext)
+}
+
+// runTimesheet writes a monthly timesheet for each employee on the
+// published schedules, from their punches when given, as PDF or CSV files
+// ready to sign and file.
+func runTimesheet(args []string) error {
+	fs := flag.NewFlagSet("timesheet", flag.ContinueOnError)
+	monthFlag := fs.String("month", time.Now().Format("2006-01"), "month of the timesheets, written 2006-01")
+	punchesFrom := fs.String("punches", "", "punches.csv or timeclock:<start>/<end> to record the hours worked")
+	format := fs.String("format", "pdf", "file format: pdf or csv")
+	employee := fs.String("employee", "", "write only this employee's timesheet")
+	out := fs.String("out", ".", "directory to write the timesheets in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: timesheet [-month 2006-01] [-punches punches.csv | timeclock:<start>/<end>] [-format pdf|csv] [-employee name] [-out dir] <schedule dir> ...")
+	}
+	if *format != "pdf" && *format != "csv" {
+		return fmt.Errorf("unknown format %q (want pdf or csv)", *format)
+	}
+	month, err := time.Parse("2006-01", *monthFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -month: %w", err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, e := range roster {
+		ids[e.Name] = e.HRISID
+	}
+	var punches []Punch
+	if *punchesFrom != "" {
+		if punches, err = loadPunches(*punchesFrom); err != nil {
+			return err
+		}
+	}
+
+	// A day on several of the schedules is taken from the one published
+	// last, so overlapping or regenerated schedules aren't counted twice.
+	dirs := make([]string, 0, fs.NArg())
+	for _, dir := range fs.Args() {
+		dirs = append(dirs, scheduleDir(dir))
+	}
+	published := make(map[string]time.Time, len(dirs))
+	for _, dir := range dirs {
+		published[dir] = publishedTime(dir)
+	}
+	sort.SliceStable(dirs, func(i, j int) bool { return published[dirs[i]].Before(published[dirs[j]]) })
+	byDate := make(map[string]map[string][]timesheetDay)
+	for _, dir := range dirs {
+		entries, err := loadScheduleDir(dir)
+		if err != nil {
+			return err
+		}
+		for name, days := range timesheetDays(entries, shifts, punches, cfg.Register, month) {
+			if *employee != "" && name != *employee {
+				continue
+			}
+			dates := make(map[string][]timesheetDay)
+			for _, d := range days {
+				date := d.Date.Format(time.DateOnly)
+				dates[date] = append(dates[date], d)
+			}
+			if byDate[name] == nil {
+				byDate[name] = make(map[string][]timesheetDay)
+			}
+			maps.Copy(byDate[name], dates)
+		}
+	}
+	sheets := make(map[string]*timesheet)
+	for name, dates := range byDate {
+		t := &timesheet{Employee: name, ID: ids[name], Month: month, Attendance: len(punches) > 0}
+		if t.ID == "" {
+			t.ID = name
+		}
+		for _, days := range dates {
+			t.Days = append(t.Days, days...)
+		}
+		sheets[name] = t
+	}
+	if len(sheets) == 0 {
+		return fmt.Errorf("no scheduled days in %s", month.Format("January 2006"))
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return fmt.Errorf("error creating timesheet directory: %w", err)
+	}
+
+	names := make([]string, 0, len(sheets))
+	for name := range sheets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "EMPLOYEE\tDAYS\tHOURS\tFILE")
+	for _, name := range names {
+		t := sheets[name]
+		sort.SliceStable(t.Days, func(i, j int) bool { return t.Days[i].Date.Before(t.Days[j].Date) })
+		path := filepath.Join(*out, timesheetFileName(month, name, *format))
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creating timesheet: %w", err)
+		}
+		if *format == "pdf" {
+			err = writeTimesheetPDF(file, t)
+		} else {
+			err = writeTimesheetCSV(file, t, cfg.CSV)
+		}
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing timesheet of %s: %w", name, err)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%s\n", name, len(t.Days), t.hours(), path)
+	}
+	return tw.Flush()
+}
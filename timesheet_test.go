@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimesheetTakesLatestPublished(t *testing.T) {
+	older := map[string][]string{"Ann": {"Early", "Early", "Off", "Off", "Off", "Off", "Off"}}
+	newer := map[string][]string{"Ann": {"Off", "Late", "Late", "Off", "Off", "Off", "Off"}}
+	tests := []struct {
+		name string
+		// stamp marks dir as published at.
+		stamp func(t *testing.T, dir string, at time.Time)
+	}{
+		{
+			name: "manifests",
+			stamp: func(t *testing.T, dir string, at time.Time) {
+				if err := writeManifest(dir, manifest{PublishedAt: at}); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "file times",
+			stamp: func(t *testing.T, dir string, at time.Time) {
+				paths, _ := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv"))
+				for _, path := range paths {
+					if err := os.Chtimes(path, at, at); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldDir, newDir, out := t.TempDir(), t.TempDir(), t.TempDir()
+			writePublished(t, oldDir, weekRows("Week 1", day(10, 19), older))
+			writePublished(t, newDir, weekRows("Week 1", day(10, 19), newer))
+			tt.stamp(t, oldDir, day(10, 1))
+			tt.stamp(t, newDir, day(10, 8))
+
+			// The newer schedule comes first, so order on the command line
+			// doesn't decide.
+			if err := runTimesheet([]string{"-month", "2026-10", "-format", "csv", "-out", out, newDir, oldDir}); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Open(filepath.Join(out, timesheetFileName(day(10, 1), "Ann", "csv")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			r := csv.NewReader(file)
+			r.FieldsPerRecord = -1
+			rows, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, row := range rows {
+				if len(row) > 2 && strings.HasPrefix(row[0], "2026-10-") {
+					got = append(got, row[0]+" "+row[2])
+				}
+			}
+			want := []string{"2026-10-19 Early", "2026-10-20 Late", "2026-10-21 Late"}
+			if !slices.Equal(got, want) {
+				t.Errorf("days %q, want %q", got, want)
+			}
+		})
+	}
+}
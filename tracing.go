@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracing follows the OpenTelemetry conventions: spans are exported as OTLP
+// JSON over HTTP to the collector named by OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT), so runs show up in Jaeger or Tempo.
+// Without an endpoint spans are discarded. A TRACEPARENT variable set by the
+// job runner makes every run a child of the runner's trace.
+
+// span is one timed stage of the pipeline.
+type span struct {
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]any
+	err      error
+}
+
+type spanContextKey struct{}
+
+// tracer buffers finished spans and ships them to the collector.
+type tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	service  string
+	parent   *span
+	spans    []*span
+	client   *http.Client
+}
+
+var (
+	tracerOnce   sync.Once
+	globalTracer *tracer
+)
+
+// getTracer configures the tracer from the environment on first use.
+func getTracer() *tracer {
+	tracerOnce.Do(func() {
+		t := &tracer{
+			service: os.Getenv("OTEL_SERVICE_NAME"),
+			client:  &http.Client{Timeout: 10 * time.Second},
+		}
+		if t.service == "" {
+			t.service = "employee-scheduler"
+		}
+		if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+			t.endpoint = endpoint
+		} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+			t.endpoint = strings.TrimRight(endpoint, "/") + "/v1/traces"
+		}
+		t.parent = parseTraceparent(os.Getenv("TRACEPARENT"))
+		globalTracer = t
+	})
+	return globalTracer
+}
+
+// parseTraceparent reads a W3C traceparent header value.
+func parseTraceparent(value string) *span {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return nil
+	}
+	return &span{traceID: parts[1], spanID: parts[2]}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("error reading random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// startSpan starts a span as a child of the span in ctx, or of the job
+// runner's trace when ctx holds none.
+func startSpan(ctx context.Context, name string) (context.Context, *span) {
+	s := &span{name: name, spanID: randomHex(8), start: time.Now(), attrs: make(map[string]any)}
+	parent, _ := ctx.Value(spanContextKey{}).(*span)
+	if parent == nil {
+		parent = getTracer().parent
+	}
+	if parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// setAttr records an attribute. Values may be strings, ints, floats, or bools.
+func (s *span) setAttr(key string, value any) {
+	s.attrs[key] = value
+}
+
+// finish ends the span, marking it as failed when err is not nil.
+func (s *span) finish(err error) {
+	s.end = time.Now()
+	s.err = err
+	t := getTracer()
+	if t.endpoint == "" {
+		return
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+}
+
+// OTLP JSON encoding, see opentelemetry-proto's trace.proto.
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func otlpAttr(key string, value any) otlpAttribute {
+	var v otlpValue
+	switch x := value.(type) {
+	case string:
+		v.StringValue = &x
+	case int:
+		s := strconv.Itoa(x)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &x
+	case bool:
+		v.BoolValue = &x
+	default:
+		s := fmt.Sprint(x)
+		v.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
+
+func (s *span) otlp() otlpSpan {
+	out := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              1, // SPAN_KIND_INTERNAL
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: 1},
+	}
+	for key, value := range s.attrs {
+		out.Attributes = append(out.Attributes, otlpAttr(key, value))
+	}
+	if s.err != nil {
+		out.Status = otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	return out
+}
+
+// flushTracing sends all buffered spans to the collector. Export failures are
+// logged rather than failing the run.
+func flushTracing(ctx context.Context) {
+	t := getTracer()
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, s.otlp())
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{otlpAttr("service.name", t.service)},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "employee-scheduler"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding spans: %v", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error exporting spans: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		log.Printf("Error exporting spans: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Error exporting spans: collector returned %s", resp.Status)
+	}
+}
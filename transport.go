@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-schedular/schedule"
+	"employee-schedular/validator"
+)
+
+// rosterWorkHours returns the earliest start and latest end times of the
+// scheduled employees, for those who rely on public transport.
+func rosterWorkHours(roster []Employee, employees []string) (map[string]validator.WorkHours, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	hours := make(map[string]validator.WorkHours)
+	for _, e := range roster {
+		if !scheduled[e.Name] || (e.EarliestStart == "" && e.LatestEnd == "") {
+			continue
+		}
+		wh := validator.WorkHours{EarliestStart: 0, LatestEnd: 24 * 60}
+		var err error
+		if e.EarliestStart != "" {
+			if wh.EarliestStart, err = schedule.ParseClock(e.EarliestStart); err != nil {
+				return nil, fmt.Errorf("employee %s: earliest start: %w", e.Name, err)
+			}
+		}
+		if e.LatestEnd != "" {
+			if wh.LatestEnd, err = schedule.ParseClock(e.LatestEnd); err != nil {
+				return nil, fmt.Errorf("employee %s: latest end: %w", e.Name, err)
+			}
+		}
+		hours[e.Name] = wh
+	}
+	return hours, nil
+}
+
+// workHoursNote describes the transport cutoffs for the scheduling prompt.
+func workHoursNote(hours map[string]validator.WorkHours) string {
+	names := make([]string, 0, len(hours))
+	for name := range hours {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		wh := hours[name]
+		var limits []string
+		if wh.EarliestStart > 0 {
+			limits = append(limits, "start before "+wh.EarliestStart.String())
+		}
+		if wh.LatestEnd < 24*60 {
+			limits = append(limits, "finish after "+wh.LatestEnd.String())
+		}
+		parts = append(parts, fmt.Sprintf("%s can't %s", name, strings.Join(limits, " or ")))
+	}
+	return "Transport cutoffs: " + strings.Join(parts, "; ") +
+		". Only assign them shifts that fit, even if that means fewer of their preferred shifts."
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pack is a versioned set of the built-in checks. A new check, or a change
+// to what a check accepts, goes into a new pack, so schedules validated
+// under a pinned pack are judged the same way after an upgrade.
+type Pack struct {
+	Version        string
+	EmployeeChecks []EmployeeCheck
+	WeekChecks     []WeekCheck
+}
+
+// packs are the rule packs, oldest first.
+var packs = []Pack{
+	{
+		Version:        "1",
+		EmployeeChecks: []EmployeeCheck{checkWeeklyHours, checkMonthlyHours, checkOvertime, checkShiftLengths, checkWorkdays, checkConsecutiveShifts, checkRotation, checkRotationDirection, checkNightShifts, checkUnavailable, checkLeave, checkWorkHours, checkCapacity, checkCertifications, checkMinors},
+		WeekChecks:     []WeekCheck{checkCoverage, checkShiftDemand, checkPairings, checkSafety, checkVolunteers, checkSkills},
+	},
+	{
+		// 2 adds the cap of two days off a week, allows going back to the
+		// earliest shift after a day off from any shift, and caps night
+		// shifts per calendar month rather than per schedule.
+		Version:        "2",
+		EmployeeChecks: []EmployeeCheck{checkWeeklyHours, checkMonthlyHours, checkOvertime, checkShiftLengths, checkWorkdays, checkDaysOff, checkConsecutiveShifts, checkRotation, checkRotationAfterRest, checkMonthlyNightShifts, checkUnavailable, checkLeave, checkWorkHours, checkCapacity, checkCertifications, checkMinors},
+		WeekChecks:     []WeekCheck{checkCoverage, checkShiftDemand, checkPairings, checkSafety, checkVolunteers, checkSkills},
+	},
+}
+
+// PackVersions returns the versions of the rule packs, oldest first.
+func PackVersions() []string {
+	versions := make([]string, len(packs))
+	for i, p := range packs {
+		versions[i] = p.Version
+	}
+	return versions
+}
+
+// LookupPack returns the rule pack of a version, the latest when version
+// is empty.
+func LookupPack(version string) (Pack, error) {
+	if version == "" {
+		return packs[len(packs)-1], nil
+	}
+	for _, p := range packs {
+		if p.Version == version {
+			return p, nil
+		}
+	}
+	return Pack{}, fmt.Errorf("unknown rule pack %q (this version has %s)", version, strings.Join(PackVersions(), ", "))
+}
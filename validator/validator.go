@@ -0,0 +1,1294 @@
+// Package validator checks a parsed schedule against the operational
+// constraints given to the generator.
+package validator
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// Severity tells whether a violation makes a schedule unusable.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Violation is one broken constraint.
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Employee string   `json:"employee,omitempty"`
+	Week     int      `json:"week,omitempty"`
+	Day      string   `json:"day,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (v Violation) String() string {
+	var where []string
+	if v.Employee != "" {
+		where = append(where, v.Employee)
+	}
+	week := ""
+	if v.Week > 0 {
+		week = fmt.Sprintf("Week %d", v.Week)
+	}
+	if v.Day != "" {
+		week = strings.TrimSpace(week + " " + v.Day)
+	}
+	if week != "" {
+		where = append(where, week)
+	}
+	return fmt.Sprintf("[%s] %s: %s (%s)", v.Severity, strings.Join(where, ", "), v.Message, v.Rule)
+}
+
+// Rules holds the limits the checks enforce.
+type Rules struct {
+	MaxWeeklyHours  float64
+	MaxMonthlyHours float64
+	MinPerShift     int
+	// MaxDailyOvertime and MaxWeeklyOvertime cap the overtime hours of a day
+	// and of a week. Overtime doesn't count toward the weekly and monthly
+	// hours. Zero means no cap.
+	MaxDailyOvertime  float64
+	MaxWeeklyOvertime float64
+	// MaxConsecutiveSameShift caps the days in a row on one shift.
+	MaxConsecutiveSameShift int
+	// MaxDaysOff caps the days off in a week, not counting leave. Employees
+	// on a compressed workweek are left to checkWorkdays.
+	MaxDaysOff int
+	// RotationWeeks is how many weeks employees stay on a shift.
+	RotationWeeks int
+	// ForwardRotation rejects moves to a shift starting earlier than the
+	// previous one, except from the latest shift to the earliest across a
+	// day off.
+	ForwardRotation bool
+	// ShiftTargets is the headcount each shift needs per day given its
+	// share of demand. Shifts without a target only need MinPerShift.
+	ShiftTargets map[string]int
+	// LengthLimits caps the shifts of a length, in hours, an employee works
+	// per week.
+	LengthLimits map[float64]int
+	// NightShifts names the shifts NightShiftCaps counts.
+	NightShifts []string
+	// NightShiftCaps caps each employee's night shifts over the schedule.
+	NightShiftCaps map[string]int
+	// Unavailable lists the recurring times each employee can't work.
+	Unavailable map[string][]Unavailability
+	// Leave lists each employee's approved leave.
+	Leave       map[string][]Leave
+	Pairings    []Pairing
+	Separations []Separation
+	Safety      []SafetyRule
+	// WorkHours bounds each employee's working hours.
+	WorkHours map[string]WorkHours
+	// Volunteers lists, per shift, the employees who asked for more of it.
+	// They should get the shift before anyone else.
+	Volunteers map[string][]string
+	// RequiredSkills lists, per shift, the skills someone working it must
+	// have, and Skills lists each employee's skills.
+	RequiredSkills map[string][]string
+	Skills         map[string][]string
+	// Workdays is the exact number of days per week worked by employees on
+	// a compressed workweek.
+	Workdays map[string]int
+	// Capacity lists each employee's temporary limits, such as reduced
+	// hours during exams.
+	Capacity map[string][]Capacity
+	// RequiredCertifications lists, per shift, the certifications everyone
+	// working it must hold, and Certifications those of each employee.
+	RequiredCertifications map[string][]string
+	Certifications         map[string][]Certification
+	// Today places schedule days, which carry no year, in the year nearest
+	// to it, for checks against dates such as certification expiry.
+	Today time.Time
+	// Minors lists the employees under 18, who may work at most
+	// MinorMaxDailyHours a day, on shifts ending by MinorLatestEnd.
+	Minors             []string
+	MinorLatestEnd     schedule.Clock
+	MinorMaxDailyHours float64
+}
+
+// DefaultRules returns the limits stated in the scheduling prompt.
+func DefaultRules() Rules {
+	return Rules{
+		MaxWeeklyHours:          45,
+		MaxMonthlyHours:         225,
+		MinPerShift:             2,
+		MaxConsecutiveSameShift: 5,
+		MaxDaysOff:              2,
+		RotationWeeks:           1,
+		ForwardRotation:         true,
+	}
+}
+
+// Pairing keeps Employee on the same shift as With whenever Employee works.
+type Pairing struct {
+	Employee string `json:"employee"`
+	With     string `json:"with"`
+}
+
+// Separation keeps two employees off the same shift of a day.
+type Separation struct {
+	Employees [2]string `json:"employees"`
+}
+
+// SafetyRule requires a minimum number of people together, either on a
+// shift whenever anyone works it, or on site at every moment from a time
+// of day on whenever anyone is there.
+type SafetyRule struct {
+	Shift    string          `json:"shift,omitempty"`
+	After    *schedule.Clock `json:"after,omitempty"`
+	MinStaff int             `json:"min_staff"`
+	Label    string          `json:"label,omitempty"`
+}
+
+// Unavailability is a recurring window of a weekday an employee can't
+// work.
+type Unavailability struct {
+	Weekday  time.Weekday
+	From, To schedule.Clock
+	Label    string
+}
+
+// Leave is approved leave from Start to End, both inclusive dates.
+type Leave struct {
+	Start, End time.Time
+	Label      string
+}
+
+// covers reports whether the leave includes a schedule day.
+func (l Leave) covers(d schedule.Day, today time.Time) bool {
+	return periodCovers(l.Start, l.End, d, today)
+}
+
+// Capacity is a temporary limit on an employee's work from Start to End,
+// both inclusive dates, such as fewer hours and no lates during exams.
+type Capacity struct {
+	Start, End time.Time
+	// MaxWeeklyHours caps the hours worked within the period in a week.
+	// Zero means no cap.
+	MaxWeeklyHours float64
+	// NoShifts are the shifts the employee can't work within the period.
+	NoShifts []string
+	Label    string
+}
+
+// covers reports whether the period of the limit includes a schedule day.
+func (c Capacity) covers(d schedule.Day, today time.Time) bool {
+	return periodCovers(c.Start, c.End, d, today)
+}
+
+// Certification is one an employee holds, valid through Expires, or for
+// good when Expires is zero.
+type Certification struct {
+	Name    string
+	Expires time.Time
+}
+
+// dayDate returns the date of a schedule day in the year nearest today,
+// and false when the day names no month or there is no today.
+func dayDate(d schedule.Day, today time.Time) (time.Time, bool) {
+	if d.Month == 0 || d.DayOfMonth == 0 || today.IsZero() {
+		return time.Time{}, false
+	}
+	var best time.Time
+	for year := today.Year() - 1; year <= today.Year()+1; year++ {
+		date := time.Date(year, d.Month, d.DayOfMonth, 0, 0, 0, 0, today.Location())
+		if best.IsZero() || date.Sub(today).Abs() < best.Sub(today).Abs() {
+			best = date
+		}
+	}
+	return best, true
+}
+
+// periodCovers reports whether the dates from start to end include a
+// schedule day, placed in the year nearest today, or nearest the start of
+// the period when there is no today.
+func periodCovers(start, end time.Time, d schedule.Day, today time.Time) bool {
+	if today.IsZero() {
+		today = start
+	}
+	date, ok := dayDate(d, today)
+	if !ok {
+		return false
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, start.Location())
+	return !date.Before(start) && !date.After(end)
+}
+
+// WorkHours is the earliest an employee can start and the latest they can
+// finish on any day.
+type WorkHours struct {
+	EarliestStart, LatestEnd schedule.Clock
+}
+
+// EmployeeCheck inspects every week of one employee, in week order.
+type EmployeeCheck func(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation
+
+// WeekCheck inspects every employee's entry of one week.
+type WeekCheck func(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation
+
+// Validator runs checks over a schedule, spreading the work across a pool
+// of workers, one unit of work per employee and per week.
+type Validator struct {
+	Rules Rules
+	// Workers is the size of the worker pool; zero means GOMAXPROCS.
+	Workers        int
+	EmployeeChecks []EmployeeCheck
+	WeekChecks     []WeekCheck
+}
+
+// New returns a validator running the checks of the latest rule pack.
+func New(rules Rules) *Validator {
+	return NewPack(rules, packs[len(packs)-1])
+}
+
+// NewPack returns a validator running the checks of a rule pack.
+func NewPack(rules Rules, pack Pack) *Validator {
+	return &Validator{
+		Rules:          rules,
+		EmployeeChecks: pack.EmployeeChecks,
+		WeekChecks:     pack.WeekChecks,
+	}
+}
+
+// Validate returns every violation in the schedule, sorted by employee,
+// week, and rule, and by day within those, so the result doesn't depend on
+// the order the workers finish in.
+func (v *Validator) Validate(s *schedule.Schedule) []Violation {
+	byEmployee := make(map[string][]schedule.Entry)
+	byWeek := make(map[int][]schedule.Entry)
+	for _, e := range s.Entries {
+		byEmployee[e.Employee] = append(byEmployee[e.Employee], e)
+		byWeek[e.Week] = append(byWeek[e.Week], e)
+	}
+
+	var units []func() []Violation
+	for _, employee := range s.Employees() {
+		entries := byEmployee[employee]
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Week < entries[j].Week })
+		for _, check := range v.EmployeeChecks {
+			units = append(units, func() []Violation { return check(s, v.Rules, employee, entries) })
+		}
+	}
+	for _, week := range s.Weeks() {
+		entries := byWeek[week]
+		for _, check := range v.WeekChecks {
+			units = append(units, func() []Violation { return check(s, v.Rules, week, entries) })
+		}
+	}
+
+	workers := v.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([][]Violation, len(units))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = units[i]()
+			}
+		}()
+	}
+	for i := range units {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var violations []Violation
+	for _, r := range results {
+		violations = append(violations, r...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Employee != b.Employee {
+			return a.Employee < b.Employee
+		}
+		if a.Week != b.Week {
+			return a.Week < b.Week
+		}
+		return a.Rule < b.Rule
+	})
+	return violations
+}
+
+// HasErrors reports whether any violation is an error.
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// hours returns the scheduled hours of one entry, leaving out overtime.
+func hours(s *schedule.Schedule, e schedule.Entry) float64 {
+	total := 0.0
+	for _, d := range e.Days {
+		if !d.Overtime {
+			total += s.Hours(d)
+		}
+	}
+	return total
+}
+
+func checkWeeklyHours(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	var out []Violation
+	for _, e := range entries {
+		if h := hours(s, e); r.MaxWeeklyHours > 0 && h > r.MaxWeeklyHours {
+			out = append(out, Violation{
+				Rule:     "weekly-hours",
+				Severity: Error,
+				Employee: employee,
+				Week:     e.Week,
+				Message:  fmt.Sprintf("scheduled %.0f hours, more than the %.0f-hour weekly limit", h, r.MaxWeeklyHours),
+			})
+		}
+	}
+	return out
+}
+
+func checkOvertime(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	var out []Violation
+	for _, e := range entries {
+		weekly := 0.0
+		for _, d := range e.Days {
+			if !d.Overtime {
+				continue
+			}
+			h := s.Hours(d)
+			weekly += h
+			if r.MaxDailyOvertime > 0 && h > r.MaxDailyOvertime {
+				out = append(out, Violation{
+					Rule:     "daily-overtime",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("%.0f hours of overtime, more than the %.0f-hour daily overtime limit", h, r.MaxDailyOvertime),
+				})
+			}
+		}
+		if r.MaxWeeklyOvertime > 0 && weekly > r.MaxWeeklyOvertime {
+			out = append(out, Violation{
+				Rule:     "weekly-overtime",
+				Severity: Error,
+				Employee: employee,
+				Week:     e.Week,
+				Message:  fmt.Sprintf("%.0f hours of overtime, more than the %.0f-hour weekly overtime limit", weekly, r.MaxWeeklyOvertime),
+			})
+		}
+	}
+	return out
+}
+
+func checkMonthlyHours(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	total := 0.0
+	for _, e := range entries {
+		total += hours(s, e)
+	}
+	if r.MaxMonthlyHours > 0 && total > r.MaxMonthlyHours {
+		return []Violation{{
+			Rule:     "monthly-hours",
+			Severity: Error,
+			Employee: employee,
+			Message:  fmt.Sprintf("scheduled %.0f hours, more than the %.0f-hour monthly limit", total, r.MaxMonthlyHours),
+		}}
+	}
+	return nil
+}
+
+func checkShiftLengths(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if len(r.LengthLimits) == 0 {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		counts := make(map[float64]int)
+		for _, d := range e.Days {
+			if d.Shift != "" {
+				counts[s.Hours(d)]++
+			}
+		}
+		lengths := make([]float64, 0, len(counts))
+		for length := range counts {
+			lengths = append(lengths, length)
+		}
+		sort.Float64s(lengths)
+		for _, length := range lengths {
+			if max, ok := r.LengthLimits[length]; ok && counts[length] > max {
+				out = append(out, Violation{
+					Rule:     "shift-length",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Message:  fmt.Sprintf("scheduled %d %g-hour shifts, more than the %d allowed per week", counts[length], length, max),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkWorkdays(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	want, ok := r.Workdays[employee]
+	if !ok {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		worked := 0
+		for _, d := range e.Days {
+			if d.Shift != "" {
+				worked++
+			}
+		}
+		if worked != want {
+			out = append(out, Violation{
+				Rule:     "workweek",
+				Severity: Error,
+				Employee: employee,
+				Week:     e.Week,
+				Message:  fmt.Sprintf("scheduled %d days, but works a compressed week of %d days", worked, want),
+			})
+		}
+	}
+	return out
+}
+
+func checkDaysOff(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if _, compressed := r.Workdays[employee]; r.MaxDaysOff <= 0 || compressed {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		off := 0
+		for _, d := range e.Days {
+			onLeave := slices.ContainsFunc(r.Leave[employee], func(l Leave) bool { return l.covers(d, r.Today) })
+			if d.Shift == "" && !onLeave {
+				off++
+			}
+		}
+		if off > r.MaxDaysOff {
+			out = append(out, Violation{
+				Rule:     "days-off",
+				Severity: Warning,
+				Employee: employee,
+				Week:     e.Week,
+				Message:  fmt.Sprintf("%d days off, more than the %d allowed per week", off, r.MaxDaysOff),
+			})
+		}
+	}
+	return out
+}
+
+func checkConsecutiveShifts(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if r.MaxConsecutiveSameShift <= 0 {
+		return nil
+	}
+	var out []Violation
+	shift, run := "", 0
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" || d.Shift != shift {
+				shift, run = d.Shift, 0
+			}
+			if d.Shift == "" {
+				continue
+			}
+			run++
+			if run == r.MaxConsecutiveSameShift+1 {
+				out = append(out, Violation{
+					Rule:     "consecutive-shift",
+					Severity: Warning,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("more than %d days in a row on the %s shift", r.MaxConsecutiveSameShift, d.Shift),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// weekShift returns the shift an employee works most in a week, preferring
+// the earlier catalog entry on ties. It is empty for a week off.
+func weekShift(s *schedule.Schedule, e schedule.Entry) string {
+	counts := make(map[string]int)
+	for _, d := range e.Days {
+		if d.Shift != "" {
+			counts[d.Shift]++
+		}
+	}
+	best := ""
+	for _, sh := range s.Shifts {
+		if counts[sh.Name] > counts[best] {
+			best = sh.Name
+		}
+	}
+	return best
+}
+
+func checkRotation(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if r.RotationWeeks <= 0 {
+		return nil
+	}
+	var out []Violation
+	for i := 1; i < len(entries); i++ {
+		prev, cur := entries[i-1], entries[i]
+		if cur.Week != prev.Week+1 {
+			continue
+		}
+		from, to := weekShift(s, prev), weekShift(s, cur)
+		if from == "" || to == "" {
+			continue
+		}
+		rotate := prev.Week%r.RotationWeeks == 0
+		switch {
+		case rotate && from == to:
+			out = append(out, Violation{
+				Rule:     "rotation",
+				Severity: Warning,
+				Employee: employee,
+				Week:     cur.Week,
+				Message:  fmt.Sprintf("not rotated off the %s shift after %s", to, plural(r.RotationWeeks, "week")),
+			})
+		case !rotate && from != to:
+			out = append(out, Violation{
+				Rule:     "rotation",
+				Severity: Warning,
+				Employee: employee,
+				Week:     cur.Week,
+				Message:  fmt.Sprintf("moved from the %s to the %s shift before %s were up", from, to, plural(r.RotationWeeks, "week")),
+			})
+		}
+	}
+	return out
+}
+
+// checkRotationDirection reports moves to an earlier shift, except from the
+// latest shift back to the earliest after a day off. It is rule pack 1's
+// reading of forward rotation.
+func checkRotationDirection(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	return rotationDirection(s, r, employee, entries, false)
+}
+
+// checkRotationAfterRest reports moves to an earlier shift, except back to
+// the earliest after a day off from any shift, as the prompt allows.
+func checkRotationAfterRest(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	return rotationDirection(s, r, employee, entries, true)
+}
+
+// rotationDirection reports moves to an earlier shift. A move to the
+// earliest shift after a day off is allowed from the latest shift, or from
+// any shift with fromAny.
+func rotationDirection(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry, fromAny bool) []Violation {
+	if !r.ForwardRotation || len(s.Shifts) == 0 {
+		return nil
+	}
+	earliest, latest := s.Shifts[0], s.Shifts[0]
+	for _, sh := range s.Shifts {
+		if sh.Start < earliest.Start {
+			earliest = sh
+		}
+		if sh.Start > latest.Start {
+			latest = sh
+		}
+	}
+
+	var out []Violation
+	var prev schedule.Shift
+	working, rested := false, false
+	for _, e := range entries {
+		for _, d := range e.Days {
+			sh, ok := s.Shift(d.Shift)
+			if !ok {
+				rested = true
+				continue
+			}
+			wrap := rested && (fromAny || prev.Name == latest.Name) && sh.Name == earliest.Name
+			if working && sh.Start < prev.Start && !wrap {
+				out = append(out, Violation{
+					Rule:     "rotation-direction",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("rotated backward from the %s to the %s shift", prev.Name, sh.Name),
+				})
+			}
+			prev, working, rested = sh, true, false
+		}
+	}
+	return out
+}
+
+// checkNightShifts caps the night shifts over the whole schedule. It is
+// rule pack 1's reading of the monthly cap.
+func checkNightShifts(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	max, ok := r.NightShiftCaps[employee]
+	if !ok {
+		return nil
+	}
+	night := make(map[string]bool, len(r.NightShifts))
+	for _, name := range r.NightShifts {
+		night[name] = true
+	}
+	worked := 0
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if night[d.Shift] {
+				worked++
+			}
+		}
+	}
+	if worked > max {
+		return []Violation{{
+			Rule:     "night-shifts",
+			Severity: Error,
+			Employee: employee,
+			Message:  fmt.Sprintf("scheduled %d night shifts, more than the %d allowed per month", worked, max),
+		}}
+	}
+	return nil
+}
+
+// checkMonthlyNightShifts caps the night shifts in each calendar month.
+// Days whose label names no month count towards the month of the day
+// before them.
+func checkMonthlyNightShifts(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	max, ok := r.NightShiftCaps[employee]
+	if !ok {
+		return nil
+	}
+	night := make(map[string]bool, len(r.NightShifts))
+	for _, name := range r.NightShifts {
+		night[name] = true
+	}
+	type month struct {
+		name string
+		week int
+	}
+	var months []month
+	worked := make(map[string]int)
+	current := ""
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if date, ok := dayDate(d, r.Today); ok {
+				current = date.Format("January 2006")
+			} else if d.Month != 0 {
+				current = d.Month.String()
+			}
+			if !night[d.Shift] {
+				continue
+			}
+			if _, seen := worked[current]; !seen {
+				months = append(months, month{current, e.Week})
+			}
+			worked[current]++
+		}
+	}
+	var out []Violation
+	for _, m := range months {
+		if n := worked[m.name]; n > max {
+			where := "in " + m.name
+			if m.name == "" {
+				where = "in the schedule"
+			}
+			out = append(out, Violation{
+				Rule:     "night-shifts",
+				Severity: Error,
+				Employee: employee,
+				Week:     m.week,
+				Message:  fmt.Sprintf("scheduled %d night shifts %s, more than the %d allowed per month", n, where, max),
+			})
+		}
+	}
+	return out
+}
+
+// dayWindow returns the hours a day's shift covers.
+func dayWindow(s *schedule.Schedule, d schedule.Day) (schedule.Clock, schedule.Clock) {
+	if d.End > d.Start {
+		return d.Start, d.End
+	}
+	sh, _ := s.Shift(d.Shift)
+	return sh.Start, sh.End
+}
+
+func checkUnavailable(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	windows := r.Unavailable[employee]
+	if len(windows) == 0 {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" {
+				continue
+			}
+			start, end := dayWindow(s, d)
+			for _, u := range windows {
+				if u.Weekday != d.Weekday || start >= u.To || end <= u.From {
+					continue
+				}
+				reason := "an observance"
+				if u.Label != "" {
+					reason = u.Label
+				}
+				out = append(out, Violation{
+					Rule:     "observance",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("%s shift overlaps %s (%s-%s)", d.Shift, reason, u.From, u.To),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkLeave(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	leave := r.Leave[employee]
+	if len(leave) == 0 {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" {
+				continue
+			}
+			for _, l := range leave {
+				if !l.covers(d, r.Today) {
+					continue
+				}
+				reason := "leave"
+				if l.Label != "" {
+					reason = l.Label
+				}
+				out = append(out, Violation{
+					Rule:     "leave",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("scheduled for the %s shift while on %s", d.Shift, reason),
+				})
+				break
+			}
+		}
+	}
+	return out
+}
+
+func checkCapacity(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	limits := r.Capacity[employee]
+	if len(limits) == 0 {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		for _, c := range limits {
+			reason := "a reduced-hours period"
+			if c.Label != "" {
+				reason = c.Label
+			}
+			hours := 0.0
+			for _, d := range e.Days {
+				if d.Shift == "" || !c.covers(d, r.Today) {
+					continue
+				}
+				hours += s.Hours(d)
+				if slices.Contains(c.NoShifts, d.Shift) {
+					out = append(out, Violation{
+						Rule:     "capacity",
+						Severity: Error,
+						Employee: employee,
+						Week:     e.Week,
+						Day:      d.Label,
+						Message:  fmt.Sprintf("scheduled for the %s shift, which is ruled out during %s", d.Shift, reason),
+					})
+				}
+			}
+			if c.MaxWeeklyHours > 0 && hours > c.MaxWeeklyHours {
+				out = append(out, Violation{
+					Rule:     "capacity",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Message:  fmt.Sprintf("scheduled %.0f hours during %s, more than its %.0f-hour weekly limit", hours, reason, c.MaxWeeklyHours),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkCertifications(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if len(r.RequiredCertifications) == 0 {
+		return nil
+	}
+	held := r.Certifications[employee]
+	var out []Violation
+	for _, e := range entries {
+		for _, d := range e.Days {
+			date, dated := dayDate(d, r.Today)
+			for _, name := range r.RequiredCertifications[d.Shift] {
+				message := fmt.Sprintf("scheduled for the %s shift without the %s certification", d.Shift, name)
+				valid := false
+				for _, c := range held {
+					if c.Name != name {
+						continue
+					}
+					if c.Expires.IsZero() || !dated || !date.After(c.Expires) {
+						valid = true
+						break
+					}
+					message = fmt.Sprintf("scheduled for the %s shift with the %s certification expired on %s", d.Shift, name, c.Expires.Format(time.DateOnly))
+				}
+				if valid {
+					continue
+				}
+				out = append(out, Violation{
+					Rule:     "certification",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  message,
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkWorkHours(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	wh, ok := r.WorkHours[employee]
+	if !ok {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" {
+				continue
+			}
+			start, end := dayWindow(s, d)
+			var problems []string
+			if start < wh.EarliestStart {
+				problems = append(problems, fmt.Sprintf("starts at %s, before %s", start, wh.EarliestStart))
+			}
+			if end > wh.LatestEnd {
+				problems = append(problems, fmt.Sprintf("ends at %s, after %s", end, wh.LatestEnd))
+			}
+			if len(problems) > 0 {
+				out = append(out, Violation{
+					Rule:     "transport",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("%s shift %s", d.Shift, strings.Join(problems, " and ")),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkMinors(s *schedule.Schedule, r Rules, employee string, entries []schedule.Entry) []Violation {
+	if !slices.Contains(r.Minors, employee) {
+		return nil
+	}
+	var out []Violation
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" {
+				continue
+			}
+			var problems []string
+			if _, end := dayWindow(s, d); end > r.MinorLatestEnd {
+				problems = append(problems, fmt.Sprintf("ends at %s, after %s", end, r.MinorLatestEnd))
+			}
+			if h := s.Hours(d); r.MinorMaxDailyHours > 0 && h > r.MinorMaxDailyHours {
+				problems = append(problems, fmt.Sprintf("lasts %g hours, more than %g", h, r.MinorMaxDailyHours))
+			}
+			if len(problems) > 0 {
+				out = append(out, Violation{
+					Rule:     "minor",
+					Severity: Error,
+					Employee: employee,
+					Week:     e.Week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("%s shift %s (limits for under-18s)", d.Shift, strings.Join(problems, " and ")),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// slot is one shift on one day.
+type slot struct {
+	label string
+	shift string
+}
+
+// staffing counts the employees on each shift of each day, and returns the
+// day labels in the order they appear.
+func staffing(entries []schedule.Entry) (map[slot]int, []string) {
+	counts := make(map[slot]int)
+	var labels []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if !seen[d.Label] {
+				seen[d.Label] = true
+				labels = append(labels, d.Label)
+			}
+			if d.Shift != "" {
+				counts[slot{d.Label, d.Shift}]++
+			}
+		}
+	}
+	return counts, labels
+}
+
+func checkCoverage(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	counts, labels := staffing(entries)
+
+	var out []Violation
+	for _, label := range labels {
+		for _, sh := range s.Shifts {
+			if n := counts[slot{label, sh.Name}]; n < r.MinPerShift {
+				out = append(out, Violation{
+					Rule:     "coverage",
+					Severity: Error,
+					Week:     week,
+					Day:      label,
+					Message:  fmt.Sprintf("%s shift has %d of the required %d employees", sh.Name, n, r.MinPerShift),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// checkShiftDemand warns about shifts staffed below their demand-share
+// target on days that already meet the minimum coverage.
+func checkShiftDemand(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	if len(r.ShiftTargets) == 0 {
+		return nil
+	}
+	counts, labels := staffing(entries)
+
+	var out []Violation
+	for _, label := range labels {
+		for _, sh := range s.Shifts {
+			target := r.ShiftTargets[sh.Name]
+			if n := counts[slot{label, sh.Name}]; n >= r.MinPerShift && n < target {
+				out = append(out, Violation{
+					Rule:     "shift-demand",
+					Severity: Warning,
+					Week:     week,
+					Day:      label,
+					Message:  fmt.Sprintf("%s shift has %d of the %d employees its share of demand calls for", sh.Name, n, target),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkPairings(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	if len(r.Pairings) == 0 && len(r.Separations) == 0 {
+		return nil
+	}
+	// shifts maps an employee and day label to the shift worked.
+	type key struct{ employee, label string }
+	shifts := make(map[key]string)
+	var days []schedule.Day
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, d := range e.Days {
+			shifts[key{e.Employee, d.Label}] = d.Shift
+			if !seen[d.Label] {
+				seen[d.Label] = true
+				days = append(days, d)
+			}
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return (days[i].Weekday+6)%7 < (days[j].Weekday+6)%7 })
+
+	var out []Violation
+	for _, d := range days {
+		for _, p := range r.Pairings {
+			shift := shifts[key{p.Employee, d.Label}]
+			if shift != "" && shifts[key{p.With, d.Label}] != shift {
+				out = append(out, Violation{
+					Rule:     "pairing",
+					Severity: Error,
+					Employee: p.Employee,
+					Week:     week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("works the %s shift without %s", shift, p.With),
+				})
+			}
+		}
+		for _, sep := range r.Separations {
+			a, b := sep.Employees[0], sep.Employees[1]
+			if shift := shifts[key{a, d.Label}]; shift != "" && shift == shifts[key{b, d.Label}] {
+				out = append(out, Violation{
+					Rule:     "separation",
+					Severity: Error,
+					Employee: a,
+					Week:     week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("shares the %s shift with %s", shift, b),
+				})
+			}
+		}
+	}
+	return out
+}
+
+func checkSafety(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	if len(r.Safety) == 0 {
+		return nil
+	}
+	type window struct{ start, end schedule.Clock }
+	windows := make(map[string][]window)
+	var labels []string
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if _, ok := windows[d.Label]; !ok {
+				windows[d.Label] = nil
+				labels = append(labels, d.Label)
+			}
+			if d.Shift != "" {
+				start, end := dayWindow(s, d)
+				windows[d.Label] = append(windows[d.Label], window{start, end})
+			}
+		}
+	}
+	counts, _ := staffing(entries)
+
+	var out []Violation
+	for _, label := range labels {
+		for _, rule := range r.Safety {
+			name := rule.Label
+			if rule.Shift != "" {
+				n := counts[slot{label, rule.Shift}]
+				if n > 0 && n < rule.MinStaff {
+					if name == "" {
+						name = fmt.Sprintf("at least %d together on the %s shift", rule.MinStaff, rule.Shift)
+					}
+					out = append(out, Violation{
+						Rule:     "safety",
+						Severity: Error,
+						Week:     week,
+						Day:      label,
+						Message:  fmt.Sprintf("%d on the %s shift (%s)", n, rule.Shift, name),
+					})
+				}
+				continue
+			}
+			if rule.After == nil {
+				continue
+			}
+			// Staffing only changes when someone arrives or leaves, so it is
+			// enough to check the rule's start and every start after it.
+			moments := []schedule.Clock{*rule.After}
+			for _, w := range windows[label] {
+				if w.start > *rule.After {
+					moments = append(moments, w.start)
+				}
+				if w.end > *rule.After {
+					moments = append(moments, w.end)
+				}
+			}
+			sort.Slice(moments, func(i, j int) bool { return moments[i] < moments[j] })
+			for _, t := range moments {
+				n := 0
+				for _, w := range windows[label] {
+					if w.start <= t && t < w.end {
+						n++
+					}
+				}
+				if n > 0 && n < rule.MinStaff {
+					if name == "" {
+						name = fmt.Sprintf("at least %d on site after %s", rule.MinStaff, rule.After)
+					}
+					out = append(out, Violation{
+						Rule:     "safety",
+						Severity: Error,
+						Week:     week,
+						Day:      label,
+						Message:  fmt.Sprintf("%d on site at %s (%s)", n, t, name),
+					})
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// checkVolunteers warns about days where a shift went to someone who didn't
+// volunteer for it while a volunteer who could have worked it was on
+// another shift.
+func checkVolunteers(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	if len(r.Volunteers) == 0 {
+		return nil
+	}
+	type key struct{ employee, label string }
+	worked := make(map[key]schedule.Day)
+	var days []schedule.Day
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, d := range e.Days {
+			worked[key{e.Employee, d.Label}] = d
+			if !seen[d.Label] {
+				seen[d.Label] = true
+				days = append(days, d)
+			}
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return (days[i].Weekday+6)%7 < (days[j].Weekday+6)%7 })
+
+	shifts := make([]string, 0, len(r.Volunteers))
+	for shift := range r.Volunteers {
+		shifts = append(shifts, shift)
+	}
+	sort.Strings(shifts)
+
+	var out []Violation
+	for _, d := range days {
+		for _, shift := range shifts {
+			sh, ok := s.Shift(shift)
+			if !ok {
+				continue
+			}
+			volunteer := make(map[string]bool)
+			for _, name := range r.Volunteers[shift] {
+				volunteer[name] = true
+			}
+			var others []string
+			for _, e := range entries {
+				if !volunteer[e.Employee] && worked[key{e.Employee, d.Label}].Shift == shift {
+					others = append(others, e.Employee)
+				}
+			}
+			if len(others) == 0 {
+				continue
+			}
+			for _, name := range r.Volunteers[shift] {
+				day := worked[key{name, d.Label}]
+				if day.Shift == "" || day.Shift == shift {
+					continue
+				}
+				if wh, ok := r.WorkHours[name]; ok && (sh.Start < wh.EarliestStart || sh.End > wh.LatestEnd) {
+					continue
+				}
+				out = append(out, Violation{
+					Rule:     "volunteer",
+					Severity: Warning,
+					Employee: name,
+					Week:     week,
+					Day:      d.Label,
+					Message:  fmt.Sprintf("volunteered for the %s shift but works %s while %s works it", shift, day.Shift, strings.Join(others, ", ")),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// checkSkills flags the shifts of a day worked without anyone having a
+// required skill, and warns about skills only one of them has, since that
+// coverage rests on a single person.
+func checkSkills(s *schedule.Schedule, r Rules, week int, entries []schedule.Entry) []Violation {
+	if len(r.RequiredSkills) == 0 {
+		return nil
+	}
+	type slot struct{ label, shift string }
+	working := make(map[slot][]string)
+	var slots []slot
+	for _, e := range entries {
+		for _, d := range e.Days {
+			if d.Shift == "" || len(r.RequiredSkills[d.Shift]) == 0 {
+				continue
+			}
+			k := slot{d.Label, d.Shift}
+			if _, ok := working[k]; !ok {
+				slots = append(slots, k)
+			}
+			working[k] = append(working[k], e.Employee)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].label != slots[j].label {
+			return slots[i].label < slots[j].label
+		}
+		return slots[i].shift < slots[j].shift
+	})
+
+	var out []Violation
+	for _, k := range slots {
+		for _, skill := range r.RequiredSkills[k.shift] {
+			var holders []string
+			for _, name := range working[k] {
+				if slices.Contains(r.Skills[name], skill) {
+					holders = append(holders, name)
+				}
+			}
+			switch len(holders) {
+			case 0:
+				out = append(out, Violation{
+					Rule:     "skill-coverage",
+					Severity: Error,
+					Week:     week,
+					Day:      k.label,
+					Message:  fmt.Sprintf("no one on the %s shift has the %s skill", k.shift, skill),
+				})
+			case 1:
+				out = append(out, Violation{
+					Rule:     "skill-bus-factor",
+					Severity: Warning,
+					Employee: holders[0],
+					Week:     week,
+					Day:      k.label,
+					Message:  fmt.Sprintf("the only one on the %s shift with the %s skill", k.shift, skill),
+				})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,342 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodCovers(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Time
+		day        schedule.Day
+		today      time.Time
+		want       bool
+	}{
+		{name: "inside", start: date(2026, 10, 19), end: date(2026, 10, 20), day: schedule.Day{DayOfMonth: 20, Month: 10}, today: date(2026, 10, 14), want: true},
+		{name: "same day a year earlier", start: date(2025, 10, 19), end: date(2025, 10, 20), day: schedule.Day{DayOfMonth: 19, Month: 10}, today: date(2026, 10, 14)},
+		{name: "same day a year later", start: date(2027, 10, 19), end: date(2027, 10, 20), day: schedule.Day{DayOfMonth: 19, Month: 10}, today: date(2026, 10, 14)},
+		{name: "across new year", start: date(2026, 12, 30), end: date(2027, 1, 2), day: schedule.Day{DayOfMonth: 1, Month: 1}, today: date(2026, 12, 20), want: true},
+		{name: "long leave in a later year", start: date(2025, 1, 1), end: date(2025, 12, 31), day: schedule.Day{DayOfMonth: 19, Month: 10}, today: date(2026, 10, 14)},
+		{name: "without today", start: date(2026, 10, 19), end: date(2026, 10, 20), day: schedule.Day{DayOfMonth: 19, Month: 10}, want: true},
+		{name: "without a month", start: date(2026, 10, 19), end: date(2026, 10, 20), day: schedule.Day{DayOfMonth: 19}, today: date(2026, 10, 14)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodCovers(tt.start, tt.end, tt.day, tt.today); got != tt.want {
+				t.Errorf("periodCovers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLeaveAndCapacity(t *testing.T) {
+	s := &schedule.Schedule{Shifts: schedule.DefaultShifts}
+	entries := []schedule.Entry{{Employee: "Ann", Week: 1, Days: []schedule.Day{
+		{Weekday: time.Monday, Label: "Monday (19th October)", DayOfMonth: 19, Month: 10, Shift: "Late"},
+		{Weekday: time.Tuesday, Label: "Tuesday (20th October)", DayOfMonth: 20, Month: 10, Shift: "Late"},
+	}}}
+	today := date(2026, 10, 14)
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       int
+	}{
+		{name: "this year", start: date(2026, 10, 19), end: date(2026, 10, 19), want: 1},
+		{name: "last year", start: date(2025, 10, 19), end: date(2025, 10, 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := DefaultRules()
+			r.Today = today
+			r.Leave = map[string][]Leave{"Ann": {{Start: tt.start, End: tt.end}}}
+			r.Capacity = map[string][]Capacity{"Ann": {{Start: tt.start, End: tt.end, NoShifts: []string{"Late"}}}}
+			if got := len(checkLeave(s, r, "Ann", entries)); got != tt.want {
+				t.Errorf("%d leave violations, want %d", got, tt.want)
+			}
+			if got := len(checkCapacity(s, r, "Ann", entries)); got != tt.want {
+				t.Errorf("%d capacity violations, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// week returns a week of Ann's days from Monday, with "" for a day off and
+// a trailing "+" for overtime.
+func week(n int, shifts ...string) schedule.Entry {
+	e := schedule.Entry{Employee: "Ann", Week: n}
+	for i, sh := range shifts {
+		wd := time.Weekday((i + 1) % 7)
+		name, overtime := strings.CutSuffix(sh, "+")
+		e.Days = append(e.Days, schedule.Day{Weekday: wd, Label: wd.String(), Shift: name, Overtime: overtime})
+	}
+	return e
+}
+
+// ruleNames returns the rules of violations, in order.
+func ruleNames(violations []Violation) []string {
+	var names []string
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+// employeeCheckTest is a case for an EmployeeCheck run on Ann's weeks.
+type employeeCheckTest struct {
+	name    string
+	rules   func(*Rules)
+	entries []schedule.Entry
+	want    []string
+}
+
+func runEmployeeChecks(t *testing.T, check EmployeeCheck, tests []employeeCheckTest) {
+	t.Helper()
+	s := &schedule.Schedule{Shifts: schedule.DefaultShifts}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := DefaultRules()
+			if tt.rules != nil {
+				tt.rules(&r)
+			}
+			if got := ruleNames(check(s, r, "Ann", tt.entries)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMonthlyHours(t *testing.T) {
+	fiveDays := []string{"Early", "Early", "Early", "Early", "Early", "", ""}
+	runEmployeeChecks(t, checkMonthlyHours, []employeeCheckTest{
+		{
+			name:    "at the limit",
+			entries: []schedule.Entry{week(1, fiveDays...), week(2, fiveDays...), week(3, fiveDays...), week(4, fiveDays...), week(5, fiveDays...)},
+		},
+		{
+			name:    "over the limit",
+			entries: []schedule.Entry{week(1, fiveDays...), week(2, fiveDays...), week(3, fiveDays...), week(4, fiveDays...), week(5, "Early", "Early", "Early", "Early", "Early", "Early", "")},
+			want:    []string{"monthly-hours"},
+		},
+	})
+}
+
+func TestValidateWorkers(t *testing.T) {
+	s := &schedule.Schedule{Shifts: schedule.DefaultShifts}
+	for i := range 40 {
+		for w := 1; w <= 5; w++ {
+			e := week(w, "Early", "Early", "Early", "Early", "Early", "Early", "")
+			e.Employee = fmt.Sprintf("Employee %02d", i)
+			s.Entries = append(s.Entries, e)
+		}
+	}
+	want := New(DefaultRules()).Validate(s)
+	if len(want) == 0 {
+		t.Fatal("no violations to compare")
+	}
+	for _, workers := range []int{1, 3, 16} {
+		v := New(DefaultRules())
+		v.Workers = workers
+		if got := v.Validate(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: violations differ from the default pool", workers)
+		}
+	}
+}
+
+func TestCheckShiftLengths(t *testing.T) {
+	fourDays := []schedule.Entry{week(1, "Early", "Late", "Early", "Normal", "", "", "")}
+	runEmployeeChecks(t, checkShiftLengths, []employeeCheckTest{
+		{name: "no limits", entries: fourDays},
+		{name: "within the limit", rules: func(r *Rules) { r.LengthLimits = map[float64]int{9: 4} }, entries: fourDays},
+		{name: "over the limit", rules: func(r *Rules) { r.LengthLimits = map[float64]int{9: 3} }, entries: fourDays, want: []string{"shift-length"}},
+		{name: "limit on another length", rules: func(r *Rules) { r.LengthLimits = map[float64]int{12: 1} }, entries: fourDays},
+	})
+}
+
+func TestCheckWorkdays(t *testing.T) {
+	fourDays := func(r *Rules) { r.Workdays = map[string]int{"Ann": 4} }
+	runEmployeeChecks(t, checkWorkdays, []employeeCheckTest{
+		{name: "no pattern", entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "", "")}},
+		{name: "compressed week kept", rules: fourDays, entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "", "", "")}},
+		{name: "compressed week broken", rules: fourDays, entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "", "")}, want: []string{"workweek"}},
+	})
+}
+
+func TestCheckConsecutiveShifts(t *testing.T) {
+	runEmployeeChecks(t, checkConsecutiveShifts, []employeeCheckTest{
+		{name: "six days in a row", entries: []schedule.Entry{week(1, "Late", "Late", "Late", "Late", "Late", "Late", "")}, want: []string{"consecutive-shift"}},
+		{name: "broken by another shift", entries: []schedule.Entry{week(1, "Late", "Late", "Late", "Late", "Late", "Early", "")}},
+		{name: "broken by a day off", entries: []schedule.Entry{week(1, "Late", "Late", "Late", "", "Late", "Late", "Late")}},
+		{
+			name:    "across weeks",
+			entries: []schedule.Entry{week(1, "", "", "", "Late", "Late", "Late", "Late"), week(2, "Late", "Late", "", "", "", "", "")},
+			want:    []string{"consecutive-shift"},
+		},
+		{name: "configured limit", rules: func(r *Rules) { r.MaxConsecutiveSameShift = 3 }, entries: []schedule.Entry{week(1, "Late", "Late", "Late", "Late", "", "", "")}, want: []string{"consecutive-shift"}},
+	})
+}
+
+func TestCheckOvertime(t *testing.T) {
+	sixth := []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "Early+", "")}
+	runEmployeeChecks(t, checkOvertime, []employeeCheckTest{
+		{name: "no caps", entries: sixth},
+		{name: "within the caps", rules: func(r *Rules) { r.MaxDailyOvertime, r.MaxWeeklyOvertime = 9, 18 }, entries: sixth},
+		{name: "over the daily and weekly caps", rules: func(r *Rules) { r.MaxDailyOvertime, r.MaxWeeklyOvertime = 8, 8 }, entries: sixth, want: []string{"daily-overtime", "weekly-overtime"}},
+	})
+	// Overtime doesn't count towards the weekly hour cap.
+	runEmployeeChecks(t, checkWeeklyHours, []employeeCheckTest{{name: "overtime over 45 hours", entries: sixth}})
+}
+
+func TestCheckWeeklyHours(t *testing.T) {
+	runEmployeeChecks(t, checkWeeklyHours, []employeeCheckTest{
+		{name: "at 45 hours", entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "", "")}},
+		{name: "over 45 hours", entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "Early", "")}, want: []string{"weekly-hours"}},
+	})
+}
+
+func TestCheckRotation(t *testing.T) {
+	early := []string{"Early", "Early", "Early", "Early", "Early", "", ""}
+	late := []string{"Late", "Late", "Late", "Late", "Late", "", ""}
+	runEmployeeChecks(t, checkRotation, []employeeCheckTest{
+		{name: "rotated weekly", entries: []schedule.Entry{week(1, early...), week(2, late...)}},
+		{name: "not rotated", entries: []schedule.Entry{week(1, early...), week(2, early...)}, want: []string{"rotation"}},
+		{name: "week off between", entries: []schedule.Entry{week(1, early...), week(2, "", "", "", "", "", "", ""), week(3, early...)}},
+		{name: "every two weeks", rules: func(r *Rules) { r.RotationWeeks = 2 }, entries: []schedule.Entry{week(1, early...), week(2, early...), week(3, late...)}},
+		{name: "rotated early", rules: func(r *Rules) { r.RotationWeeks = 2 }, entries: []schedule.Entry{week(1, early...), week(2, late...)}, want: []string{"rotation"}},
+	})
+}
+
+func TestCheckDaysOff(t *testing.T) {
+	threeOff := []schedule.Entry{{Employee: "Ann", Week: 1, Days: []schedule.Day{
+		{Weekday: time.Monday, Label: "Monday (19th October)", DayOfMonth: 19, Month: 10},
+		{Weekday: time.Tuesday, Label: "Tuesday (20th October)", DayOfMonth: 20, Month: 10, Shift: "Early"},
+		{Weekday: time.Wednesday, Label: "Wednesday (21st October)", DayOfMonth: 21, Month: 10, Shift: "Early"},
+		{Weekday: time.Thursday, Label: "Thursday (22nd October)", DayOfMonth: 22, Month: 10, Shift: "Early"},
+		{Weekday: time.Friday, Label: "Friday (23rd October)", DayOfMonth: 23, Month: 10, Shift: "Early"},
+		{Weekday: time.Saturday, Label: "Saturday (24th October)", DayOfMonth: 24, Month: 10},
+		{Weekday: time.Sunday, Label: "Sunday (25th October)", DayOfMonth: 25, Month: 10},
+	}}}
+	runEmployeeChecks(t, checkDaysOff, []employeeCheckTest{
+		{name: "two days off", entries: []schedule.Entry{week(1, "Early", "Early", "Early", "Early", "Early", "", "")}},
+		{name: "three days off", entries: threeOff, want: []string{"days-off"}},
+		{
+			name: "a day off on leave",
+			rules: func(r *Rules) {
+				r.Today = date(2026, 10, 14)
+				r.Leave = map[string][]Leave{"Ann": {{Start: date(2026, 10, 19), End: date(2026, 10, 19)}}}
+			},
+			entries: threeOff,
+		},
+		{name: "compressed week", rules: func(r *Rules) { r.Workdays = map[string]int{"Ann": 4} }, entries: threeOff},
+	})
+}
+
+func TestCheckCoverage(t *testing.T) {
+	s := &schedule.Schedule{Shifts: schedule.DefaultShifts}
+	staff := func(shifts ...string) []schedule.Entry {
+		var entries []schedule.Entry
+		for i, sh := range shifts {
+			e := week(1, sh)
+			e.Employee = fmt.Sprintf("Employee %d", i)
+			entries = append(entries, e)
+		}
+		return entries
+	}
+	tests := []struct {
+		name    string
+		entries []schedule.Entry
+		want    int
+	}{
+		{name: "two on every shift", entries: staff("Early", "Early", "Normal", "Normal", "Late", "Late")},
+		{name: "one short", entries: staff("Early", "Early", "Normal", "Late", "Late"), want: 1},
+		{name: "nobody on a shift", entries: staff("Early", "Early", "Normal", "Normal"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkCoverage(s, DefaultRules(), 1, tt.entries)
+			if len(got) != tt.want {
+				t.Errorf("%d coverage violations, want %d: %v", len(got), tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckRotationDirection(t *testing.T) {
+	tests := []employeeCheckTest{
+		{name: "forward", entries: []schedule.Entry{week(1, "Early", "Normal", "Late", "", "", "", "")}},
+		{name: "backward without rest", entries: []schedule.Entry{week(1, "Late", "Early", "", "", "", "", "")}, want: []string{"rotation-direction"}},
+		{name: "latest to earliest after rest", entries: []schedule.Entry{week(1, "Late", "", "Early", "", "", "", "")}},
+		{name: "back to a middle shift after rest", entries: []schedule.Entry{week(1, "Late", "", "Normal", "", "", "", "")}, want: []string{"rotation-direction"}},
+		{name: "backward allowed", rules: func(r *Rules) { r.ForwardRotation = false }, entries: []schedule.Entry{week(1, "Late", "Early", "", "", "", "", "")}},
+	}
+	runEmployeeChecks(t, checkRotationAfterRest, append(tests, employeeCheckTest{
+		name: "middle to earliest after rest", entries: []schedule.Entry{week(1, "Normal", "", "Early", "", "", "", "")},
+	}))
+	// Rule pack 1 allows the move back only from the latest shift.
+	runEmployeeChecks(t, checkRotationDirection, append(tests, employeeCheckTest{
+		name: "middle to earliest after rest", entries: []schedule.Entry{week(1, "Normal", "", "Early", "", "", "", "")}, want: []string{"rotation-direction"},
+	}))
+}
+
+func TestCheckMonthlyNightShifts(t *testing.T) {
+	// lates returns n Late shifts from a date on, one a day.
+	lates := func(from time.Time, n int) []schedule.Day {
+		var days []schedule.Day
+		for i := range n {
+			d := from.AddDate(0, 0, i)
+			days = append(days, schedule.Day{Weekday: d.Weekday(), Label: d.Format("Monday (2 January)"), DayOfMonth: d.Day(), Month: d.Month(), Shift: "Late"})
+		}
+		return days
+	}
+	capTwo := func(r *Rules) {
+		r.Today = date(2026, 10, 14)
+		r.NightShifts = []string{"Late"}
+		r.NightShiftCaps = map[string]int{"Ann": 2}
+	}
+	tests := []struct {
+		name    string
+		entries []schedule.Entry
+		want    []string
+	}{
+		{
+			name:    "two in each month",
+			entries: []schedule.Entry{{Employee: "Ann", Week: 1, Days: lates(date(2026, 10, 30), 4)}},
+		},
+		{
+			name:    "three in one month",
+			entries: []schedule.Entry{{Employee: "Ann", Week: 1, Days: lates(date(2026, 10, 29), 4)}},
+			want:    []string{"scheduled 3 night shifts in October 2026, more than the 2 allowed per month"},
+		},
+	}
+	s := &schedule.Schedule{Shifts: schedule.DefaultShifts}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := DefaultRules()
+			capTwo(&r)
+			var got []string
+			for _, v := range checkMonthlyNightShifts(s, r, "Ann", tt.entries) {
+				got = append(got, v.Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations %q, want %q", got, tt.want)
+			}
+		})
+	}
+	// Rule pack 1 counts the whole schedule.
+	runEmployeeChecks(t, checkNightShifts, []employeeCheckTest{{
+		name:    "whole schedule",
+		rules:   capTwo,
+		entries: []schedule.Entry{{Employee: "Ann", Week: 1, Days: lates(date(2026, 10, 30), 4)}},
+		want:    []string{"night-shifts"},
+	}})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runView renders a published schedule without changing anything: as a
+// list of shifts, an iCalendar file, or in an export template's layout,
+// optionally for one employee or a range of days.
+func runView(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	employee := fs.String("employee", "", "show only this employee's shifts")
+	from := fs.String("from", "", "first day to show, e.g. 2025-03-10")
+	to := fs.String("to", "", "last day to show")
+	format := fs.String("format", "text", "text, ics, or an export template: "+strings.Join(exportTemplateNames(cfg), ", "))
+	out := fs.String("out", "", "file to write instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: view [-employee name] [-from date] [-to date] [-format text|ics|template] [-out file] <schedule dir>")
+	}
+	var first, last time.Time
+	if *from != "" {
+		if first, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("invalid -from date: %w", err)
+		}
+	}
+	if *to != "" {
+		if last, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("invalid -to date: %w", err)
+		}
+	}
+	var t ExportTemplate
+	if *format != "text" && *format != "ics" {
+		if t, err = exportTemplate(cfg, *format); err != nil {
+			return err
+		}
+	}
+
+	entries, err := loadScheduleDir(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	catalog, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var shifts []exportedShift
+	for _, s := range exportedShifts(entries, catalog, now) {
+		day := time.Date(s.Start.Year(), s.Start.Month(), s.Start.Day(), 0, 0, 0, 0, time.UTC)
+		if *employee != "" && s.Employee != *employee {
+			continue
+		}
+		if (!first.IsZero() && day.Before(first)) || (!last.IsZero() && day.After(last)) {
+			continue
+		}
+		shifts = append(shifts, s)
+	}
+	if *employee != "" && len(shifts) == 0 {
+		log.Printf("No shifts for %s in %s", *employee, scheduleDir(fs.Arg(0)))
+	}
+	if err := attachHandoverNotes(shifts); err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %w", *out, err)
+		}
+		defer file.Close()
+		w = file
+	}
+	switch *format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tEMPLOYEE\tSHIFT\tTIME")
+		for _, s := range shifts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s-%s\n", s.Start.Format("Mon 2006-01-02"), s.Employee, s.Shift, s.Start.Format("15:04"), s.End.Format("15:04"))
+		}
+		return tw.Flush()
+	case "ics":
+		name := "Schedule"
+		if *employee != "" {
+			name = *employee
+		}
+		writeICS(w, name, shifts, *employee == "", now)
+		return nil
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, e := range roster {
+		ids[e.Name] = e.HRISID
+	}
+	if err := writeExport(w, t, shifts, ids); err != nil {
+		return fmt.Errorf("error writing export: %w", err)
+	}
+	return nil
+}
+
+// runViewer is the whole of the viewer edition: the view command, taken
+// with or without its name.
+func runViewer() {
+	args, err := viewerArgs(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := runView(args); err != nil {
+		log.Fatalf("Error running view: %v", err)
+	}
+}
+
+// viewerArgs takes the global flags and the optional view command name off
+// the viewer edition's args, leaving those of view.
+func viewerArgs(args []string) ([]string, error) {
+	args, err := parseGlobalFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "view" {
+		args = args[1:]
+	}
+	return args, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// rosterVolunteers returns, per shift, the scheduled employees who
+// volunteered for more of it.
+func rosterVolunteers(roster []Employee, employees []string, shifts []schedule.Shift) (map[string][]string, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	volunteers := make(map[string][]string)
+	for _, e := range roster {
+		if !scheduled[e.Name] {
+			continue
+		}
+		for _, shift := range e.VolunteerShifts {
+			if _, found := findShift(shifts, shift); !found {
+				return nil, fmt.Errorf("employee %s volunteers for unknown shift %q", e.Name, shift)
+			}
+			volunteers[shift] = append(volunteers[shift], e.Name)
+		}
+	}
+	for _, names := range volunteers {
+		sort.Strings(names)
+	}
+	return volunteers, nil
+}
+
+// volunteerNote asks the model to give volunteered shifts to their
+// volunteers first and share out the rest evenly.
+func volunteerNote(volunteers map[string][]string) string {
+	shifts := make([]string, 0, len(volunteers))
+	for shift := range volunteers {
+		shifts = append(shifts, shift)
+	}
+	sort.Strings(shifts)
+	var parts []string
+	for _, shift := range shifts {
+		parts = append(parts, fmt.Sprintf("%s (%s)", shift, strings.Join(volunteers[shift], ", ")))
+	}
+	return "Volunteers: " + strings.Join(parts, "; ") +
+		". Give these shifts to their volunteers first, as far as the other constraints allow, then spread the remaining ones evenly across everyone else."
+}
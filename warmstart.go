@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-schedular/schedule"
+)
+
+// schedulePattern is each employee's usual shift on each weekday, indexed
+// from Sunday, in a previous schedule. An empty shift is a day off.
+type schedulePattern map[string][7]string
+
+// previousPattern returns the pattern of the schedule in dir, or of its
+// latest run folder, or nil when there is none.
+func previousPattern(dir string, shifts []schedule.Shift) (schedulePattern, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	dir = scheduleDir(dir)
+	if paths, _ := filepath.Glob(filepath.Join(dir, "generated_schedule_*.csv")); len(paths) == 0 {
+		return nil, nil
+	}
+	entries, err := loadScheduleDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]map[string]string, len(entries))
+	for i, obj := range entries {
+		objs[i] = obj
+	}
+	sch, err := schedule.Parse(objs, shifts)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing previous schedule: %w", err)
+	}
+	return patternOf(sch), nil
+}
+
+// patternOf returns the shift each employee worked most often on each
+// weekday, the latest of equally frequent ones.
+func patternOf(sch *schedule.Schedule) schedulePattern {
+	type key struct {
+		employee string
+		weekday  time.Weekday
+	}
+	counts := make(map[key]map[string]int)
+	pattern := make(schedulePattern)
+	for _, e := range sch.Entries {
+		for _, d := range e.Days {
+			k := key{e.Employee, d.Weekday}
+			if counts[k] == nil {
+				counts[k] = make(map[string]int)
+			}
+			counts[k][d.Shift]++
+			days := pattern[e.Employee]
+			if counts[k][d.Shift] >= counts[k][days[d.Weekday]] {
+				days[d.Weekday] = d.Shift
+			}
+			pattern[e.Employee] = days
+		}
+	}
+	return pattern
+}
+
+// stability returns the share of the days of a schedule's employees with a
+// previous pattern on which they work their usual shift or stay off as
+// usual, and false when no employee has one.
+func (p schedulePattern) stability(sch *schedule.Schedule) (float64, bool) {
+	kept, total := 0, 0
+	for _, e := range sch.Entries {
+		days, ok := p[e.Employee]
+		if !ok {
+			continue
+		}
+		for _, d := range e.Days {
+			total++
+			if d.Shift == days[d.Weekday] {
+				kept++
+			}
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(kept) / float64(total), true
+}
+
+// note describes the pattern of the employees for the scheduling prompt.
+func (p schedulePattern) note(employees []string) string {
+	var rhythms []string
+	for _, name := range employees {
+		days, ok := p[name]
+		if !ok {
+			continue
+		}
+		var parts []string
+		for i := range 7 {
+			weekday := time.Weekday((i + 1) % 7)
+			shift := days[weekday]
+			if shift == "" {
+				shift = "off"
+			}
+			parts = append(parts, weekday.String()[:3]+" "+shift)
+		}
+		rhythms = append(rhythms, name+": "+strings.Join(parts, ", "))
+	}
+	if len(rhythms) == 0 {
+		return ""
+	}
+	sort.Strings(rhythms)
+	return "Previous schedule: keep each employee's usual weekly rhythm from the last schedule unless a constraint or the shift targets force a change. " +
+		strings.Join(rhythms, "; ") + "."
+}
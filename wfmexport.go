@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"employee-schedular/schedule"
+)
+
+// Fields an export column can hold.
+const (
+	fieldEmployee      = "employee"
+	fieldEmployeeID    = "employee_id"
+	fieldDate          = "date"
+	fieldShift         = "shift"
+	fieldActivity      = "activity"
+	fieldStart         = "start"
+	fieldEnd           = "end"
+	fieldStartDateTime = "start_datetime"
+	fieldEndDateTime   = "end_datetime"
+	fieldHours         = "hours"
+	fieldMinutes       = "minutes"
+	fieldNote          = "note"
+)
+
+// ExportColumn is one column of an export template.
+type ExportColumn struct {
+	Header string `json:"header"`
+	Field  string `json:"field"`
+}
+
+// ExportTemplate lays out a schedule export as one row per employee and
+// worked day, for the import formats of workforce-management tools.
+type ExportTemplate struct {
+	Delimiter string `json:"delimiter,omitempty"`
+	// DateLayout and TimeLayout are Go reference-time layouts, 2006-01-02
+	// and 15:04 by default. Datetime fields join them with a space.
+	DateLayout string `json:"date_layout,omitempty"`
+	TimeLayout string `json:"time_layout,omitempty"`
+	// Activity is written in activity columns; the shift name is used when
+	// it is empty.
+	Activity string         `json:"activity,omitempty"`
+	Columns  []ExportColumn `json:"columns"`
+}
+
+// exportTemplates are the built-in layouts. Further templates can be added
+// under export_templates in the config file.
+var exportTemplates = map[string]ExportTemplate{
+	// NICE IEX agent schedule import.
+	"nice-iex": {
+		DateLayout: "01/02/2006",
+		Activity:   "Open",
+		Columns: []ExportColumn{
+			{"Agent ID", fieldEmployeeID},
+			{"Date", fieldDate},
+			{"Start Time", fieldStart},
+			{"End Time", fieldEnd},
+			{"Activity", fieldActivity},
+		},
+	},
+	// Verint WFM shift import.
+	"verint": {
+		Activity: "Work",
+		Columns: []ExportColumn{
+			{"Employee ID", fieldEmployeeID},
+			{"Activity", fieldActivity},
+			{"Start", fieldStartDateTime},
+			{"End", fieldEndDateTime},
+			{"Duration", fieldMinutes},
+		},
+	},
+	// One row per shift with every field, for spreadsheets and custom
+	// imports.
+	"generic": {
+		Columns: []ExportColumn{
+			{"employee", fieldEmployee},
+			{"date", fieldDate},
+			{"shift", fieldShift},
+			{"start", fieldStart},
+			{"end", fieldEnd},
+			{"hours", fieldHours},
+			{"note", fieldNote},
+		},
+	},
+}
+
+// exportTemplate returns a built-in or configured template.
+func exportTemplate(cfg Config, name string) (ExportTemplate, error) {
+	t, ok := cfg.ExportTemplates[name]
+	if !ok {
+		t, ok = exportTemplates[strings.ToLower(name)]
+	}
+	if !ok {
+		return ExportTemplate{}, fmt.Errorf("unknown export template %q (want one of %s)", name, strings.Join(exportTemplateNames(cfg), ", "))
+	}
+	if t.Delimiter == "" {
+		t.Delimiter = ","
+	}
+	if t.DateLayout == "" {
+		t.DateLayout = time.DateOnly
+	}
+	if t.TimeLayout == "" {
+		t.TimeLayout = "15:04"
+	}
+	for _, c := range t.Columns {
+		switch c.Field {
+		case fieldEmployee, fieldEmployeeID, fieldDate, fieldShift, fieldActivity, fieldStart, fieldEnd, fieldStartDateTime, fieldEndDateTime, fieldHours, fieldMinutes, fieldNote:
+		default:
+			return ExportTemplate{}, fmt.Errorf("export template %s: unknown field %q", name, c.Field)
+		}
+	}
+	return t, nil
+}
+
+// exportTemplateNames returns the names of the built-in and configured
+// templates in order.
+func exportTemplateNames(cfg Config) []string {
+	names := make([]string, 0, len(exportTemplates)+len(cfg.ExportTemplates))
+	for name := range exportTemplates {
+		names = append(names, name)
+	}
+	for name := range cfg.ExportTemplates {
+		if _, ok := exportTemplates[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exportedShift is one worked day of the schedule being exported.
+type exportedShift struct {
+	Employee   string
+	Shift      string
+	Start, End time.Time
+	// Note is the handover note of the shift's date and shift, if any.
+	Note string
+}
+
+// exportedShifts lists the worked days of a schedule in employee and date
+// order. The day columns carry no year, so the one nearest now is used.
+func exportedShifts(entries []FlatSchedule, shifts []schedule.Shift, now time.Time) []exportedShift {
+	var out []exportedShift
+	for _, obj := range entries {
+		for label, value := range obj {
+			date, ok := labelDate(label, now)
+			if !ok {
+				continue
+			}
+			start, end, found := cellWindow(shifts, value)
+			if !found {
+				continue
+			}
+			name, _ := schedule.SplitCell(value)
+			out = append(out, exportedShift{
+				Employee: obj["Employee"],
+				Shift:    name,
+				Start:    date.Add(time.Duration(start) * time.Minute),
+				End:      date.Add(time.Duration(end) * time.Minute),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Employee != out[j].Employee {
+			return out[i].Employee < out[j].Employee
+		}
+		return out[i].Start.Before(out[j].Start)
+	})
+	return out
+}
+
+// writeExport writes shifts in a template's layout. ids maps employees to
+// their IDs in the receiving system; employees without one use their name.
+func writeExport(w io.Writer, t ExportTemplate, shifts []exportedShift, ids map[string]string) error {
+	cw := csv.NewWriter(w)
+	cw.Comma, _ = utf8.DecodeRuneInString(t.Delimiter)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Header
+	}
+	cw.Write(header)
+	for _, s := range shifts {
+		row := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			switch c.Field {
+			case fieldEmployee:
+				row[i] = s.Employee
+			case fieldEmployeeID:
+				row[i] = s.Employee
+				if id := ids[s.Employee]; id != "" {
+					row[i] = id
+				}
+			case fieldDate:
+				row[i] = s.Start.Format(t.DateLayout)
+			case fieldShift:
+				row[i] = s.Shift
+			case fieldActivity:
+				row[i] = t.Activity
+				if row[i] == "" {
+					row[i] = s.Shift
+				}
+			case fieldStart:
+				row[i] = s.Start.Format(t.TimeLayout)
+			case fieldEnd:
+				row[i] = s.End.Format(t.TimeLayout)
+			case fieldStartDateTime:
+				row[i] = s.Start.Format(t.DateLayout + " " + t.TimeLayout)
+			case fieldEndDateTime:
+				row[i] = s.End.Format(t.DateLayout + " " + t.TimeLayout)
+			case fieldHours:
+				row[i] = strconv.FormatFloat(s.End.Sub(s.Start).Hours(), 'f', -1, 64)
+			case fieldMinutes:
+				row[i] = strconv.Itoa(int(s.End.Sub(s.Start).Minutes()))
+			case fieldNote:
+				row[i] = s.Note
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runExport writes a published schedule in the import format of a
+// workforce-management tool.
+func runExport(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	name := fs.String("template", "generic", "export layout: "+strings.Join(exportTemplateNames(cfg), ", "))
+	out := fs.String("out", "", "file to write instead of standard output")
+	redact := fs.Bool("redact", false, "replace employee names and IDs with stable pseudonyms")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: export [-template name] [-out file] [-redact] <schedule dir>")
+	}
+	t, err := exportTemplate(cfg, *name)
+	if err != nil {
+		return err
+	}
+	entries, err := loadScheduleDir(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	shifts, err := shiftCatalog(cfg)
+	if err != nil {
+		return err
+	}
+	roster, err := loadRoster()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, e := range roster {
+		ids[e.Name] = e.HRISID
+	}
+	if *redact {
+		// Pseudonyms stand in for the IDs too, which would identify
+		// employees as well as their names.
+		if entries, err = redactEntries(entries); err != nil {
+			return err
+		}
+		ids = nil
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("error creating export: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	rows := exportedShifts(entries, shifts, time.Now())
+	if err := attachHandoverNotes(rows); err != nil {
+		return err
+	}
+	if err := writeExport(w, t, rows, ids); err != nil {
+		return fmt.Errorf("error writing export: %w", err)
+	}
+	if *out != "" {
+		log.Printf("Exported %d shifts to %s", len(rows), *out)
+	}
+	return nil
+}
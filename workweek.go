@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"employee-schedular/schedule"
+)
+
+// workweek is a compressed pattern such as 4x10: Days working days a week
+// of Hours hours each.
+type workweek struct {
+	Days  int
+	Hours float64
+}
+
+func (w workweek) String() string {
+	return fmt.Sprintf("%dx%g", w.Days, w.Hours)
+}
+
+// parseWorkweek parses a pattern written "<days>x<hours>".
+func parseWorkweek(s string) (workweek, error) {
+	days, hours, found := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !found {
+		return workweek{}, fmt.Errorf("invalid workweek %q: want <days>x<hours>, e.g. 4x10", s)
+	}
+	var w workweek
+	var err error
+	if w.Days, err = strconv.Atoi(days); err != nil || w.Days < 1 || w.Days > 7 {
+		return workweek{}, fmt.Errorf("invalid workweek %q: days must be 1 to 7", s)
+	}
+	if w.Hours, err = strconv.ParseFloat(hours, 64); err != nil || w.Hours <= 0 || w.Hours >= 24 {
+		return workweek{}, fmt.Errorf("invalid workweek %q: hours must be between 0 and 24", s)
+	}
+	return w, nil
+}
+
+// rosterWorkweeks returns the compressed workweeks of the given employees
+// as recorded in the roster.
+func rosterWorkweeks(roster []Employee, employees []string) (map[string]workweek, error) {
+	scheduled := make(map[string]bool, len(employees))
+	for _, name := range employees {
+		scheduled[name] = true
+	}
+	patterns := make(map[string]workweek)
+	for _, e := range roster {
+		if e.Workweek == "" || !scheduled[e.Name] {
+			continue
+		}
+		w, err := parseWorkweek(e.Workweek)
+		if err != nil {
+			return nil, fmt.Errorf("employee %s: %w", e.Name, err)
+		}
+		patterns[e.Name] = w
+	}
+	return patterns, nil
+}
+
+// workweekNote describes the compressed workweeks for the scheduling prompt.
+func workweekNote(patterns map[string]workweek) string {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		w := patterns[name]
+		parts = append(parts, fmt.Sprintf("%s works exactly %d days of %g hours", name, w.Days, w.Hours))
+	}
+	return "Compressed workweeks: " + strings.Join(parts, "; ") +
+		" each week, starting at the start of their shift. Count their longer days toward the hour limits."
+}
+
+// applyWorkweeks lengthens the working days of employees on a compressed
+// workweek to their pattern's hours, from the start of their shift or of
+// their staggered start. Days that would run past midnight end at the end
+// of the shift instead.
+func applyWorkweeks(weeks map[string][]FlatSchedule, shifts []schedule.Shift, patterns map[string]workweek) {
+	for _, objs := range weeks {
+		for _, obj := range objs {
+			w, ok := patterns[obj["Employee"]]
+			if !ok {
+				continue
+			}
+			for key, value := range obj {
+				if !strings.Contains(key, "(") {
+					continue
+				}
+				name, hours := schedule.SplitCell(value)
+				sh, ok := findShift(shifts, name)
+				if !ok {
+					continue
+				}
+				start, end := sh.Start, sh.End
+				if from, to, err := schedule.ParseWindow(hours); err == nil {
+					start, end = from, to
+				}
+				start, end = w.day(start, end)
+				obj[key] = schedule.FormatCell(name, start, end)
+			}
+		}
+	}
+}
+
+// day returns the hours of a compressed day on a shift from start to end:
+// the pattern's hours from the start, or up to the end when they would run
+// past midnight.
+func (w workweek) day(start, end schedule.Clock) (schedule.Clock, schedule.Clock) {
+	length := schedule.Clock(w.Hours * 60)
+	if start+length >= 24*60 {
+		start = max(end-length, 0)
+	}
+	return start, start + length
+}
+
+// workdayCounts returns the days per week each compressed employee works,
+// for validation.
+func workdayCounts(patterns map[string]workweek) map[string]int {
+	if len(patterns) == 0 {
+		return nil
+	}
+	days := make(map[string]int, len(patterns))
+	for name, w := range patterns {
+		days[name] = w.Days
+	}
+	return days
+}
+
+// annotateHours sets the Hours column of every row to the hours the row
+// schedules, so exports can be used for payroll as they are.
+func annotateHours(objs []FlatSchedule, shifts []schedule.Shift) {
+	for _, obj := range objs {
+		total := 0.0
+		for key, value := range obj {
+			if !strings.Contains(key, "(") {
+				continue
+			}
+			if h, ok := cellHours(shifts, value); ok {
+				total += h
+			}
+		}
+		obj["Hours"] = strconv.FormatFloat(total, 'f', -1, 64)
+	}
+}